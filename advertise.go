@@ -0,0 +1,110 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AddressAnnotation records on the lock the address the leader advertises
+// with WithAdvertiseAddress, as host:port, or host alone without a port.
+const AddressAnnotation = "leader.seamounts.io/address"
+
+// ErrNoLeader is returned when the address of the leader is asked for while
+// no pod holds the lock.
+var ErrNoLeader = errors.New("no pod holds the lock")
+
+// advertisedAddress returns the address pod advertises under o, or "" if it
+// advertises none. An empty host is the pod IP, taken from the pod or, if
+// its status did not have one yet, from the POD_IP env var.
+func (o *options) advertisedAddress(pod *v1.Pod) string {
+	if !o.advertise {
+		return ""
+	}
+	host := o.advertiseHost
+	if host == "" {
+		host = pod.Status.PodIP
+	}
+	if host == "" {
+		host = os.Getenv(PodIPEnvVar)
+	}
+	if host == "" {
+		return ""
+	}
+	if o.advertisePort == 0 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(o.advertisePort))
+}
+
+// LeaderAddress returns the address of the pod holding the lock. See
+// ResolveLeaderAddress.
+func (e *PodElector) LeaderAddress(ctx context.Context) (_ string, err error) {
+	defer func() { err = e.wrap("resolve leader address of", err) }()
+	return resolveAddress(ctx, e.lockBackend(), e.kube(), e.ns, e.lockName, e.opts.requestTimeout)
+}
+
+// ResolveLeaderAddress returns the address of the pod holding lockName, for
+// clients that connect to the leader, such as proxies and redirects. It is
+// the address the leader advertises with WithAdvertiseAddress or, if it
+// advertises none, the IP of its pod. The options select the client,
+// namespace and backend as they do for an Elector. If the lock is free it
+// returns ErrNoLeader.
+func ResolveLeaderAddress(ctx context.Context, lockName string, opts ...Option) (string, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	client, ns, err := clientAndNamespace(&o)
+	if err != nil {
+		return "", err
+	}
+	b, err := newBackend(o.backend, client, ns, o.requestTimeout, o.getLogger())
+	if err != nil {
+		return "", err
+	}
+	addr, err := resolveAddress(ctx, b, client, ns, lockName, o.requestTimeout)
+	if err != nil {
+		return "", fmt.Errorf("resolve leader address of lock %s/%s: %w", ns, lockName, err)
+	}
+	return addr, nil
+}
+
+func resolveAddress(ctx context.Context, b backend, client kubernetes.Interface, ns, lockName string, timeout time.Duration) (string, error) {
+	lock, err := b.Get(ctx, lockName)
+	switch {
+	case apierrors.IsNotFound(err):
+		return "", ErrNoLeader
+	case err != nil:
+		return "", err
+	}
+	if addr := lock.GetAnnotations()[AddressAnnotation]; addr != "" {
+		return addr, nil
+	}
+
+	leader := lockStatus(lock).Leader
+	if leader == "" {
+		return "", ErrNoLeader
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	pod, err := client.CoreV1().Pods(ns).Get(ctx, leader, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return "", ErrNoLeader
+	case err != nil:
+		return "", forbidden(err, "get", v1.Resource("pods"), ns)
+	case pod.Status.PodIP == "":
+		return "", fmt.Errorf("leader pod %s has no IP", leader)
+	}
+	return pod.Status.PodIP, nil
+}
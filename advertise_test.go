@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAdvertisedAddress(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		podIP string
+		envIP string
+		want  string
+	}{
+		{name: "not advertised", podIP: "10.0.0.1"},
+		{name: "host and port", opts: []Option{WithAdvertiseAddress("leader.example", 8080)}, want: "leader.example:8080"},
+		{name: "host alone", opts: []Option{WithAdvertiseAddress("leader.example", 0)}, want: "leader.example"},
+		{name: "pod IP", opts: []Option{WithAdvertiseAddress("", 8080)}, podIP: "10.0.0.1", envIP: "10.0.0.2", want: "10.0.0.1:8080"},
+		{name: "POD_IP before the status has one", opts: []Option{WithAdvertiseAddress("", 8080)}, envIP: "10.0.0.2", want: "10.0.0.2:8080"},
+		{name: "IPv6", opts: []Option{WithAdvertiseAddress("", 8080)}, podIP: "fd00::1", want: "[fd00::1]:8080"},
+		{name: "no IP yet", opts: []Option{WithAdvertiseAddress("", 8080)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer os.Setenv(PodIPEnvVar, os.Getenv(PodIPEnvVar))
+			os.Setenv(PodIPEnvVar, tc.envIP)
+			o := defaultOptions()
+			for _, opt := range tc.opts {
+				opt(&o)
+			}
+			pod := &v1.Pod{Status: v1.PodStatus{PodIP: tc.podIP}}
+			if got := o.advertisedAddress(pod); got != tc.want {
+				t.Fatalf("advertisedAddress = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// setPodIP gives the pod name the IP ip.
+func setPodIP(t *testing.T, client *fake.Clientset, name, ip string) {
+	t.Helper()
+	pods := client.CoreV1().Pods(testNamespace)
+	pod, err := pods.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod.Status.PodIP = ip
+	if _, err := pods.UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaderAddress(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		acquire bool
+		want    string
+		wantErr error
+	}{
+		{name: "free", wantErr: ErrNoLeader},
+		{name: "advertised", opts: []Option{WithAdvertiseAddress("", 8080)}, acquire: true, want: "10.0.0.1:8080"},
+		{name: "pod IP of a leader advertising none", acquire: true, want: "10.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			setPodIP(t, client, "pod-1", "10.0.0.1")
+			if tc.acquire {
+				e := newTestElector(t, client, "pod-1", tc.opts...)
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+
+			// resolved by another pod
+			got, err := newTestElector(t, client, "pod-2").LeaderAddress(context.Background())
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("LeaderAddress error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf("LeaderAddress = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package leader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// addPriorityGroup makes requests with conf act as conf's own identity,
+// or the one conf already impersonates, with group added to its groups, so
+// that a FlowSchema of API Priority and Fairness can select the election
+// traffic by the group. Impersonating groups replaces the groups the
+// apiserver would have assigned, so those of a service account are kept
+// explicitly.
+func addPriorityGroup(conf *rest.Config, group string) error {
+	imp := &conf.Impersonate
+	if imp.UserName == "" {
+		user, err := tokenUser(conf)
+		if err != nil {
+			return fmt.Errorf("find the user to add priority group %s to: %w", group, err)
+		}
+		imp.UserName = user
+	}
+	if len(imp.Groups) == 0 && strings.HasPrefix(imp.UserName, serviceAccountUserPrefix) {
+		if parts := strings.Split(strings.TrimPrefix(imp.UserName, serviceAccountUserPrefix), ":"); len(parts) == 2 {
+			imp.Groups = []string{"system:serviceaccounts", "system:serviceaccounts:" + parts[0]}
+		}
+	}
+	imp.Groups = append(imp.Groups, group)
+	return nil
+}
+
+// tokenUser returns the user conf authenticates as, read from the subject
+// of its bearer token, as service account tokens carry it. The token is not
+// verified; the apiserver does that on every request.
+func tokenUser(conf *rest.Config) (string, error) {
+	token := conf.BearerToken
+	if token == "" && conf.BearerTokenFile != "" {
+		b, err := ioutil.ReadFile(conf.BearerTokenFile)
+		if err != nil {
+			return "", err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("no service account token; impersonate a user with WithImpersonation")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// holdOff records the delay the apiserver asked for in err, through the
+// Retry-After header of a 429 or of a server timeout, so that the next
+// backoff waits at least as long. It is called from retryable, so it only
+// sees the 429s that client-go's own retries gave up on, and server timeouts
+// carrying a Retry-After. Under API Priority and Fairness, retrying a
+// rejected request sooner only takes another seat of its priority level.
+func (e *PodElector) holdOff(err error) {
+	seconds, ok := apierrors.SuggestsClientDelay(err)
+	if !ok || seconds <= 0 {
+		return
+	}
+	until := time.Now().Add(time.Duration(seconds) * time.Second)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if until.After(e.retryAfter) {
+		e.retryAfter = until
+	}
+}
+
+// retryAfterDelay returns how long is left of the delay the apiserver last
+// asked for.
+func (e *PodElector) retryAfterDelay() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return time.Until(e.retryAfter)
+}
@@ -0,0 +1,154 @@
+package leader
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testToken returns an unsigned bearer token with the subject sub.
+func testToken(sub string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none"}`)) + "." + enc([]byte(`{"sub":"`+sub+`"}`)) + ".sig"
+}
+
+func TestAddPriorityGroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte(testToken("system:serviceaccount:ns:sa")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	saGroups := []string{"system:serviceaccounts", "system:serviceaccounts:ns", "election"}
+	for _, tc := range []struct {
+		name   string
+		conf   rest.Config
+		user   string
+		groups []string
+		err    bool
+	}{
+		{
+			name:   "service account token",
+			conf:   rest.Config{BearerToken: testToken("system:serviceaccount:ns:sa")},
+			user:   "system:serviceaccount:ns:sa",
+			groups: saGroups,
+		},
+		{
+			name:   "token file",
+			conf:   rest.Config{BearerTokenFile: tokenFile},
+			user:   "system:serviceaccount:ns:sa",
+			groups: saGroups,
+		},
+		{
+			name:   "user token",
+			conf:   rest.Config{BearerToken: testToken("alice")},
+			user:   "alice",
+			groups: []string{"election"},
+		},
+		{
+			name:   "impersonated service account",
+			conf:   rest.Config{Impersonate: rest.ImpersonationConfig{UserName: "system:serviceaccount:ns:sa"}},
+			user:   "system:serviceaccount:ns:sa",
+			groups: saGroups,
+		},
+		{
+			name:   "impersonated groups kept",
+			conf:   rest.Config{Impersonate: rest.ImpersonationConfig{UserName: "system:serviceaccount:ns:sa", Groups: []string{"admins"}}},
+			user:   "system:serviceaccount:ns:sa",
+			groups: []string{"admins", "election"},
+		},
+		{name: "no token", conf: rest.Config{}, err: true},
+		{name: "not a token", conf: rest.Config{BearerToken: "secret"}, err: true},
+		{name: "token without subject", conf: rest.Config{BearerToken: testToken("")}, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := tc.conf
+			err := addPriorityGroup(&conf, "election")
+			if (err != nil) != tc.err {
+				t.Fatalf("addPriorityGroup = %v, want error %v", err, tc.err)
+			}
+			if tc.err {
+				return
+			}
+			if conf.Impersonate.UserName != tc.user || !reflect.DeepEqual(conf.Impersonate.Groups, tc.groups) {
+				t.Fatalf("impersonating %s %v, want %s %v", conf.Impersonate.UserName, conf.Impersonate.Groups, tc.user, tc.groups)
+			}
+		})
+	}
+}
+
+func TestHoldOff(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		errs []error
+		want time.Duration
+	}{
+		{name: "too many requests", errs: []error{apierrors.NewTooManyRequests("slow down", 5)}, want: 5 * time.Second},
+		{name: "server timeout", errs: []error{apierrors.NewServerTimeout(v1.Resource("configmaps"), "create", 3)}, want: 3 * time.Second},
+		{name: "no delay", errs: []error{apierrors.NewTooManyRequests("slow down", 0)}},
+		{name: "not retryable", errs: []error{apierrors.NewNotFound(v1.Resource("configmaps"), testLock)}},
+		{
+			name: "longest delay kept",
+			errs: []error{apierrors.NewTooManyRequests("slow down", 5), apierrors.NewTooManyRequests("slow down", 1)},
+			want: 5 * time.Second,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestElector(t, newTestClient(t, "pod-1"), "pod-1")
+			for _, err := range tc.errs {
+				e.retryable(context.Background(), err)
+			}
+			got := e.retryAfterDelay()
+			if tc.want == 0 && got > 0 || tc.want > 0 && (got > tc.want || got < tc.want-time.Second) {
+				t.Fatalf("retryAfterDelay = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBecomeHonoursRetryAfter(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	var mu sync.Mutex
+	var attempts []time.Time
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			return true, nil, apierrors.NewTooManyRequests("slow down", 2)
+		}
+		return false, nil, nil
+	})
+	e := newTestElector(t, client, "pod-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Become(ctx); err != nil {
+		t.Fatalf("Become: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) < 2 {
+		t.Fatalf("%d attempts to create the lock", len(attempts))
+	}
+	// the backoff alone would have retried after at most 1.2s
+	if waited := attempts[1].Sub(attempts[0]); waited < 1900*time.Millisecond {
+		t.Fatalf("retried after %v, want the 2s Retry-After honoured", waited)
+	}
+}
@@ -0,0 +1,142 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// FieldManager is the field manager of the lock objects we create, and the
+// prefix of the field managers we apply their annotations with.
+const FieldManager = "k8s-leader"
+
+// applyManager returns the field manager setting the annotation keys. Each
+// set of keys written together gets its own manager, so that applying one
+// set never removes fields applied with another.
+func applyManager(keys []string) string {
+	short := make([]string, 0, len(keys))
+	for _, key := range keys {
+		short = append(short, key[strings.LastIndex(key, "/")+1:])
+	}
+	sort.Strings(short)
+	return FieldManager + "-" + strings.Join(short, "+")
+}
+
+// patchLockAnnotations sets annotations on the lock we hold. Annotations with
+// a value are written with server-side apply, so the leader, candidates and
+// leaderctl updating different annotations of the same lock never conflict
+// or take each other's fields; a nil value removes the annotation with a
+// merge patch. Both carry the UID of our lock, so a write racing a
+// successor's lock that replaced ours fails with a Conflict instead of
+// landing on it.
+func (e *PodElector) patchLockAnnotations(ctx context.Context, annotations map[string]interface{}) error {
+	uid, ok := e.heldUID()
+	if !ok {
+		return ErrNotLeader
+	}
+	return setAnnotations(ctx, e.lockBackend(), e.lockName, uid, annotations)
+}
+
+// annotateLock sets annotations on lock, held by another pod, as
+// patchLockAnnotations does on ours: only ever on the lock object we read.
+func (e *PodElector) annotateLock(ctx context.Context, lock metav1.Object, annotations map[string]interface{}) error {
+	return setAnnotations(ctx, e.lockBackend(), e.lockName, lock.GetUID(), annotations)
+}
+
+// setAnnotations sets annotations on the lock name. A non-empty uid is put
+// in the body of every write, so that it only applies to that lock object.
+func setAnnotations(ctx context.Context, b backend, name string, uid types.UID, annotations map[string]interface{}) error {
+	set := map[string]string{}
+	cleared := map[string]interface{}{}
+	for key, value := range annotations {
+		if value == nil {
+			cleared[key] = nil
+			continue
+		}
+		set[key] = fmt.Sprint(value)
+	}
+
+	if len(set) > 0 {
+		err := applyAnnotations(ctx, b, name, uid, set)
+		if apierrors.IsUnsupportedMediaType(err) {
+			// the apiserver predates server-side apply
+			for key, value := range set {
+				cleared[key] = value
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(cleared) == 0 {
+		return nil
+	}
+
+	metadata := map[string]interface{}{
+		"annotations": cleared,
+	}
+	if uid != "" {
+		metadata["uid"] = uid
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+	return b.Patch(ctx, name, patch)
+}
+
+// applyAnnotations applies annotations to the lock. Apply creates objects
+// that do not exist, but must not create a lock. The UID in the apply
+// configuration makes the write fail instead, with NotFound or Conflict;
+// should a lock still appear without an owner, and with no other manager
+// than ours, it was created by us and is deleted again.
+func applyAnnotations(ctx context.Context, b backend, name string, uid types.UID, annotations map[string]string) error {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	manager := applyManager(keys)
+
+	lock, err := b.Apply(ctx, name, manager, uid, annotations)
+	if err != nil {
+		return err
+	}
+	if len(lock.GetOwnerReferences()) > 0 || !onlyManager(lock, manager) {
+		return nil
+	}
+	if err := b.Delete(ctx, name, unchanged(lock)); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete lock %s recreated by apply: %w", name, err)
+	}
+	return apierrors.NewNotFound(b.Resource(), name)
+}
+
+func onlyManager(obj metav1.Object, manager string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != manager {
+			return false
+		}
+	}
+	return true
+}
+
+// applyPatch returns the apply configuration setting annotations on the
+// object name of kind, and of UID uid unless it is empty.
+func applyPatch(apiVersion, kind, name string, uid types.UID, annotations map[string]string) ([]byte, error) {
+	metadata := map[string]interface{}{
+		"name":        name,
+		"annotations": annotations,
+	}
+	if uid != "" {
+		metadata["uid"] = uid
+	}
+	return json.Marshal(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	})
+}
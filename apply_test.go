@@ -0,0 +1,165 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// recordPatches records the types of the patches of configmaps on client.
+// With apply set it also answers apply patches as an apiserver serving
+// server-side apply would.
+func recordPatches(client *fake.Clientset, apply bool) func() []types.PatchType {
+	var mu sync.Mutex
+	var patches []types.PatchType
+	client.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		mu.Lock()
+		patches = append(patches, patch.GetPatchType())
+		mu.Unlock()
+		if !apply || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		var applied v1.ConfigMap
+		if err := json.Unmarshal(patch.GetPatch(), &applied); err != nil {
+			return true, nil, err
+		}
+		obj, err := client.Tracker().Get(v1.SchemeGroupVersion.WithResource("configmaps"), patch.GetNamespace(), patch.GetName())
+		if apierrors.IsNotFound(err) {
+			// apply creates what does not exist
+			applied.Namespace = patch.GetNamespace()
+			applied.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: applyManager(keysOf(applied.Annotations))}}
+			return true, &applied, client.Tracker().Create(v1.SchemeGroupVersion.WithResource("configmaps"), &applied, patch.GetNamespace())
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		lock := obj.(*v1.ConfigMap).DeepCopy()
+		if applied.UID != "" && applied.UID != lock.UID {
+			return true, nil, apierrors.NewConflict(v1.Resource("configmaps"), lock.Name, errors.New("uid mismatch"))
+		}
+		if lock.Annotations == nil {
+			lock.Annotations = map[string]string{}
+		}
+		for key, value := range applied.Annotations {
+			lock.Annotations[key] = value
+		}
+		return true, lock, client.Tracker().Update(v1.SchemeGroupVersion.WithResource("configmaps"), lock, patch.GetNamespace())
+	})
+	return func() []types.PatchType {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]types.PatchType(nil), patches...)
+	}
+}
+
+func keysOf(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestApplyManager(t *testing.T) {
+	for _, tc := range []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{TransferToAnnotation}, want: "k8s-leader-transfer-to"},
+		{keys: []string{"b.example.com/b", "a"}, want: "k8s-leader-a+b"},
+	} {
+		if got := applyManager(tc.keys); got != tc.want {
+			t.Errorf("applyManager(%v) = %q, want %q", tc.keys, got, tc.want)
+		}
+	}
+}
+
+func TestPatchLockAnnotations(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		apply       bool
+		annotations map[string]interface{}
+		patches     []types.PatchType
+	}{
+		{
+			name:        "applied",
+			apply:       true,
+			annotations: map[string]interface{}{"a": "1", "b": 2},
+			patches:     []types.PatchType{types.ApplyPatchType},
+		},
+		{
+			name:        "applied and removed",
+			apply:       true,
+			annotations: map[string]interface{}{"a": "1", "c": nil},
+			patches:     []types.PatchType{types.ApplyPatchType, types.MergePatchType},
+		},
+		{
+			name:        "no server-side apply",
+			annotations: map[string]interface{}{"a": "1", "b": 2, "c": nil},
+			patches:     []types.PatchType{types.ApplyPatchType, types.MergePatchType},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			if err := e.patchLockAnnotations(context.Background(), tc.annotations); !errors.Is(err, ErrNotLeader) {
+				t.Fatalf("patchLockAnnotations without the lock = %v, want ErrNotLeader", err)
+			}
+			if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+			if err := e.patchLockAnnotations(context.Background(), map[string]interface{}{"c": "old"}); err != nil {
+				t.Fatal(err)
+			}
+			patches := recordPatches(client, tc.apply)
+
+			if err := e.patchLockAnnotations(context.Background(), tc.annotations); err != nil {
+				t.Fatalf("patchLockAnnotations: %v", err)
+			}
+			if got := patches(); !reflect.DeepEqual(got, tc.patches) {
+				t.Fatalf("patches = %v, want %v", got, tc.patches)
+			}
+			lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tc.annotations {
+				got, ok := lock.Annotations[key]
+				if value == nil && ok {
+					t.Errorf("annotation %s = %q, want it removed", key, got)
+				}
+				if value != nil && got != fmt.Sprint(value) {
+					t.Errorf("annotation %s = %q, want %v", key, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDoesNotCreateLocks(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	recordPatches(client, true)
+	e := newTestElector(t, client, "pod-1")
+
+	err := setAnnotations(context.Background(), e.lockBackend(), testLock, "", map[string]interface{}{"a": "1"})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("setAnnotations on a missing lock = %v, want NotFound", err)
+	}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("lock created by apply was left behind: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	auditRole = "audit"
+
+	// defaultAuditRetention is how many audit entries are kept per lock.
+	defaultAuditRetention = 100
+)
+
+// AuditReason is the reason code of an audit entry.
+type AuditReason string
+
+const (
+	// AuditAcquired records that we created the lock.
+	AuditAcquired AuditReason = "Acquired"
+	// AuditResumed records that we found our own lock, usually after a
+	// container restart, and continued as the leader.
+	AuditResumed AuditReason = "Resumed"
+	// AuditResigned records that we released the lock.
+	AuditResigned AuditReason = "Resigned"
+	// AuditLost records that the lock was deleted or replaced while we
+	// held it.
+	AuditLost AuditReason = "Lost"
+	// AuditTookOver records that we deleted an evicted leader to free its
+	// lock.
+	AuditTookOver AuditReason = "TookOver"
+)
+
+// audit records an entry in the audit trail if it is enabled. Entries are
+// Events labeled with the lock, so they can be listed apart from the
+// aggregated Events of WithEvents, and carry the reason code as their
+// reason. Only the newest entries up to the retention limit are kept.
+func (e *PodElector) audit(reason AuditReason, format string, args ...interface{}) {
+	if e.opts.auditRetention <= 0 {
+		return
+	}
+
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+
+	ts := metav1.NewTime(time.Now())
+	_, err := e.kube().CoreV1().Events(e.ns).Create(ctx, &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: e.lockName + "-audit-",
+			Namespace:    e.ns,
+			Labels: map[string]string{
+				LockLabel: e.lockName,
+				RoleLabel: auditRole,
+			},
+			Annotations: map[string]string{
+				EpochAnnotation: fmt.Sprint(e.Epoch()),
+			},
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion: e.owner.APIVersion,
+			Kind:       e.owner.Kind,
+			Namespace:  e.ns,
+			Name:       e.owner.Name,
+			UID:        e.owner.UID,
+		},
+		Type:                v1.EventTypeNormal,
+		Reason:              string(reason),
+		Action:              string(reason),
+		Message:             fmt.Sprintf(format, args...),
+		Source:              v1.EventSource{Component: eventSource},
+		ReportingController: eventSource,
+		ReportingInstance:   e.owner.Name,
+		FirstTimestamp:      ts,
+		LastTimestamp:       ts,
+		Count:               1,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		e.log.Error(err, "Failed to record audit entry", "lock", e.lockName, "reason", reason)
+		return
+	}
+
+	if err := e.pruneAudit(ctx); err != nil {
+		e.log.Error(err, "Failed to prune audit trail", "lock", e.lockName)
+	}
+}
+
+// pruneAudit deletes the oldest audit entries beyond the retention limit.
+func (e *PodElector) pruneAudit(ctx context.Context) error {
+	events := e.kube().CoreV1().Events(e.ns)
+	list, err := events.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			LockLabel: e.lockName,
+			RoleLabel: auditRole,
+		}).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	excess := len(list.Items) - e.opts.auditRetention
+	if excess <= 0 {
+		return nil
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		ti, tj := list.Items[i].CreationTimestamp, list.Items[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	for _, ev := range list.Items[:excess] {
+		if err := events.Delete(ctx, ev.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
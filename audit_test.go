@@ -0,0 +1,72 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// auditTrail returns the reasons and messages of the audit entries of
+// testLock, oldest first.
+func auditTrail(t *testing.T, client *fake.Clientset) (reasons, messages []string) {
+	t.Helper()
+	list, err := client.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{LockLabel: testLock, RoleLabel: auditRole}).String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].CreationTimestamp.Before(&list.Items[j].CreationTimestamp)
+	})
+	for _, ev := range list.Items {
+		reasons = append(reasons, ev.Reason)
+		messages = append(messages, ev.Message)
+	}
+	return reasons, messages
+}
+
+func TestAuditRetention(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		entries int
+		want    []string
+	}{
+		{name: "disabled", entries: 3},
+		{name: "within the limit", opts: []Option{WithAudit(5)}, entries: 3, want: []string{"entry 0", "entry 1", "entry 2"}},
+		{name: "beyond the limit", opts: []Option{WithAudit(3)}, entries: 5, want: []string{"entry 2", "entry 3", "entry 4"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1", tc.opts...)
+			for i := 0; i < tc.entries; i++ {
+				e.audit(AuditAcquired, "entry %d", i)
+			}
+			if _, messages := auditTrail(t, client); !reflect.DeepEqual(messages, tc.want) {
+				t.Fatalf("audit trail = %v, want %v", messages, tc.want)
+			}
+		})
+	}
+}
+
+func TestAuditTransitions(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithAudit(0))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	reasons, _ := auditTrail(t, client)
+	if want := []string{string(AuditAcquired), string(AuditResigned)}; !reflect.DeepEqual(reasons, want) {
+		t.Fatalf("audit reasons = %v, want %v", reasons, want)
+	}
+}
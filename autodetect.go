@@ -0,0 +1,95 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// probeName is the name of the objects a dry-run create is attempted for to
+// find out whether writes of a kind are admitted. Nothing is persisted.
+const probeName = "leader-backend-probe"
+
+// detectBackend picks the lock backend the cluster can serve: Leases if
+// coordination.k8s.io/v1 is served and creating one is admitted, else
+// ConfigMaps if creating one is admitted. Admission is checked with
+// server-side dry-run creates, which authorization, quotas and admission
+// webhooks all see. Every pod of a fleet runs with the same permissions and
+// policies, so they all come to the same decision.
+func detectBackend(ctx context.Context, client kubernetes.Interface, ns string, timeout time.Duration, logger Logger) (Backend, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	dryRun := metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}
+
+	leaseErr := leasesServed(client)
+	if leaseErr == nil {
+		_, leaseErr = client.CoordinationV1().Leases(ns).Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: probeName, Namespace: ns},
+		}, dryRun)
+		leaseErr = forbidden(leaseErr, "create", coordinationv1.Resource("leases"), ns)
+	}
+	if leaseErr == nil || apierrors.IsAlreadyExists(leaseErr) {
+		logger.Info("Detected lock backend", "backend", LeaseBackend, "namespace", ns)
+		return LeaseBackend, nil
+	}
+
+	_, cmErr := client.CoreV1().ConfigMaps(ns).Create(ctx, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: probeName, Namespace: ns},
+	}, dryRun)
+	if cmErr == nil || apierrors.IsAlreadyExists(cmErr) {
+		logger.Info("Detected lock backend, Leases cannot be used", "backend", ConfigMapBackend, "namespace", ns, "reason", leaseErr)
+		return ConfigMapBackend, nil
+	}
+	cmErr = forbidden(cmErr, "create", v1.Resource("configmaps"), ns)
+	return "", fmt.Errorf("no workable lock backend in %s: leases: %v; configmaps: %w", ns, leaseErr, cmErr)
+}
+
+// DetectBackend returns the lock backend AutoBackend resolves to in ns, for
+// components that read the electors' locks without taking part, such as
+// followers. It needs create on leases and configmaps for the dry-run
+// probes.
+func DetectBackend(ctx context.Context, client kubernetes.Interface, ns string) (Backend, error) {
+	return detectBackend(ctx, client, ns, defaultRequestTimeout, defaultLogger)
+}
+
+// leasesServed returns an error unless the apiserver serves
+// coordination.k8s.io/v1 Leases.
+func leasesServed(client kubernetes.Interface) error {
+	gv := coordinationv1.SchemeGroupVersion.String()
+	resources, err := client.Discovery().ServerResourcesForGroupVersion(gv)
+	if err != nil {
+		return fmt.Errorf("discover %s: %w", gv, err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "leases" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not serve leases", gv)
+}
+
+// backendKind returns the Backend b implements.
+func backendKind(b backend) Backend {
+	switch b := b.(type) {
+	case *leaseBackend:
+		return LeaseBackend
+	case *dualBackend:
+		if b.legacyName != "" {
+			return backendKind(b.primary)
+		}
+		return MigrationBackend
+	}
+	return ConfigMapBackend
+}
+
+// Backend returns the lock backend in use, which is the detected one for
+// AutoBackend.
+func (e *PodElector) Backend() Backend {
+	return backendKind(e.lockBackend())
+}
@@ -0,0 +1,77 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDetectBackend(t *testing.T) {
+	denied := func(resource string) error {
+		return apierrors.NewForbidden(v1.Resource(resource), probeName, errors.New("denied by policy"))
+	}
+	for _, tc := range []struct {
+		name         string
+		leasesServed bool
+		leaseErr     error
+		cmErr        error
+		want         Backend
+	}{
+		{name: "leases", leasesServed: true, want: LeaseBackend},
+		{name: "leases not served", want: ConfigMapBackend},
+		{name: "leases denied", leasesServed: true, leaseErr: denied("leases"), want: ConfigMapBackend},
+		{name: "probe left over", leasesServed: true, leaseErr: apierrors.NewAlreadyExists(coordinationv1.Resource("leases"), probeName), want: LeaseBackend},
+		{name: "nothing admitted", leasesServed: true, leaseErr: denied("leases"), cmErr: denied("configmaps")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t)
+			if tc.leasesServed {
+				client.Resources = []*metav1.APIResourceList{{
+					GroupVersion: coordinationv1.SchemeGroupVersion.String(),
+					APIResources: []metav1.APIResource{{Name: "leases", Namespaced: true, Kind: "Lease"}},
+				}}
+			}
+			fail := func(err error) k8stesting.ReactionFunc {
+				return func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return err != nil, nil, err
+				}
+			}
+			client.PrependReactor("create", "leases", fail(tc.leaseErr))
+			client.PrependReactor("create", "configmaps", fail(tc.cmErr))
+			o := defaultOptions()
+			o.logLevel = ErrorLevel
+
+			got, err := detectBackend(context.Background(), client, testNamespace, 0, o.getLogger())
+			if tc.want == "" {
+				if !apierrors.IsForbidden(err) {
+					t.Fatalf("detectBackend = %v, %v, want the configmaps error", got, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("detectBackend = %v, %v, want %v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestAutoBackend(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithBackend(AutoBackend))
+	if e.Backend() != ConfigMapBackend {
+		t.Fatalf("Backend = %v on a cluster without Leases", e.Backend())
+	}
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if owner := lockOwner(t, client); owner != "pod-1" {
+		t.Fatalf("lock owner = %q", owner)
+	}
+}
@@ -0,0 +1,445 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Backend selects the kind of object used as the lock.
+type Backend string
+
+const (
+	// ConfigMapBackend uses a ConfigMap as the lock. It is the default.
+	ConfigMapBackend Backend = "ConfigMap"
+
+	// LeaseBackend uses a coordination.k8s.io/v1 Lease as the lock.
+	LeaseBackend Backend = "Lease"
+
+	// MigrationBackend uses a Lease as the lock while also holding and
+	// honoring the ConfigMap lock of older releases. Roll a fleet from
+	// ConfigMapBackend to MigrationBackend, then from MigrationBackend to
+	// LeaseBackend, and no two pods ever both lead during the upgrade.
+	MigrationBackend Backend = "Migration"
+
+	// AutoBackend detects at startup whether Leases can be used, falling
+	// back to ConfigMaps, for software installed into clusters of unknown
+	// version and policy.
+	AutoBackend Backend = "Auto"
+)
+
+// backend stores the lock object. Whatever the kind, protocol state lives in
+// the object's annotations and the holder in its owner references.
+// Each call is bounded by the request timeout the backend was built with.
+type backend interface {
+	Get(ctx context.Context, name string) (metav1.Object, error)
+	Create(ctx context.Context, meta metav1.ObjectMeta) (metav1.Object, error)
+	Patch(ctx context.Context, name string, patch []byte) error
+	// Apply sets annotations with server-side apply as manager, on the lock
+	// of UID uid unless it is empty, and returns the lock as it is
+	// afterwards.
+	Apply(ctx context.Context, name, manager string, uid types.UID, annotations map[string]string) (metav1.Object, error)
+	// Delete deletes the lock, provided it still meets the preconditions,
+	// which always include its UID.
+	Delete(ctx context.Context, name string, pre metav1.Preconditions) error
+	Resource() schema.GroupResource
+}
+
+func newBackend(b Backend, client kubernetes.Interface, ns string, timeout time.Duration, logger Logger) (backend, error) {
+	switch b {
+	case "", ConfigMapBackend:
+		return &configMapBackend{client: client, ns: ns, timeout: timeout}, nil
+	case LeaseBackend:
+		return &leaseBackend{client: client, ns: ns, timeout: timeout}, nil
+	case MigrationBackend:
+		return &dualBackend{
+			legacy:  &configMapBackend{client: client, ns: ns, timeout: timeout},
+			primary: &leaseBackend{client: client, ns: ns, timeout: timeout},
+			log:     logger,
+		}, nil
+	case AutoBackend:
+		detected, err := detectBackend(context.Background(), client, ns, timeout, logger)
+		if err != nil {
+			return nil, err
+		}
+		return newBackend(detected, client, ns, timeout, logger)
+	default:
+		return nil, fmt.Errorf("unknown lock backend %q", b)
+	}
+}
+
+func applyOptions(manager string) metav1.PatchOptions {
+	force := true
+	return metav1.PatchOptions{FieldManager: manager, Force: &force}
+}
+
+func uidPrecondition(uid types.UID) metav1.DeleteOptions {
+	return metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &uid},
+	}
+}
+
+// uidOnly guards a delete against the object having been replaced.
+func uidOnly(uid types.UID) metav1.Preconditions {
+	return metav1.Preconditions{UID: &uid}
+}
+
+// foregroundOptions deletes with pre and foreground propagation: the object
+// is only removed once the objects it owns are.
+func foregroundOptions(pre metav1.Preconditions) metav1.DeleteOptions {
+	policy := metav1.DeletePropagationForeground
+	return metav1.DeleteOptions{Preconditions: &pre, PropagationPolicy: &policy}
+}
+
+// unchanged guards a delete against obj having been replaced, by its UID,
+// or modified, by its resourceVersion, since it was read. Takeovers delete
+// objects of other pods with it, so they never act on state they did not
+// see.
+func unchanged(obj metav1.Object) metav1.Preconditions {
+	uid, rv := obj.GetUID(), obj.GetResourceVersion()
+	return metav1.Preconditions{UID: &uid, ResourceVersion: &rv}
+}
+
+type configMapBackend struct {
+	client  kubernetes.Interface
+	ns      string
+	timeout time.Duration
+}
+
+func (b *configMapBackend) Get(ctx context.Context, name string) (metav1.Object, error) {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	lock, err := b.client.CoreV1().ConfigMaps(b.ns).Get(ctx, name, metav1.GetOptions{})
+	return lock, forbidden(err, "get", b.Resource(), b.ns)
+}
+
+func (b *configMapBackend) Create(ctx context.Context, meta metav1.ObjectMeta) (metav1.Object, error) {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	lock, err := b.client.CoreV1().ConfigMaps(b.ns).Create(ctx, &v1.ConfigMap{ObjectMeta: meta}, metav1.CreateOptions{FieldManager: FieldManager})
+	return lock, forbidden(err, "create", b.Resource(), b.ns)
+}
+
+func (b *configMapBackend) Patch(ctx context.Context, name string, patch []byte) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	_, err := b.client.CoreV1().ConfigMaps(b.ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return forbidden(err, "patch", b.Resource(), b.ns)
+}
+
+func (b *configMapBackend) Apply(ctx context.Context, name, manager string, uid types.UID, annotations map[string]string) (metav1.Object, error) {
+	patch, err := applyPatch("v1", "ConfigMap", name, uid, annotations)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	lock, err := b.client.CoreV1().ConfigMaps(b.ns).Patch(ctx, name, types.ApplyPatchType, patch, applyOptions(manager))
+	return lock, forbidden(err, "patch", b.Resource(), b.ns)
+}
+
+func (b *configMapBackend) Delete(ctx context.Context, name string, pre metav1.Preconditions) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	err := b.client.CoreV1().ConfigMaps(b.ns).Delete(ctx, name, metav1.DeleteOptions{Preconditions: &pre})
+	return forbidden(err, "delete", b.Resource(), b.ns)
+}
+
+func (b *configMapBackend) DeleteForeground(ctx context.Context, name string, pre metav1.Preconditions) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	err := b.client.CoreV1().ConfigMaps(b.ns).Delete(ctx, name, foregroundOptions(pre))
+	return forbidden(err, "delete", b.Resource(), b.ns)
+}
+
+func (b *configMapBackend) Resource() schema.GroupResource {
+	return v1.Resource("configmaps")
+}
+
+type leaseBackend struct {
+	client  kubernetes.Interface
+	ns      string
+	timeout time.Duration
+}
+
+func (b *leaseBackend) Get(ctx context.Context, name string) (metav1.Object, error) {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	lock, err := b.client.CoordinationV1().Leases(b.ns).Get(ctx, name, metav1.GetOptions{})
+	return lock, forbidden(err, "get", b.Resource(), b.ns)
+}
+
+func (b *leaseBackend) Create(ctx context.Context, meta metav1.ObjectMeta) (metav1.Object, error) {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+
+	lease := &coordinationv1.Lease{ObjectMeta: meta}
+	if len(meta.OwnerReferences) == 1 {
+		now := metav1.NewMicroTime(time.Now())
+		lease.Spec.HolderIdentity = &meta.OwnerReferences[0].Name
+		lease.Spec.AcquireTime = &now
+		lease.Spec.RenewTime = &now
+	}
+	created, err := b.client.CoordinationV1().Leases(b.ns).Create(ctx, lease, metav1.CreateOptions{FieldManager: FieldManager})
+	return created, forbidden(err, "create", b.Resource(), b.ns)
+}
+
+func (b *leaseBackend) Patch(ctx context.Context, name string, patch []byte) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	_, err := b.client.CoordinationV1().Leases(b.ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return forbidden(err, "patch", b.Resource(), b.ns)
+}
+
+func (b *leaseBackend) Apply(ctx context.Context, name, manager string, uid types.UID, annotations map[string]string) (metav1.Object, error) {
+	patch, err := applyPatch(coordinationv1.SchemeGroupVersion.String(), "Lease", name, uid, annotations)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	lock, err := b.client.CoordinationV1().Leases(b.ns).Patch(ctx, name, types.ApplyPatchType, patch, applyOptions(manager))
+	return lock, forbidden(err, "patch", b.Resource(), b.ns)
+}
+
+func (b *leaseBackend) Delete(ctx context.Context, name string, pre metav1.Preconditions) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	err := b.client.CoordinationV1().Leases(b.ns).Delete(ctx, name, metav1.DeleteOptions{Preconditions: &pre})
+	return forbidden(err, "delete", b.Resource(), b.ns)
+}
+
+func (b *leaseBackend) DeleteForeground(ctx context.Context, name string, pre metav1.Preconditions) error {
+	ctx, cancel := withTimeout(ctx, b.timeout)
+	defer cancel()
+	err := b.client.CoordinationV1().Leases(b.ns).Delete(ctx, name, foregroundOptions(pre))
+	return forbidden(err, "delete", b.Resource(), b.ns)
+}
+
+func (b *leaseBackend) Resource() schema.GroupResource {
+	return coordinationv1.Resource("leases")
+}
+
+// dualBackend holds a legacy lock alongside the primary one. Reads fall back
+// to the legacy lock, so a lock held by a pod that only knows the legacy
+// format is honored; creates take the legacy lock first, so such pods see
+// our lock too, and deletes give it back last. Patches and applies are
+// mirrored to the legacy lock of the same holder. It backs MigrationBackend
+// and WithCompatibilityLock.
+type dualBackend struct {
+	legacy     backend
+	legacyName string
+	primary    backend
+	log        Logger
+}
+
+// nameOf returns the legacy lock's name for the primary lock name.
+func (b *dualBackend) nameOf(name string) string {
+	if b.legacyName != "" {
+		return b.legacyName
+	}
+	return name
+}
+
+func (b *dualBackend) Get(ctx context.Context, name string) (metav1.Object, error) {
+	lock, err := b.primary.Get(ctx, name)
+	if !apierrors.IsNotFound(err) {
+		return lock, err
+	}
+	return b.legacy.Get(ctx, b.nameOf(name))
+}
+
+func (b *dualBackend) Create(ctx context.Context, meta metav1.ObjectMeta) (metav1.Object, error) {
+	legacyMeta := meta
+	legacyMeta.Name = b.nameOf(meta.Name)
+	legacyMeta.Finalizers = nil
+
+	legacy, err := b.legacy.Create(ctx, legacyMeta)
+	if apierrors.IsAlreadyExists(err) {
+		// a restarted leader of ours still owns the legacy lock
+		legacy, err = b.legacy.Get(ctx, legacyMeta.Name)
+		if err == nil && !sameOwners(legacy, meta.OwnerReferences) {
+			return nil, apierrors.NewAlreadyExists(b.legacy.Resource(), legacyMeta.Name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := b.primary.Create(ctx, meta)
+	if err != nil {
+		// someone already holds the primary lock; give the legacy one back
+		if derr := b.legacy.Delete(ctx, legacyMeta.Name, uidOnly(legacy.GetUID())); derr != nil && !apierrors.IsNotFound(derr) {
+			b.log.Error(derr, "Failed to roll back legacy lock", "lock", legacyMeta.Name)
+		}
+		return nil, err
+	}
+	return lock, nil
+}
+
+func (b *dualBackend) Patch(ctx context.Context, name string, patch []byte) error {
+	if err := b.primary.Patch(ctx, name, patch); err != nil {
+		return err
+	}
+	legacy, err := b.heldLegacy(ctx, name)
+	if err != nil || legacy == nil {
+		return err
+	}
+	if patch, err = pinUID(patch, legacy.GetUID()); err != nil {
+		return err
+	}
+	if err := b.legacy.Patch(ctx, legacy.GetName(), patch); err != nil {
+		b.log.Error(err, "Failed to mirror patch to legacy lock", "lock", legacy.GetName())
+	}
+	return nil
+}
+
+func (b *dualBackend) Apply(ctx context.Context, name, manager string, uid types.UID, annotations map[string]string) (metav1.Object, error) {
+	lock, err := b.primary.Apply(ctx, name, manager, uid, annotations)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := b.heldLegacy(ctx, name)
+	if err != nil || legacy == nil {
+		return lock, err
+	}
+	if _, err := b.legacy.Apply(ctx, legacy.GetName(), manager, legacy.GetUID(), annotations); err != nil {
+		b.log.Error(err, "Failed to mirror apply to legacy lock", "lock", legacy.GetName())
+	}
+	return lock, nil
+}
+
+// heldLegacy returns the legacy lock of the holder of the primary lock
+// name, or nil if there is none.
+func (b *dualBackend) heldLegacy(ctx context.Context, name string) (metav1.Object, error) {
+	lock, err := b.primary.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := b.legacy.Get(ctx, b.nameOf(name))
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case !sameOwners(legacy, lock.GetOwnerReferences()):
+		return nil, nil
+	}
+	return legacy, nil
+}
+
+// pinUID replaces the UID a merge patch is pinned to with uid, so that a
+// patch of the primary lock applies to the legacy lock. Unpinned patches
+// are returned as they are.
+func pinUID(patch []byte, uid types.UID) ([]byte, error) {
+	var p map[string]interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	metadata, _ := p["metadata"].(map[string]interface{})
+	if _, pinned := metadata["uid"]; !pinned {
+		return patch, nil
+	}
+	metadata["uid"] = uid
+	return json.Marshal(p)
+}
+
+// Delete applies pre to the lock Get returned, and deletes the legacy lock
+// of the same holder along with the primary one.
+func (b *dualBackend) Delete(ctx context.Context, name string, pre metav1.Preconditions) error {
+	return b.delete(ctx, name, pre, backend.Delete)
+}
+
+// DeleteForeground is Delete with foreground propagation for the lock Get
+// returned.
+func (b *dualBackend) DeleteForeground(ctx context.Context, name string, pre metav1.Preconditions) error {
+	return b.delete(ctx, name, pre, func(l backend, ctx context.Context, name string, pre metav1.Preconditions) error {
+		return deleteForeground(ctx, l, name, pre)
+	})
+}
+
+func (b *dualBackend) delete(ctx context.Context, name string, pre metav1.Preconditions, del func(backend, context.Context, string, metav1.Preconditions) error) error {
+	lock, err := b.primary.Get(ctx, name)
+	switch {
+	case apierrors.IsNotFound(err):
+		return del(b.legacy, ctx, b.nameOf(name), pre)
+	case err != nil:
+		return err
+	case pre.UID == nil || lock.GetUID() != *pre.UID:
+		return del(b.legacy, ctx, b.nameOf(name), pre)
+	}
+
+	// the legacy lock is given back last, the reverse of Create, so pods
+	// that only know the legacy format cannot take it while we still hold
+	// the primary lock
+	legacy, err := b.legacy.Get(ctx, b.nameOf(name))
+	switch {
+	case apierrors.IsNotFound(err):
+		legacy = nil
+	case err != nil:
+		return err
+	case !sameOwners(legacy, lock.GetOwnerReferences()):
+		legacy = nil
+	}
+	if err := del(b.primary, ctx, name, pre); err != nil {
+		return err
+	}
+	if legacy == nil {
+		return nil
+	}
+	// the primary lock is gone, so the release has happened either way
+	err = b.legacy.Delete(ctx, legacy.GetName(), uidOnly(legacy.GetUID()))
+	if err != nil && !apierrors.IsNotFound(err) {
+		b.log.Error(err, "Failed to release legacy lock", "lock", legacy.GetName())
+	}
+	return nil
+}
+
+func (b *dualBackend) Resource() schema.GroupResource {
+	return b.primary.Resource()
+}
+
+// Validate checks that the legacy lock is still held by the owner of lock,
+// restoring it if it went missing. It reports false if a pod that only knows
+// the legacy format took it in the meantime.
+func (b *dualBackend) Validate(ctx context.Context, lock metav1.Object) (bool, error) {
+	legacyName := b.nameOf(lock.GetName())
+	legacy, err := b.legacy.Get(ctx, legacyName)
+	switch {
+	case apierrors.IsNotFound(err):
+		b.log.Warn("Legacy lock is missing, restoring it", "lock", legacyName)
+		_, err = b.legacy.Create(ctx, metav1.ObjectMeta{
+			Name:            legacyName,
+			Namespace:       lock.GetNamespace(),
+			OwnerReferences: lock.GetOwnerReferences(),
+		})
+		if apierrors.IsAlreadyExists(err) {
+			return b.Validate(ctx, lock)
+		}
+		return err == nil, err
+	case err != nil:
+		return false, err
+	}
+	return sameOwners(legacy, lock.GetOwnerReferences()), nil
+}
+
+// validator is implemented by backends whose lock spans several objects
+// that the leader must keep checking.
+type validator interface {
+	Validate(ctx context.Context, lock metav1.Object) (bool, error)
+}
+
+// sameOwners reports whether lock is owned by the first of owners.
+func sameOwners(lock metav1.Object, owners []metav1.OwnerReference) bool {
+	refs := lock.GetOwnerReferences()
+	return len(refs) == 1 && len(owners) == 1 && refs[0].UID == owners[0].UID
+}
@@ -0,0 +1,188 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// recordWrites records the creates and deletes of locks on client, in the
+// order the apiserver sees them, as "<verb> <resource>".
+func recordWrites(client *fake.Clientset) func() []string {
+	var mu sync.Mutex
+	var writes []string
+	record := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		writes = append(writes, action.GetVerb()+" "+action.GetResource().Resource)
+		return false, nil, nil
+	}
+	for _, verb := range []string{"create", "delete"} {
+		client.PrependReactor(verb, "configmaps", record)
+		client.PrependReactor(verb, "leases", record)
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), writes...)
+	}
+}
+
+func newTestMigrationBackend(t *testing.T, client *fake.Clientset) backend {
+	t.Helper()
+	o := defaultOptions()
+	o.logLevel = ErrorLevel
+	b, err := newBackend(MigrationBackend, client, testNamespace, time.Second, o.getLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testLockMeta(owner string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            testLock,
+		Namespace:       testNamespace,
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: owner, UID: types.UID(owner + "-uid")}},
+	}
+}
+
+func TestDualBackendOrdering(t *testing.T) {
+	client := newTestClient(t)
+	writes := recordWrites(client)
+	b := newTestMigrationBackend(t, client)
+
+	lock, err := b.Create(context.Background(), testLockMeta("pod-1"))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := b.Delete(context.Background(), testLock, uidOnly(lock.GetUID())); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	// the legacy lock is taken first and given back last, so pods that only
+	// know it never see it free while the Lease is held
+	want := []string{"create configmaps", "create leases", "delete leases", "delete configmaps"}
+	if got := writes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("writes = %v, want %v", got, want)
+	}
+	if _, err := b.Get(context.Background(), testLock); !apierrors.IsNotFound(err) {
+		t.Fatalf("Get after Delete = %v, want NotFound", err)
+	}
+}
+
+func TestDualBackendRollsBackLegacyLock(t *testing.T) {
+	client := newTestClient(t)
+	held := &coordinationv1.Lease{ObjectMeta: testLockMeta("pod-2")}
+	if _, err := client.CoordinationV1().Leases(testNamespace).Create(context.Background(), held, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	writes := recordWrites(client)
+	b := newTestMigrationBackend(t, client)
+
+	if _, err := b.Create(context.Background(), testLockMeta("pod-1")); !apierrors.IsAlreadyExists(err) {
+		t.Fatalf("Create of a held Lease = %v, want AlreadyExists", err)
+	}
+	want := []string{"create configmaps", "create leases", "delete configmaps"}
+	if got := writes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("writes = %v, want %v", got, want)
+	}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("legacy lock left behind: %v", err)
+	}
+}
+
+func TestCompatibilityLock(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	e := newTestElector(t, client, "pod-1", WithBackend(LeaseBackend), WithCompatibilityLock(ConfigMapBackend, "old-lock"))
+
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	legacy, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "old-lock", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("legacy lock was not taken: %v", err)
+	}
+	if !sameOwners(legacy, []metav1.OwnerReference{*e.owner}) {
+		t.Fatalf("legacy lock is owned by %v", legacy.OwnerReferences)
+	}
+
+	// annotations are mirrored for older releases to read
+	if err := e.patchLockAnnotations(context.Background(), map[string]interface{}{TransferToAnnotation: "pod-2"}); err != nil {
+		t.Fatalf("patchLockAnnotations: %v", err)
+	}
+	legacy, err = client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "old-lock", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.Annotations[TransferToAnnotation] != "pod-2" {
+		t.Fatalf("legacy lock annotations = %v, want the transfer intent", legacy.Annotations)
+	}
+
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "old-lock", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("legacy lock left behind: %v", err)
+	}
+}
+
+func TestCompatibilityLockHeldByOldRelease(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	old := newTestElectorOf(t, client, "old-lock", "pod-2")
+	if ok, err := old.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire of the old release = %v, %v", ok, err)
+	}
+	e := newTestElector(t, client, "pod-1", WithBackend(LeaseBackend), WithCompatibilityLock(ConfigMapBackend, "old-lock"))
+
+	if ok, err := e.TryAcquire(context.Background()); err != nil || ok {
+		t.Fatalf("TryAcquire while an old release leads = %v, %v", ok, err)
+	}
+	if _, err := client.CoordinationV1().Leases(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("Lease taken while an old release leads: %v", err)
+	}
+}
+
+func TestPinUID(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{
+			name:  "pinned",
+			patch: `{"metadata":{"annotations":{"a":"b"},"uid":"primary"}}`,
+			want:  `{"metadata":{"annotations":{"a":"b"},"uid":"legacy"}}`,
+		},
+		{
+			name:  "unpinned",
+			patch: `{"metadata":{"annotations":{"a":"b"}}}`,
+			want:  `{"metadata":{"annotations":{"a":"b"}}}`,
+		},
+		{
+			name:  "no metadata",
+			patch: `{"spec":{"renewTime":null}}`,
+			want:  `{"spec":{"renewTime":null}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pinUID([]byte(tc.patch), "legacy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Fatalf("pinUID = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// selectLeader is the transition hook pointing the selector of the Service
+// of WithBackendService at our pod when we gain leadership. Gateway API
+// implementations route an HTTPRoute's backendRefs to the endpoints of
+// selector Services, not to ExternalName Services such as the one of
+// WithLeaderService, so an HTTPRoute referencing this Service sends
+// north-south traffic to the leader only. As for WithIstioRouting, our pod
+// is labelled with our epoch first and the Service selects that label, so
+// it never selects a former leader.
+func (e *PodElector) selectLeader(leading bool) {
+	if !leading {
+		return
+	}
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+
+	epoch := strconv.FormatInt(e.Epoch(), 10)
+	if err := e.labelEpoch(ctx, epoch); err != nil {
+		e.log.Error(err, "Failed to label my pod with my epoch", "pod", e.owner.Name)
+		return
+	}
+	if err := e.pointSelector(ctx, epoch); err != nil {
+		e.log.Error(err, "Failed to point the backend Service at me", "service", e.opts.backendServiceName)
+		return
+	}
+	e.log.Info("Pointed the backend Service at me", "service", e.opts.backendServiceName, "epoch", epoch)
+}
+
+// pointSelector replaces the selector of the backend Service with our
+// epoch. A merge patch would keep the keys of the old selector, so it is set
+// with a JSON patch, whose add replaces an existing member. The Service and
+// its ports come from the application's manifests.
+func (e *PodElector) pointSelector(ctx context.Context, epoch string) error {
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "add", "path": "/spec/selector", "value": map[string]string{EpochLabel: epoch}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = e.kube().CoreV1().Services(e.ns).Patch(ctx, e.opts.backendServiceName, types.JSONPatchType, patch, metav1.PatchOptions{})
+	return forbidden(err, "patch", v1.Resource("services"), e.ns)
+}
@@ -0,0 +1,63 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBackendService(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2")
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: testNamespace},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{"app": "backend"},
+			Ports:    []v1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	if _, err := client.CoreV1().Services(testNamespace).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	first := newTestElector(t, client, "pod-1", WithBackendService("backend"))
+	second := newTestElector(t, client, "pod-2", WithBackendService("backend"))
+
+	check := func(what, epoch string) {
+		t.Helper()
+		svc, err := client.CoreV1().Services(testNamespace).Get(ctx, "backend", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{EpochLabel: epoch}; !reflect.DeepEqual(svc.Spec.Selector, want) {
+			t.Fatalf("%s: selector = %v, want %v", what, svc.Spec.Selector, want)
+		}
+		if len(svc.Spec.Ports) != 1 {
+			t.Fatalf("%s: ports = %v, want them kept", what, svc.Spec.Ports)
+		}
+	}
+
+	if ok, err := first.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	check("first leader", "1")
+	if err := client.CoreV1().ConfigMaps(testNamespace).Delete(ctx, testLock, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := second.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire of the successor = %v, %v", ok, err)
+	}
+	check("successor", "2")
+	first.selectLeader(false)
+	check("former leader stepped down", "2")
+
+	pod, err := client.CoreV1().Pods(testNamespace).Get(ctx, "pod-2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Labels[EpochLabel] != "2" {
+		t.Fatalf("successor pod labels = %v, want epoch 2", pod.Labels)
+	}
+}
@@ -0,0 +1,147 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EligibleSelectorAnnotation restricts, on the lock, which pods may lead to
+// those whose labels match the label selector it holds, such as
+// track=green. Every new leader carries it over to the lock it creates.
+const EligibleSelectorAnnotation = "leader.seamounts.io/eligible-selector"
+
+// SwitchEligibility shifts leadership of the lock lockName in ns to the
+// pods matching selector, for example from track=blue to track=green
+// during a release. It records selector on the lock; candidates that do
+// not match stop competing, and a leader that does not match hands the
+// lock to a matching candidate with a cooperative transfer, falling back to
+// resigning. An empty selector lifts the restriction. b is the backend the
+// electors use.
+func SwitchEligibility(ctx context.Context, client kubernetes.Interface, ns, lockName string, b Backend, selector string) error {
+	if _, err := labels.Parse(selector); err != nil {
+		return fmt.Errorf("parse selector %q: %w", selector, err)
+	}
+	lb, err := newBackend(b, client, ns, defaultRequestTimeout, defaultLogger)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if selector != "" {
+		value = selector
+	}
+	err = setAnnotations(ctx, lb, lockName, "", map[string]interface{}{
+		EligibleSelectorAnnotation: value,
+	})
+	if err != nil {
+		return fmt.Errorf("switch eligibility of lock %s/%s: %w", ns, lockName, err)
+	}
+	return nil
+}
+
+// observeEligibility remembers the eligibility selector and pinned leader
+// recorded on lock, so we honour them even after the lock is gone and
+// before the next leader records them again.
+func (e *PodElector) observeEligibility(lock metav1.Object) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.eligibleSelector = lock.GetAnnotations()[EligibleSelectorAnnotation]
+	e.pinnedLeader = lock.GetAnnotations()[PinnedLeaderAnnotation]
+}
+
+// eligibility returns the last eligibility selector we observed.
+func (e *PodElector) eligibility() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.eligibleSelector
+}
+
+// selectedOut returns why our pod is excluded from leading, by maintenance
+// mode, by its node under WithNodeSelector, or by its labels under
+// WithEligibilitySelector or the selector recorded on the lock, or "".
+func (e *PodElector) selectedOut() string {
+	if e.InMaintenance() {
+		return "in maintenance mode"
+	}
+	if reason := e.deniedReason(); reason != "" {
+		return reason
+	}
+	if reason := e.nodeSelectedOut(); reason != "" {
+		return reason
+	}
+	mine := e.myLabels()
+	if s := e.tuned().eligibilitySelector; s != nil && !s.Matches(mine) {
+		return fmt.Sprintf("my labels do not match the eligibility selector %q", s.String())
+	}
+
+	selector, err := labels.Parse(e.eligibility())
+	if err != nil {
+		e.log.Warn("Ignoring malformed eligibility selector", "lock", e.lockName, "selector", e.eligibility())
+		return ""
+	}
+	if !selector.Matches(mine) {
+		return fmt.Sprintf("my labels do not match the eligibility selector %q", e.eligibility())
+	}
+	return ""
+}
+
+// eligibleSuccessor returns a running pod matching the eligibility
+// selector to hand leadership to, or "" if there is none. With the
+// registry, only live members are considered.
+func (e *PodElector) eligibleSuccessor(ctx context.Context) string {
+	members := map[string]bool{}
+	if e.opts.registry {
+		list, err := e.ListMembers(ctx)
+		if err != nil {
+			e.log.Error(err, "Failed to list members", "lock", e.lockName)
+			return ""
+		}
+		for _, m := range list {
+			if m.competing() {
+				members[m.Name] = true
+			}
+		}
+	}
+
+	listCtx, cancel := e.request(ctx)
+	defer cancel()
+	pods, err := e.kube().CoreV1().Pods(e.ns).List(listCtx, metav1.ListOptions{LabelSelector: e.eligibility()})
+	if err != nil {
+		e.log.Error(forbidden(err, "list", v1.Resource("pods"), e.ns), "Failed to list eligible pods", "lock", e.lockName)
+		return ""
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning || pod.GetDeletionTimestamp() != nil || pod.Name == e.owner.Name || excludedPod(pod.Annotations) {
+			continue
+		}
+		if s := e.tuned().eligibilitySelector; s != nil && !s.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if e.opts.registry && !members[pod.Name] {
+			continue
+		}
+		return pod.Name
+	}
+	return ""
+}
+
+// handOverIneligible gives up the leadership we may no longer hold under
+// the eligibility selector, preferably to a matching candidate.
+func (e *PodElector) handOverIneligible(ctx context.Context, reason string) {
+	successor := e.eligibleSuccessor(ctx)
+	e.log.Info("No longer eligible to lead", "lock", e.lockName, "reason", reason, "successor", successor)
+	if successor != "" {
+		if err := e.stepDown(ctx, successor); err != nil {
+			e.log.Error(err, "Failed to step down", "lock", e.lockName)
+		}
+		return
+	}
+	if err := e.Resign(ctx); err != nil {
+		e.log.Error(err, "Failed to resign", "lock", e.lockName)
+	}
+}
@@ -0,0 +1,113 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSwitchEligibility(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		selector string
+		noLock   bool
+		wantErr  bool
+	}{
+		{name: "restrict", selector: "track=green"},
+		{name: "lift", selector: ""},
+		{name: "malformed selector", selector: "track in (green", wantErr: true},
+		{name: "no lock", selector: "track=green", noLock: true, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if !tc.noLock {
+				meta := testLockMeta("pod-1")
+				meta.Annotations = map[string]string{EligibleSelectorAnnotation: "track=blue"}
+				if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), &v1.ConfigMap{ObjectMeta: meta}, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := SwitchEligibility(context.Background(), client, testNamespace, testLock, ConfigMapBackend, tc.selector)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SwitchEligibility = %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := lock.Annotations[EligibleSelectorAnnotation]; got != tc.selector || ok != (tc.selector != "") {
+				t.Fatalf("eligibility selector = %q, want %q", got, tc.selector)
+			}
+		})
+	}
+}
+
+func TestEligibleSuccessor(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		track string
+		phase v1.PodPhase
+		want  string
+	}{
+		{name: "running on the new track", track: "green", phase: v1.PodRunning, want: "pod-2"},
+		{name: "pending on the new track", track: "green", phase: v1.PodPending, want: ""},
+		{name: "running on the old track", track: "blue", phase: v1.PodRunning, want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			for pod, track := range map[string]string{"pod-1": "green", "pod-2": tc.track} {
+				phase := tc.phase
+				updatePod(t, client, pod, func(p *v1.Pod) {
+					p.Labels = map[string]string{"track": track}
+					p.Status.Phase = phase
+				})
+			}
+			e := newTestElector(t, client, "pod-1")
+			e.observeEligibility(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{EligibleSelectorAnnotation: "track=green"},
+			}})
+
+			// we are never our own successor
+			if got := e.eligibleSuccessor(context.Background()); got != tc.want {
+				t.Fatalf("eligibleSuccessor = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIneligibleLeaderHandsOver(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	updatePod(t, client, "pod-1", func(p *v1.Pod) { p.Labels = map[string]string{"track": "blue"} })
+	e := newTestElector(t, client, "pod-1", WithMaintenanceInterval(10*time.Millisecond))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if reason := e.selectedOut(); reason != "" {
+		t.Fatalf("selected out before the switch: %s", reason)
+	}
+
+	if err := SwitchEligibility(context.Background(), client, testNamespace, testLock, ConfigMapBackend, "track=green"); err != nil {
+		t.Fatalf("SwitchEligibility: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.maintain(ctx)
+
+	// without a green candidate the leader resigns
+	if e.IsLeader() {
+		t.Fatal("still the leader on the old track")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock still held by %q", owner)
+	}
+	if ok, err := e.TryAcquire(context.Background()); err != nil || ok {
+		t.Fatalf("TryAcquire on the old track = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,159 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// callLogSize is how many apiserver calls the call log keeps.
+const callLogSize = 256
+
+// minThrottleRecorded is the shortest wait on the client-side rate limiter
+// that is recorded.
+const minThrottleRecorded = time.Millisecond
+
+// APICall is a breadcrumb of one apiserver call, or of a wait on the
+// client-side rate limiter before one.
+type APICall struct {
+	Time    time.Time
+	Method  string
+	Path    string
+	Code    int
+	Latency time.Duration
+	Err     string
+	// Throttled is true for a wait on the client-side rate limiter; Latency
+	// is then the time waited.
+	Throttled bool
+}
+
+func (c APICall) String() string {
+	if c.Throttled {
+		return fmt.Sprintf("%s throttled by client-side rate limiter for %s", c.Time.Format(time.RFC3339Nano), c.Latency)
+	}
+	s := fmt.Sprintf("%s %s %s %d in %s", c.Time.Format(time.RFC3339Nano), c.Method, c.Path, c.Code, c.Latency)
+	if c.Err != "" {
+		s += ": " + c.Err
+	}
+	return s
+}
+
+// callLog is a ring buffer of the latest apiserver calls. Electors sharing a
+// client share its log.
+type callLog struct {
+	mu    sync.Mutex
+	calls []APICall
+	next  int
+	full  bool
+}
+
+func newCallLog(size int) *callLog {
+	return &callLog{calls: make([]APICall, size)}
+}
+
+func (l *callLog) record(c APICall) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls[l.next] = c
+	l.next = (l.next + 1) % len(l.calls)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// snapshot returns the recorded calls, oldest first.
+func (l *callLog) snapshot() []APICall {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]APICall(nil), l.calls[:l.next]...)
+	}
+	return append(append([]APICall(nil), l.calls[l.next:]...), l.calls[:l.next]...)
+}
+
+// Dump returns the latest apiserver calls made with our client, oldest
+// first, with their latency and response code, and the waits imposed by the
+// client-side rate limiter, so throttling during an election can be told
+// apart from a slow apiserver. Calls are only recorded at DebugLevel and
+// when the client is built by the package rather than injected.
+func (e *PodElector) Dump() []APICall {
+	if e.opts.calls == nil {
+		return nil
+	}
+	return e.opts.calls.snapshot()
+}
+
+// recordCalls makes conf record its calls and rate limiter waits in l.
+func recordCalls(conf *rest.Config, l *callLog) {
+	wrap := conf.WrapTransport
+	conf.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &recordingTransport{next: rt, log: l}
+	}
+
+	limiter := conf.RateLimiter
+	if limiter == nil {
+		qps, burst := conf.QPS, conf.Burst
+		if qps == 0 {
+			qps = rest.DefaultQPS
+		}
+		if burst == 0 {
+			burst = rest.DefaultBurst
+		}
+		limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+	conf.RateLimiter = &recordingLimiter{RateLimiter: limiter, log: l}
+}
+
+type recordingTransport struct {
+	next http.RoundTripper
+	log  *callLog
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	c := APICall{
+		Time:    start,
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Latency: time.Since(start),
+	}
+	if resp != nil {
+		c.Code = resp.StatusCode
+	}
+	if err != nil {
+		c.Err = err.Error()
+	}
+	t.log.record(c)
+	return resp, err
+}
+
+type recordingLimiter struct {
+	flowcontrol.RateLimiter
+	log *callLog
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	if waited := time.Since(start); waited >= minThrottleRecorded {
+		l.log.record(APICall{Time: start, Latency: waited, Throttled: true})
+	}
+	return err
+}
+
+func (l *recordingLimiter) Accept() {
+	start := time.Now()
+	l.RateLimiter.Accept()
+	if waited := time.Since(start); waited >= minThrottleRecorded {
+		l.log.record(APICall{Time: start, Latency: waited, Throttled: true})
+	}
+}
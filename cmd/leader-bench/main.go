@@ -0,0 +1,320 @@
+// Command leader-bench measures how an election of
+// github.com/seamounts/k8s-leader behaves with hundreds of candidates for
+// one lock, against a disposable cluster such as kind. It creates a
+// placeholder pod per candidate, kept Pending by a node selector no node
+// matches, runs every candidate's Elector in this process, and reports how
+// long the first election and every failover took, and how many apiserver
+// requests the candidates made while waiting and while failing over.
+//
+// Usage:
+//
+//	leader-bench -candidates 200 -rounds 5 [-profile large-fleet] [-committee 5] [-failure kill]
+//
+// The failure modes are:
+//
+//	resign  the leader releases the lock
+//	kill    the leader stops without releasing the lock and its pod is
+//	        deleted, leaving the lock to the garbage collector
+//
+// Run it against a cluster nothing else uses: it needs to create pods and
+// lock objects in -namespace, and its request rates are only meaningful
+// when the apiserver is otherwise idle.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	leader "github.com/seamounts/k8s-leader"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// benchLabel marks the placeholder pods, and keeps them from being
+// scheduled through their node selector.
+const benchLabel = "leader.seamounts.io/bench"
+
+type bench struct {
+	client    kubernetes.Interface
+	ns        string
+	lock      string
+	opts      []leader.Option
+	requests  *counter
+	mu        sync.Mutex
+	members   []*member
+	generated int
+}
+
+// member is one candidate: its placeholder pod and Elector.
+type member struct {
+	pod     string
+	elector *leader.PodElector
+	stop    context.CancelFunc
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "leader-bench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	kubeconfig := flag.String("kubeconfig", "", "path to the kubeconfig file; defaults to the usual loading rules")
+	ns := flag.String("namespace", "leader-bench", "namespace to run in; created if missing")
+	lock := flag.String("lock", "bench", "name of the lock")
+	candidates := flag.Int("candidates", 200, "number of candidates")
+	rounds := flag.Int("rounds", 5, "number of failovers")
+	failure := flag.String("failure", "resign", "how the leader goes: resign or kill")
+	profile := flag.String("profile", "default", "options profile: default or large-fleet")
+	backend := flag.String("backend", string(leader.ConfigMapBackend), "lock backend: ConfigMap or Lease")
+	steady := flag.Duration("steady", 30*time.Second, "time to measure the request rate of waiting candidates over")
+	timeout := flag.Duration("failover-timeout", 5*time.Minute, "longest a failover may take")
+	qps := flag.Float64("qps", 1000, "client-side rate limit shared by the candidates")
+	committee := flag.Int("committee", 0, "committee seats, as set with WithCommittee; 0 for a one-stage election")
+	flag.Parse()
+
+	if *failure != "resign" && *failure != "kill" {
+		return fmt.Errorf("unknown failure %q", *failure)
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = *kubeconfig
+	conf, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return err
+	}
+	// a shared client must not throttle the candidates below what as many
+	// pods would be allowed
+	conf.QPS, conf.Burst = float32(*qps), int(*qps)
+	requests := &counter{}
+	conf.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return &countingTransport{next: rt, counter: requests}
+	}
+	client, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	b := &bench{
+		client:   client,
+		ns:       *ns,
+		lock:     *lock,
+		requests: requests,
+		opts: []leader.Option{
+			leader.WithClient(client),
+			leader.WithNamespace(*ns),
+			leader.WithBackend(leader.Backend(*backend)),
+			leader.WithLogLevel(leader.ErrorLevel),
+		},
+	}
+	switch *profile {
+	case "default":
+	case "large-fleet":
+		b.opts = append(b.opts, leader.WithLargeFleetProfile())
+	default:
+		return fmt.Errorf("unknown profile %q", *profile)
+	}
+	if *committee > 0 {
+		b.opts = append(b.opts, leader.WithCommittee(*committee))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.setUp(ctx); err != nil {
+		return err
+	}
+	defer b.tearDown()
+
+	fmt.Printf("%d candidates for %s/%s, profile %s, backend %s\n", *candidates, *ns, *lock, *profile, *backend)
+	start := time.Now()
+	for i := 0; i < *candidates; i++ {
+		if err := b.join(ctx); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("  candidates started in %s\n", time.Since(start).Round(time.Millisecond))
+	if _, err := b.waitLeader(ctx, nil, *timeout); err != nil {
+		return err
+	}
+	fmt.Printf("  first leader elected after %s\n", time.Since(start).Round(time.Millisecond))
+
+	before := requests.load()
+	time.Sleep(*steady)
+	fmt.Printf("  waiting candidates: %.1f requests/s\n", float64(requests.load()-before)/steady.Seconds())
+
+	var failovers []time.Duration
+	for round := 1; round <= *rounds; round++ {
+		old, err := b.waitLeader(ctx, nil, *timeout)
+		if err != nil {
+			return err
+		}
+		before := requests.load()
+		start := time.Now()
+		if err := b.fail(ctx, old, *failure); err != nil {
+			return err
+		}
+		if _, err := b.waitLeader(ctx, old, *timeout); err != nil {
+			return err
+		}
+		took := time.Since(start)
+		failovers = append(failovers, took)
+		fmt.Printf("  failover %d: %s, %d requests\n", round, took.Round(time.Millisecond), requests.load()-before)
+
+		if *failure == "kill" {
+			// keep the number of candidates up
+			if err := b.join(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if len(failovers) > 0 {
+		sort.Slice(failovers, func(i, j int) bool { return failovers[i] < failovers[j] })
+		fmt.Printf("  failover p50 %s, max %s\n", failovers[len(failovers)/2].Round(time.Millisecond), failovers[len(failovers)-1].Round(time.Millisecond))
+	}
+	return nil
+}
+
+func (b *bench) setUp(ctx context.Context) error {
+	_, err := b.client.CoreV1().Namespaces().Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: b.ns}}, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// tearDown stops every candidate and deletes the placeholder pods, and with
+// them the lock.
+func (b *bench) tearDown() {
+	b.mu.Lock()
+	members := b.members
+	b.mu.Unlock()
+	for _, m := range members {
+		m.stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := b.client.CoreV1().Pods(b.ns).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: benchLabel})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "leader-bench: deleting placeholder pods: %v\n", err)
+	}
+}
+
+// join creates a placeholder pod and starts a candidate in it.
+func (b *bench) join(ctx context.Context) error {
+	b.mu.Lock()
+	b.generated++
+	name := fmt.Sprintf("%s-%d", b.lock, b.generated)
+	b.mu.Unlock()
+
+	_, err := b.client.CoreV1().Pods(b.ns).Create(ctx, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{benchLabel: b.lock},
+		},
+		Spec: v1.PodSpec{
+			NodeSelector: map[string]string{benchLabel: "unschedulable"},
+			Containers:   []v1.Container{{Name: "placeholder", Image: "registry.k8s.io/pause:3.2"}},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("create placeholder pod: %w", err)
+	}
+
+	opts := append(append([]leader.Option(nil), b.opts...), leader.WithPodName(name))
+	e, err := leader.NewElector(b.lock, opts...)
+	if err != nil {
+		return err
+	}
+	memberCtx, stop := context.WithCancel(ctx)
+	m := &member{pod: name, elector: e, stop: stop}
+	go m.run(memberCtx)
+
+	b.mu.Lock()
+	b.members = append(b.members, m)
+	b.mu.Unlock()
+	return nil
+}
+
+// run competes for the lock again whenever leadership is lost, until ctx is
+// cancelled. Cancelling ctx stops the candidate without releasing the lock.
+func (m *member) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := m.elector.Become(ctx); err != nil {
+			return
+		}
+		for m.elector.IsLeader() && ctx.Err() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// waitLeader returns the member that leads, other than not.
+func (b *bench) waitLeader(ctx context.Context, not *member, timeout time.Duration) (*member, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		members := b.members
+		b.mu.Unlock()
+		for _, m := range members {
+			if m != not && m.elector.IsLeader() {
+				return m, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return nil, errors.New("no leader was elected in time")
+}
+
+// fail makes m, the leader, go as the failure mode says.
+func (b *bench) fail(ctx context.Context, m *member, failure string) error {
+	if failure == "resign" {
+		return m.elector.Resign(ctx)
+	}
+
+	m.stop()
+	b.mu.Lock()
+	for i, other := range b.members {
+		if other == m {
+			b.members = append(b.members[:i:i], b.members[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+	grace := int64(0)
+	return b.client.CoreV1().Pods(b.ns).Delete(ctx, m.pod, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+}
+
+// counter counts apiserver requests.
+type counter struct {
+	n int64
+}
+
+func (c *counter) load() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+type countingTransport struct {
+	next    http.RoundTripper
+	counter *counter
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&t.counter.n, 1)
+	return t.next.RoundTrip(req)
+}
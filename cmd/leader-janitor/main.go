@@ -0,0 +1,158 @@
+// Command leader-janitor deletes the lock objects of
+// github.com/seamounts/k8s-leader left behind by pods that no longer exist,
+// in one namespace or across the cluster. Run it as a Deployment bound to
+// the rules of leader.ClusterJanitorRBAC; with -lock its replicas elect a
+// leader among themselves and only the leader sweeps.
+//
+// Usage:
+//
+//	leader-janitor [-namespace ns] [-interval 10m] [-stale-after 1h] [-dry-run] [-once]
+//	leader-janitor -rbac -service-account-namespace ns -service-account name
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	leader "github.com/seamounts/k8s-leader"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "leader-janitor: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	kubeconfig := flag.String("kubeconfig", "", "path to the kubeconfig file; defaults to the in-cluster config or the usual loading rules")
+	namespace := flag.String("namespace", metav1.NamespaceAll, "namespace to sweep; empty sweeps every namespace")
+	interval := flag.Duration("interval", 0, "time between sweeps; defaults to ten minutes")
+	staleAfter := flag.Duration("stale-after", 0, "also delete locks that expired longer ago than this; 0 keeps them")
+	dryRun := flag.Bool("dry-run", false, "only report what would be deleted")
+	once := flag.Bool("once", false, "sweep once and exit")
+	lock := flag.String("lock", "", "lock the replicas elect a sweeping leader with; empty sweeps without an election")
+	metricsAddr := flag.String("metrics-addr", "", "address to serve Prometheus metrics on, e.g. :8080")
+	rbac := flag.Bool("rbac", false, "print the ClusterRole and ClusterRoleBinding the janitor needs and exit")
+	saNamespace := flag.String("service-account-namespace", "default", "namespace of the janitor's service account, for -rbac")
+	sa := flag.String("service-account", "leader-janitor", "name of the janitor's service account, for -rbac")
+	flag.Parse()
+
+	if *rbac {
+		out, err := leader.ClusterJanitorRBAC(*saNamespace, *sa).YAML()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = *kubeconfig
+	conf, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	if *metricsAddr != "" {
+		registry := prometheus.NewRegistry()
+		if err := leader.RegisterMetrics(registry); err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				fmt.Fprintf(os.Stderr, "leader-janitor: serving metrics: %v\n", err)
+			}
+		}()
+	}
+
+	j := &leader.Janitor{
+		Client:     client,
+		Namespace:  *namespace,
+		Interval:   *interval,
+		StaleAfter: *staleAfter,
+		DryRun:     *dryRun,
+	}
+	if *once {
+		deleted, err := j.Sweep(ctx)
+		for _, name := range deleted {
+			fmt.Println(name)
+		}
+		return err
+	}
+	if *lock == "" {
+		return ignoreCancel(j.Run(ctx))
+	}
+	return ignoreCancel(sweepWhileLeading(ctx, j, *lock, leader.WithClient(client)))
+}
+
+// sweepWhileLeading runs j for as long as we lead lock, and takes part in
+// the election again whenever leadership is lost, until ctx is cancelled.
+func sweepWhileLeading(ctx context.Context, j *leader.Janitor, lock string, opts ...leader.Option) error {
+	elector, err := leader.NewElector(lock, opts...)
+	if err != nil {
+		return err
+	}
+	events := elector.Subscribe()
+	for {
+		if err := elector.Become(ctx); err != nil {
+			return err
+		}
+
+		leading, stop := context.WithCancel(ctx)
+		go func() {
+			defer stop()
+			for elector.IsLeader() {
+				select {
+				case <-leading.Done():
+					return
+				case <-events:
+				}
+			}
+		}()
+		err := j.Run(leading)
+		stop()
+		if ctx.Err() != nil {
+			if err := elector.Resign(context.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "leader-janitor: releasing %s: %v\n", lock, err)
+			}
+			return ctx.Err()
+		}
+		if err != nil && leading.Err() == nil {
+			return err
+		}
+	}
+}
+
+func ignoreCancel(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,243 @@
+// Command leader-sim estimates how long failover takes with a given
+// configuration of github.com/seamounts/k8s-leader, before it is deployed.
+// It simulates the election loop of the standby pods, with their jittered
+// exponential backoff, against a leader that goes away, and prints
+// percentiles of the time from the leader going to a standby taking over.
+//
+// Usage:
+//
+//	leader-sim -replicas 3 -failure delete -gc-delay 2s [-watch] [-delete-lock]
+//	leader-sim -failure hang -expiry 15s -maintenance-interval 5s
+//
+// The failure modes are:
+//
+//	release  the leader releases the lock as it shuts down, as Run does
+//	delete   the leader's pod is deleted without releasing the lock, which
+//	         the garbage collector removes -gc-delay later
+//	hang     the leader's pod keeps running but stops renewing the lock;
+//	         only a lock expiry, WithLockExpiry, ends its term
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// The defaults of the package the flags default to.
+const (
+	defaultInitialBackoff      = time.Second
+	defaultMaxBackoff          = 16 * time.Second
+	defaultMaintenanceInterval = 5 * time.Second
+
+	// jitterFactor is the jitter the package applies to every backoff.
+	jitterFactor = .2
+)
+
+// never is the takeover time of a trial in which nobody takes over.
+const never = time.Duration(math.MaxInt64)
+
+type config struct {
+	replicas            int
+	failure             string
+	initialBackoff      time.Duration
+	maxBackoff          time.Duration
+	gcDelay             time.Duration
+	gcJitter            float64
+	rtt                 time.Duration
+	watch               bool
+	watchLatency        time.Duration
+	deleteLock          bool
+	expiry              time.Duration
+	maintenanceInterval time.Duration
+}
+
+func main() {
+	var c config
+	flag.IntVar(&c.replicas, "replicas", 3, "number of replicas, the leader included")
+	flag.StringVar(&c.failure, "failure", "delete", "how the leader goes: release, delete or hang")
+	flag.DurationVar(&c.initialBackoff, "initial-backoff", defaultInitialBackoff, "backoff of a standby that saw the leader go")
+	flag.DurationVar(&c.maxBackoff, "max-backoff", defaultMaxBackoff, "longest backoff, as set with WithMaxBackoff")
+	flag.DurationVar(&c.gcDelay, "gc-delay", 2*time.Second, "time the garbage collector takes to remove the lock of a deleted pod")
+	flag.Float64Var(&c.gcJitter, "gc-jitter", 1, "the GC delay varies up to this factor above -gc-delay")
+	flag.DurationVar(&c.rtt, "rtt", 20*time.Millisecond, "round trip time of an apiserver request")
+	flag.BoolVar(&c.watch, "watch", false, "standbys watch the lock and leader pod, as with WithFailoverWatch")
+	flag.DurationVar(&c.watchLatency, "watch-latency", 50*time.Millisecond, "time a watch event takes to reach a standby")
+	flag.BoolVar(&c.deleteLock, "delete-lock", false, "standbys delete the lock of a deleted leader, as with LeaderGoneDeleteLock")
+	flag.DurationVar(&c.expiry, "expiry", 0, "lock expiry, as set with WithLockExpiry; 0 for none")
+	flag.DurationVar(&c.maintenanceInterval, "maintenance-interval", defaultMaintenanceInterval, "interval the leader renews the lock at")
+	trials := flag.Int("trials", 10000, "number of failovers to simulate")
+	seed := flag.Int64("seed", 0, "seed of the simulation; 0 picks one")
+	flag.Parse()
+
+	if err := c.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "leader-sim: %v\n", err)
+		os.Exit(2)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(*seed))
+
+	results := make([]time.Duration, 0, *trials)
+	stranded := 0
+	for i := 0; i < *trials; i++ {
+		d := c.trial(r)
+		if d == never {
+			stranded++
+			continue
+		}
+		results = append(results, d)
+	}
+
+	fmt.Printf("%d failovers of %d replicas, failure %s, seed %d\n", *trials, c.replicas, c.failure, *seed)
+	if stranded > 0 {
+		fmt.Printf("  %d never failed over\n", stranded)
+	}
+	if len(results) == 0 {
+		return
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i] < results[j] })
+	var sum time.Duration
+	for _, d := range results {
+		sum += d
+	}
+	fmt.Printf("  mean %s\n", round(sum/time.Duration(len(results))))
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		fmt.Printf("  p%-4g %s\n", p, round(percentile(results, p)))
+	}
+	fmt.Printf("  max   %s\n", round(results[len(results)-1]))
+}
+
+func (c *config) validate() error {
+	switch {
+	case c.replicas < 2:
+		return errors.New("-replicas must be at least 2 for a standby to take over")
+	case c.initialBackoff <= 0 || c.maxBackoff < c.initialBackoff:
+		return errors.New("-max-backoff must be at least -initial-backoff, which must be positive")
+	case c.failure != "release" && c.failure != "delete" && c.failure != "hang":
+		return fmt.Errorf("unknown failure %q", c.failure)
+	}
+	return nil
+}
+
+// trial simulates one failover, with the leader going at time 0, and
+// returns when a standby took over.
+func (c *config) trial(r *rand.Rand) time.Duration {
+	// podGone is when standbys can see the leader's pod is gone, lockGone
+	// when the lock is, and free when a standby polling may take over
+	podGone, lockGone, free := never, never, never
+	switch c.failure {
+	case "release":
+		lockGone = 0
+	case "delete":
+		podGone = 0
+		lockGone = jitter(r, c.gcDelay, c.gcJitter)
+	case "hang":
+		if c.expiry > 0 {
+			lastRenewal := -time.Duration(r.Int63n(int64(c.maintenanceInterval)))
+			free = lastRenewal + c.expiry
+		}
+	}
+
+	standbys := make([]*standby, c.replicas-1)
+	for i := range standbys {
+		// waiting on a healthy leader has taken their backoff to the
+		// maximum, and they poll out of phase
+		standbys[i] = &standby{
+			next:    time.Duration(r.Int63n(int64(jitter(r, c.maxBackoff, jitterFactor)))),
+			backoff: c.maxBackoff,
+		}
+	}
+
+	if c.deleteLock && podGone != never {
+		// the first standby to see the pod gone deletes the lock, after
+		// verifying its owner
+		for _, s := range standbys {
+			t := s.next
+			if c.watch && podGone+c.watchLatency < t {
+				t = podGone + c.watchLatency
+			}
+			if deleted := t + 2*c.rtt; deleted < lockGone {
+				lockGone = deleted
+			}
+		}
+	}
+
+	best := never
+	for _, s := range standbys {
+		if t := c.takeOver(r, s, podGone, lockGone, free); t < best {
+			best = t
+		}
+	}
+	return best
+}
+
+// standby is the state of a standby's election loop.
+type standby struct {
+	next    time.Duration
+	backoff time.Duration
+}
+
+// takeOver returns when s takes over, polling until it sees the lock gone
+// or free.
+func (c *config) takeOver(r *rand.Rand, s *standby, podGone, lockGone, free time.Duration) time.Duration {
+	if lockGone == never && free == never {
+		return never
+	}
+	resetPod := false
+	for {
+		t := s.next
+		// a watch wakes the loop early
+		if c.watch {
+			if w := podGone + c.watchLatency; podGone != never && !resetPod && w < t {
+				t = w
+			}
+			if w := lockGone + c.watchLatency; lockGone != never && w < t {
+				t = w
+			}
+		}
+
+		switch {
+		case t >= lockGone:
+			return t + c.rtt
+		case t >= free:
+			// delete the expired lock, then create it
+			return t + 2*c.rtt
+		case t >= podGone && !resetPod:
+			// the election loop resets the backoff once the pod is gone
+			resetPod = true
+			s.backoff = c.initialBackoff
+		}
+		s.next = t + c.rtt + jitter(r, s.backoff, jitterFactor)
+		if s.backoff < c.maxBackoff {
+			s.backoff *= 2
+		}
+	}
+}
+
+// jitter returns a duration between d and d+maxFactor*d.
+func jitter(r *rand.Rand, d time.Duration, maxFactor float64) time.Duration {
+	return d + time.Duration(r.Float64()*maxFactor*float64(d))
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func round(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
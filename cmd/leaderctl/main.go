@@ -0,0 +1,293 @@
+// Command leaderctl operates on the locks of github.com/seamounts/k8s-leader
+// from outside the cluster.
+//
+// Usage:
+//
+//	leaderctl switch -namespace ns -lock name -selector track=green
+//	leaderctl status -namespace ns [name ...]
+//	leaderctl preflight -lock name
+//	leaderctl maintenance -namespace ns -pod name [-off]
+//	leaderctl pin -namespace ns -lock name -pod name
+//	leaderctl decisions -namespace ns -lock name
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	leader "github.com/seamounts/k8s-leader"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: leaderctl <command> [flags]
+
+Commands:
+  switch       shift leadership to the pods matching a label selector
+  status       show the leaders of locks
+  preflight    check that this pod could take part in an election
+  maintenance  take a pod out of the leader rotation, or back in with -off
+  pin          pin leadership to a pod; an empty -pod lifts the pin
+  decisions    show the recorded decisions of the pods electing a lock
+`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch cmd := os.Args[1]; cmd {
+	case "switch":
+		err = switchCmd(os.Args[2:])
+	case "status":
+		err = statusCmd(os.Args[2:])
+	case "preflight":
+		err = preflightCmd(os.Args[2:])
+	case "maintenance":
+		err = maintenanceCmd(os.Args[2:])
+	case "pin":
+		err = pinCmd(os.Args[2:])
+	case "decisions":
+		err = decisionsCmd(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "leaderctl: unknown command %q\n", cmd)
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "leaderctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// common are the flags shared by every command.
+type common struct {
+	kubeconfig string
+	namespace  string
+	lock       string
+	backend    string
+	timeout    time.Duration
+}
+
+func (c *common) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.kubeconfig, "kubeconfig", "", "path to the kubeconfig file; defaults to the usual loading rules")
+	fs.StringVar(&c.namespace, "namespace", "", "namespace of the lock; defaults to the kubeconfig context's")
+	fs.StringVar(&c.lock, "lock", "", "name of the lock")
+	fs.StringVar(&c.backend, "backend", string(leader.ConfigMapBackend), "lock backend: ConfigMap, Lease, Migration or Auto")
+	fs.DurationVar(&c.timeout, "timeout", time.Second*30, "time limit for the command")
+}
+
+// client returns a client and the namespace to operate in.
+func (c *common) client() (kubernetes.Interface, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	ns := c.namespace
+	if ns == "" {
+		var err error
+		if ns, _, err = config.Namespace(); err != nil {
+			return nil, "", err
+		}
+	}
+	conf, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, ns, nil
+}
+
+func switchCmd(args []string) error {
+	var c common
+	fs := flag.NewFlagSet("switch", flag.ExitOnError)
+	c.register(fs)
+	selector := fs.String("selector", "", "label selector of the pods that may lead, e.g. track=green; empty lifts the restriction")
+	fs.Parse(args)
+
+	if c.lock == "" {
+		return fmt.Errorf("-lock is required")
+	}
+	client, ns, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := leader.SwitchEligibility(ctx, client, ns, c.lock, leader.Backend(c.backend), *selector); err != nil {
+		return err
+	}
+	fmt.Printf("lock %s/%s: eligibility set to %q\n", ns, c.lock, *selector)
+	return nil
+}
+
+func statusCmd(args []string) error {
+	var c common
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	c.register(fs)
+	fs.Parse(args)
+
+	names := fs.Args()
+	if c.lock != "" {
+		names = append(names, c.lock)
+	}
+	client, ns, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	statuses, err := leader.Status(ctx, client, ns, leader.Backend(c.backend), names)
+	if err != nil {
+		return err
+	}
+	sorted := make([]string, 0, len(statuses))
+	for name := range statuses {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCK\tLEADER\tEPOCH\tSINCE")
+	for _, name := range sorted {
+		s := statuses[name]
+		if !s.Completed.IsZero() {
+			fmt.Fprintf(w, "%s\t<completed>\t%d\t%s\n", name, s.Epoch, s.Completed.Format(time.RFC3339))
+			continue
+		}
+		if !s.Held {
+			fmt.Fprintf(w, "%s\t<none>\t\t\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", name, s.Leader, s.Epoch, s.Since.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// preflightCmd runs the checks of leader.Preflight. Without -kubeconfig it
+// uses the in-cluster configuration, as the elector would; run it in the
+// application's pod, for example with kubectl exec.
+func preflightCmd(args []string) error {
+	var c common
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	c.register(fs)
+	fs.Parse(args)
+
+	if c.lock == "" {
+		return fmt.Errorf("-lock is required")
+	}
+	opts := []leader.Option{leader.WithBackend(leader.Backend(c.backend))}
+	if c.namespace != "" {
+		opts = append(opts, leader.WithNamespace(c.namespace))
+	}
+	if c.kubeconfig != "" {
+		client, ns, err := c.client()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, leader.WithClient(client), leader.WithNamespace(ns))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	report := leader.Preflight(ctx, c.lock, opts...)
+	fmt.Print(report)
+	if !report.OK() {
+		return fmt.Errorf("preflight checks failed")
+	}
+	return nil
+}
+
+// maintenanceCmd puts a pod into maintenance mode, or takes it out. Its
+// electors must use leader.WithRemoteMaintenance to notice.
+func maintenanceCmd(args []string) error {
+	var c common
+	fs := flag.NewFlagSet("maintenance", flag.ExitOnError)
+	c.register(fs)
+	pod := fs.String("pod", "", "name of the pod")
+	off := fs.Bool("off", false, "take the pod out of maintenance mode")
+	fs.Parse(args)
+
+	if *pod == "" {
+		return fmt.Errorf("-pod is required")
+	}
+	client, ns, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := leader.SetMaintenance(ctx, client, ns, *pod, !*off); err != nil {
+		return err
+	}
+	if *off {
+		fmt.Printf("pod %s/%s: maintenance mode off\n", ns, *pod)
+	} else {
+		fmt.Printf("pod %s/%s: maintenance mode on\n", ns, *pod)
+	}
+	return nil
+}
+
+func pinCmd(args []string) error {
+	var c common
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	c.register(fs)
+	pod := fs.String("pod", "", "name of the pod to pin leadership to; empty lifts the pin")
+	fs.Parse(args)
+
+	if c.lock == "" {
+		return fmt.Errorf("-lock is required")
+	}
+	client, ns, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := leader.PinLeader(ctx, client, ns, c.lock, leader.Backend(c.backend), *pod); err != nil {
+		return err
+	}
+	fmt.Printf("lock %s/%s: leader pinned to %q\n", ns, c.lock, *pod)
+	return nil
+}
+
+func decisionsCmd(args []string) error {
+	var c common
+	fs := flag.NewFlagSet("decisions", flag.ExitOnError)
+	c.register(fs)
+	fs.Parse(args)
+
+	if c.lock == "" {
+		return fmt.Errorf("-lock is required")
+	}
+	client, ns, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	decisions, err := leader.LockDecisions(ctx, client, ns, c.lock)
+	if err != nil {
+		return err
+	}
+	for _, d := range decisions {
+		fmt.Printf("%s %s\n", d.Pod, d.Decision)
+	}
+	return nil
+}
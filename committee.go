@@ -0,0 +1,190 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	committeeRole = "committee"
+
+	// seatRenewInterval is how often a seat holder renews its seat.
+	seatRenewInterval = candidateHeartbeatInterval
+	// seatTTL is how long a seat outlives its last renewal before another
+	// candidate of its bucket may take it over.
+	seatTTL = seatRenewInterval * 3
+)
+
+// committeeBucket returns the bucket of the committee, of seats buckets,
+// pod belongs to. Pods are spread over the buckets by a hash of their name,
+// so every pod computes the same bucket for a name.
+func committeeBucket(pod string, seats int) int {
+	h := fnv.New32a()
+	h.Write([]byte(pod))
+	return int(h.Sum32() % uint32(seats))
+}
+
+// seatName is the name of the Lease of the committee seat our bucket
+// elects.
+func (e *PodElector) seatName() string {
+	return fmt.Sprintf("%s-committee-%d", e.lockName, committeeBucket(e.owner.Name, e.opts.committee))
+}
+
+// takeSeat makes sure we hold the committee seat of our bucket and reports
+// whether we do. Only the candidates of a bucket contend for its seat, and
+// only seat holders for the lock, so a mass failover puts at most one
+// candidate per seat on the lock. A seat is a Lease owned by its holder's
+// pod, so it is garbage collected with it; a seat whose holder is verified
+// gone is freed right away, as with LeaderGoneDeleteLock. A seat is renewed
+// in the background for as long as we hold it, and one that has not been
+// renewed for seatTTL is freed as well, so a holder that is stuck, or left
+// the election without leaving its seat, does not keep its bucket out.
+func (e *PodElector) takeSeat(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	seated := e.seat != ""
+	e.mu.Unlock()
+	if seated {
+		return true, nil
+	}
+
+	leases := e.kube().CoordinationV1().Leases(e.ns)
+	ttl := int32(seatTTL / time.Second)
+	now := metav1.NewMicroTime(time.Now())
+	reqCtx, cancel := e.request(ctx)
+	seat, err := leases.Create(reqCtx, &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            e.seatName(),
+			Namespace:       e.ns,
+			OwnerReferences: []metav1.OwnerReference{*e.owner},
+			Labels: map[string]string{
+				LockLabel: e.lockName,
+				RoleLabel: committeeRole,
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &e.owner.Name,
+			LeaseDurationSeconds: &ttl,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}, metav1.CreateOptions{FieldManager: FieldManager})
+	cancel()
+	switch {
+	case err == nil:
+		e.log.Info("Took a seat on the committee", "lock", e.lockName, "seat", seat.Name)
+		e.holdSeat(ctx, seat.UID)
+		return true, nil
+	case !apierrors.IsAlreadyExists(err):
+		return false, forbidden(err, "create", coordinationv1.Resource("leases"), e.ns)
+	}
+
+	reqCtx, cancel = e.request(ctx)
+	seat, err = leases.Get(reqCtx, e.seatName(), metav1.GetOptions{})
+	cancel()
+	switch {
+	case apierrors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, forbidden(err, "get", coordinationv1.Resource("leases"), e.ns)
+	}
+	owners := seat.GetOwnerReferences()
+	if len(owners) != 1 {
+		return false, nil
+	}
+	if owners[0].UID == e.owner.UID {
+		// taken by us before a container restart
+		e.holdSeat(ctx, seat.UID)
+		return true, nil
+	}
+
+	if leaseExpired(seat, time.Now()) {
+		e.log.Info("Committee seat was not renewed in time, freeing the seat", "lock", e.lockName, "seat", seat.Name, "holder", owners[0].Name)
+	} else {
+		gone, err := e.ownerGone(ctx, owners[0])
+		if err != nil || !gone {
+			return false, err
+		}
+		e.log.Info("Committee seat holder no longer exists, freeing the seat", "lock", e.lockName, "seat", seat.Name, "holder", owners[0].Name)
+	}
+	reqCtx, cancel = e.request(ctx)
+	defer cancel()
+	pre := unchanged(seat)
+	err = leases.Delete(reqCtx, seat.Name, metav1.DeleteOptions{Preconditions: &pre})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return false, forbidden(err, "delete", coordinationv1.Resource("leases"), e.ns)
+	}
+	return false, nil
+}
+
+// holdSeat records the seat of uid as ours and renews it in the background
+// until we leave it or lose it. The seat is left once ctx is cancelled, as
+// it would no longer be renewed.
+func (e *PodElector) holdSeat(ctx context.Context, uid types.UID) {
+	e.mu.Lock()
+	e.seat = uid
+	e.mu.Unlock()
+
+	e.background.Add(1)
+	go func() {
+		defer e.background.Done()
+		for {
+			if err := e.sleep(ctx, seatRenewInterval); err != nil {
+				if e.holdsSeat(uid) {
+					e.leaveSeat()
+				}
+				return
+			}
+			if !e.holdsSeat(uid) {
+				return
+			}
+			err := e.renewLease(ctx, e.seatName(), uid)
+			switch {
+			case err == nil:
+			case apierrors.IsNotFound(err) || apierrors.IsConflict(err):
+				e.log.Info("Lost the committee seat", "lock", e.lockName, "seat", e.seatName())
+				e.mu.Lock()
+				if e.seat == uid {
+					e.seat = ""
+				}
+				e.mu.Unlock()
+				return
+			case ctx.Err() == nil:
+				e.log.Error(err, "Failed to renew the committee seat", "lock", e.lockName, "seat", e.seatName())
+			}
+		}
+	}()
+}
+
+func (e *PodElector) holdsSeat(uid types.UID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seat == uid
+}
+
+// leaveSeat gives up our committee seat, once we stop taking part in the
+// election or have to wait before we may compete again, so that another
+// candidate of our bucket can take it.
+func (e *PodElector) leaveSeat() {
+	e.mu.Lock()
+	uid := e.seat
+	e.seat = ""
+	e.mu.Unlock()
+	if uid == "" {
+		return
+	}
+
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+	pre := uidOnly(uid)
+	err := e.kube().CoordinationV1().Leases(e.ns).Delete(ctx, e.seatName(), metav1.DeleteOptions{Preconditions: &pre})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		e.log.Error(forbidden(err, "delete", coordinationv1.Resource("leases"), e.ns), "Failed to leave the committee", "lock", e.lockName, "seat", e.seatName())
+	}
+}
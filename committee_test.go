@@ -0,0 +1,94 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCommitteeBucket(t *testing.T) {
+	counts := map[int]int{}
+	for i := 0; i < 100; i++ {
+		pod := fmt.Sprintf("pod-%d", i)
+		bucket := committeeBucket(pod, 3)
+		if bucket < 0 || bucket >= 3 {
+			t.Fatalf("bucket of %s = %d, out of range", pod, bucket)
+		}
+		if again := committeeBucket(pod, 3); again != bucket {
+			t.Fatalf("bucket of %s = %d, then %d", pod, bucket, again)
+		}
+		counts[bucket]++
+	}
+	for bucket := 0; bucket < 3; bucket++ {
+		if counts[bucket] < 10 {
+			t.Fatalf("pods spread over buckets as %v", counts)
+		}
+	}
+}
+
+func TestTakeSeat(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// holder is the pod, of UID uid, holding the seat, last renewed
+		// renewed ago
+		holder  string
+		uid     types.UID
+		renewed time.Duration
+		seated  bool
+		freed   bool
+	}{
+		{name: "free", seated: true},
+		{name: "ours before a restart", holder: "pod-1", uid: "pod-1-uid", seated: true},
+		{name: "held", holder: "pod-2", uid: "pod-2-uid"},
+		{name: "holder gone", holder: "pod-3", uid: "pod-3-uid", freed: true},
+		{name: "holder recreated", holder: "pod-2", uid: "pod-2-old-uid", freed: true},
+		{name: "not renewed", holder: "pod-2", uid: "pod-2-uid", renewed: seatTTL * 2, freed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			e := newTestElector(t, client, "pod-1", WithCommittee(1))
+			leases := client.CoordinationV1().Leases(testNamespace)
+			if tc.holder != "" {
+				ttl := int32(seatTTL / time.Second)
+				renewed := metav1.NewMicroTime(time.Now().Add(-tc.renewed))
+				seat := &coordinationv1.Lease{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            e.seatName(),
+						Namespace:       testNamespace,
+						OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: tc.holder, UID: tc.uid}},
+					},
+					Spec: coordinationv1.LeaseSpec{LeaseDurationSeconds: &ttl, RenewTime: &renewed},
+				}
+				if _, err := leases.Create(context.Background(), seat, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			seated, err := e.takeSeat(ctx)
+			if err != nil {
+				t.Fatalf("takeSeat: %v", err)
+			}
+			if seated != tc.seated {
+				t.Fatalf("seated = %v, want %v", seated, tc.seated)
+			}
+			_, err = leases.Get(context.Background(), e.seatName(), metav1.GetOptions{})
+			if freed := apierrors.IsNotFound(err); freed != tc.freed {
+				t.Fatalf("seat freed = %v, want %v", freed, tc.freed)
+			}
+
+			// the seat is left with the election
+			cancel()
+			e.background.Wait()
+			if _, err := leases.Get(context.Background(), e.seatName(), metav1.GetOptions{}); tc.seated && !apierrors.IsNotFound(err) {
+				t.Fatalf("seat kept after the election ended: %v", err)
+			}
+		})
+	}
+}
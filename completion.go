@@ -0,0 +1,96 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// CompletedAnnotation is set on a lock whose holder has completed the
+	// work the lock guards, to the time it did. A completed lock is no
+	// longer held by anyone and cannot be acquired again.
+	CompletedAnnotation = "leader.seamounts.io/completed"
+
+	// CompletedByAnnotation names the pod that completed the work.
+	CompletedByAnnotation = "leader.seamounts.io/completed-by"
+)
+
+// AuditCompleted records that we completed the work guarded by the lock.
+const AuditCompleted AuditReason = "Completed"
+
+// ErrCompleted is returned by Become for a lock whose work has been
+// completed by an earlier holder.
+var ErrCompleted = errors.New("work already completed")
+
+// completedAt returns when the work guarded by lock was completed.
+func completedAt(lock metav1.Object) (time.Time, bool) {
+	value, ok := lock.GetAnnotations()[CompletedAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, _ := time.Parse(time.RFC3339, value)
+	return t, true
+}
+
+// complete records on the lock we hold that the work it guards is done, and
+// hands the lock from our pod to owners, so that it outlives our pod for as
+// long as they exist; with no owners it stays until it is deleted. We stop
+// leading, and candidates waiting for the lock get ErrCompleted.
+func (e *PodElector) complete(ctx context.Context, owners []metav1.OwnerReference) (err error) {
+	defer func() { err = e.wrap("complete", err) }()
+
+	e.mu.Lock()
+	if !e.leading {
+		e.mu.Unlock()
+		return ErrNotLeader
+	}
+	uid := *e.lockUID
+	e.mu.Unlock()
+
+	if owners == nil {
+		owners = []metav1.OwnerReference{}
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lock, err := e.getLock(ctx)
+		if err != nil {
+			return err
+		}
+		if lock.GetUID() != uid {
+			return ErrNotLeader
+		}
+		// the resourceVersion makes the patch fail rather than complete a
+		// lock changed since we read it
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": lock.GetResourceVersion(),
+				"ownerReferences": owners,
+				"annotations": map[string]interface{}{
+					CompletedAnnotation:   time.Now().UTC().Format(time.RFC3339),
+					CompletedByAnnotation: e.owner.Name,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return e.lockBackend().Patch(ctx, e.lockName, patch)
+	})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.leading = false
+	e.lockUID = nil
+	e.mu.Unlock()
+
+	e.log.Info("Completed the work of the lock", "lock", e.lockName)
+	e.audit(AuditCompleted, "Completed %s", e.lockName)
+	e.transition(false)
+	return nil
+}
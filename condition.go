@@ -0,0 +1,65 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// LeaderPodCondition is the pod condition type conventionally used with
+// WithPodCondition. It is True on the leader and False on other candidates.
+const LeaderPodCondition v1.PodConditionType = "seamounts.io/leader"
+
+// setPodCondition patches the leadership condition onto our pod's status.
+// The strategic merge patch replaces only the condition of our type. The
+// condition's LastTransitionTime only moves when its status flips, and a
+// condition that is already in place is left alone.
+func (e *PodElector) setPodCondition(leading bool) {
+	condition := v1.PodCondition{
+		Type:               e.opts.podConditionType,
+		Status:             v1.ConditionFalse,
+		Reason:             "NotLeader",
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	}
+	if leading {
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "Leader"
+		condition.Message = "Holds lock " + e.lockName
+	}
+
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+	pod, err := e.kube().CoreV1().Pods(e.ns).Get(ctx, e.owner.Name, metav1.GetOptions{})
+	if err != nil {
+		e.log.Error(err, "Failed to get pod for its condition", "condition", e.opts.podConditionType, "pod", e.owner.Name)
+		return
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type != condition.Type || c.Status != condition.Status {
+			continue
+		}
+		if c.Reason == condition.Reason && c.Message == condition.Message {
+			return
+		}
+		condition.LastTransitionTime = c.LastTransitionTime
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.PodCondition{condition},
+		},
+	})
+	if err != nil {
+		e.log.Error(err, "Failed to encode pod condition")
+		return
+	}
+
+	_, err = e.kube().CoreV1().Pods(e.ns).Patch(ctx, e.owner.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "status")
+	if err != nil {
+		e.log.Error(err, "Failed to set pod condition", "condition", e.opts.podConditionType, "pod", e.owner.Name)
+	}
+}
@@ -0,0 +1,101 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// podCondition returns the LeaderPodCondition of pod, or nil.
+func podCondition(t *testing.T, client *fake.Clientset, pod string) *v1.PodCondition {
+	t.Helper()
+	p, err := client.CoreV1().Pods(testNamespace).Get(context.Background(), pod, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range p.Status.Conditions {
+		if c.Type == LeaderPodCondition {
+			return &p.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestSetPodCondition(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	for _, tc := range []struct {
+		name     string
+		existing *v1.PodCondition
+		leading  bool
+		status   v1.ConditionStatus
+		reason   string
+		// kept is whether LastTransitionTime stays that of existing
+		kept bool
+	}{
+		{name: "gaining leadership", leading: true, status: v1.ConditionTrue, reason: "Leader"},
+		{name: "candidate", leading: false, status: v1.ConditionFalse, reason: "NotLeader"},
+		{
+			name:     "still leading",
+			existing: &v1.PodCondition{Type: LeaderPodCondition, Status: v1.ConditionTrue, Reason: "Leader", Message: "Holds lock " + testLock, LastTransitionTime: earlier},
+			leading:  true, status: v1.ConditionTrue, reason: "Leader", kept: true,
+		},
+		{
+			name:     "leading another lock before",
+			existing: &v1.PodCondition{Type: LeaderPodCondition, Status: v1.ConditionTrue, Reason: "Leader", Message: "Holds lock other", LastTransitionTime: earlier},
+			leading:  true, status: v1.ConditionTrue, reason: "Leader", kept: true,
+		},
+		{
+			name:     "losing leadership",
+			existing: &v1.PodCondition{Type: LeaderPodCondition, Status: v1.ConditionTrue, Reason: "Leader", LastTransitionTime: earlier},
+			leading:  false, status: v1.ConditionFalse, reason: "NotLeader", kept: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if tc.existing != nil {
+				pod, err := client.CoreV1().Pods(testNamespace).Get(context.Background(), "pod-1", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				pod.Status.Conditions = []v1.PodCondition{*tc.existing}
+				if _, err := client.CoreV1().Pods(testNamespace).UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1", WithPodCondition(LeaderPodCondition))
+
+			e.setPodCondition(tc.leading)
+			c := podCondition(t, client, "pod-1")
+			if c == nil {
+				t.Fatal("condition was not set")
+			}
+			if c.Status != tc.status || c.Reason != tc.reason {
+				t.Fatalf("condition = %s/%s, want %s/%s", c.Status, c.Reason, tc.status, tc.reason)
+			}
+			if kept := c.LastTransitionTime.Equal(&earlier); kept != tc.kept {
+				t.Fatalf("LastTransitionTime = %v, kept = %v, want %v", c.LastTransitionTime, kept, tc.kept)
+			}
+		})
+	}
+}
+
+func TestPodConditionFollowsLeadership(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithPodCondition(LeaderPodCondition))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if c := podCondition(t, client, "pod-1"); c == nil || c.Status != v1.ConditionTrue {
+		t.Fatalf("condition of the leader = %v, want True", c)
+	}
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if c := podCondition(t, client, "pod-1"); c == nil || c.Status != v1.ConditionFalse {
+		t.Fatalf("condition after releasing = %v, want False", c)
+	}
+}
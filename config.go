@@ -0,0 +1,152 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fieldsel "k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Keys of the configuration ConfigMap read with WithConfigMap. Durations
+// are in time.ParseDuration format. A key that is missing or removed
+// restores the value set by options.
+const (
+	ConfigMaxBackoff          = "maxBackoff"
+	ConfigMaintenanceInterval = "maintenanceInterval"
+	ConfigTransferTimeout     = "transferTimeout"
+	ConfigDrainTimeout        = "drainTimeout"
+	ConfigHealthFailures      = "healthFailures"
+	ConfigEligibilitySelector = "eligibilitySelector"
+)
+
+// configRewatchInterval is how long to wait before re-establishing a watch
+// on the configuration ConfigMap that failed.
+const configRewatchInterval = time.Second * 2
+
+// tuned returns the options in effect, including changes made through the
+// configuration ConfigMap.
+func (e *PodElector) tuned() options {
+	e.optsMu.RLock()
+	defer e.optsMu.RUnlock()
+	return e.tunedOpts
+}
+
+// startConfigWatch applies the configuration ConfigMap and keeps watching
+// it in the background until ctx is cancelled.
+func (e *PodElector) startConfigWatch(ctx context.Context) {
+	e.mu.Lock()
+	if e.watchingConfig {
+		e.mu.Unlock()
+		return
+	}
+	e.watchingConfig = true
+	e.mu.Unlock()
+
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	selector := fieldsel.OneTermEqualSelector("metadata.name", e.opts.configName).String()
+
+	resourceVersion := ""
+	getCtx, cancel := e.request(ctx)
+	cm, err := configMaps.Get(getCtx, e.opts.configName, metav1.GetOptions{})
+	cancel()
+	switch {
+	case err == nil:
+		e.applyConfig(cm.Data)
+		resourceVersion = cm.ResourceVersion
+	case !apierrors.IsNotFound(err):
+		e.log.Error(err, "Failed to get configuration", "configMap", e.opts.configName)
+	}
+
+	e.background.Add(1)
+	go func() {
+		defer e.background.Done()
+		defer func() {
+			e.mu.Lock()
+			e.watchingConfig = false
+			e.mu.Unlock()
+		}()
+		for ctx.Err() == nil {
+			w, err := e.kube().CoreV1().ConfigMaps(e.ns).Watch(ctx, metav1.ListOptions{
+				FieldSelector:   selector,
+				ResourceVersion: resourceVersion,
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					e.log.Error(err, "Failed to watch configuration", "configMap", e.opts.configName)
+				}
+				resourceVersion = ""
+				if e.sleep(ctx, configRewatchInterval) != nil {
+					return
+				}
+				continue
+			}
+			for ev := range w.ResultChan() {
+				cm, ok := ev.Object.(*v1.ConfigMap)
+				if !ok {
+					// the watch expired, start over from the current state
+					resourceVersion = ""
+					continue
+				}
+				resourceVersion = cm.ResourceVersion
+				switch ev.Type {
+				case watch.Added, watch.Modified:
+					e.applyConfig(cm.Data)
+				case watch.Deleted:
+					e.applyConfig(nil)
+				}
+			}
+			w.Stop()
+		}
+	}()
+}
+
+// applyConfig sets the options given in data on top of those set by
+// options. Malformed values are logged and ignored.
+func (e *PodElector) applyConfig(data map[string]string) {
+	o := e.opts
+
+	duration := func(key string, d *time.Duration) {
+		s, ok := data[key]
+		if !ok {
+			return
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil || v <= 0 {
+			e.log.Warn("Ignoring malformed configuration", "configMap", e.opts.configName, "key", key, "value", s)
+			return
+		}
+		*d = v
+	}
+	duration(ConfigMaxBackoff, &o.maxBackoff)
+	duration(ConfigMaintenanceInterval, &o.maintenanceInterval)
+	duration(ConfigTransferTimeout, &o.transferTimeout)
+	duration(ConfigDrainTimeout, &o.drainTimeout)
+
+	if s, ok := data[ConfigHealthFailures]; ok {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			o.healthFailures = n
+		} else {
+			e.log.Warn("Ignoring malformed configuration", "configMap", e.opts.configName, "key", ConfigHealthFailures, "value", s)
+		}
+	}
+	if s, ok := data[ConfigEligibilitySelector]; ok {
+		if selector, err := labels.Parse(s); err == nil {
+			o.eligibilitySelector = selector
+		} else {
+			e.log.Warn("Ignoring malformed configuration", "configMap", e.opts.configName, "key", ConfigEligibilitySelector, "value", s)
+		}
+	}
+
+	e.optsMu.Lock()
+	e.tunedOpts = o
+	e.optsMu.Unlock()
+	e.log.Info("Applied configuration", "configMap", e.opts.configName, "keys", len(data))
+	// let a loop sleeping on the old intervals pick up the new ones
+	e.wake()
+}
@@ -0,0 +1,110 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		data           map[string]string
+		maxBackoff     time.Duration
+		healthFailures int
+		selector       string
+	}{
+		{name: "no keys", maxBackoff: time.Minute, healthFailures: 5, selector: ""},
+		{
+			name:       "max backoff",
+			data:       map[string]string{ConfigMaxBackoff: "2s"},
+			maxBackoff: 2 * time.Second, healthFailures: 5,
+		},
+		{
+			name:       "malformed duration",
+			data:       map[string]string{ConfigMaxBackoff: "soon", ConfigHealthFailures: "2"},
+			maxBackoff: time.Minute, healthFailures: 2,
+		},
+		{
+			name:       "negative duration",
+			data:       map[string]string{ConfigMaxBackoff: "-1s"},
+			maxBackoff: time.Minute, healthFailures: 5,
+		},
+		{
+			name:       "malformed count",
+			data:       map[string]string{ConfigHealthFailures: "0"},
+			maxBackoff: time.Minute, healthFailures: 5,
+		},
+		{
+			name:       "selector",
+			data:       map[string]string{ConfigEligibilitySelector: "track=green"},
+			maxBackoff: time.Minute, healthFailures: 5, selector: "track=green",
+		},
+		{
+			name:       "malformed selector",
+			data:       map[string]string{ConfigEligibilitySelector: "track in (green"},
+			maxBackoff: time.Minute, healthFailures: 5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1", WithConfigMap("election-config"), WithMaxBackoff(time.Minute),
+				WithHealthCheck(func(context.Context) error { return nil }, 5, time.Minute))
+
+			e.applyConfig(tc.data)
+			o := e.tuned()
+			if o.maxBackoff != tc.maxBackoff || o.healthFailures != tc.healthFailures {
+				t.Fatalf("maxBackoff %v and healthFailures %d, want %v and %d", o.maxBackoff, o.healthFailures, tc.maxBackoff, tc.healthFailures)
+			}
+			selector := ""
+			if o.eligibilitySelector != nil {
+				selector = o.eligibilitySelector.String()
+			}
+			if selector != tc.selector {
+				t.Fatalf("eligibility selector = %q, want %q", selector, tc.selector)
+			}
+		})
+	}
+}
+
+func TestConfigWatch(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	config := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "election-config", Namespace: testNamespace},
+		Data:       map[string]string{ConfigMaxBackoff: "2s"},
+	}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), config, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	w := watch.NewFake()
+	client.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, w, nil
+	})
+	e := newTestElector(t, client, "pod-1", WithConfigMap("election-config"), WithMaxBackoff(time.Minute))
+	maxBackoff := func() time.Duration { return e.tuned().maxBackoff }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		w.Stop()
+		e.background.Wait()
+	}()
+	e.startConfigWatch(ctx)
+	if got := maxBackoff(); got != 2*time.Second {
+		t.Fatalf("maxBackoff = %v, want the configured 2s", got)
+	}
+
+	changed := config.DeepCopy()
+	changed.Data[ConfigMaxBackoff] = "3s"
+	w.Modify(changed)
+	waitFor(t, "the change to apply", func() bool { return maxBackoff() == 3*time.Second })
+
+	// deleting the ConfigMap restores the options
+	w.Delete(changed)
+	waitFor(t, "the options to be restored", func() bool { return maxBackoff() == time.Minute })
+}
@@ -0,0 +1,75 @@
+package leader
+
+import (
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// minReloadInterval is how long after reloading credentials we wait before
+// reloading them again, so a genuinely revoked identity does not rebuild
+// the client on every request.
+const minReloadInterval = time.Second * 10
+
+func (e *PodElector) kube() kubernetes.Interface {
+	e.clientMu.RLock()
+	defer e.clientMu.RUnlock()
+	return e.client
+}
+
+func (e *PodElector) lockBackend() backend {
+	e.clientMu.RLock()
+	defer e.clientMu.RUnlock()
+	return e.backend
+}
+
+// reloadCredentials rebuilds the client after the apiserver rejected our
+// credentials, for example because a projected service account token or a
+// client certificate was rotated underneath a long-running election. It
+// reports whether the request is worth retrying: a new client is in place,
+// or one was put in place less than minReloadInterval ago and may just not
+// have been accepted everywhere yet. Injected clients are never replaced.
+//
+// Watches pick up the new client when they are re-established. The shared
+// pod informer keeps the client it was started with until it is stopped.
+func (e *PodElector) reloadCredentials() bool {
+	if e.opts.client != nil {
+		return false
+	}
+
+	e.clientMu.Lock()
+	if time.Since(e.reloadedAt) < minReloadInterval {
+		e.clientMu.Unlock()
+		return true
+	}
+	e.reloadedAt = time.Now()
+	e.clientMu.Unlock()
+
+	// building the backend may probe the apiserver, so requests keep using
+	// the old client until the new one is ready
+	conf, err := restConfig(&e.opts)
+	if err != nil {
+		e.log.Error(err, "Failed to reload credentials", "lock", e.lockName)
+		return false
+	}
+	client, err := kubernetes.NewForConfig(conf)
+	if err != nil {
+		e.log.Error(err, "Failed to rebuild client", "lock", e.lockName)
+		return false
+	}
+	b, err := electorBackend(&e.opts, client, e.ns, e.log)
+	if err != nil {
+		e.log.Error(err, "Failed to rebuild lock backend", "lock", e.lockName)
+		return false
+	}
+
+	e.log.Info("Credentials were rejected, reloaded them", "lock", e.lockName)
+	e.clientMu.Lock()
+	e.client = client
+	e.backend = b
+	e.clientMu.Unlock()
+	if e.events != nil {
+		e.events.setClient(client)
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+func TestReloadCredentials(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// injected is whether the client was injected with WithClient rather
+		// than built from a config
+		injected   bool
+		reloadedAt time.Duration
+		retry      bool
+		replaced   bool
+	}{
+		{name: "injected client", injected: true, retry: false, replaced: false},
+		{name: "built client", retry: true, replaced: true},
+		{name: "reloaded just now", reloadedAt: time.Second, retry: true, replaced: false},
+		{name: "reloaded a while ago", reloadedAt: 2 * minReloadInterval, retry: true, replaced: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			if !tc.injected {
+				// as if built from a config; the fake client serves the pod
+				// lookup of NewElector
+				e.opts.client = nil
+				e.opts.restConfig = &rest.Config{Host: "https://apiserver.example.com"}
+			}
+			if tc.reloadedAt > 0 {
+				e.reloadedAt = time.Now().Add(-tc.reloadedAt)
+			}
+
+			unauthorized := apierrors.NewUnauthorized("token expired")
+			if got := e.retryable(context.Background(), unauthorized); got != tc.retry {
+				t.Fatalf("retryable after Unauthorized = %v, want %v", got, tc.retry)
+			}
+			if replaced := e.kube() != client; replaced != tc.replaced {
+				t.Fatalf("client replaced = %v, want %v", replaced, tc.replaced)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultDecisionLogSize is how many decisions the decision log keeps
+	// by default.
+	defaultDecisionLogSize = 128
+
+	// decisionRetention is how long the decisions of a pod are kept in the
+	// companion ConfigMap after its last one, so that pods long gone do
+	// not fill it up.
+	decisionRetention = 24 * time.Hour
+
+	decisionsRole = "decisions"
+)
+
+// Decision is a record of one decision of the election loop: the inputs it
+// observed, the rule that applied to them and the action it took.
+type Decision struct {
+	Time time.Time `json:"time"`
+	// Rule names the rule that applied, such as "leader pod deleted".
+	Rule string `json:"rule"`
+	// Action is what the Elector did about it, such as "wait" or "delete
+	// lock".
+	Action string `json:"action"`
+	// Observed holds the inputs the rule was applied to, such as the
+	// holder of the lock and the phase of its pod.
+	Observed map[string]string `json:"observed,omitempty"`
+}
+
+func (d Decision) String() string {
+	s := fmt.Sprintf("%s %s: %s", d.Time.Format(time.RFC3339Nano), d.Rule, d.Action)
+	keys := make([]string, 0, len(d.Observed))
+	for k := range d.Observed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s += fmt.Sprintf(" %s=%s", k, d.Observed[k])
+	}
+	return s
+}
+
+// decisionLog is a ring buffer of the latest decisions of an Elector.
+type decisionLog struct {
+	mu        sync.Mutex
+	decisions []Decision
+	next      int
+	full      bool
+}
+
+func newDecisionLog(size int) *decisionLog {
+	return &decisionLog{decisions: make([]Decision, size)}
+}
+
+// record appends d unless it repeats the latest decision, as a candidate
+// waiting for a healthy leader makes every attempt.
+func (l *decisionLog) record(d Decision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last := (l.next - 1 + len(l.decisions)) % len(l.decisions); (l.next > 0 || l.full) && sameDecision(l.decisions[last], d) {
+		return
+	}
+	l.decisions[l.next] = d
+	l.next = (l.next + 1) % len(l.decisions)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// snapshot returns the recorded decisions, oldest first.
+func (l *decisionLog) snapshot() []Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]Decision(nil), l.decisions[:l.next]...)
+	}
+	return append(append([]Decision(nil), l.decisions[l.next:]...), l.decisions[:l.next]...)
+}
+
+// decide records a decision of the election loop, with the observed inputs
+// given as alternating keys and values, as to a Logger.
+func (e *PodElector) decide(rule, action string, observed ...interface{}) {
+	if e.decisions == nil {
+		return
+	}
+	d := Decision{Time: time.Now(), Rule: rule, Action: action}
+	if len(observed) > 0 {
+		d.Observed = make(map[string]string, len(observed)/2)
+		for i := 0; i+1 < len(observed); i += 2 {
+			d.Observed[fmt.Sprint(observed[i])] = fmt.Sprint(observed[i+1])
+		}
+	}
+	e.decisions.record(d)
+}
+
+func sameDecision(a, b Decision) bool {
+	if a.Rule != b.Rule || a.Action != b.Action || len(a.Observed) != len(b.Observed) {
+		return false
+	}
+	for k, v := range a.Observed {
+		if b.Observed[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Decisions returns the latest decisions of the election and maintenance
+// loops, oldest first, so that a failover can be reconstructed after the
+// fact, as Dump does for the apiserver calls behind them. Decisions are
+// only recorded with WithDecisionLog.
+func (e *PodElector) Decisions() []Decision {
+	if e.decisions == nil {
+		return nil
+	}
+	return e.decisions.snapshot()
+}
+
+func (e *PodElector) decisionsName() string {
+	return e.lockName + "-decisions"
+}
+
+// persistDecisions is the transition hook writing our decisions to the
+// lock's companion ConfigMap, under our pod's name, so that they survive
+// the pod. Every change of leadership is recorded by the pod gaining it and,
+// if it still can, the one losing it.
+func (e *PodElector) persistDecisions(bool) {
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+	if err := e.writeDecisions(ctx); err != nil {
+		e.log.Error(err, "Failed to record decisions", "lock", e.lockName, "configmap", e.decisionsName())
+	}
+}
+
+func (e *PodElector) writeDecisions(ctx context.Context) error {
+	out, err := json.Marshal(e.Decisions())
+	if err != nil {
+		return err
+	}
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	cm, err := configMaps.Get(ctx, e.decisionsName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// the companion has no owner, so decisions outlive the pods
+		_, err = configMaps.Create(ctx, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      e.decisionsName(),
+				Namespace: e.ns,
+				Labels: map[string]string{
+					LockLabel: e.lockName,
+					RoleLabel: decisionsRole,
+				},
+			},
+			Data: map[string]string{e.owner.Name: string(out)},
+		}, metav1.CreateOptions{FieldManager: FieldManager})
+		if !apierrors.IsAlreadyExists(err) {
+			return forbidden(err, "create", v1.Resource("configmaps"), e.ns)
+		}
+	case err != nil:
+		return forbidden(err, "get", v1.Resource("configmaps"), e.ns)
+	}
+
+	data := map[string]interface{}{e.owner.Name: string(out)}
+	if cm != nil {
+		for pod, value := range cm.Data {
+			if pod != e.owner.Name && decisionsExpired(value) {
+				data[pod] = nil
+			}
+		}
+	}
+	patch, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return err
+	}
+	_, err = configMaps.Patch(ctx, e.decisionsName(), types.MergePatchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	return forbidden(err, "patch", v1.Resource("configmaps"), e.ns)
+}
+
+// LockDecisions returns the decisions recorded for the lock lockName in ns
+// with WithDecisionLog and persistence, merged across pods and oldest first,
+// with the pod that made each one.
+func LockDecisions(ctx context.Context, client kubernetes.Interface, ns, lockName string) ([]PodDecision, error) {
+	cm, err := client.CoreV1().ConfigMaps(ns).Get(ctx, lockName+"-decisions", metav1.GetOptions{})
+	if err != nil {
+		return nil, forbidden(err, "get", v1.Resource("configmaps"), ns)
+	}
+	var all []PodDecision
+	for pod, value := range cm.Data {
+		var decisions []Decision
+		if err := json.Unmarshal([]byte(value), &decisions); err != nil {
+			return nil, fmt.Errorf("decisions of %s: %w", pod, err)
+		}
+		for _, d := range decisions {
+			all = append(all, PodDecision{Pod: pod, Decision: d})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+	return all, nil
+}
+
+// PodDecision is a Decision made by the pod Pod.
+type PodDecision struct {
+	Pod string
+	Decision
+}
+
+// decisionsExpired reports whether the last of the decisions recorded in
+// value is older than decisionRetention.
+func decisionsExpired(value string) bool {
+	var decisions []Decision
+	if err := json.Unmarshal([]byte(value), &decisions); err != nil || len(decisions) == 0 {
+		return true
+	}
+	return time.Since(decisions[len(decisions)-1].Time) > decisionRetention
+}
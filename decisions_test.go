@@ -0,0 +1,132 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDecisionLog(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		size  int
+		rules []string
+		want  []string
+	}{
+		{name: "empty", size: 3},
+		{name: "partly filled", size: 3, rules: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "wrapped", size: 3, rules: []string{"a", "b", "c", "d"}, want: []string{"b", "c", "d"}},
+		{name: "repeats collapsed", size: 3, rules: []string{"a", "a", "b", "b", "a"}, want: []string{"a", "b", "a"}},
+		{name: "repeat across the wrap", size: 2, rules: []string{"a", "b", "b"}, want: []string{"a", "b"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newDecisionLog(tc.size)
+			for _, rule := range tc.rules {
+				l.record(Decision{Time: time.Now(), Rule: rule, Action: "wait"})
+			}
+			var got []string
+			for _, d := range l.snapshot() {
+				got = append(got, d.Rule)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("rules = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecisions(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	leader := newTestElector(t, client, "pod-2")
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if got := leader.Decisions(); got != nil {
+		t.Fatalf("Decisions without a decision log = %v", got)
+	}
+
+	e := newTestElector(t, client, "pod-1", WithDecisionLog(0, false))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := e.Become(ctx); err == nil {
+		t.Fatal("Become succeeded while pod-2 leads")
+	}
+
+	decisions := e.Decisions()
+	if len(decisions) != 1 {
+		t.Fatalf("decisions = %v, want the repeated wait recorded once", decisions)
+	}
+	d := decisions[0]
+	if d.Rule != "leader healthy" || d.Action != "wait" || d.Observed["leader"] != "pod-2" {
+		t.Fatalf("decision = %v, want to wait for pod-2", d)
+	}
+}
+
+func TestPersistDecisions(t *testing.T) {
+	old, err := json.Marshal([]Decision{{Time: time.Now().Add(-2 * decisionRetention), Rule: "lock free", Action: "became leader"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent, err := json.Marshal([]Decision{{Time: time.Now().Add(-time.Minute), Rule: "lock free", Action: "became leader"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		// data is what the companion ConfigMap holds beforehand, or nil if
+		// it does not exist
+		data map[string]string
+		pods []string
+	}{
+		{name: "no companion", pods: []string{"pod-1"}},
+		{name: "recent decisions kept", data: map[string]string{"pod-2": string(recent)}, pods: []string{"pod-1", "pod-2"}},
+		{name: "old decisions pruned", data: map[string]string{"pod-2": string(old)}, pods: []string{"pod-1"}},
+		{name: "unreadable decisions pruned", data: map[string]string{"pod-2": "{"}, pods: []string{"pod-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if tc.data != nil {
+				cm := &v1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: testLock + "-decisions", Namespace: testNamespace},
+					Data:       tc.data,
+				}
+				if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), cm, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1", WithDecisionLog(0, true))
+			if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+
+			cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock+"-decisions", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("decisions were not persisted: %v", err)
+			}
+			got := keysOf(cm.Data)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.pods) {
+				t.Fatalf("pods with decisions = %v, want %v", got, tc.pods)
+			}
+			if tc.data == nil && cm.Labels[LockLabel] != testLock {
+				t.Fatalf("companion labels = %v, want the lock", cm.Labels)
+			}
+
+			all, err := LockDecisions(context.Background(), client, testNamespace, testLock)
+			if err != nil {
+				t.Fatalf("LockDecisions: %v", err)
+			}
+			last := all[len(all)-1]
+			if last.Pod != "pod-1" || last.Rule != "lock free" || last.Action != "became leader" {
+				t.Fatalf("latest decision = %s %v, want pod-1 becoming the leader", last.Pod, last.Decision)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package leader
+
+import "time"
+
+// CandidacyDeniedAnnotation excludes the pod it is set on from candidacy,
+// whatever its value, which may say why: the pod does not compete for any
+// lock, and gives up leadership if it leads. It is the knob for admins and
+// automation to get a pod out of the leader rotation with kubectl annotate.
+const CandidacyDeniedAnnotation = "leader.seamounts.io/deny-candidacy"
+
+// denyCheckInterval is how often our pod is re-read for
+// CandidacyDeniedAnnotation when no option has it re-read every attempt.
+const denyCheckInterval = time.Second * 10
+
+// deniedReason returns why our pod is denied candidacy, or "".
+func (e *PodElector) deniedReason() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.denied {
+		return ""
+	}
+	if e.deniedBy == "" {
+		return "candidacy denied by " + CandidacyDeniedAnnotation
+	}
+	return "candidacy denied by " + CandidacyDeniedAnnotation + ": " + e.deniedBy
+}
+
+// observeDenied records whether annotations, those of our pod, deny us
+// candidacy. Called with e.mu held.
+func (e *PodElector) observeDenied(annotations map[string]string) {
+	e.deniedBy, e.denied = annotations[CandidacyDeniedAnnotation]
+}
+
+// excludedPod reports whether annotations put the pod they belong to out of
+// the leader rotation.
+func excludedPod(annotations map[string]string) bool {
+	_, denied := annotations[CandidacyDeniedAnnotation]
+	_, maintenance := annotations[MaintenanceAnnotation]
+	return denied || maintenance
+}
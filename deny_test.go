@@ -0,0 +1,78 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestDeniedCandidacy(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		at     string
+		reason string
+		want   string
+	}{
+		{name: "not denied"},
+		{name: "denied at start", at: "start", want: "candidacy denied by " + CandidacyDeniedAnnotation},
+		{name: "denied with a reason", at: "start", reason: "disk errors", want: "candidacy denied by " + CandidacyDeniedAnnotation + ": disk errors"},
+		{name: "denied later", at: "later", reason: "disk errors", want: "candidacy denied by " + CandidacyDeniedAnnotation + ": disk errors"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			deny := func() {
+				updatePod(t, client, "pod-1", func(pod *v1.Pod) {
+					pod.Annotations = map[string]string{CandidacyDeniedAnnotation: tc.reason}
+				})
+			}
+			if tc.at == "start" {
+				deny()
+			}
+			e := newTestElector(t, client, "pod-1", WithCandidateRegistry())
+			if tc.at == "later" {
+				deny()
+				// the pod is re-read once the check is due
+				e.refreshLabels(context.Background())
+				if got := e.ineligible(); got != "" {
+					t.Fatalf("ineligible before the check is due = %q", got)
+				}
+				e.mu.Lock()
+				e.refreshedAt = time.Now().Add(-denyCheckInterval)
+				e.mu.Unlock()
+				e.refreshLabels(context.Background())
+			}
+
+			if got := e.ineligible(); got != tc.want {
+				t.Fatalf("ineligible = %q, want %q", got, tc.want)
+			}
+			if err := e.heartbeat(context.Background()); err != nil {
+				t.Fatalf("heartbeat: %v", err)
+			}
+			candidates, err := e.ListCandidates(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(candidates) != 1 || candidates[0].Denied != (tc.want != "") || candidates[0].competing() == (tc.want != "") {
+				t.Fatalf("candidates = %+v, want pod-1 denied %v", candidates, tc.want != "")
+			}
+		})
+	}
+}
+
+func TestExcludedPod(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		want        bool
+	}{
+		{},
+		{annotations: map[string]string{"other": "true"}},
+		{annotations: map[string]string{CandidacyDeniedAnnotation: ""}, want: true},
+		{annotations: map[string]string{MaintenanceAnnotation: "upgrade"}, want: true},
+	} {
+		if got := excludedPod(tc.annotations); got != tc.want {
+			t.Errorf("excludedPod(%v) = %v, want %v", tc.annotations, got, tc.want)
+		}
+	}
+}
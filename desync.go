@@ -0,0 +1,47 @@
+package leader
+
+import (
+	"hash/fnv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// retrySlots is the number of slots the backoff window is divided into
+// with WithRetryDesync.
+const retrySlots = 64
+
+// retrySlot returns the slot of the backoff window the pod with uid retries
+// in. UIDs are random, so a fleet's pods fill the slots evenly.
+func retrySlot(uid types.UID) int {
+	h := fnv.New64a()
+	h.Write([]byte(uid))
+	return int(h.Sum64() % retrySlots)
+}
+
+// retryDelay returns how long to wait before the next attempt of a backoff
+// the length of window. By default it is window with random jitter. With
+// WithRetryDesync the attempt is instead put in our slot of the window, on a
+// grid of the wall clock that every pod shares, so that the retries of a
+// fleet woken at the same moment, as by a mass failover, land spread over
+// the window instead of in one burst. The delay is never under half the
+// window, so the attempts keep their average rate.
+func (e *PodElector) retryDelay(window time.Duration) time.Duration {
+	if !e.opts.retryDesync || window <= 0 {
+		return e.opts.random().jitter(window, .2)
+	}
+	now := time.Now()
+	next := now.Truncate(window).Add(window * time.Duration(retrySlot(e.owner.UID)) / retrySlots)
+	for next.Before(now.Add(window / 2)) {
+		next = next.Add(window)
+	}
+	return next.Sub(now)
+}
+
+// observeRetrySlot exports our slot, as a fraction of the backoff window,
+// so that the spread of a fleet's retries can be checked across its pods.
+func (e *PodElector) observeRetrySlot() {
+	if e.opts.retryDesync {
+		retryPhaseGauge.WithLabelValues(e.lockName).Set(float64(retrySlot(e.owner.UID)) / retrySlots)
+	}
+}
@@ -0,0 +1,85 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRetrySlot(t *testing.T) {
+	counts := make([]int, retrySlots)
+	for i := 0; i < 100*retrySlots; i++ {
+		slot := retrySlot(types.UID(fmt.Sprintf("uid-%d", i)))
+		if slot < 0 || slot >= retrySlots {
+			t.Fatalf("retrySlot = %d, want within [0, %d)", slot, retrySlots)
+		}
+		counts[slot]++
+	}
+	for slot, n := range counts {
+		// a hundred per slot on average
+		if n < 50 || n > 150 {
+			t.Fatalf("slot %d got %d of %d pods, want them spread evenly", slot, n, 100*retrySlots)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	const window = time.Minute
+	for _, tc := range []struct {
+		name     string
+		opts     []Option
+		min, max time.Duration
+		slotted  bool
+	}{
+		{name: "jittered", min: window, max: window * 6 / 5},
+		{name: "desynced", opts: []Option{WithRetryDesync()}, min: window / 2, max: window * 3 / 2, slotted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1", tc.opts...)
+			now := time.Now()
+			delay := e.retryDelay(window)
+			if delay < tc.min || delay > tc.max {
+				t.Fatalf("retryDelay = %v, want within [%v, %v]", delay, tc.min, tc.max)
+			}
+			if !tc.slotted {
+				return
+			}
+			offset := time.Duration(now.Add(delay).UnixNano() % int64(window))
+			want := window * time.Duration(retrySlot(e.owner.UID)) / retrySlots
+			if d := offset - want; d < -100*time.Millisecond || d > 100*time.Millisecond {
+				t.Fatalf("retry at %v into the window, want our slot at %v", offset, want)
+			}
+			if got := e.retryDelay(0); got != 0 {
+				t.Fatalf("retryDelay without a window = %v", got)
+			}
+		})
+	}
+}
+
+func TestRetryMetrics(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	leader := newTestElector(t, client, "pod-2")
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	held := testutil.ToFloat64(lockAttemptsCounter.WithLabelValues(testLock, "held"))
+
+	e := newTestElector(t, client, "pod-1", WithRetryDesync())
+	if ok, err := e.TryAcquire(context.Background()); err != nil || ok {
+		t.Fatalf("TryAcquire while pod-2 leads = %v, %v", ok, err)
+	}
+	if got := testutil.ToFloat64(lockAttemptsCounter.WithLabelValues(testLock, "held")); got != held+1 {
+		t.Fatalf("held attempts = %v, want %v", got, held+1)
+	}
+
+	e.observeRetrySlot()
+	want := float64(retrySlot(e.owner.UID)) / retrySlots
+	if got := testutil.ToFloat64(retryPhaseGauge.WithLabelValues(testLock)); got != want {
+		t.Fatalf("retry phase = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultClusterDomain = "cluster.local"
+
+	serviceRole = "service"
+
+	// Annotations read by external-dns.
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	externalDNSTargetAnnotation   = "external-dns.alpha.kubernetes.io/target"
+)
+
+// podDNSName returns the cluster DNS name of pod: its hostname under its
+// headless service subdomain if it has one, and its IP-based pod record
+// otherwise.
+func podDNSName(pod *v1.Pod, domain string) string {
+	if pod.Spec.Hostname != "" && pod.Spec.Subdomain != "" {
+		return fmt.Sprintf("%s.%s.%s.svc.%s", pod.Spec.Hostname, pod.Spec.Subdomain, pod.Namespace, domain)
+	}
+	if pod.Status.PodIP == "" {
+		return ""
+	}
+	ip := strings.NewReplacer(".", "-", ":", "-").Replace(pod.Status.PodIP)
+	return fmt.Sprintf("%s.%s.pod.%s", ip, pod.Namespace, domain)
+}
+
+// pointLeaderService is the transition hook keeping the configured
+// ExternalName Service pointed at the leader, so every client reaches the
+// active instance by one hostname. With an external hostname the Service is
+// also annotated for external-dns, which then publishes the leader's IP to
+// clients outside the cluster.
+func (e *PodElector) pointLeaderService(leading bool) {
+	if !leading {
+		return
+	}
+	if e.podDNS == "" {
+		e.log.Warn("My pod has no DNS name yet, not updating the leader Service", "service", e.opts.leaderServiceName)
+		return
+	}
+
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+
+	annotations := map[string]string{}
+	if e.opts.leaderServiceHostname != "" {
+		annotations[externalDNSHostnameAnnotation] = e.opts.leaderServiceHostname
+		annotations[externalDNSTargetAnnotation] = e.podIP
+	}
+
+	services := e.kube().CoreV1().Services(e.ns)
+	svc, err := services.Get(ctx, e.opts.leaderServiceName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = services.Create(ctx, &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      e.opts.leaderServiceName,
+				Namespace: e.ns,
+				Labels: map[string]string{
+					LockLabel: e.lockName,
+					RoleLabel: serviceRole,
+				},
+				Annotations: annotations,
+			},
+			Spec: v1.ServiceSpec{
+				Type:         v1.ServiceTypeExternalName,
+				ExternalName: e.podDNS,
+			},
+		}, metav1.CreateOptions{})
+	case err == nil:
+		svc.Spec.Type = v1.ServiceTypeExternalName
+		svc.Spec.ExternalName = e.podDNS
+		if svc.Annotations == nil {
+			svc.Annotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			svc.Annotations[k] = v
+		}
+		_, err = services.Update(ctx, svc, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		e.log.Error(err, "Failed to point the leader Service at me", "service", e.opts.leaderServiceName)
+		return
+	}
+	e.log.Info("Pointed the leader Service at me", "service", e.opts.leaderServiceName, "externalName", e.podDNS)
+}
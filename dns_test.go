@@ -0,0 +1,81 @@
+package leader
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodDNSName(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec v1.PodSpec
+		ip   string
+		want string
+	}{
+		{name: "headless service", spec: v1.PodSpec{Hostname: "app-0", Subdomain: "app"}, ip: "10.0.0.1", want: "app-0.app.test.svc.cluster.local"},
+		{name: "IPv4", ip: "10.0.0.1", want: "10-0-0-1.test.pod.cluster.local"},
+		{name: "IPv6", ip: "fd00::1", want: "fd00--1.test.pod.cluster.local"},
+		{name: "hostname without subdomain", spec: v1.PodSpec{Hostname: "app-0"}, ip: "10.0.0.1", want: "10-0-0-1.test.pod.cluster.local"},
+		{name: "no IP yet", want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: testNamespace}, Spec: tc.spec}
+			pod.Status.PodIP = tc.ip
+			if got := podDNSName(pod, defaultClusterDomain); got != tc.want {
+				t.Fatalf("podDNSName = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPointLeaderService(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		existing *v1.Service
+		hostname string
+	}{
+		{name: "new Service"},
+		{
+			name: "existing Service",
+			existing: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "leader", Namespace: testNamespace, Annotations: map[string]string{"owner": "ops"}},
+				Spec:       v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "old.test.pod.cluster.local"},
+			},
+		},
+		{name: "external hostname", hostname: "leader.example.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			setPodIP(t, client, "pod-1", "10.0.0.1")
+			if tc.existing != nil {
+				if _, err := client.CoreV1().Services(testNamespace).Create(context.Background(), tc.existing, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1", WithLeaderService("leader", tc.hostname))
+			if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+
+			svc, err := client.CoreV1().Services(testNamespace).Get(context.Background(), "leader", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("leader Service: %v", err)
+			}
+			if svc.Spec.Type != v1.ServiceTypeExternalName || svc.Spec.ExternalName != "10-0-0-1.test.pod.cluster.local" {
+				t.Fatalf("Service is %s %q, want it to name the leader", svc.Spec.Type, svc.Spec.ExternalName)
+			}
+			if tc.existing != nil && svc.Annotations["owner"] != "ops" {
+				t.Fatalf("annotations of the existing Service = %v", svc.Annotations)
+			}
+			if got := svc.Annotations[externalDNSHostnameAnnotation]; got != tc.hostname {
+				t.Fatalf("external-dns hostname = %q, want %q", got, tc.hostname)
+			}
+			if tc.hostname != "" && svc.Annotations[externalDNSTargetAnnotation] != "10.0.0.1" {
+				t.Fatalf("external-dns target = %q, want the pod IP", svc.Annotations[externalDNSTargetAnnotation])
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultDrainTimeout bounds how long giving up leadership waits for work
+// to drain by default.
+const defaultDrainTimeout = time.Second * 30
+
+// Drainer tracks the leader's in-flight work so that leadership is only
+// given up once that work has finished. Resign and TransferTo drain it
+// before deleting the lock, which keeps the next leader from processing
+// the same work a second time. Register it with WithDrainer.
+type Drainer struct {
+	mu       sync.Mutex
+	inFlight int
+	draining bool
+	idle     chan struct{}
+	funcs    []func(ctx context.Context) error
+}
+
+// NewDrainer returns an empty Drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Begin records the start of a unit of work and returns the func that
+// records its end. ok is false once draining has begun: leadership is being
+// handed over and the work should not be started.
+func (d *Drainer) Begin() (end func(), ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, false
+	}
+	d.inFlight++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.inFlight--
+			if d.inFlight == 0 && d.idle != nil {
+				close(d.idle)
+				d.idle = nil
+			}
+		})
+	}, true
+}
+
+// OnDrain registers f to be run, in order, when draining begins, for
+// example to stop consumers, before waiting for in-flight work.
+func (d *Drainer) OnDrain(f func(ctx context.Context) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.funcs = append(d.funcs, f)
+}
+
+// Drain refuses new work, runs the OnDrain funcs and waits until no work is
+// in flight or ctx is done. The OnDrain funcs run once per drain: calling
+// Drain again before work is accepted again only waits.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	var funcs []func(context.Context) error
+	if !d.draining {
+		funcs = append(funcs, d.funcs...)
+	}
+	d.draining = true
+	d.mu.Unlock()
+
+	for _, f := range funcs {
+		if err := f(ctx); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	if d.inFlight == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-idle:
+		return nil
+	}
+}
+
+// InFlight returns the number of units of work in flight.
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight
+}
+
+// reopen accepts new work again.
+func (d *Drainer) reopen() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draining = false
+}
+
+// drainWork runs the drain callback and drains the Drainer, if any, before we
+// give up leadership. Draining is bounded by the drain timeout; work still
+// in flight then is logged and leadership is given up regardless.
+func (e *PodElector) drainWork(ctx context.Context) {
+	if e.opts.drain != nil {
+		if err := e.opts.drain(withEpoch(ctx, e.Epoch())); err != nil {
+			e.log.Error(err, "Drain before stepping down failed", "lock", e.lockName)
+		}
+	}
+	if e.opts.drainer == nil {
+		return
+	}
+	ctx, cancel := withTimeout(ctx, e.tuned().drainTimeout)
+	defer cancel()
+	if err := e.opts.drainer.Drain(ctx); err != nil {
+		e.log.Warn("Work did not drain, giving up leadership anyway", "lock", e.lockName, "inFlight", e.opts.drainer.InFlight(), "error", err)
+	}
+}
+
+// undrain accepts work again after giving up leadership failed and we still
+// lead.
+func (e *PodElector) undrain() {
+	if e.opts.drainer != nil && e.IsLeader() {
+		e.opts.drainer.reopen()
+	}
+}
+
+// reopenDrainer is the transition hook accepting work again once we lead.
+func (e *PodElector) reopenDrainer(leading bool) {
+	if leading {
+		e.opts.drainer.reopen()
+	}
+}
@@ -0,0 +1,102 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrainer(t *testing.T) {
+	d := NewDrainer()
+	var drained int
+	d.OnDrain(func(ctx context.Context) error {
+		drained++
+		return nil
+	})
+	end, ok := d.Begin()
+	if !ok {
+		t.Fatal("Begin refused work before draining")
+	}
+	if d.InFlight() != 1 {
+		t.Fatalf("InFlight = %d, want 1", d.InFlight())
+	}
+
+	// draining waits for the work in flight
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain with work in flight = %v, want to wait", err)
+	}
+	if _, ok := d.Begin(); ok {
+		t.Fatal("Begin accepted work while draining")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- d.Drain(context.Background()) }()
+	end()
+	end()
+	if err := <-done; err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if d.InFlight() != 0 {
+		t.Fatalf("InFlight = %d after the work ended twice, want 0", d.InFlight())
+	}
+	if drained != 1 {
+		t.Fatalf("OnDrain funcs ran %d times in one drain, want once", drained)
+	}
+
+	d.reopen()
+	if end, ok := d.Begin(); !ok {
+		t.Fatal("Begin refused work after reopening")
+	} else {
+		end()
+	}
+}
+
+func TestDrainerOnDrainError(t *testing.T) {
+	d := NewDrainer()
+	stuck := errors.New("consumer did not stop")
+	d.OnDrain(func(ctx context.Context) error { return stuck })
+	if err := d.Drain(context.Background()); !errors.Is(err, stuck) {
+		t.Fatalf("Drain = %v, want the OnDrain error", err)
+	}
+}
+
+func TestResignDrains(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// hold is whether work stays in flight past the drain timeout
+		hold     bool
+		inFlight int
+	}{
+		{name: "drained", inFlight: 0},
+		{name: "drain timeout", hold: true, inFlight: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			d := NewDrainer()
+			e := newTestElector(t, client, "pod-1", WithDrainer(d, 50*time.Millisecond))
+			if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+			end, ok := d.Begin()
+			if !ok {
+				t.Fatal("Begin refused work while leading")
+			}
+			if !tc.hold {
+				time.AfterFunc(10*time.Millisecond, end)
+			}
+
+			if err := e.Resign(context.Background()); err != nil {
+				t.Fatalf("Resign: %v", err)
+			}
+			if inFlight := d.InFlight(); inFlight != tc.inFlight {
+				t.Fatalf("InFlight = %d after Resign, want %d", inFlight, tc.inFlight)
+			}
+			if owner := lockOwner(t, client); owner != "" {
+				t.Fatalf("lock still held by %q", owner)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package leader
+
+import (
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// dryRunExempt are the API groups whose writes are reviews rather than
+// changes, and are sent as they are in a dry run.
+var dryRunExempt = []string{
+	"/apis/authorization.k8s.io/",
+	"/apis/authentication.k8s.io/",
+}
+
+// dryRun makes conf send every write as a server-side dry run, which the
+// apiserver validates and admits, preconditions and permissions included,
+// but does not persist, and log it.
+func dryRun(conf *rest.Config, logger Logger) {
+	wrap := conf.WrapTransport
+	conf.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &dryRunTransport{next: rt, log: logger}
+	}
+}
+
+type dryRunTransport struct {
+	next http.RoundTripper
+	log  Logger
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.next.RoundTrip(req)
+	}
+	for _, prefix := range dryRunExempt {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return t.next.RoundTrip(req)
+		}
+	}
+
+	req = req.Clone(req.Context())
+	q := req.URL.Query()
+	q.Set("dryRun", metav1.DryRunAll)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := t.next.RoundTrip(req)
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	t.log.Info("Dry run: would have sent", "method", req.Method, "path", req.URL.Path, "code", code)
+	return resp, err
+}
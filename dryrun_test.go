@@ -0,0 +1,97 @@
+package leader
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDryRunTransport(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		path   string
+		dryRun bool
+	}{
+		{method: http.MethodGet, path: "/api/v1/namespaces/test/configmaps/test-lock"},
+		{method: http.MethodPost, path: "/api/v1/namespaces/test/configmaps", dryRun: true},
+		{method: http.MethodPatch, path: "/apis/coordination.k8s.io/v1/namespaces/test/leases/test-lock", dryRun: true},
+		{method: http.MethodDelete, path: "/api/v1/namespaces/test/configmaps/test-lock", dryRun: true},
+		{method: http.MethodPost, path: "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"},
+	} {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			var sent *http.Request
+			conf := &rest.Config{WrapTransport: func(http.RoundTripper) http.RoundTripper {
+				return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					sent = req
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				})
+			}}
+			o := defaultOptions()
+			o.logLevel = ErrorLevel
+			dryRun(conf, o.getLogger())
+
+			req, err := http.NewRequest(tc.method, "https://apiserver"+tc.path+"?fieldManager=k8s-leader", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conf.WrapTransport(nil).RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+			if dryRun := sent.URL.Query().Get("dryRun") == metav1.DryRunAll; dryRun != tc.dryRun {
+				t.Fatalf("sent as a dry run = %v, want %v (%s)", dryRun, tc.dryRun, sent.URL)
+			}
+			if sent.URL.Query().Get("fieldManager") != "k8s-leader" {
+				t.Fatalf("query of the request was lost: %s", sent.URL)
+			}
+		})
+	}
+}
+
+func TestTryAcquireDryRun(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	// a dry run goes through a client made from a REST config, which sends
+	// every write as a server-side dry run; answer creates as the apiserver
+	// would then, without persisting them
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.CreateAction).GetObject(), nil
+	})
+	o := defaultOptions()
+	o.dryRun = true
+	o.logLevel = ErrorLevel
+	pod, err := client.CoreV1().Pods(testNamespace).Get(context.Background(), "pod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := newElector(testLock, o, &identity{client: client, clientID: &clientID{}, ns: testNamespace, pod: pod})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := e.TryAcquire(context.Background()); err != nil || ok {
+		t.Fatalf("TryAcquire in a dry run = %v, %v", ok, err)
+	}
+	if e.IsLeader() {
+		t.Fatal("IsLeader is true after a dry run")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("a dry run left the lock to %q", owner)
+	}
+}
+
+func TestDryRunNeedsOwnClient(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	if _, err := NewElector(testLock, WithClient(client), WithNamespace(testNamespace), WithPodName("pod-1"), WithDryRun()); err == nil {
+		t.Fatal("NewElector accepted WithDryRun with an injected client")
+	}
+}
@@ -0,0 +1,1137 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrNotLeader is returned by operations that require the Elector to hold
+// the lock when it does not.
+var ErrNotLeader = errors.New("not the leader")
+
+// Elector is the part of the election API most applications depend on.
+// PodElector implements it; code that accepts an Elector can be handed a
+// fake in tests.
+type Elector interface {
+	// Run takes part in the election until leadership ends or ctx is
+	// cancelled.
+	Run(ctx context.Context) error
+	// IsLeader reports whether the lock is currently believed held.
+	IsLeader() bool
+	// Resign gives up leadership.
+	Resign(ctx context.Context) error
+	// Subscribe returns a channel receiving an Event for every change of
+	// leadership.
+	Subscribe() <-chan Event
+}
+
+var _ Elector = &PodElector{}
+
+// PodElector competes for a single named lock on behalf of the current pod.
+type PodElector struct {
+	lockName string
+	ns       string
+	owner    *metav1.OwnerReference
+	opts     options
+	log      Logger
+
+	// optsMu guards tunedOpts, the options with the changes made through
+	// the configuration ConfigMap. Read the tunable fields through tuned.
+	optsMu         sync.RWMutex
+	tunedOpts      options
+	watchingConfig bool
+
+	// serviceAccount is the service account our pod runs as.
+	serviceAccount string
+
+	// clientMu guards client and backend, which are replaced when
+	// credentials are reloaded.
+	clientMu   sync.RWMutex
+	client     kubernetes.Interface
+	backend    backend
+	reloadedAt time.Time
+	// clientID identifies the client we were built with in the caches
+	// shared with other Electors. It is kept when credentials are reloaded.
+	clientID *clientID
+
+	mu          sync.Mutex
+	leading     bool
+	lockUID     *types.UID
+	maintaining bool
+	epoch       int64
+
+	// leadingSince is when the current term started, verifiedAt when the
+	// lock was last confirmed to be ours.
+	leadingSince time.Time
+	verifiedAt   time.Time
+
+	// resumed is set while candidacy is paused and closed on Resume.
+	resumed chan struct{}
+
+	// healthFailures counts consecutive failed health checks of the leader,
+	// demotedUntil is when a leader that demoted itself may compete again.
+	healthFailures int
+	demotedUntil   time.Time
+
+	// background tracks the goroutines serving the election, which Run
+	// waits for before it returns.
+	background sync.WaitGroup
+
+	// hooks run, in order, whenever leadership is gained or lost.
+	hooks []func(leading bool)
+
+	subscribers []chan Event
+
+	// sampler limits how often the election loop repeats wait messages.
+	sampler logSampler
+
+	// forbiddenLogged records the permissions we already logged as missing.
+	forbiddenLogged map[string]bool
+
+	// heldKeys are the resource locks taken with Lock and not yet unlocked.
+	heldKeys map[string]bool
+
+	// stopReading ends the renewal of our reader entry while we hold read
+	// access.
+	stopReading context.CancelFunc
+
+	shards shardState
+
+	// swept is when the leader last ran the janitor.
+	swept time.Time
+
+	heartbeating bool
+
+	events *eventEmitter
+
+	// zone is the topology zone of our node, resolved only when a topology
+	// preference is configured. leaderZone is the zone of the last leader we
+	// observed.
+	zone       string
+	leaderZone string
+
+	// spot is true when our node is spot or preemptible capacity.
+	spot bool
+
+	// virtual is true when our pod runs on a virtual kubelet node, whose
+	// labels and taints say nothing about the machine it really runs on.
+	virtual bool
+
+	// nodeLabels are the labels of our node, re-read at nodeCheckedAt, for
+	// WithNodeSelector.
+	nodeLabels    map[string]string
+	nodeCheckedAt time.Time
+
+	nodeName string
+	podIP    string
+	podDNS   string
+	// address is the address we advertise, if any.
+	address   string
+	podLabels map[string]string
+
+	// maintenance is the value of MaintenanceAnnotation we honour, "" when
+	// our pod is not in maintenance mode. denied is set while
+	// CandidacyDeniedAnnotation, of value deniedBy, is on our pod;
+	// refreshedAt is when our pod was last re-read.
+	maintenance string
+	denied      bool
+	deniedBy    string
+	refreshedAt time.Time
+
+	// gateStale is set when our pod's readiness gate condition was left
+	// True by an earlier run of our container.
+	gateStale bool
+
+	// eligibleSelector is the eligibility selector last seen on the lock,
+	// pinnedLeader the pinned leader.
+	eligibleSelector string
+	pinnedLeader     string
+
+	// woken cuts the backoff of the election loop short when something
+	// worth acting on is observed; nodeWatch and podWatch watch the
+	// leader's node and pod for that.
+	woken     chan struct{}
+	nodeWatch *nodeWatch
+	podWatch  *podWatch
+
+	// failoverStart is when we first saw the leader we wait for going.
+	failoverStart time.Time
+
+	// exportedHints are the scaling hints we export as the leader.
+	exportedHints map[string]bool
+
+	// expiresAt is the expiry last recorded on the lock we hold.
+	expiresAt time.Time
+
+	// decisions records the decisions of the election and maintenance
+	// loops with WithDecisionLog.
+	decisions *decisionLog
+
+	// seat is the UID of the committee seat we hold, with WithCommittee.
+	seat types.UID
+
+	// retryAfter is when the apiserver, through a Retry-After header, last
+	// asked us to retry at the earliest.
+	retryAfter time.Time
+
+	// deferUntil is set when another pod has been named as the successor
+	// of a cooperative transfer; we do not compete for the lock before then.
+	deferUntil time.Time
+
+	// electionCtx is the context of the running Become, for the transition
+	// hooks, which are not passed one.
+	electionCtx context.Context
+}
+
+// NewElector returns an Elector for lockName. It resolves the namespace,
+// client and the current pod's identity up front so that configuration
+// errors surface before any election is attempted.
+func NewElector(lockName string, opts ...Option) (*PodElector, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	id, err := resolveIdentity(&o)
+	if err != nil {
+		return nil, err
+	}
+	return newElector(lockName, o, id)
+}
+
+// identity is what an Elector learns about its environment before it
+// competes. Electors built by one Manager share it.
+type identity struct {
+	client   kubernetes.Interface
+	clientID *clientID
+	ns       string
+	pod      *v1.Pod
+	// node is only looked up when an option needs it.
+	node *v1.Node
+	// backend is set by a Manager, whose Electors share the backend it
+	// resolved, so that AutoBackend probes the apiserver only once.
+	backend backend
+}
+
+func resolveIdentity(o *options) (*identity, error) {
+	client, ns, err := clientAndNamespace(o)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(context.Background(), o.requestTimeout)
+	defer cancel()
+	myPod, err := cachedMyPod(ctx, o.clientID, client, ns, o.myPodName(), o.getLogger())
+	if err != nil {
+		return nil, err
+	}
+
+	cid := o.clientID
+	if cid == nil {
+		cid = &clientID{}
+	}
+	id := &identity{client: client, clientID: cid, ns: ns, pod: myPod}
+	if o.nodeAware() && myPod.Spec.NodeName != "" && !o.virtual(myPod) {
+		id.node, err = client.CoreV1().Nodes().Get(ctx, myPod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get node %s: %w", myPod.Spec.NodeName, err)
+		}
+	}
+	return id, nil
+}
+
+func newElector(lockName string, o options, id *identity) (*PodElector, error) {
+	logger := o.getLogger()
+	lockName, err := o.groupedLockName(lockName, id.pod)
+	if err != nil {
+		return nil, err
+	}
+	b := id.backend
+	if b == nil {
+		if b, err = electorBackend(&o, id.client, id.ns, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	myPod := id.pod
+	e := &PodElector{
+		lockName:      lockName,
+		ns:            id.ns,
+		client:        id.client,
+		clientID:      id.clientID,
+		backend:       b,
+		owner:         myOwnerRef(myPod),
+		nodeName:      myPod.Spec.NodeName,
+		podIP:         myPod.Status.PodIP,
+		podDNS:        podDNSName(myPod, o.clusterDomain),
+		address:       o.advertisedAddress(myPod),
+		podLabels:     myPod.Labels,
+		maintenance:   myPod.Annotations[MaintenanceAnnotation],
+		virtual:       o.virtual(myPod),
+		opts:          o,
+		tunedOpts:     o,
+		woken:         make(chan struct{}, 1),
+		exportedHints: map[string]bool{},
+		log:           logger,
+	}
+	e.observeDenied(myPod.Annotations)
+	e.refreshedAt = time.Now()
+	if o.drainer != nil {
+		e.hooks = append(e.hooks, e.reopenDrainer)
+	}
+	e.hooks = append(e.hooks, e.publish, e.countTransition, e.unexportScalingHints)
+	if o.decisionLogSize > 0 {
+		e.decisions = newDecisionLog(o.decisionLogSize)
+	}
+	if o.persistDecisions {
+		e.hooks = append(e.hooks, e.persistDecisions)
+	}
+	e.serviceAccount = myPod.Spec.ServiceAccountName
+	if e.serviceAccount == "" {
+		e.serviceAccount = o.serviceAccountName()
+	}
+
+	if o.events {
+		e.events = newEventEmitter(id.client, myPod, o.eventInterval, o.requestTimeout, logger)
+		e.hooks = append(e.hooks, e.leadershipEvent)
+	}
+
+	if o.podConditionType != "" {
+		e.hooks = append(e.hooks, e.setPodCondition)
+	}
+	if o.readinessGate {
+		e.checkReadinessGate(myPod)
+	}
+
+	if o.leaderLabelKey != "" || o.leaderAnnotationKey != "" {
+		e.hooks = append(e.hooks, e.markLeaderPod)
+	}
+
+	if o.registry {
+		e.hooks = append(e.hooks, e.membershipChanged)
+	}
+
+	if o.backendServiceName != "" {
+		e.hooks = append(e.hooks, e.selectLeader)
+	}
+	if o.meshDestinationRule != "" {
+		e.hooks = append(e.hooks, e.routeMesh)
+	}
+	if o.envoyConfigMap != "" {
+		e.hooks = append(e.hooks, e.exportEnvoyEndpoints)
+	}
+	if o.leaderInfoName != "" {
+		e.hooks = append(e.hooks, e.publishLeaderInfo)
+	}
+
+	if o.leaderServiceName != "" {
+		e.hooks = append(e.hooks, e.pointLeaderService)
+	}
+
+	if len(o.notifiers) > 0 {
+		e.hooks = append(e.hooks, e.notify)
+	}
+
+	if e.virtual && o.nodeAware() {
+		logger.Info("Running on a virtual node, ignoring node topology and capacity preferences", "lock", lockName, "node", myPod.Spec.NodeName)
+	}
+	if id.node != nil && !e.virtual {
+		e.zone = nodeZone(id.node)
+		e.spot = isSpotNode(id.node)
+		e.nodeLabels = id.node.Labels
+		e.nodeCheckedAt = time.Now()
+	}
+
+	return e, nil
+}
+
+// electorBackend returns the lock backend configured by o, including any
+// compatibility lock.
+func electorBackend(o *options, client kubernetes.Interface, ns string, logger Logger) (backend, error) {
+	b, err := newBackend(o.backend, client, ns, o.requestTimeout, logger)
+	if err != nil {
+		return nil, err
+	}
+	if o.compatName != "" {
+		legacy, err := newBackend(o.compatBackend, client, ns, o.requestTimeout, logger)
+		if err != nil {
+			return nil, err
+		}
+		b = &dualBackend{legacy: legacy, legacyName: o.compatName, primary: b, log: logger}
+	}
+	return b, nil
+}
+
+// IsLeader reports whether the Elector currently believes it holds the lock.
+// With WithLockExpiry it stops doing so shortly before the lock expires
+// unrenewed, as candidates may take it over from then on.
+func (e *PodElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading && !e.expiredLocked(time.Now())
+}
+
+// Become blocks until the current pod holds the lock or ctx is cancelled.
+// See the package-level Become for a description of the protocol.
+func (e *PodElector) Become(ctx context.Context) (err error) {
+	defer func() { err = e.wrap("become leader of", err) }()
+	defer func() {
+		if err != nil && e.opts.committee > 0 {
+			e.leaveSeat()
+		}
+	}()
+	defer e.stopNodeWatch()
+	defer e.stopPodWatch()
+
+	e.log.Info("Trying to become the leader", "lock", e.lockName)
+	e.mu.Lock()
+	e.electionCtx = ctx
+	e.mu.Unlock()
+
+	if e.opts.configName != "" {
+		e.startConfigWatch(ctx)
+	}
+	if e.opts.readinessGate {
+		e.clearStaleGate()
+	}
+	if e.opts.podConditionType != "" && !e.IsLeader() {
+		// candidates report False from the start, not only once they lead
+		// and lose
+		e.setPodCondition(false)
+	}
+	if e.opts.failoverWatch {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		e.watchLock(watchCtx)
+	}
+
+	existing, err := e.getLock(ctx)
+
+	switch {
+	case err == nil:
+		if _, done := completedAt(existing); done {
+			return ErrCompleted
+		}
+		e.observeEligibility(existing)
+		if e.ownedByUs(existing) {
+			e.log.Info("Found existing lock with my name. I was likely restarted")
+			e.log.Info("Continuing as the leader", "lock", e.lockName)
+			e.decide("lock held by our pod", "resume leadership", "leader", e.owner.Name)
+			if err := e.resume(ctx, existing); err == nil {
+				e.startMaintenance(ctx)
+				return nil
+			} else if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+		for _, existingOwner := range existing.GetOwnerReferences() {
+			e.log.Info("Found existing lock", "lock", e.lockName, "owner", existingOwner.Name)
+		}
+	case apierrors.IsNotFound(err):
+		e.log.Info("No pre-existing lock was found", "lock", e.lockName)
+	case e.retryable(ctx, err):
+		// the loop below finds out soon enough
+	default:
+		e.log.Error(err, "Failed to get lock", "lock", e.lockName)
+		return err
+	}
+
+	// try to create a lock
+	backoff := initialBackoffInterval
+	successor := false
+	e.observeRetrySlot()
+	if e.opts.registry {
+		e.startHeartbeat(ctx)
+	}
+	for {
+		if e.Paused() || e.demoted() {
+			e.leaveSeat()
+		}
+		if err := e.waitResumed(ctx); err != nil {
+			return err
+		}
+		if err := e.waitDemotion(ctx); err != nil {
+			return err
+		}
+
+		if e.opts.fairQueue && !successor {
+			turn, err := e.myTurn(ctx)
+			if err != nil {
+				e.log.Error(err, "Failed to consult candidate queue, competing anyway", "lock", e.lockName)
+				turn = true
+			}
+			if !turn {
+				if err := e.backoff(ctx, &backoff); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if ok, err := e.mayCompete(ctx, successor); err != nil {
+			return err
+		} else if !ok {
+			if err := e.backoff(ctx, &backoff); err != nil {
+				return err
+			}
+			continue
+		}
+
+		created, err := e.lockBackend().Create(ctx, e.lockMeta())
+		countAttempt(e.lockName, err)
+		switch {
+		case err == nil && e.opts.dryRun:
+			e.infoSampled("Dry run: would have become the leader", "lock", e.lockName)
+			e.decide("lock free", "would become leader (dry run)")
+			if err := e.backoff(ctx, &backoff); err != nil {
+				return err
+			}
+
+		case err == nil:
+			if err := e.acquired(ctx, created); err != nil {
+				if err := e.backoff(ctx, &backoff); err != nil {
+					return err
+				}
+				continue
+			}
+			e.startMaintenance(ctx)
+			return nil
+		case apierrors.IsAlreadyExists(err):
+			existing, err = e.getLock(ctx)
+			switch {
+			case apierrors.IsNotFound(err):
+				// released between our create and get, retry right away
+				backoff = initialBackoffInterval
+				continue
+			case e.retryable(ctx, err):
+				if err := e.backoff(ctx, &backoff); err != nil {
+					return err
+				}
+				continue
+			case err != nil:
+				return err
+			}
+
+			if _, done := completedAt(existing); done {
+				e.log.Info("The work of the lock has been completed", "lock", e.lockName, "by", existing.GetAnnotations()[CompletedByAnnotation])
+				e.decide("lock completed", "stop", "by", existing.GetAnnotations()[CompletedByAnnotation])
+				return ErrCompleted
+			}
+			e.observeHeartbeat(existing)
+			if expiry, expired := lockExpiry(existing); expired {
+				e.noteFailover(expiry)
+				e.decide("lock expired", "delete lock", "expired", expiry.Format(time.RFC3339), "resourceVersion", existing.GetResourceVersion())
+				if err := e.takeOverExpired(ctx, existing, expiry); err != nil {
+					e.log.Error(err, "Failed to take over expired lock", "lock", e.lockName)
+				} else if !e.opts.dryRun {
+					backoff = initialBackoffInterval
+					continue
+				}
+			}
+			e.observeEligibility(existing)
+
+			if zone, ok := existing.GetAnnotations()[ZoneAnnotation]; ok && zone != "" {
+				e.leaderZone = zone
+			}
+
+			// we are only the successor while the lock names us
+			successor = false
+			if target, ok := pendingTransfer(existing); ok {
+				if target == e.owner.Name {
+					successor = true
+					if err := e.acknowledgeTransfer(ctx, existing); err != nil && !e.retryable(ctx, err) {
+						return err
+					}
+					if err := e.nap(ctx, e.opts.transferPollInterval); err != nil {
+						return err
+					}
+					continue
+				}
+
+				e.infoSampled("Leadership is being transferred, deferring", "lock", e.lockName, "successor", target)
+				e.decide("transfer pending", "defer", "successor", target)
+				e.deferUntil = time.Now().Add(e.tuned().transferTimeout)
+			} else if err := e.requestStepDown(ctx, existing); err != nil {
+				e.log.Error(err, "Failed to request step-down", "lock", e.lockName)
+			}
+
+			existingOwners := existing.GetOwnerReferences()
+			switch {
+			case len(existingOwners) != 1:
+				e.warnSampled("Leader lock must have exactly one owner reference", "lock", e.lockName, "owners", len(existingOwners))
+				e.decide("lock has no single owner", "wait", "owners", len(existingOwners))
+
+			case existingOwners[0].Kind != "Pod":
+				e.log.Warn("Leader lock owner reference must be a pod", "lock", e.lockName, "kind", existingOwners[0].Kind, "owner", existingOwners[0].Name)
+				e.decide("lock not owned by a pod", "wait", "kind", existingOwners[0].Kind, "owner", existingOwners[0].Name)
+
+			default:
+				leaderPod, err := e.leaderPod(ctx, existingOwners[0].Name)
+				switch {
+				case apierrors.IsNotFound(err):
+					e.noteFailover(time.Now())
+					// the lock goes any moment now, do not sleep through it
+					backoff = initialBackoffInterval
+					switch {
+					case existing.GetDeletionTimestamp() != nil:
+						e.decide("leader pod deleted, lock terminating", "remove finalizer", "leader", existingOwners[0].Name)
+						e.removeFinalizer(ctx, existing)
+					case e.opts.leaderGone == LeaderGoneDeleteLock:
+						e.decide("leader pod deleted", "delete lock", "leader", existingOwners[0].Name, "uid", existingOwners[0].UID)
+						if err := e.deleteOrphanedLock(ctx, existing, existingOwners[0]); err != nil {
+							e.log.Error(err, "Failed to delete the lock of the deleted leader", "leader", existingOwners[0].Name)
+						}
+					default:
+						e.infoSampled("Leader pod has been deleted, waiting for garbage collection to remove the lock", "lock", e.lockName, "leader", existingOwners[0].Name)
+						e.decide("leader pod deleted", "wait for garbage collection", "leader", existingOwners[0].Name)
+					}
+				case e.retryable(ctx, err):
+				case err != nil:
+					return err
+				case e.opts.leaderGone == LeaderGoneDeleteLock && leaderPod.UID != existingOwners[0].UID:
+					e.noteFailover(time.Now())
+					e.log.Info("Leader pod was recreated under the same name", "lock", e.lockName, "leader", leaderPod.Name)
+					e.decide("leader pod recreated", "delete lock", "leader", leaderPod.Name, "uid", existingOwners[0].UID, "podUID", leaderPod.UID)
+					if err := e.deleteOrphanedLock(ctx, existing, existingOwners[0]); err != nil {
+						e.log.Error(err, "Failed to delete the lock of the deleted leader", "leader", existingOwners[0].Name)
+					} else {
+						backoff = initialBackoffInterval
+					}
+				case e.opts.nodeLoss != NodeLossWait && e.opts.nodeReflectsPod(leaderPod) && e.leaderNodeGone(ctx, leaderPod):
+					e.decide("leader node deleted", "take over ("+string(e.opts.nodeLoss)+")", "leader", leaderPod.Name, "node", leaderPod.Spec.NodeName)
+					if err := e.adoptFromDeletedNode(ctx, existing, leaderPod); err != nil {
+						e.log.Error(err, "Failed to take over from leader on deleted node", "leader", leaderPod.Name)
+					} else {
+						backoff = initialBackoffInterval
+					}
+				case isPodEvicted(leaderPod) && leaderPod.GetDeletionTimestamp() == nil:
+					e.log.Info("Pod with leader lock has been evicted", "lock", e.lockName, "leader", leaderPod.Name)
+					e.decide("leader pod evicted", "delete pod", "leader", leaderPod.Name, "reason", leaderPod.Status.Reason)
+					e.log.Info("Deleting evicted leader", "leader", leaderPod.Name)
+					switch err := e.deletePod(ctx, leaderPod); {
+					case apierrors.IsConflict(err), apierrors.IsNotFound(err):
+						e.log.Info("Evicted leader changed before it could be deleted, re-evaluating", "leader", leaderPod.Name)
+						backoff = initialBackoffInterval
+					case err != nil:
+						e.log.Error(err, "Leader pod could not be deleted", "leader", leaderPod.Name)
+					default:
+						backoff = initialBackoffInterval
+						e.event(v1.EventTypeNormal, "DeletedEvictedLeader", "Deleted evicted leader %s of %s", leaderPod.Name, e.lockName)
+						e.audit(AuditTookOver, "Deleted evicted leader %s of %s", leaderPod.Name, e.lockName)
+					}
+				case e.opts.finishedLeader != FinishedLeaderWait && podFinished(leaderPod) && leaderPod.GetDeletionTimestamp() == nil:
+					e.decide("leader pod finished", "take over ("+string(e.opts.finishedLeader)+")", "leader", leaderPod.Name, "phase", leaderPod.Status.Phase)
+					if err := e.takeOverFromFinished(ctx, existing, leaderPod); err != nil {
+						e.log.Error(err, "Failed to take over from finished leader", "leader", leaderPod.Name)
+					} else {
+						backoff = initialBackoffInterval
+					}
+				default:
+					if ts := leaderPod.GetDeletionTimestamp(); ts != nil {
+						e.noteFailover(ts.Time)
+					} else {
+						e.clearFailover()
+					}
+					if e.opts.leaderPodInformer || e.opts.failoverWatch {
+						e.watchLeaderPod(leaderPod.Name)
+					}
+					if e.opts.watchLeaderNode && leaderPod.Spec.NodeName != "" {
+						e.watchLeaderNode(ctx, leaderPod)
+					}
+					e.infoSampled("Not the leader. Waiting", "lock", e.lockName, "leader", leaderPod.Name)
+					e.decide("leader healthy", "wait", "leader", leaderPod.Name, "phase", leaderPod.Status.Phase, "ready", podReady(leaderPod), "terminating", leaderPod.GetDeletionTimestamp() != nil)
+					e.event(v1.EventTypeNormal, "Waiting", "Waiting for %s to release %s", leaderPod.Name, e.lockName)
+				}
+			}
+
+			if err := e.backoff(ctx, &backoff); err != nil {
+				return err
+			}
+
+		case e.retryable(ctx, err):
+			if err := e.backoff(ctx, &backoff); err != nil {
+				return err
+			}
+
+		case namespaceTerminating(err):
+			e.log.Info("Namespace is terminating, giving up", "lock", e.lockName, "namespace", e.ns)
+			e.decide("namespace terminating", "stop", "namespace", e.ns)
+			return terminatingError(err, e.ns)
+
+		default:
+			e.log.Error(err, "Failed to create lock", "lock", e.lockName)
+			return err
+		}
+	}
+}
+
+// BecomeAsync calls Become in the background. The returned channel delivers
+// nil once we lead, or the error that ended the attempt, and is then closed.
+func (e *PodElector) BecomeAsync(ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		done <- e.Become(ctx)
+	}()
+	return done
+}
+
+// lockMeta returns the metadata of the lock object we create. It is labeled
+// so that tooling can find every lock, and owned by our pod so that it is
+// garbage collected with it.
+func (e *PodElector) lockMeta() metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:            e.lockName,
+		Namespace:       e.ns,
+		OwnerReferences: []metav1.OwnerReference{*e.owner},
+		Labels: map[string]string{
+			LockLabel: e.lockName,
+			RoleLabel: LockRole,
+		},
+		Annotations: map[string]string{
+			PriorityAnnotation: strconv.Itoa(e.opts.priority),
+			ZoneAnnotation:     e.zone,
+		},
+	}
+	if e.opts.lockFinalizer {
+		meta.Finalizers = []string{LockFinalizer}
+	}
+	if selector := e.eligibility(); selector != "" {
+		meta.Annotations[EligibleSelectorAnnotation] = selector
+	}
+	if pinned := e.pinned(); pinned != "" {
+		meta.Annotations[PinnedLeaderAnnotation] = pinned
+	}
+	if e.address != "" {
+		meta.Annotations[AddressAnnotation] = e.address
+	}
+	if e.opts.lockHeartbeat {
+		meta.Annotations[LastHeartbeatAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if e.opts.lockExpiry > 0 {
+		meta.Annotations[ExpiresAtAnnotation] = time.Now().Add(e.opts.lockExpiry).UTC().Format(time.RFC3339)
+	}
+	return meta
+}
+
+func (e *PodElector) getLock(ctx context.Context) (metav1.Object, error) {
+	return e.lockBackend().Get(ctx, e.lockName)
+}
+
+// leaderPod returns the leader's pod, from the informer on it if we watch
+// it.
+func (e *PodElector) leaderPod(ctx context.Context, name string) (*v1.Pod, error) {
+	if pod, ok := e.watchedLeaderPod(name); ok {
+		return pod, nil
+	}
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	pod, err := e.kube().CoreV1().Pods(e.ns).Get(ctx, name, metav1.GetOptions{})
+	return pod, forbidden(err, "get", v1.Resource("pods"), e.ns)
+}
+
+// deletePod deletes pod, provided it is unchanged since it was read.
+func (e *PodElector) deletePod(ctx context.Context, pod *v1.Pod) error {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	pre := unchanged(pod)
+	err := e.kube().CoreV1().Pods(e.ns).Delete(ctx, pod.Name, metav1.DeleteOptions{Preconditions: &pre})
+	return forbidden(err, "delete", v1.Resource("pods"), e.ns)
+}
+
+// electionContext returns the context of the running Become, or the
+// background context outside of one.
+func (e *PodElector) electionContext() context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.electionCtx == nil {
+		return context.Background()
+	}
+	return e.electionCtx
+}
+
+// holds reports whether uid is the UID of the lock we hold.
+func (e *PodElector) holds(uid types.UID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading && e.lockUID != nil && *e.lockUID == uid
+}
+
+// heldUID returns the UID of the lock we hold, and whether we hold one.
+func (e *PodElector) heldUID() (types.UID, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leading || e.lockUID == nil {
+		return "", false
+	}
+	return *e.lockUID, true
+}
+
+// lost records that leadership was taken from us.
+func (e *PodElector) lost() {
+	e.mu.Lock()
+	wasLeading := e.leading
+	e.leading = false
+	e.lockUID = nil
+	e.mu.Unlock()
+
+	if wasLeading {
+		e.audit(AuditLost, "Lost %s", e.lockName)
+		e.transition(false)
+	}
+}
+
+// transition runs the hooks registered for changes of leadership.
+func (e *PodElector) transition(leading bool) {
+	for _, hook := range e.hooks {
+		hook(leading)
+	}
+}
+
+// clearLeading forgets the lock we took without announcing it, when it is
+// given up before leadership was.
+func (e *PodElector) clearLeading() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leading = false
+	e.lockUID = nil
+}
+
+func (e *PodElector) setLeading(lock metav1.Object) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	uid := lock.GetUID()
+	e.leading = true
+	e.lockUID = &uid
+	e.epoch = 0
+	e.expiresAt = time.Time{}
+	e.verifiedAt = time.Now()
+}
+
+// Leader returns the name of the pod currently holding the lock, or "" if
+// the lock is free.
+func (e *PodElector) Leader(ctx context.Context) (_ string, err error) {
+	defer func() { err = e.wrap("resolve leader of", err) }()
+
+	lock, err := e.getLock(ctx)
+	switch {
+	case apierrors.IsNotFound(err):
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	for _, owner := range lock.GetOwnerReferences() {
+		if owner.Kind == "Pod" {
+			return owner.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// TryAcquire makes a single attempt to take the lock and reports whether we
+// hold it afterwards. It applies the same gates as Become, but unlike Become
+// it does not wait, queue, defer to a transfer or take over, and it does not
+// maintain the lock it takes.
+func (e *PodElector) TryAcquire(ctx context.Context) (_ bool, err error) {
+	defer func() { err = e.wrap("acquire", err) }()
+
+	if e.IsLeader() {
+		return true, nil
+	}
+	if e.Paused() || e.demoted() {
+		return false, nil
+	}
+	if ok, err := e.mayCompete(ctx, false); err != nil || !ok {
+		return false, err
+	}
+
+	created, err := e.lockBackend().Create(ctx, e.lockMeta())
+	countAttempt(e.lockName, err)
+	switch {
+	case err == nil && e.opts.dryRun:
+		// nothing was persisted, so nothing is held
+		e.infoSampled("Dry run: would have become the leader", "lock", e.lockName)
+		e.decide("lock free", "would become leader (dry run)")
+		return false, nil
+	case err == nil:
+		if err := e.acquired(ctx, created); err != nil {
+			return false, err
+		}
+		return true, nil
+	case !apierrors.IsAlreadyExists(err):
+		return false, terminatingError(err, e.ns)
+	}
+
+	existing, err := e.getLock(ctx)
+	switch {
+	case apierrors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if !e.ownedByUs(existing) {
+		return false, nil
+	}
+	e.decide("lock held by our pod", "resume leadership", "leader", e.owner.Name)
+	if err := e.resume(ctx, existing); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mayCompete applies the gates in front of every attempt to take the lock,
+// for Become and TryAcquire alike, and reports whether we may try now. It
+// logs and records why not. A successor of a transfer is not held back by
+// pinning or the committee, which the leader already weighed.
+func (e *PodElector) mayCompete(ctx context.Context, successor bool) (bool, error) {
+	e.refreshLabels(ctx)
+	if reason := e.ineligible(); reason != "" {
+		e.infoSampled("Not eligible to become the leader", "lock", e.lockName, "reason", reason)
+		e.decide("not eligible", "wait", "reason", reason)
+		e.leaveSeat()
+		return false, nil
+	}
+
+	if pinned := e.pinnedElsewhere(ctx); pinned != "" && !successor {
+		e.infoSampled("Leadership is pinned to another pod, deferring", "lock", e.lockName, "pinned", pinned)
+		e.decide("pinned elsewhere", "defer", "pinned", pinned)
+		return false, nil
+	}
+
+	if err := e.checkReady(ctx); err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		e.log.Info("Not ready to become the leader", "lock", e.lockName, "reason", err.Error())
+		e.decide("not ready", "wait", "reason", err.Error())
+		e.leaveSeat()
+		return false, nil
+	}
+
+	if e.opts.committee > 0 && !successor {
+		seated, err := e.takeSeat(ctx)
+		if err != nil && !e.retryable(ctx, err) {
+			e.log.Error(err, "Failed to take a seat on the committee", "lock", e.lockName, "seat", e.seatName())
+			return false, err
+		}
+		if !seated {
+			e.infoSampled("Not on the committee. Waiting for a seat", "lock", e.lockName, "seat", e.seatName())
+			e.decide("not on the committee", "wait for a seat", "seat", e.seatName())
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ownedByUs reports whether lock is owned by our pod. Ownership is matched
+// by UID: a lock owned by an earlier pod of our name, as StatefulSets
+// recreate, belongs to that pod and goes with it.
+func (e *PodElector) ownedByUs(lock metav1.Object) bool {
+	for _, owner := range lock.GetOwnerReferences() {
+		if owner.UID == e.owner.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// acquired takes up leadership of lock, which we just created: it issues
+// the next epoch, warms up and announces leadership. If either fails the
+// lock is given back and the error returned.
+func (e *PodElector) acquired(ctx context.Context, lock metav1.Object) error {
+	e.setLeading(lock)
+	e.recordExpiry(lock)
+	if err := e.advanceEpoch(ctx); err != nil {
+		e.giveBack()
+		return err
+	}
+	if err := e.warmUp(ctx); err != nil {
+		return err
+	}
+	e.observeFailover()
+	e.log.Info("Became the leader", "lock", e.lockName, "epoch", e.Epoch())
+	e.decide("lock free", "became leader", "epoch", e.Epoch())
+	e.audit(AuditAcquired, "Acquired %s", e.lockName)
+	e.transition(true)
+	return nil
+}
+
+// resume takes up leadership of lock, which our pod already holds, as after
+// a restart of our container. It keeps the lock's epoch, renews its expiry
+// and warms up before announcing leadership.
+func (e *PodElector) resume(ctx context.Context, lock metav1.Object) error {
+	e.setLeading(lock)
+	e.recordExpiry(lock)
+	e.setEpoch(lockEpoch(lock))
+	if err := e.renewOnResume(ctx); err != nil {
+		return err
+	}
+	if err := e.warmUp(ctx); err != nil {
+		return err
+	}
+	e.audit(AuditResumed, "Resumed leadership of %s after a restart", e.lockName)
+	e.transition(true)
+	return nil
+}
+
+// Held re-reads the lock and reports whether we still hold it.
+func (e *PodElector) Held(ctx context.Context) (_ bool, err error) {
+	defer func() { err = e.wrap("verify", err) }()
+
+	lock, err := e.getLock(ctx)
+	switch {
+	case apierrors.IsNotFound(err):
+		e.lost()
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if !e.holds(lock.GetUID()) {
+		e.lost()
+		return false, nil
+	}
+	return true, nil
+}
+
+// Release gives up the lock without logging a resignation. It implements
+// Lock.
+func (e *PodElector) Release(ctx context.Context) error {
+	return e.wrap("release", e.release(ctx))
+}
+
+// Resign gives up leadership by deleting the lock, allowing another
+// candidate to acquire it.
+func (e *PodElector) Resign(ctx context.Context) error {
+	e.log.Info("Resigning leadership", "lock", e.lockName)
+	if !e.IsLeader() {
+		return e.wrap("resign", e.release(ctx))
+	}
+	e.drainWork(ctx)
+	if err := e.release(ctx); err != nil {
+		e.undrain()
+		return e.wrap("resign", err)
+	}
+	return nil
+}
+
+// wrap adds the operation and the lock's namespace and name to err. The
+// original error stays reachable through errors.Is and errors.As, so
+// apierrors.IsForbidden and friends keep working on what callers receive.
+func (e *PodElector) wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e.explainForbidden(err)
+	return fmt.Errorf("%s lock %s/%s: %w", op, e.ns, e.lockName, err)
+}
+
+// request derives the context for a single API call from ctx, bounded by
+// the request timeout.
+func (e *PodElector) request(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, e.opts.requestTimeout)
+}
+
+// release deletes the lock we hold and announces the end of leadership.
+func (e *PodElector) release(ctx context.Context) error {
+	if err := e.dropLock(ctx); err != nil {
+		return err
+	}
+
+	e.audit(AuditResigned, "Released %s", e.lockName)
+	e.transition(false)
+	return nil
+}
+
+// dropLock deletes the lock we hold. The UID precondition guarantees we
+// never delete a lock that has since been recreated by another pod. e.mu is
+// not held across the API calls; leadership is only cleared if it is still
+// the lock we deleted.
+func (e *PodElector) dropLock(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.leading {
+		e.mu.Unlock()
+		return ErrNotLeader
+	}
+	uid := *e.lockUID
+	e.mu.Unlock()
+
+	err := e.lockBackend().Delete(ctx, e.lockName, uidOnly(uid))
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return err
+	}
+	if e.opts.lockFinalizer && err == nil {
+		if lock, err := e.getLock(ctx); err == nil && lock.GetUID() == uid {
+			e.removeFinalizer(ctx, lock)
+		}
+	}
+
+	e.mu.Lock()
+	if e.lockUID != nil && *e.lockUID == uid {
+		e.leading = false
+		e.lockUID = nil
+	}
+	e.mu.Unlock()
+	return nil
+}
+
+// backoff waits for the jittered or slotted backoff, or until any transfer we
+// are deferring to has had its chance or the apiserver's Retry-After has
+// passed, and then doubles the backoff. A wake ends the wait early and resets
+// the backoff.
+func (e *PodElector) backoff(ctx context.Context, backoff *time.Duration) error {
+	delay := e.retryDelay(time.Duration(float64(*backoff) * e.topologyWeight()))
+	if d := time.Until(e.deferUntil); d > delay {
+		delay = d
+	}
+	if d := e.retryAfterDelay(); d > delay {
+		delay = d
+	}
+	woken, err := sleepOrWake(ctx, delay, e.woken)
+	if err != nil || woken {
+		*backoff = initialBackoffInterval
+		return err
+	}
+	if *backoff < e.tuned().maxBackoff {
+		*backoff *= 2
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx is cancelled.
+func (e *PodElector) sleep(ctx context.Context, d time.Duration) error {
+	_, err := sleepOrWake(ctx, d, nil)
+	return err
+}
+
+// nap is sleep cut short by a wake. Only the election and maintenance loops
+// nap, so that they, rather than a helper goroutine, receive wakes.
+func (e *PodElector) nap(ctx context.Context, d time.Duration) error {
+	_, err := sleepOrWake(ctx, d, e.woken)
+	return err
+}
+
+// sleepOrWake waits for d, until ctx is cancelled or until wake fires, and
+// reports whether it was woken. A nil wake never fires. Unlike time.After,
+// the timer is released as soon as the wait ends.
+func sleepOrWake(ctx context.Context, d time.Duration, wake <-chan struct{}) (bool, error) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timer.C:
+		return false, nil
+	case <-wake:
+		return true, nil
+	}
+}
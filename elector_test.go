@@ -0,0 +1,252 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReleaseKeepsReplacedLock(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	e := newTestElector(t, client, "pod-1")
+	b := enforcePreconditions(e)
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	uid, _ := e.heldUID()
+
+	// our lock is taken over and recreated by pod-2 while we still think
+	// we lead
+	if err := client.CoreV1().ConfigMaps(testNamespace).Delete(context.Background(), testLock, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expiredLock(t, client)
+
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if e.IsLeader() {
+		t.Fatal("IsLeader is true after Release")
+	}
+	if owner := lockOwner(t, client); owner != "pod-2" {
+		t.Fatalf("Release deleted the lock of pod-2, owner is %q", owner)
+	}
+	if len(b.deletes) != 1 || b.deletes[0].UID == nil || *b.deletes[0].UID != uid {
+		t.Fatalf("lock was deleted with preconditions %+v, want UID %s", b.deletes, uid)
+	}
+}
+
+func TestErrorsKeepAPITypes(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		verb string
+		// acquire is whether the lock is taken before the failing call
+		acquire bool
+		call    func(e *PodElector) error
+		prefix  string
+	}{
+		{
+			name: "TryAcquire", verb: "get",
+			call: func(e *PodElector) error {
+				_, err := e.TryAcquire(context.Background())
+				return err
+			},
+			prefix: "acquire lock test/test-lock: ",
+		},
+		{
+			name: "Leader", verb: "get",
+			call: func(e *PodElector) error {
+				_, err := e.Leader(context.Background())
+				return err
+			},
+			prefix: "resolve leader of lock test/test-lock: ",
+		},
+		{
+			name: "Held", verb: "get", acquire: true,
+			call: func(e *PodElector) error {
+				_, err := e.Held(context.Background())
+				return err
+			},
+			prefix: "verify lock test/test-lock: ",
+		},
+		{
+			name: "Release", verb: "delete", acquire: true,
+			call:   func(e *PodElector) error { return e.Release(context.Background()) },
+			prefix: "release lock test/test-lock: ",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			if tc.acquire {
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+			client.PrependReactor(tc.verb, "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewInternalError(errors.New("etcd is unavailable"))
+			})
+
+			err := tc.call(e)
+			if !apierrors.IsInternalError(err) {
+				t.Fatalf("error = %v, want an InternalError", err)
+			}
+			if !strings.HasPrefix(err.Error(), tc.prefix) {
+				t.Fatalf("error = %q, want it prefixed %q", err, tc.prefix)
+			}
+		})
+	}
+}
+
+func TestNewElectorWithoutPod(t *testing.T) {
+	client := newTestClient(t)
+	_, err := NewElector(testLock, WithClient(client), WithNamespace(testNamespace), WithPodName("pod-1"), WithLogLevel(ErrorLevel))
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("NewElector without our pod = %v, want NotFound", err)
+	}
+	if !strings.Contains(err.Error(), "test/pod-1") {
+		t.Fatalf("error = %q, want it to name the pod", err)
+	}
+}
+
+func TestBecomeAsync(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		held bool
+		want error
+	}{
+		{name: "free lock", want: nil},
+		{name: "held lock", held: true, want: context.DeadlineExceeded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			if tc.held {
+				other := newTestElector(t, client, "pod-2")
+				if ok, err := other.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1")
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			done := e.BecomeAsync(ctx)
+			if err := <-done; !errors.Is(err, tc.want) {
+				t.Fatalf("BecomeAsync = %v, want %v", err, tc.want)
+			}
+			if _, open := <-done; open {
+				t.Fatal("BecomeAsync sent a second result")
+			}
+			if e.IsLeader() != (tc.want == nil) {
+				t.Fatalf("IsLeader = %v after BecomeAsync returned %v", e.IsLeader(), tc.want)
+			}
+		})
+	}
+}
+
+func TestSleepOrWake(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		d       time.Duration
+		woken   bool
+		cancel  bool
+		wantErr error
+	}{
+		{name: "elapsed", d: time.Millisecond},
+		{name: "woken", d: time.Hour, woken: true},
+		{name: "cancelled", d: time.Hour, cancel: true, wantErr: context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+			wake := make(chan struct{}, 1)
+			if tc.woken {
+				wake <- struct{}{}
+			}
+
+			woken, err := sleepOrWake(ctx, tc.d, wake)
+			if woken != tc.woken || !errors.Is(err, tc.wantErr) {
+				t.Fatalf("sleepOrWake = %v, %v, want %v, %v", woken, err, tc.woken, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNapWokenByConfig(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithConfigMap("election-config"))
+
+	done := make(chan error, 1)
+	go func() { done <- e.nap(context.Background(), time.Hour) }()
+	e.applyConfig(map[string]string{ConfigMaxBackoff: "2s"})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("nap: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nap was not cut short by a configuration reload")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		start time.Duration
+		want  []time.Duration
+	}{
+		{name: "doubles", start: time.Millisecond, want: []time.Duration{2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond}},
+		{
+			name:  "capped",
+			opts:  []Option{WithMaxBackoff(4 * time.Millisecond)},
+			start: time.Millisecond,
+			want:  []time.Duration{2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond},
+		},
+		{
+			name:  "zero cap ignored",
+			opts:  []Option{WithMaxBackoff(0)},
+			start: time.Millisecond,
+			want:  []time.Duration{2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1", tc.opts...)
+
+			backoff := tc.start
+			for i, want := range tc.want {
+				if err := e.backoff(context.Background(), &backoff); err != nil {
+					t.Fatalf("backoff: %v", err)
+				}
+				if backoff != want {
+					t.Fatalf("backoff after %d waits = %v, want %v", i+1, backoff, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffWoken(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+
+	e.wake()
+	backoff := time.Hour
+	if err := e.backoff(context.Background(), &backoff); err != nil {
+		t.Fatalf("backoff: %v", err)
+	}
+	if backoff != initialBackoffInterval {
+		t.Fatalf("backoff after a wake = %v, want it reset to %v", backoff, initialBackoffInterval)
+	}
+}
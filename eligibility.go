@@ -0,0 +1,66 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ineligible returns why we may not compete for the lock, or "" if we may.
+func (e *PodElector) ineligible() string {
+	if e.opts.excludeSpot && e.spot {
+		return "running on a spot node"
+	}
+	return e.selectedOut()
+}
+
+// myLabels returns the labels of our pod as last read.
+func (e *PodElector) myLabels() labels.Set {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return labels.Set(e.podLabels)
+}
+
+// refreshLabels re-reads our pod's labels when WithEligibilitySelector is
+// used, and its maintenance mode with WithRemoteMaintenance, so adding or
+// removing a label or MaintenanceAnnotation takes effect on a running pod.
+// CandidacyDeniedAnnotation is picked up whenever the pod is re-read, and at
+// least every denyCheckInterval.
+func (e *PodElector) refreshLabels(ctx context.Context) {
+	e.refreshNodeLabels(ctx)
+
+	e.mu.Lock()
+	due := time.Since(e.refreshedAt) >= denyCheckInterval
+	e.mu.Unlock()
+	if e.tuned().eligibilitySelector == nil && !e.opts.remoteMaintenance && !due {
+		return
+	}
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	myPod, err := e.kube().CoreV1().Pods(e.ns).Get(ctx, e.owner.Name, metav1.GetOptions{})
+	if err != nil {
+		e.log.Error(err, "Failed to get my pod", "pod", e.owner.Name)
+		return
+	}
+	e.mu.Lock()
+	e.refreshedAt = time.Now()
+	e.podLabels = myPod.Labels
+	e.observeDenied(myPod.Annotations)
+	if e.opts.remoteMaintenance {
+		e.maintenance = myPod.Annotations[MaintenanceAnnotation]
+	}
+	e.mu.Unlock()
+}
+
+// checkReady runs the readiness check, if any, bounded by the request
+// timeout. A pod that is not ready must not take the lock.
+func (e *PodElector) checkReady(ctx context.Context) error {
+	if e.opts.readinessCheck == nil {
+		return nil
+	}
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	return e.opts.readinessCheck(ctx)
+}
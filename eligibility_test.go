@@ -0,0 +1,99 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestReadinessCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		check error
+		taken bool
+	}{
+		{name: "ready", check: nil, taken: true},
+		{name: "not ready", check: errors.New("cache not synced"), taken: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			checked := 0
+			e := newTestElector(t, client, "pod-1", WithReadinessCheck(func(ctx context.Context) error {
+				checked++
+				return tc.check
+			}))
+
+			ok, err := e.TryAcquire(context.Background())
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+			if ok != tc.taken {
+				t.Fatalf("TryAcquire = %v, want %v", ok, tc.taken)
+			}
+			if checked != 1 {
+				t.Fatalf("readiness checked %d times, want once", checked)
+			}
+			if owner := lockOwner(t, client); (owner != "") != tc.taken {
+				t.Fatalf("lock owner = %q", owner)
+			}
+		})
+	}
+}
+
+func TestEligibilitySelector(t *testing.T) {
+	canary := labels.SelectorFromSet(labels.Set{"canary": "true"})
+	for _, tc := range []struct {
+		name string
+		// labels are those of our pod when we start and later, while we
+		// compete
+		labels, later map[string]string
+		taken         bool
+	}{
+		{name: "matching", labels: map[string]string{"canary": "true"}, taken: true},
+		{name: "not matching", labels: map[string]string{"canary": "false"}, taken: false},
+		{name: "labeled later", later: map[string]string{"canary": "true"}, taken: true},
+		{name: "unlabeled later", labels: map[string]string{"canary": "true"}, later: map[string]string{}, taken: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			updatePod(t, client, "pod-1", func(p *v1.Pod) { p.Labels = tc.labels })
+			e := newTestElector(t, client, "pod-1", WithEligibilitySelector(canary))
+			if tc.later != nil {
+				updatePod(t, client, "pod-1", func(p *v1.Pod) { p.Labels = tc.later })
+			}
+
+			ok, err := e.TryAcquire(context.Background())
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+			if ok != tc.taken {
+				t.Fatalf("TryAcquire = %v, want %v", ok, tc.taken)
+			}
+		})
+	}
+}
+
+func TestIneligibleLeaderStepsDown(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	updatePod(t, client, "pod-1", func(p *v1.Pod) { p.Labels = map[string]string{"canary": "true"} })
+	e := newTestElector(t, client, "pod-1", WithEligibilitySelector(labels.SelectorFromSet(labels.Set{"canary": "true"})), WithMaintenanceInterval(10*time.Millisecond))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	// removing the label excludes the leader in an emergency
+	updatePod(t, client, "pod-1", func(p *v1.Pod) { delete(p.Labels, "canary") })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.maintain(ctx)
+	if e.IsLeader() {
+		t.Fatal("still the leader without the label")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock still held by %q", owner)
+	}
+}
@@ -0,0 +1,167 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// EDSKey is the key of the ConfigMap of WithEnvoyEndpoints holding the
+	// Envoy discovery response.
+	EDSKey = "eds.json"
+
+	clusterLoadAssignmentType = "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment"
+)
+
+// edsResponse is an Envoy v3 DiscoveryResponse carrying one
+// ClusterLoadAssignment, in the JSON form Envoy reads from files and REST
+// endpoints.
+type edsResponse struct {
+	VersionInfo string        `json:"version_info"`
+	TypeURL     string        `json:"type_url"`
+	Resources   []edsResource `json:"resources"`
+}
+
+type edsResource struct {
+	Type        string             `json:"@type"`
+	ClusterName string             `json:"cluster_name"`
+	Endpoints   []edsLocalityGroup `json:"endpoints"`
+}
+
+type edsLocalityGroup struct {
+	LBEndpoints []edsEndpoint `json:"lb_endpoints"`
+}
+
+type edsEndpoint struct {
+	Endpoint struct {
+		Address struct {
+			SocketAddress struct {
+				Address   string `json:"address"`
+				PortValue int    `json:"port_value"`
+			} `json:"socket_address"`
+		} `json:"address"`
+	} `json:"endpoint"`
+}
+
+// edsDocument returns the discovery response routing the cluster to ip, or
+// to no endpoint if ip is empty. The epoch is its version, so a response
+// of a later leader always supersedes the one of an earlier leader.
+func edsDocument(cluster, ip string, port int, epoch int64) ([]byte, error) {
+	group := edsLocalityGroup{LBEndpoints: []edsEndpoint{}}
+	if ip != "" {
+		var ep edsEndpoint
+		ep.Endpoint.Address.SocketAddress.Address = ip
+		ep.Endpoint.Address.SocketAddress.PortValue = port
+		group.LBEndpoints = append(group.LBEndpoints, ep)
+	}
+	return json.Marshal(edsResponse{
+		VersionInfo: strconv.FormatInt(epoch, 10),
+		TypeURL:     clusterLoadAssignmentType,
+		Resources: []edsResource{{
+			Type:        clusterLoadAssignmentType,
+			ClusterName: cluster,
+			Endpoints:   []edsLocalityGroup{group},
+		}},
+	})
+}
+
+// exportEnvoyEndpoints is the transition hook writing the Envoy endpoints of
+// the leader to the ConfigMap of WithEnvoyEndpoints. On losing leadership
+// the cluster is emptied, unless a later leader has already written its
+// own endpoint.
+func (e *PodElector) exportEnvoyEndpoints(leading bool) {
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+
+	ip := e.podIP
+	if !leading {
+		ip = ""
+		if current, err := e.edsVersion(ctx); err != nil || current != strconv.FormatInt(e.Epoch(), 10) {
+			return
+		}
+	}
+	doc, err := edsDocument(e.opts.envoyCluster, ip, e.opts.envoyPort, e.Epoch())
+	if err != nil {
+		e.log.Error(err, "Failed to encode Envoy endpoints")
+		return
+	}
+	if err := e.writeEDS(ctx, string(doc)); err != nil {
+		e.log.Error(err, "Failed to export Envoy endpoints", "configMap", e.opts.envoyConfigMap, "cluster", e.opts.envoyCluster)
+	}
+}
+
+// edsVersion returns the version of the exported discovery response.
+func (e *PodElector) edsVersion(ctx context.Context) (string, error) {
+	cm, err := e.kube().CoreV1().ConfigMaps(e.ns).Get(ctx, e.opts.envoyConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	var current edsResponse
+	err = json.Unmarshal([]byte(cm.Data[EDSKey]), &current)
+	return current.VersionInfo, err
+}
+
+// writeEDS sets EDSKey of the ConfigMap to doc, creating the ConfigMap if
+// needed.
+func (e *PodElector) writeEDS(ctx context.Context, doc string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{EDSKey: doc},
+	})
+	if err != nil {
+		return err
+	}
+
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	_, err = configMaps.Patch(ctx, e.opts.envoyConfigMap, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	_, err = configMaps.Create(ctx, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: e.opts.envoyConfigMap, Namespace: e.ns},
+		Data:       map[string]string{EDSKey: doc},
+	}, metav1.CreateOptions{FieldManager: FieldManager})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Patch(ctx, e.opts.envoyConfigMap, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
+
+// EDSHandler returns an http.Handler serving the exported discovery
+// response as an Envoy REST EDS endpoint, for clusters configured with an
+// api_type of REST and a short refresh_delay, such as 500ms: a failover
+// then reaches Envoy within the refresh delay, where a mounted ConfigMap
+// takes up to the kubelet's sync period. Every pod serves it. It needs
+// WithEnvoyEndpoints.
+func (e *PodElector) EDSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := e.request(r.Context())
+		defer cancel()
+		cm, err := e.kube().CoreV1().ConfigMaps(e.ns).Get(ctx, e.opts.envoyConfigMap, metav1.GetOptions{})
+		doc := ""
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		default:
+			doc = cm.Data[EDSKey]
+		}
+		if doc == "" {
+			empty, err := edsDocument(e.opts.envoyCluster, "", 0, 0)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			doc = string(empty)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(doc))
+	})
+}
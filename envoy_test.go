@@ -0,0 +1,80 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// servedEndpoints returns the version and the addresses of the endpoints
+// EDSHandler of e serves.
+func servedEndpoints(t *testing.T, e *PodElector) (string, []string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.EDSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v3/discovery:endpoints", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("EDS handler = %d %s", rec.Code, rec.Body)
+	}
+	var resp edsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Resources) != 1 || resp.Resources[0].ClusterName != "leader" || resp.TypeURL != clusterLoadAssignmentType {
+		t.Fatalf("EDS response = %+v, want one assignment of the leader cluster", resp)
+	}
+	addresses := []string{}
+	for _, ep := range resp.Resources[0].Endpoints[0].LBEndpoints {
+		addr := ep.Endpoint.Address.SocketAddress
+		if addr.PortValue != 8080 {
+			t.Fatalf("endpoint %s has port %d, want 8080", addr.Address, addr.PortValue)
+		}
+		addresses = append(addresses, addr.Address)
+	}
+	return resp.VersionInfo, addresses
+}
+
+func TestEnvoyEndpoints(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2")
+	setPodIP(t, client, "pod-1", "10.0.0.1")
+	setPodIP(t, client, "pod-2", "10.0.0.2")
+	opt := WithEnvoyEndpoints("envoy-eds", "leader", 8080)
+	first := newTestElector(t, client, "pod-1", opt)
+	second := newTestElector(t, client, "pod-2", opt)
+
+	check := func(what, version string, addresses ...string) {
+		t.Helper()
+		gotVersion, got := servedEndpoints(t, second)
+		if gotVersion != version || len(got) != len(addresses) || (len(got) == 1 && got[0] != addresses[0]) {
+			t.Fatalf("%s: endpoints %v at version %s, want %v at %s", what, got, gotVersion, addresses, version)
+		}
+	}
+	check("before any leader", "0")
+
+	if ok, err := first.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	check("first leader", "1", "10.0.0.1")
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	check("after Release", "1")
+
+	if ok, err := first.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	// the lock is lost and taken over before the former leader notices
+	if err := client.CoreV1().ConfigMaps(testNamespace).Delete(ctx, testLock, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := second.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire of the successor = %v, %v", ok, err)
+	}
+	check("successor", "3", "10.0.0.2")
+	first.exportEnvoyEndpoints(false)
+	check("former leader stepped down", "3", "10.0.0.2")
+}
@@ -0,0 +1,159 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EpochAnnotation records the term of the current leader on the lock.
+	// It increases with every acquisition, so applications can tag external
+	// writes with it and discard operations from stale terms.
+	EpochAnnotation = "leader.seamounts.io/epoch"
+
+	// epochKey is the key in the companion ConfigMap holding the highest
+	// epoch ever issued for a lock.
+	epochKey = "epoch"
+)
+
+type epochContextKey struct{}
+
+// EpochFromContext returns the epoch of the leadership term a callback runs
+// in, and whether one was recorded in ctx.
+func EpochFromContext(ctx context.Context) (int64, bool) {
+	epoch, ok := ctx.Value(epochContextKey{}).(int64)
+	return epoch, ok
+}
+
+func withEpoch(ctx context.Context, epoch int64) context.Context {
+	return context.WithValue(ctx, epochContextKey{}, epoch)
+}
+
+// CurrentEpoch returns the epoch of the current holder of lockName, or 0 if
+// the lock is free or predates epochs.
+func CurrentEpoch(ctx context.Context, lockName string, opts ...Option) (int64, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	client, ns, err := clientAndNamespace(&o)
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := newBackend(o.backend, client, ns, o.requestTimeout, o.getLogger())
+	if err != nil {
+		return 0, err
+	}
+
+	lock, err := b.Get(ctx, lockName)
+	switch {
+	case apierrors.IsNotFound(err):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("get lock %s/%s: %w", ns, lockName, err)
+	}
+	return lockEpoch(lock), nil
+}
+
+// Epoch returns the epoch of our current term, or 0 if we do not lead.
+func (e *PodElector) Epoch() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.epoch
+}
+
+func (e *PodElector) setEpoch(epoch int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.epoch = epoch
+}
+
+func lockEpoch(lock metav1.Object) int64 {
+	epoch, err := strconv.ParseInt(lock.GetAnnotations()[EpochAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return epoch
+}
+
+func (e *PodElector) epochName() string {
+	return e.lockName + "-epoch"
+}
+
+// advanceEpoch issues the next epoch from the lock's companion ConfigMap and
+// records it on the lock. The companion has no owner, so it outlives any
+// single lock, and updates are guarded by its resourceVersion, so epochs
+// strictly increase even when two terms start close together. A term
+// without an epoch must not start, so errors are returned for the caller to
+// give the lock back.
+func (e *PodElector) advanceEpoch(ctx context.Context) error {
+	epoch, err := e.nextEpoch(ctx)
+	if err != nil {
+		e.log.Error(err, "Failed to advance epoch", "lock", e.lockName)
+		return err
+	}
+
+	err = e.patchLockAnnotations(ctx, map[string]interface{}{
+		EpochAnnotation: strconv.FormatInt(epoch, 10),
+	})
+	if err != nil {
+		e.log.Error(err, "Failed to record epoch on the lock", "lock", e.lockName, "epoch", epoch)
+		return err
+	}
+	e.setEpoch(epoch)
+	return nil
+}
+
+func (e *PodElector) nextEpoch(ctx context.Context) (int64, error) {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	for {
+		counter, err := configMaps.Get(ctx, e.epochName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = configMaps.Create(ctx, &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      e.epochName(),
+					Namespace: e.ns,
+					Labels: map[string]string{
+						LockLabel: e.lockName,
+						RoleLabel: epochRole,
+					},
+				},
+				Data: map[string]string{epochKey: "1"},
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return 1, err
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		epoch, err := strconv.ParseInt(counter.Data[epochKey], 10, 64)
+		if err != nil {
+			// issuing 1 would go back on the epochs issued so far
+			return 0, fmt.Errorf("epoch counter %s/%s holds %q: %w", e.ns, e.epochName(), counter.Data[epochKey], err)
+		}
+		epoch++
+		if counter.Data == nil {
+			counter.Data = map[string]string{}
+		}
+		counter.Data[epochKey] = strconv.FormatInt(epoch, 10)
+
+		_, err = configMaps.Update(ctx, counter, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			continue
+		}
+		return epoch, err
+	}
+}
@@ -0,0 +1,105 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNextEpoch(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		counter   map[string]string
+		conflicts int
+		want      int64
+		wantErr   bool
+	}{
+		{name: "first term", want: 1},
+		{name: "next term", counter: map[string]string{epochKey: "41"}, want: 42},
+		{name: "after a conflict", counter: map[string]string{epochKey: "41"}, conflicts: 1, want: 42},
+		{name: "empty counter", counter: map[string]string{}, wantErr: true},
+		{name: "corrupt counter", counter: map[string]string{epochKey: "forty-one"}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			if tc.counter != nil {
+				counter := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: e.epochName(), Namespace: testNamespace}, Data: tc.counter}
+				if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), counter, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			conflicts := tc.conflicts
+			client.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if conflicts == 0 {
+					return false, nil, nil
+				}
+				// another term started at the same time
+				conflicts--
+				return true, nil, apierrors.NewConflict(v1.Resource("configmaps"), e.epochName(), errors.New("the object has been modified"))
+			})
+
+			epoch, err := e.nextEpoch(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("nextEpoch = %d, %v, want error %v", epoch, err, tc.wantErr)
+			}
+			if err == nil && epoch != tc.want {
+				t.Fatalf("nextEpoch = %d, want %d", epoch, tc.want)
+			}
+		})
+	}
+}
+
+func TestEpochIncreasesAcrossTerms(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	first := newTestElector(t, client, "pod-1", WithEvents(time.Minute))
+	second := newTestElector(t, client, "pod-2")
+
+	if ok, err := first.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	lock, err := first.getLock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Epoch() != 1 || lockEpoch(lock) != 1 {
+		t.Fatalf("first term has epoch %d, %d on the lock, want 1", first.Epoch(), lockEpoch(lock))
+	}
+	if err := first.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := second.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if second.Epoch() != 2 {
+		t.Fatalf("second term has epoch %d, want 2", second.Epoch())
+	}
+
+	// both ends of a term name its epoch
+	events, err := client.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, event := range events.Items {
+		messages = append(messages, event.Reason+": "+event.Message)
+	}
+	sort.Strings(messages)
+	want := []string{
+		"LeaderElected: Became the leader of " + testLock + " for epoch 1",
+		"LeadershipLost: No longer the leader of " + testLock + ", ending epoch 1",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events = %q, want %q", messages, want)
+	}
+}
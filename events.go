@@ -0,0 +1,171 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultEventInterval is the minimum time between two Events with the
+	// same reason and message.
+	defaultEventInterval = time.Minute
+
+	eventSource = "k8s-leader"
+)
+
+// eventEmitter records Kubernetes Events about our pod. Repeats of the same
+// Event within the interval are only counted, and folded into the Event's
+// count when the interval has passed, so a long-waiting standby does not
+// create thousands of Events.
+type eventEmitter struct {
+	client   kubernetes.Interface
+	pod      v1.ObjectReference
+	interval time.Duration
+	timeout  time.Duration
+	log      Logger
+
+	mu         sync.Mutex
+	aggregates map[string]*eventAggregate
+}
+
+type eventAggregate struct {
+	event    *v1.Event
+	message  string
+	pending  int32
+	lastSent time.Time
+	// sending is set while the aggregate's Event is being written, so
+	// repeats in the meantime are only counted
+	sending bool
+}
+
+func newEventEmitter(client kubernetes.Interface, pod *v1.Pod, interval, timeout time.Duration, logger Logger) *eventEmitter {
+	return &eventEmitter{
+		client: client,
+		pod: v1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  pod.Namespace,
+			Name:       pod.Name,
+			UID:        pod.UID,
+		},
+		interval:   interval,
+		timeout:    timeout,
+		log:        logger,
+		aggregates: map[string]*eventAggregate{},
+	}
+}
+
+func (r *eventEmitter) setClient(client kubernetes.Interface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.client = client
+}
+
+// emit records an Event, or counts it if the same Event was sent less than
+// the interval ago.
+func (r *eventEmitter) emit(eventType, reason, message string) {
+	r.mu.Lock()
+	key := eventType + "/" + reason + "/" + message
+	now := time.Now()
+	agg, ok := r.aggregates[key]
+	if !ok {
+		// aggregates not sent within the interval would start a new Event
+		// anyway, and messages carrying an epoch or a pod name never repeat
+		for k, old := range r.aggregates {
+			if !old.sending && now.Sub(old.lastSent) >= r.interval {
+				delete(r.aggregates, k)
+			}
+		}
+		agg = &eventAggregate{message: message}
+		r.aggregates[key] = agg
+	}
+
+	agg.pending++
+	if agg.sending || agg.event != nil && now.Sub(agg.lastSent) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+
+	agg.sending = true
+	client, event, count := r.client, agg.event, agg.pending
+	r.mu.Unlock()
+
+	sent, err := r.send(client, event, eventType, reason, message, count, now)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agg.sending = false
+	if err != nil {
+		r.log.Error(err, "Failed to record event", "reason", reason)
+		return
+	}
+	agg.event = sent
+	agg.pending -= count
+	agg.lastSent = now
+}
+
+// send creates an Event counting count occurrences of message, or folds
+// count into event if there is one, and returns the Event as stored.
+func (r *eventEmitter) send(client kubernetes.Interface, event *v1.Event, eventType, reason, message string, count int32, now time.Time) (*v1.Event, error) {
+	ctx, cancel := withTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	events := client.CoreV1().Events(r.pod.Namespace)
+	ts := metav1.NewTime(now)
+
+	if event != nil {
+		event = event.DeepCopy()
+		event.Count += count
+		event.LastTimestamp = ts
+		event.Message = fmt.Sprintf("%s (seen %d times)", message, event.Count)
+
+		updated, err := events.Update(ctx, event, metav1.UpdateOptions{})
+		if err == nil {
+			return updated, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		// the Event expired, start a new one
+	}
+
+	return events.Create(ctx, &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: r.pod.Name + ".",
+			Namespace:    r.pod.Namespace,
+		},
+		InvolvedObject: r.pod,
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		Source:         v1.EventSource{Component: eventSource},
+		FirstTimestamp: ts,
+		LastTimestamp:  ts,
+		Count:          count,
+	}, metav1.CreateOptions{})
+}
+
+// event records an Event about our pod if Events are enabled.
+func (e *PodElector) event(eventType, reason, format string, args ...interface{}) {
+	if e.events == nil {
+		return
+	}
+	e.events.emit(eventType, reason, fmt.Sprintf(format, args...))
+}
+
+// leadershipEvent is the transition hook recording Events for gained and
+// lost leadership, tagged with the epoch of the term.
+func (e *PodElector) leadershipEvent(leading bool) {
+	if leading {
+		e.event(v1.EventTypeNormal, "LeaderElected", "Became the leader of %s for epoch %d", e.lockName, e.Epoch())
+		return
+	}
+	e.event(v1.EventTypeNormal, "LeadershipLost", "No longer the leader of %s, ending epoch %d", e.lockName, e.Epoch())
+}
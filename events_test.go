@@ -0,0 +1,113 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestEmitter(t *testing.T, client *fake.Clientset, interval time.Duration) *eventEmitter {
+	t.Helper()
+	pod, err := client.CoreV1().Pods(testNamespace).Get(context.Background(), "pod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := defaultOptions()
+	o.logLevel = ErrorLevel
+	return newEventEmitter(client, pod, interval, time.Second, o.getLogger())
+}
+
+func listEvents(t *testing.T, client *fake.Clientset) []v1.Event {
+	t.Helper()
+	events, err := client.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.Items
+}
+
+func TestEventAggregation(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval time.Duration
+		emits    int
+		wait     bool
+		count    int32
+		message  string
+	}{
+		{name: "once", interval: time.Hour, emits: 1, count: 1, message: "Waiting"},
+		{name: "repeats within the interval", interval: time.Hour, emits: 5, count: 1, message: "Waiting"},
+		{name: "repeats after the interval", interval: 20 * time.Millisecond, emits: 3, wait: true, count: 3, message: "Waiting (seen 3 times)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			r := newTestEmitter(t, client, tc.interval)
+			for i := 0; i < tc.emits; i++ {
+				if tc.wait && i == tc.emits-1 {
+					time.Sleep(tc.interval)
+				}
+				r.emit(v1.EventTypeNormal, "Standby", "Waiting")
+			}
+
+			events := listEvents(t, client)
+			if len(events) != 1 {
+				t.Fatalf("%d Events, want 1", len(events))
+			}
+			if events[0].Count != tc.count || events[0].Message != tc.message {
+				t.Fatalf("Event count %d message %q, want %d %q", events[0].Count, events[0].Message, tc.count, tc.message)
+			}
+		})
+	}
+}
+
+func TestEventAggregatesArePruned(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	r := newTestEmitter(t, client, 20*time.Millisecond)
+	r.emit(v1.EventTypeNormal, "LeaderElected", "Became the leader for epoch 1")
+	time.Sleep(20 * time.Millisecond)
+	r.emit(v1.EventTypeNormal, "LeaderElected", "Became the leader for epoch 2")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.aggregates) != 1 {
+		t.Fatalf("%d aggregates kept, want only the latest", len(r.aggregates))
+	}
+}
+
+func TestEventSentWithoutLock(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	r := newTestEmitter(t, client, time.Hour)
+	client.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		// the fake clientset serializes calls, so other Events can't be
+		// sent from here, but the emitter must be free to take them
+		locked := make(chan struct{})
+		go func() {
+			r.mu.Lock()
+			r.mu.Unlock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Error("emitter locked while sending")
+		}
+		r.emit(v1.EventTypeWarning, "Slow", "Apiserver is slow")
+		return false, nil, nil
+	})
+
+	r.emit(v1.EventTypeWarning, "Slow", "Apiserver is slow")
+	if events := listEvents(t, client); len(events) != 1 || events[0].Count != 1 {
+		t.Fatalf("Events = %v, want one sent once", events)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if agg := r.aggregates[v1.EventTypeWarning+"/Slow/Apiserver is slow"]; agg.pending != 1 {
+		t.Fatalf("pending repeats = %d, want the repeat during the send", agg.pending)
+	}
+}
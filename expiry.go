@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExpiresAtAnnotation records on the lock when it expires unless the leader
+// renews it, with WithLockExpiry. Candidates treat a lock past its expiry as
+// stale and take it over even if its owner pod still exists, which bounds
+// failover from a leader that is alive but wedged or partitioned from the
+// apiserver. Locks without it never expire.
+const ExpiresAtAnnotation = "leader.seamounts.io/expires-at"
+
+// expiryMargin is how long before the expiry of its lock a leader stops
+// believing it leads. It covers the second precision of ExpiresAtAnnotation
+// and modest clock skew between the leader and the candidates taking over.
+const expiryMargin = 2 * time.Second
+
+// lockExpiry returns the expiry recorded on lock, and whether it has passed.
+func lockExpiry(lock metav1.Object) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, lock.GetAnnotations()[ExpiresAtAnnotation])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, time.Now().After(t)
+}
+
+// recordExpiry remembers the expiry recorded on lock, the one we hold, so
+// the maintenance loop can tell when it passes without a renewal.
+func (e *PodElector) recordExpiry(lock metav1.Object) {
+	t, _ := lockExpiry(lock)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expiresAt = t
+}
+
+// selfExpired reports whether the lock we hold expired, give or take
+// expiryMargin, before we could renew it, after which candidates may take it
+// over at any time.
+func (e *PodElector) selfExpired() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.expiredLocked(time.Now())
+}
+
+// expiredLocked is selfExpired at now. Must be called with mu held.
+func (e *PodElector) expiredLocked(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt.Add(-expiryMargin))
+}
+
+// untilExpired returns how long is left before selfExpired, or d if that
+// is longer or our lock does not expire.
+func (e *PodElector) untilExpired(d time.Duration) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.expiresAt.IsZero() {
+		return d
+	}
+	if left := time.Until(e.expiresAt.Add(-expiryMargin)); left < d {
+		if left < 0 {
+			return 0
+		}
+		return left
+	}
+	return d
+}
+
+// renewOnResume renews the expiry of the lock we resumed, which may be
+// about to pass, before leadership is announced.
+func (e *PodElector) renewOnResume(ctx context.Context) error {
+	if e.opts.lockExpiry <= 0 {
+		return nil
+	}
+	if err := e.beat(ctx); err != nil {
+		e.log.Error(err, "Failed to renew the lock we resumed", "lock", e.lockName)
+		e.clearLeading()
+		return err
+	}
+	return nil
+}
+
+// takeOverExpired deletes lock, which expired at expiry, so that the next
+// attempt can create it. The deletion is guarded by UID and resourceVersion
+// preconditions, so a renewal by the leader since we read the lock wins.
+func (e *PodElector) takeOverExpired(ctx context.Context, lock metav1.Object, expiry time.Time) error {
+	holder := ""
+	if owners := lock.GetOwnerReferences(); len(owners) > 0 {
+		holder = owners[0].Name
+	}
+	e.log.Info("Leader lock has expired, taking over", "lock", e.lockName, "leader", holder, "expired", expiry)
+
+	err := e.lockBackend().Delete(ctx, e.lockName, unchanged(lock))
+	switch {
+	case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+		// gone or renewed since we read it; the next attempt looks again
+		return nil
+	case err != nil:
+		return err
+	}
+	e.event(v1.EventTypeWarning, "DeletedExpiredLock", "Deleted %s of %s, expired at %s", e.lockName, holder, expiry.Format(time.RFC3339))
+	e.audit(AuditTookOver, "Deleted %s of %s, expired at %s", e.lockName, holder, expiry.Format(time.RFC3339))
+	return nil
+}
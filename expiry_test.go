@@ -0,0 +1,125 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// expiredLock stores a lock of pod-2 that expired a minute ago.
+func expiredLock(t *testing.T, client *fake.Clientset) *v1.ConfigMap {
+	t.Helper()
+	lock := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:            testLock,
+		Namespace:       testNamespace,
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "pod-2", UID: "pod-2-uid"}},
+		Annotations:     map[string]string{ExpiresAtAnnotation: time.Now().Add(-time.Minute).Format(time.RFC3339)},
+	}}
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), lock, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lock
+}
+
+func TestTakeOverExpired(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	lock := expiredLock(t, client)
+	e := newTestElector(t, client, "pod-1")
+	b := enforcePreconditions(e)
+
+	expiry, expired := lockExpiry(lock)
+	if !expired {
+		t.Fatal("lock is not seen as expired")
+	}
+	if err := e.takeOverExpired(context.Background(), lock, expiry); err != nil {
+		t.Fatalf("takeOverExpired: %v", err)
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("expired lock of %s was not deleted", owner)
+	}
+	if len(b.deletes) != 1 || b.deletes[0].ResourceVersion == nil || *b.deletes[0].ResourceVersion != lock.ResourceVersion {
+		t.Fatalf("expired lock was deleted with preconditions %+v, want its resourceVersion", b.deletes)
+	}
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire after the takeover = %v, %v", ok, err)
+	}
+}
+
+func TestLockExpiry(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name       string
+		annotation string
+		expired    bool
+	}{
+		{name: "none"},
+		{name: "malformed", annotation: "tomorrow"},
+		{name: "ahead", annotation: now.Add(time.Minute).Format(time.RFC3339)},
+		{name: "passed", annotation: now.Add(-time.Minute).Format(time.RFC3339), expired: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lock := &v1.ConfigMap{}
+			if tc.annotation != "" {
+				lock.Annotations = map[string]string{ExpiresAtAnnotation: tc.annotation}
+			}
+			if _, expired := lockExpiry(lock); expired != tc.expired {
+				t.Fatalf("expired = %v, want %v", expired, tc.expired)
+			}
+		})
+	}
+}
+
+func TestUntilExpired(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		expiresIn time.Duration
+		want      time.Duration
+	}{
+		{name: "no expiry", want: time.Minute},
+		{name: "far", expiresIn: time.Hour, want: time.Minute},
+		{name: "near", expiresIn: expiryMargin + 10*time.Second, want: 10 * time.Second},
+		{name: "within the margin", expiresIn: expiryMargin / 2, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &PodElector{}
+			if tc.expiresIn > 0 {
+				e.expiresAt = time.Now().Add(tc.expiresIn)
+			}
+			got := e.untilExpired(time.Minute)
+			if got > tc.want || got < tc.want-time.Second {
+				t.Fatalf("untilExpired = %v, want %v", got, tc.want)
+			}
+			if expired := e.selfExpired(); expired != (tc.want == 0) {
+				t.Fatalf("selfExpired = %v", expired)
+			}
+		})
+	}
+}
+
+func TestTakeOverExpiredKeepsRenewedLock(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	lock := expiredLock(t, client)
+	e := newTestElector(t, client, "pod-1")
+	enforcePreconditions(e)
+
+	// the leader renews the lock after we read it
+	renewed := lock.DeepCopy()
+	renewed.ResourceVersion = "2"
+	renewed.Annotations[ExpiresAtAnnotation] = time.Now().Add(time.Minute).Format(time.RFC3339)
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Update(context.Background(), renewed, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expiry, _ := lockExpiry(lock)
+	if err := e.takeOverExpired(context.Background(), lock, expiry); err != nil {
+		t.Fatalf("takeOverExpired of a renewed lock: %v", err)
+	}
+	if owner := lockOwner(t, client); owner != "pod-2" {
+		t.Fatalf("renewed lock is owned by %q, want pod-2", owner)
+	}
+}
@@ -0,0 +1,205 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Explain describes the state of lockName as seen from the current pod:
+// who holds it and since when, the health of the holder's pod and node,
+// and whether and why the current pod would take over. It is meant for
+// humans, for example behind a debug endpoint, and spares reconstructing
+// the same from logs and kubectl.
+func Explain(ctx context.Context, lockName string, opts ...Option) (string, error) {
+	e, err := NewElector(lockName, opts...)
+	if err != nil {
+		return "", err
+	}
+	return e.Explain(ctx)
+}
+
+// Explain describes the state of the lock as seen from the Elector. See the
+// package-level Explain.
+func (e *PodElector) Explain(ctx context.Context) (_ string, err error) {
+	defer func() { err = e.wrap("explain", err) }()
+
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	line("Lock %s/%s (%s)", e.ns, e.lockName, e.lockBackend().Resource())
+	lock, err := e.getLock(ctx)
+	switch {
+	case apierrors.IsNotFound(err):
+		lock = nil
+		line("  free: no pod holds it")
+	case err != nil:
+		return "", err
+	default:
+		e.explainLock(ctx, line, lock)
+	}
+
+	line("This pod %s", e.owner.Name)
+	e.explainCandidate(ctx, line, lock)
+	return b.String(), nil
+}
+
+func (e *PodElector) explainLock(ctx context.Context, line func(string, ...interface{}), lock metav1.Object) {
+	s := lockStatus(lock)
+	if !s.Completed.IsZero() {
+		line("  completed by %s at %s; it cannot be acquired again", lock.GetAnnotations()[CompletedByAnnotation], s.Completed.Format(time.RFC3339))
+		return
+	}
+	line("  held by %s since %s (%s ago), epoch %d", s.Leader, s.Since.Format(time.RFC3339), time.Since(s.Since).Round(time.Second), s.Epoch)
+	if s.Terminating {
+		line("  being deleted, kept by finalizers %v", lock.GetFinalizers())
+	}
+	if !s.LastHeartbeat.IsZero() {
+		line("  last heartbeat %s ago", time.Since(s.LastHeartbeat).Round(time.Second))
+	}
+	if !s.ExpiresAt.IsZero() {
+		if left := time.Until(s.ExpiresAt); left > 0 {
+			line("  expires in %s unless renewed", left.Round(time.Second))
+		} else {
+			line("  expired %s ago; a candidate will take it over", (-left).Round(time.Second))
+		}
+	}
+	if s.Successor != "" {
+		line("  being transferred to %s", s.Successor)
+	}
+	if requester := lock.GetAnnotations()[StepDownRequestAnnotation]; requester != "" {
+		line("  %s asked the leader to step down", requester)
+	}
+	if selector := lock.GetAnnotations()[EligibleSelectorAnnotation]; selector != "" {
+		line("  only pods matching %q may lead", selector)
+	}
+	if pinned := lock.GetAnnotations()[PinnedLeaderAnnotation]; pinned != "" {
+		line("  leadership is pinned to %s while it runs", pinned)
+	}
+	if s.Leader == "" {
+		return
+	}
+
+	pod, err := e.leaderPod(ctx, s.Leader)
+	switch {
+	case apierrors.IsNotFound(err):
+		if e.opts.leaderGone == LeaderGoneDeleteLock {
+			line("  holder pod %s no longer exists; a candidate will delete the lock", s.Leader)
+		} else {
+			line("  holder pod %s no longer exists; garbage collection should remove the lock", s.Leader)
+		}
+		return
+	case err != nil:
+		line("  holder pod %s could not be read: %v", s.Leader, err)
+		return
+	}
+	phase := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		phase += ", " + pod.Status.Reason
+	}
+	line("  holder pod %s is %s on node %s", pod.Name, phase, pod.Spec.NodeName)
+	if pod.GetDeletionTimestamp() != nil {
+		line("  holder pod is being deleted")
+	}
+	switch {
+	case isPodEvicted(pod):
+		line("  holder pod was evicted; a candidate will delete it to free the lock")
+	case podFinished(pod) && e.opts.finishedLeader != FinishedLeaderWait:
+		line("  holder pod has finished; a candidate will free the lock (policy %s)", e.opts.finishedLeader)
+	case podFinished(pod):
+		line("  holder pod has finished but keeps the lock until it is deleted; see WithFinishedLeaderPolicy")
+	}
+	if pod.Spec.NodeName == "" {
+		return
+	}
+	if !e.opts.nodeReflectsPod(pod) {
+		line("  node %s is a shared virtual node; its state is not taken as the holder's", pod.Spec.NodeName)
+		return
+	}
+
+	nodeCtx, cancel := e.request(ctx)
+	defer cancel()
+	node, err := e.kube().CoreV1().Nodes().Get(nodeCtx, pod.Spec.NodeName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		line("  node %s no longer exists", pod.Spec.NodeName)
+	case err != nil:
+		line("  node %s could not be read: %v", pod.Spec.NodeName, err)
+	default:
+		ready := "unknown"
+		for _, c := range node.Status.Conditions {
+			if c.Type == v1.NodeReady {
+				ready = string(c.Status)
+			}
+		}
+		line("  node %s Ready=%s", node.Name, ready)
+		if reason := nodeDraining(node); reason != "" {
+			line("  %s", reason)
+		}
+	}
+}
+
+func (e *PodElector) explainCandidate(ctx context.Context, line func(string, ...interface{}), lock metav1.Object) {
+	if e.IsLeader() {
+		line("  leads, epoch %d", e.Epoch())
+		return
+	}
+	if lock != nil {
+		for _, owner := range lock.GetOwnerReferences() {
+			if owner.UID == e.owner.UID {
+				line("  holds the lock and would resume leading on Become")
+				return
+			}
+		}
+	}
+
+	var blocked []string
+	if e.Paused() {
+		blocked = append(blocked, "candidacy is paused")
+	}
+	e.mu.Lock()
+	demoted := time.Until(e.demotedUntil)
+	e.mu.Unlock()
+	if demoted > 0 {
+		blocked = append(blocked, fmt.Sprintf("demoted itself, may compete again in %s", demoted.Round(time.Second)))
+	}
+	if lock != nil {
+		e.observeEligibility(lock)
+		if successor, ok := pendingTransfer(lock); ok && successor != e.owner.Name {
+			blocked = append(blocked, "defers to the transfer to "+successor)
+		}
+	}
+	if reason := e.ineligible(); reason != "" {
+		blocked = append(blocked, reason)
+	}
+	if e.opts.committee > 0 {
+		e.mu.Lock()
+		seated := e.seat != ""
+		e.mu.Unlock()
+		if !seated {
+			blocked = append(blocked, "not on the committee, contends for seat "+e.seatName()+" first")
+		}
+	}
+	if err := e.checkReady(ctx); err != nil {
+		blocked = append(blocked, "readiness check fails: "+err.Error())
+	}
+
+	if len(blocked) == 0 {
+		if lock == nil {
+			line("  eligible, would take the lock on its next attempt")
+		} else {
+			line("  eligible, would take over once the lock is released or its holder goes away")
+		}
+		return
+	}
+	for _, reason := range blocked {
+		line("  not eligible: %s", reason)
+	}
+}
@@ -0,0 +1,96 @@
+package leader
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExplain(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		held  bool
+		setup func(t *testing.T, client *fake.Clientset, holder, e *PodElector)
+		want  []string
+	}{
+		{
+			name: "free",
+			want: []string{"Lock test/test-lock (configmaps)", "free: no pod holds it", "This pod pod-1", "eligible, would take the lock on its next attempt"},
+		},
+		{
+			name: "held",
+			held: true,
+			setup: func(t *testing.T, client *fake.Clientset, holder, e *PodElector) {
+				scheduleOn(t, client, "pod-2", zonedNode("node-a", "zone-a"))
+				updatePod(t, client, "pod-2", func(pod *v1.Pod) { pod.Status.Phase = v1.PodRunning })
+			},
+			want: []string{"held by pod-2", "epoch 1", "holder pod pod-2 is Running on node node-a", "node node-a Ready=unknown", "eligible, would take over once the lock is released"},
+		},
+		{
+			name: "holder gone",
+			held: true,
+			setup: func(t *testing.T, client *fake.Clientset, holder, e *PodElector) {
+				if err := client.CoreV1().Pods(testNamespace).Delete(context.Background(), "pod-2", metav1.DeleteOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"holder pod pod-2 no longer exists; garbage collection should remove the lock"},
+		},
+		{
+			name: "transfer",
+			held: true,
+			setup: func(t *testing.T, client *fake.Clientset, holder, e *PodElector) {
+				if err := holder.patchLockAnnotations(context.Background(), map[string]interface{}{TransferToAnnotation: "pod-3"}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"being transferred to pod-3", "not eligible: defers to the transfer to pod-3"},
+		},
+		{
+			name: "paused",
+			setup: func(t *testing.T, client *fake.Clientset, holder, e *PodElector) {
+				if err := e.Pause(context.Background(), false); err != nil {
+					t.Fatalf("Pause: %v", err)
+				}
+			},
+			want: []string{"free: no pod holds it", "not eligible: candidacy is paused"},
+		},
+		{
+			name: "leading",
+			setup: func(t *testing.T, client *fake.Clientset, holder, e *PodElector) {
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			},
+			want: []string{"held by pod-1", "leads, epoch 1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2", "pod-3")
+			holder := newTestElector(t, client, "pod-2")
+			if tc.held {
+				if ok, err := holder.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1")
+			if tc.setup != nil {
+				tc.setup(t, client, holder, e)
+			}
+
+			got, err := e.Explain(context.Background())
+			if err != nil {
+				t.Fatalf("Explain: %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Explain does not say %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
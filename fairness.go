@@ -0,0 +1,26 @@
+package leader
+
+import (
+	"context"
+)
+
+// myTurn reports whether fairness allows us to try to take the lock, which
+// is only the case for the longest-waiting live candidate.
+func (e *PodElector) myTurn(ctx context.Context) (bool, error) {
+	candidates, err := e.ListCandidates(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range candidates {
+		if !c.competing() {
+			continue
+		}
+		if c.Name != e.owner.Name {
+			e.log.Debug("Another candidate has been waiting longer, not my turn", "lock", e.lockName, "candidate", c.Name)
+			return false, nil
+		}
+		break
+	}
+	return true, nil
+}
@@ -0,0 +1,46 @@
+package leader
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMyTurn(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// ahead is whether pod-2 registered before us; it is out of the
+		// election in maintenance or when denied
+		ahead       bool
+		maintenance bool
+		denied      bool
+		turn        bool
+	}{
+		{name: "first in line", turn: true},
+		{name: "waiting behind another", ahead: true, turn: false},
+		{name: "ahead in maintenance", ahead: true, maintenance: true, turn: true},
+		{name: "ahead denied candidacy", ahead: true, denied: true, turn: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			if tc.ahead {
+				ahead := newTestElector(t, client, "pod-2", WithFairQueue())
+				if tc.maintenance {
+					ahead.setMaintenance("2026-01-01T00:00:00Z")
+				}
+				ahead.denied, ahead.deniedBy = tc.denied, "admin"
+				if err := ahead.heartbeat(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := register(t, client, "pod-1", WithFairQueue())
+
+			turn, err := e.myTurn(context.Background())
+			if err != nil {
+				t.Fatalf("myTurn: %v", err)
+			}
+			if turn != tc.turn {
+				t.Fatalf("myTurn = %v, want %v", turn, tc.turn)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testNamespace = "test"
+	testLock      = "test-lock"
+)
+
+// newTestClient returns a fake clientset holding a pod for each of pods.
+// Unlike the apiserver the fake generates neither names, UIDs,
+// resourceVersions nor creation timestamps and does not know server-side
+// apply, so the client does the former and answers apply patches as an
+// apiserver that predates it would.
+func newTestClient(t *testing.T, pods ...string) *fake.Clientset {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	var mu sync.Mutex
+	uids := 0
+	client.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.CreateAction).GetObject().(metav1.Object)
+		mu.Lock()
+		defer mu.Unlock()
+		uids++
+		if obj.GetName() == "" && obj.GetGenerateName() != "" {
+			obj.SetName(fmt.Sprintf("%s%d", obj.GetGenerateName(), uids))
+		}
+		if obj.GetUID() == "" {
+			obj.SetUID(types.UID(fmt.Sprintf("uid-%d", uids)))
+		}
+		if obj.GetResourceVersion() == "" {
+			obj.SetResourceVersion("1")
+		}
+		if created := obj.GetCreationTimestamp(); created.IsZero() {
+			obj.SetCreationTimestamp(metav1.Now())
+		}
+		return false, nil, nil
+	})
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "patch", patch.GetResource().GroupResource(), patch.GetName(), "", 0, false)
+	})
+	for _, name := range pods {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, UID: types.UID(name + "-uid")}}
+		if _, err := client.CoreV1().Pods(testNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return client
+}
+
+// newTestElector returns an Elector of testLock for the pod named pod.
+func newTestElector(t *testing.T, client *fake.Clientset, pod string, opts ...Option) *PodElector {
+	t.Helper()
+	return newTestElectorOf(t, client, testLock, pod, opts...)
+}
+
+// testOptions returns the options of an Elector in testNamespace for the
+// pod named pod.
+func testOptions(client *fake.Clientset, pod string) []Option {
+	return []Option{WithClient(client), WithNamespace(testNamespace), WithPodName(pod), WithLogLevel(ErrorLevel)}
+}
+
+// newTestElectorOf returns an Elector of lock for the pod named pod.
+func newTestElectorOf(t *testing.T, client *fake.Clientset, lock, pod string, opts ...Option) *PodElector {
+	t.Helper()
+	opts = append(testOptions(client, pod), opts...)
+	e, err := NewElector(lock, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.opts.transferPollInterval = 10 * time.Millisecond
+	return e
+}
+
+// preconditionBackend enforces the preconditions of deletes, which neither
+// the fake clientset nor its reactors get to see, and records them.
+type preconditionBackend struct {
+	backend
+	mu      sync.Mutex
+	deletes []metav1.Preconditions
+}
+
+func (b *preconditionBackend) Delete(ctx context.Context, name string, pre metav1.Preconditions) error {
+	b.mu.Lock()
+	b.deletes = append(b.deletes, pre)
+	b.mu.Unlock()
+	lock, err := b.backend.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if pre.UID != nil && *pre.UID != lock.GetUID() || pre.ResourceVersion != nil && *pre.ResourceVersion != lock.GetResourceVersion() {
+		return apierrors.NewConflict(b.Resource(), name, errors.New("the object has been modified"))
+	}
+	return b.backend.Delete(ctx, name, pre)
+}
+
+// enforcePreconditions makes the deletes of e meet their preconditions.
+func enforcePreconditions(e *PodElector) *preconditionBackend {
+	b := &preconditionBackend{backend: e.backend}
+	e.backend = b
+	return b
+}
+
+// updatePod applies update to the pod named name.
+func updatePod(t *testing.T, client *fake.Clientset, name string, update func(pod *v1.Pod)) {
+	t.Helper()
+	pods := client.CoreV1().Pods(testNamespace)
+	pod, err := pods.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update(pod)
+	if _, err := pods.Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lockOwner returns the pod holding testLock, or "" if it is free.
+func lockOwner(t *testing.T, client *fake.Clientset) string {
+	t.Helper()
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return ""
+	case err != nil:
+		t.Fatal(err)
+	}
+	if len(lock.OwnerReferences) != 1 {
+		t.Fatalf("lock has %d owners", len(lock.OwnerReferences))
+	}
+	return lock.OwnerReferences[0].Name
+}
+
+// waitFor fails the test unless cond holds within a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
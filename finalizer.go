@@ -0,0 +1,45 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LockFinalizer keeps a deleted lock around until leadership is released on
+// purpose. See WithLockFinalizer.
+const LockFinalizer = "leader.seamounts.io/release"
+
+// removeFinalizer drops LockFinalizer from lock. The resourceVersion in the
+// patch makes it fail rather than clobber a concurrent change.
+func (e *PodElector) removeFinalizer(ctx context.Context, lock metav1.Object) {
+	var remaining []string
+	found := false
+	for _, f := range lock.GetFinalizers() {
+		if f == LockFinalizer {
+			found = true
+			continue
+		}
+		remaining = append(remaining, f)
+	}
+	if !found {
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      remaining,
+			"resourceVersion": lock.GetResourceVersion(),
+		},
+	})
+	if err != nil {
+		e.log.Error(err, "Failed to encode finalizer patch")
+		return
+	}
+
+	e.log.Info("Removing finalizer from lock", "lock", e.lockName)
+	if err := e.lockBackend().Patch(ctx, e.lockName, patch); err != nil {
+		e.log.Error(err, "Failed to remove finalizer from lock", "lock", e.lockName)
+	}
+}
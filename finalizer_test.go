@@ -0,0 +1,92 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// keepFinalizedLocks makes deleting a ConfigMap with finalizers leave it
+// Terminating, as the apiserver does, which the fake clientset does not.
+func keepFinalizedLocks(client *fake.Clientset) {
+	client.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteAction)
+		obj, err := client.Tracker().Get(del.GetResource(), del.GetNamespace(), del.GetName())
+		if err != nil {
+			return false, nil, nil
+		}
+		lock := obj.(*v1.ConfigMap)
+		if len(lock.Finalizers) == 0 {
+			return false, nil, nil
+		}
+		now := metav1.Now()
+		lock.DeletionTimestamp = &now
+		return true, nil, client.Tracker().Update(del.GetResource(), lock, del.GetNamespace())
+	})
+}
+
+func TestRemoveFinalizer(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		finalizers []string
+		want       []string
+	}{
+		{name: "ours only", finalizers: []string{LockFinalizer}, want: nil},
+		{name: "ours and another", finalizers: []string{"example.com/keep", LockFinalizer}, want: []string{"example.com/keep"}},
+		{name: "another only", finalizers: []string{"example.com/keep"}, want: []string{"example.com/keep"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			meta := testLockMeta("pod-2")
+			meta.Finalizers = tc.finalizers
+			lock, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), &v1.ConfigMap{ObjectMeta: meta}, metav1.CreateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := newTestElector(t, client, "pod-1")
+
+			e.removeFinalizer(context.Background(), lock)
+			lock, err = client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lock.Finalizers, tc.want) {
+				t.Fatalf("finalizers = %v, want %v", lock.Finalizers, tc.want)
+			}
+		})
+	}
+}
+
+func TestLockFinalizer(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	keepFinalizedLocks(client)
+	e := newTestElector(t, client, "pod-1", WithLockFinalizer())
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lock.Finalizers, []string{LockFinalizer}) {
+		t.Fatalf("finalizers of the new lock = %v, want %s", lock.Finalizers, LockFinalizer)
+	}
+
+	// releasing on purpose lets the deletion complete
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	lock, err = client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.DeletionTimestamp == nil || len(lock.Finalizers) != 0 {
+		t.Fatalf("released lock has deletion timestamp %v and finalizers %v, want it deleted without finalizers", lock.DeletionTimestamp, lock.Finalizers)
+	}
+}
@@ -0,0 +1,63 @@
+package leader
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FinishedLeaderPolicy says what a candidate may do when the leader's pod
+// has finished, that is reached the Succeeded or Failed phase for any
+// reason: a completed Job pod, or one whose container was OOM-killed with a
+// restart policy of Never. Such a pod never runs again, yet it keeps
+// holding the lock until it is deleted. Evicted pods are deleted whatever
+// the policy.
+type FinishedLeaderPolicy string
+
+const (
+	// FinishedLeaderWait leaves the pod and its lock alone. It is the
+	// default.
+	FinishedLeaderWait FinishedLeaderPolicy = ""
+
+	// FinishedLeaderDeleteLock deletes the lock and keeps the pod, so that
+	// its logs and status stay available, as they should for Job pods.
+	FinishedLeaderDeleteLock FinishedLeaderPolicy = "DeleteLock"
+
+	// FinishedLeaderDeletePod deletes the pod, which garbage collection of
+	// the lock then follows, as is done for evicted pods.
+	FinishedLeaderDeletePod FinishedLeaderPolicy = "DeletePod"
+)
+
+// podFinished reports whether pod reached a terminal phase.
+func podFinished(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
+// takeOverFromFinished applies the finished leader policy to the finished
+// leader pod and its lock. The deletion is guarded by UID and
+// resourceVersion preconditions, so a pod or lock changed since we read it is
+// never touched.
+func (e *PodElector) takeOverFromFinished(ctx context.Context, lock metav1.Object, pod *v1.Pod) error {
+	e.log.Info("Leader pod has finished", "lock", e.lockName, "leader", pod.Name, "phase", pod.Status.Phase, "reason", pod.Status.Reason, "policy", e.opts.finishedLeader)
+
+	var err error
+	if e.opts.finishedLeader == FinishedLeaderDeletePod {
+		e.log.Info("Deleting finished leader", "leader", pod.Name)
+		err = e.deletePod(ctx, pod)
+	} else {
+		e.log.Info("Deleting the lock of the finished leader", "lock", e.lockName, "leader", pod.Name)
+		err = e.lockBackend().Delete(ctx, e.lockName, unchanged(lock))
+	}
+	switch {
+	case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+		// gone or changed since we read it; the next attempt looks again
+		return nil
+	case err != nil:
+		return err
+	}
+	e.event(v1.EventTypeNormal, "ReleasedFinishedLeader", "Released %s from finished leader %s (%s)", e.lockName, pod.Name, pod.Status.Phase)
+	e.audit(AuditTookOver, "Released %s from finished leader %s (%s)", e.lockName, pod.Name, pod.Status.Phase)
+	return nil
+}
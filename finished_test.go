@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// finishedLeader makes pod-2 the leader and moves it to phase.
+func finishedLeader(t *testing.T, client *fake.Clientset, phase v1.PodPhase) {
+	t.Helper()
+	holder := newTestElector(t, client, "pod-2")
+	if ok, err := holder.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	updatePod(t, client, "pod-2", func(pod *v1.Pod) { pod.Status.Phase = phase })
+}
+
+func TestPodFinished(t *testing.T) {
+	for phase, want := range map[v1.PodPhase]bool{
+		v1.PodPending:   false,
+		v1.PodRunning:   false,
+		v1.PodSucceeded: true,
+		v1.PodFailed:    true,
+	} {
+		if got := podFinished(&v1.Pod{Status: v1.PodStatus{Phase: phase}}); got != want {
+			t.Errorf("podFinished in phase %s = %v, want %v", phase, got, want)
+		}
+	}
+}
+
+func TestTakeOverFromFinished(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		policy      FinishedLeaderPolicy
+		podDeleted  bool
+		lockDeleted bool
+	}{
+		{name: "delete lock", policy: FinishedLeaderDeleteLock, lockDeleted: true},
+		{name: "delete pod", policy: FinishedLeaderDeletePod, podDeleted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newTestClient(t, "pod-1", "pod-2")
+			finishedLeader(t, client, v1.PodSucceeded)
+			e := newTestElector(t, client, "pod-1", WithFinishedLeaderPolicy(tc.policy))
+			leader, err := client.CoreV1().Pods(testNamespace).Get(ctx, "pod-2", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lock, err := e.getLock(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.takeOverFromFinished(ctx, lock, leader); err != nil {
+				t.Fatalf("takeOverFromFinished: %v", err)
+			}
+			_, err = client.CoreV1().Pods(testNamespace).Get(ctx, "pod-2", metav1.GetOptions{})
+			if deleted := apierrors.IsNotFound(err); deleted != tc.podDeleted {
+				t.Fatalf("leader pod deleted = %v, want %v", deleted, tc.podDeleted)
+			}
+			if deleted := lockOwner(t, client) == ""; deleted != tc.lockDeleted {
+				t.Fatalf("lock deleted = %v, want %v", deleted, tc.lockDeleted)
+			}
+		})
+	}
+}
+
+func TestBecomeAfterLeaderFinished(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy FinishedLeaderPolicy
+		phase  v1.PodPhase
+		leads  bool
+	}{
+		{name: "failed leader", policy: FinishedLeaderDeleteLock, phase: v1.PodFailed, leads: true},
+		{name: "running leader", policy: FinishedLeaderDeleteLock, phase: v1.PodRunning},
+		{name: "waiting policy", phase: v1.PodFailed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			finishedLeader(t, client, tc.phase)
+			e := newTestElector(t, client, "pod-1", WithFinishedLeaderPolicy(tc.policy))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+			defer cancel()
+			if err := e.Become(ctx); (err == nil) != tc.leads {
+				t.Fatalf("Become = %v, want to lead %v", err, tc.leads)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Package follower resolves and streams the current leader of a lock for
+// components that must find the leader but never take part in the election,
+// such as dashboards, routers and CLIs. It only needs get and watch on the
+// lock object itself, and with leader.AutoBackend create on leases and
+// configmaps to detect the backend as the electors do.
+package follower
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	leader "github.com/seamounts/k8s-leader"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rewatchInterval is how long Watch waits before re-establishing a watch
+// that failed.
+const rewatchInterval = time.Second * 2
+
+// Follower observes the lock named Lock in Namespace.
+type Follower struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Lock      string
+
+	// Backend is the kind of lock object the electors use. The default is
+	// leader.ConfigMapBackend. For leader.MigrationBackend the Lease is
+	// followed. leader.AutoBackend is resolved once, with
+	// leader.DetectBackend, to what the electors resolve it to.
+	Backend leader.Backend
+
+	// Log receives the follower's logs. It defaults to the package's
+	// default logger.
+	Log leader.Logger
+
+	mu       sync.Mutex
+	detected leader.Backend
+}
+
+func (f *Follower) logger() leader.Logger {
+	if f.Log == nil {
+		return leader.NewLogger(leader.InfoLevel, false)
+	}
+	return f.Log
+}
+
+// leases reports whether the lock is a Lease.
+func (f *Follower) leases(ctx context.Context) (bool, error) {
+	backend := f.Backend
+	if backend == leader.AutoBackend {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.detected == "" {
+			detected, err := leader.DetectBackend(ctx, f.Client, f.Namespace)
+			if err != nil {
+				return false, err
+			}
+			f.detected = detected
+		}
+		backend = f.detected
+	}
+	return backend == leader.LeaseBackend || backend == leader.MigrationBackend, nil
+}
+
+// Leader returns the name of the pod holding the lock, or "" if it is free.
+func (f *Follower) Leader(ctx context.Context) (string, error) {
+	lock, err := f.get(ctx)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return leaderOf(lock), nil
+}
+
+// Address returns the address the leader advertises with
+// leader.WithAdvertiseAddress, or "" if the lock is free or its leader
+// advertises none.
+func (f *Follower) Address(ctx context.Context) (string, error) {
+	lock, err := f.get(ctx)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return lock.GetAnnotations()[leader.AddressAnnotation], nil
+}
+
+// Watch streams the name of the pod holding the lock, or "" while it is
+// free. The current leader is sent first and then every change. The channel
+// is closed when ctx is cancelled.
+func (f *Follower) Watch(ctx context.Context) <-chan string {
+	out := make(chan string, 1)
+	go func() {
+		defer close(out)
+
+		last, sent := "", false
+		send := func(name string) bool {
+			if sent && name == last {
+				return true
+			}
+			select {
+			case out <- name:
+				last, sent = name, true
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for ctx.Err() == nil {
+			if err := f.watch(ctx, send); err != nil && ctx.Err() == nil {
+				f.logger().Error(err, "Watching the lock failed, retrying", "namespace", f.Namespace, "lock", f.Lock)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(rewatchInterval):
+			}
+		}
+	}()
+	return out
+}
+
+// watch sends the current leader and then follows changes until the watch
+// ends.
+func (f *Follower) watch(ctx context.Context, send func(string) bool) error {
+	lock, err := f.get(ctx)
+	resourceVersion := ""
+	switch {
+	case apierrors.IsNotFound(err):
+		if !send("") {
+			return nil
+		}
+	case err != nil:
+		return err
+	default:
+		resourceVersion = lock.GetResourceVersion()
+		if !send(leaderOf(lock)) {
+			return nil
+		}
+	}
+
+	opts := metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", f.Lock).String(),
+		ResourceVersion: resourceVersion,
+	}
+	var w watch.Interface
+	leases, err := f.leases(ctx)
+	if err != nil {
+		return err
+	}
+	if leases {
+		w, err = f.Client.CoordinationV1().Leases(f.Namespace).Watch(ctx, opts)
+	} else {
+		w, err = f.Client.CoreV1().ConfigMaps(f.Namespace).Watch(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for ev := range w.ResultChan() {
+		switch ev.Type {
+		case watch.Added, watch.Modified:
+			if !send(leaderOfObject(ev.Object)) {
+				return nil
+			}
+		case watch.Deleted:
+			if !send("") {
+				return nil
+			}
+		case watch.Error:
+			return apierrors.FromObject(ev.Object)
+		}
+	}
+	return nil
+}
+
+func (f *Follower) get(ctx context.Context) (metav1.Object, error) {
+	leases, err := f.leases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if leases {
+		return f.Client.CoordinationV1().Leases(f.Namespace).Get(ctx, f.Lock, metav1.GetOptions{})
+	}
+	return f.Client.CoreV1().ConfigMaps(f.Namespace).Get(ctx, f.Lock, metav1.GetOptions{})
+}
+
+func leaderOfObject(obj runtime.Object) string {
+	lock, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return leaderOf(lock)
+}
+
+func leaderOf(lock metav1.Object) string {
+	for _, owner := range lock.GetOwnerReferences() {
+		if owner.Kind == "Pod" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// Role returns the Role a follower of the lock needs: get and watch on the
+// lock object alone. With leader.AutoBackend the lock may be either kind,
+// and detecting which needs create on both.
+func (f *Follower) Role(name string) *rbacv1.Role {
+	rule := func(group, resource string) rbacv1.PolicyRule {
+		return rbacv1.PolicyRule{
+			APIGroups:     []string{group},
+			Resources:     []string{resource},
+			ResourceNames: []string{f.Lock},
+			Verbs:         []string{"get", "watch"},
+		}
+	}
+
+	var rules []rbacv1.PolicyRule
+	switch f.Backend {
+	case leader.LeaseBackend, leader.MigrationBackend:
+		rules = append(rules, rule("coordination.k8s.io", "leases"))
+	case leader.AutoBackend:
+		rules = append(rules, rule("coordination.k8s.io", "leases"), rule("", "configmaps"),
+			rbacv1.PolicyRule{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"create"}},
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}})
+	default:
+		rules = append(rules, rule("", "configmaps"))
+	}
+	return &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: f.Namespace},
+		Rules:      rules,
+	}
+}
@@ -0,0 +1,211 @@
+package follower
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	leader "github.com/seamounts/k8s-leader"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testNamespace = "test"
+	testLock      = "test-lock"
+)
+
+// lockMeta returns the metadata of the lock name owned by an owner of
+// kind, or by nobody if owner is empty.
+func lockMeta(name, kind, owner string) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: testNamespace,
+		Labels:    map[string]string{leader.RoleLabel: leader.LockRole},
+	}
+	if owner != "" {
+		meta.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: kind, Name: owner, UID: "uid"}}
+	}
+	return meta
+}
+
+func newTestFollower(client *fake.Clientset, backend leader.Backend) *Follower {
+	return &Follower{
+		Client:    client,
+		Namespace: testNamespace,
+		Lock:      testLock,
+		Backend:   backend,
+		Log:       leader.NewLogger(leader.ErrorLevel, false),
+	}
+}
+
+func TestLeader(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		backend leader.Backend
+		lock    runtime.Object
+		want    string
+	}{
+		{name: "free"},
+		{name: "configmap", lock: &v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")}, want: "pod-1"},
+		{name: "lease", backend: leader.LeaseBackend, lock: &coordinationv1.Lease{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")}, want: "pod-1"},
+		{name: "migration follows the lease", backend: leader.MigrationBackend, lock: &coordinationv1.Lease{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")}, want: "pod-1"},
+		{name: "lease of another backend", lock: &coordinationv1.Lease{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")}},
+		{name: "not owned by a pod", lock: &v1.ConfigMap{ObjectMeta: lockMeta(testLock, "StatefulSet", "db")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tc.lock != nil {
+				if err := client.Tracker().Add(tc.lock); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := newTestFollower(client, tc.backend).Leader(context.Background())
+			if err != nil {
+				t.Fatalf("Leader: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Leader = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// next returns the next value of ch, failing the test if there is none
+// within a second.
+func next(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case name, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return name
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the leader")
+		return ""
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")})
+	w := watch.NewFake()
+	client.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, w, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	leaders := newTestFollower(client, leader.ConfigMapBackend).Watch(ctx)
+
+	if name := next(t, leaders); name != "pod-1" {
+		t.Fatalf("first leader = %q, want pod-1", name)
+	}
+	// renewals by the same leader are not sent
+	w.Modify(&v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")})
+	w.Delete(&v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")})
+	if name := next(t, leaders); name != "" {
+		t.Fatalf("leader after the lock went = %q, want none", name)
+	}
+	w.Add(&v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-2")})
+	if name := next(t, leaders); name != "pod-2" {
+		t.Fatalf("next leader = %q, want pod-2", name)
+	}
+
+	// the apiserver ends watches with their context, the fake does not
+	cancel()
+	w.Stop()
+	for range leaders {
+	}
+}
+
+func TestRole(t *testing.T) {
+	for _, tc := range []struct {
+		backend leader.Backend
+		rules   int
+		lease   bool
+	}{
+		{backend: leader.ConfigMapBackend, rules: 1},
+		{backend: leader.LeaseBackend, rules: 1, lease: true},
+		{backend: leader.MigrationBackend, rules: 1, lease: true},
+		{backend: leader.AutoBackend, rules: 4, lease: true},
+	} {
+		t.Run(string(tc.backend), func(t *testing.T) {
+			role := newTestFollower(nil, tc.backend).Role("follower")
+			if len(role.Rules) != tc.rules {
+				t.Fatalf("%d rules, want %d: %v", len(role.Rules), tc.rules, role.Rules)
+			}
+			first := role.Rules[0]
+			if lease := first.Resources[0] == "leases"; lease != tc.lease {
+				t.Fatalf("first rule is on %v", first.Resources)
+			}
+			if len(first.ResourceNames) != 1 || first.ResourceNames[0] != testLock {
+				t.Fatalf("lock access is not restricted to the lock: %v", first.ResourceNames)
+			}
+			for _, verb := range first.Verbs {
+				if verb != "get" && verb != "watch" {
+					t.Fatalf("follower may %s the lock", verb)
+				}
+			}
+		})
+	}
+}
+
+func TestAddress(t *testing.T) {
+	advertised := &v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")}
+	advertised.Annotations = map[string]string{leader.AddressAnnotation: "10.0.0.1:8080"}
+	for _, tc := range []struct {
+		name string
+		lock runtime.Object
+		want string
+	}{
+		{name: "free"},
+		{name: "advertised", lock: advertised, want: "10.0.0.1:8080"},
+		{name: "advertising none", lock: &v1.ConfigMap{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tc.lock != nil {
+				if err := client.Tracker().Add(tc.lock); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := newTestFollower(client, leader.ConfigMapBackend).Address(context.Background())
+			if err != nil {
+				t.Fatalf("Address: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Address = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLeaderOfAutoBackend(t *testing.T) {
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{ObjectMeta: lockMeta(testLock, "Pod", "pod-1")})
+	client.Resources = []*metav1.APIResourceList{{
+		GroupVersion: coordinationv1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{{Name: "leases", Namespaced: true, Kind: "Lease"}},
+	}}
+	f := newTestFollower(client, leader.AutoBackend)
+	for i := 0; i < 2; i++ {
+		got, err := f.Leader(context.Background())
+		if err != nil || got != "pod-1" {
+			t.Fatalf("Leader = %q, %v, want the holder of the Lease", got, err)
+		}
+	}
+
+	// the backend is detected once
+	probes := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" {
+			probes++
+		}
+	}
+	if probes != 1 {
+		t.Fatalf("%d dry-run probes, want 1", probes)
+	}
+}
@@ -0,0 +1,170 @@
+package follower
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	leader "github.com/seamounts/k8s-leader"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LeaderChanged reports that the leader of Lock changed from Old to New.
+// Either is "" while the lock is free.
+type LeaderChanged struct {
+	Lock string
+	Old  string
+	New  string
+}
+
+// Observer observes every lock in Namespace, for meta-controllers and
+// dashboards that react to any failover. It finds the locks by label, so it
+// only needs list and watch on the lock objects.
+type Observer struct {
+	Client    kubernetes.Interface
+	Namespace string
+
+	// Backend is the kind of lock object the electors use, as for Follower.
+	Backend leader.Backend
+
+	// Log receives the observer's logs. It defaults to the package's
+	// default logger.
+	Log leader.Logger
+
+	once sync.Once
+	f    *Follower
+}
+
+// follower returns the Follower of the namespace's locks, which resolves
+// the backend for the Observer.
+func (o *Observer) follower() *Follower {
+	o.once.Do(func() {
+		o.f = &Follower{Client: o.Client, Namespace: o.Namespace, Backend: o.Backend, Log: o.Log}
+	})
+	return o.f
+}
+
+// Watch streams a LeaderChanged for every change of leader of any lock in
+// the namespace. The locks held when Watch starts are sent first, as
+// changes from "". The channel is closed when ctx is cancelled.
+func (o *Observer) Watch(ctx context.Context) <-chan LeaderChanged {
+	out := make(chan LeaderChanged, 16)
+	go func() {
+		defer close(out)
+
+		leaders := map[string]string{}
+		send := func(lock, name string) bool {
+			old := leaders[lock]
+			if old == name {
+				return true
+			}
+			select {
+			case out <- LeaderChanged{Lock: lock, Old: old, New: name}:
+				if name == "" {
+					delete(leaders, lock)
+				} else {
+					leaders[lock] = name
+				}
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for ctx.Err() == nil {
+			if err := o.watch(ctx, leaders, send); err != nil && ctx.Err() == nil {
+				o.follower().logger().Error(err, "Watching the locks failed, retrying", "namespace", o.Namespace)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(rewatchInterval):
+			}
+		}
+	}()
+	return out
+}
+
+// watch lists the locks, sends what changed since the last known leaders,
+// and then follows changes until the watch ends.
+func (o *Observer) watch(ctx context.Context, leaders map[string]string, send func(lock, name string) bool) error {
+	opts := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{leader.RoleLabel: leader.LockRole}).String(),
+	}
+
+	leases, err := o.follower().leases(ctx)
+	if err != nil {
+		return err
+	}
+	var locks []metav1.Object
+	var resourceVersion string
+	if leases {
+		list, err := o.Client.CoordinationV1().Leases(o.Namespace).List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			locks = append(locks, &list.Items[i])
+		}
+		resourceVersion = list.ResourceVersion
+	} else {
+		list, err := o.Client.CoreV1().ConfigMaps(o.Namespace).List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			locks = append(locks, &list.Items[i])
+		}
+		resourceVersion = list.ResourceVersion
+	}
+
+	current := map[string]string{}
+	for _, lock := range locks {
+		current[lock.GetName()] = leaderOf(lock)
+	}
+	// locks deleted while we were not watching
+	for lock := range leaders {
+		if _, ok := current[lock]; !ok && !send(lock, "") {
+			return nil
+		}
+	}
+	for lock, name := range current {
+		if !send(lock, name) {
+			return nil
+		}
+	}
+
+	opts.ResourceVersion = resourceVersion
+	var w watch.Interface
+	if leases {
+		w, err = o.Client.CoordinationV1().Leases(o.Namespace).Watch(ctx, opts)
+	} else {
+		w, err = o.Client.CoreV1().ConfigMaps(o.Namespace).Watch(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for ev := range w.ResultChan() {
+		if ev.Type == watch.Error {
+			return apierrors.FromObject(ev.Object)
+		}
+		lock, err := meta.Accessor(ev.Object)
+		if err != nil {
+			continue
+		}
+		name := leaderOf(lock)
+		if ev.Type == watch.Deleted {
+			name = ""
+		}
+		if !send(lock.GetName(), name) {
+			return nil
+		}
+	}
+	return nil
+}
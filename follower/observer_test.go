@@ -0,0 +1,78 @@
+package follower
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	leader "github.com/seamounts/k8s-leader"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func nextChange(t *testing.T, ch <-chan LeaderChanged) LeaderChanged {
+	t.Helper()
+	select {
+	case change, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a leader change")
+		return LeaderChanged{}
+	}
+}
+
+func TestObserverWatch(t *testing.T) {
+	unlabeled := &v1.ConfigMap{ObjectMeta: lockMeta("settings", "Pod", "pod-9")}
+	unlabeled.Labels = nil
+	client := fake.NewSimpleClientset(&v1.ConfigMap{ObjectMeta: lockMeta("lock-a", "Pod", "pod-1")}, unlabeled)
+	w := watch.NewFake()
+	client.PrependWatchReactor("configmaps", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, w, nil
+	})
+	o := &Observer{Client: client, Namespace: testNamespace, Backend: leader.ConfigMapBackend, Log: leader.NewLogger(leader.ErrorLevel, false)}
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := o.Watch(ctx)
+
+	for _, want := range []struct {
+		event  func()
+		change LeaderChanged
+	}{
+		{
+			// locks held at the start come first, and other ConfigMaps never
+			change: LeaderChanged{Lock: "lock-a", New: "pod-1"},
+		},
+		{
+			event:  func() { w.Add(&v1.ConfigMap{ObjectMeta: lockMeta("lock-b", "Pod", "pod-2")}) },
+			change: LeaderChanged{Lock: "lock-b", New: "pod-2"},
+		},
+		{
+			event: func() {
+				// a renewal changes nothing
+				w.Modify(&v1.ConfigMap{ObjectMeta: lockMeta("lock-a", "Pod", "pod-1")})
+				w.Modify(&v1.ConfigMap{ObjectMeta: lockMeta("lock-a", "Pod", "pod-3")})
+			},
+			change: LeaderChanged{Lock: "lock-a", Old: "pod-1", New: "pod-3"},
+		},
+		{
+			event:  func() { w.Delete(&v1.ConfigMap{ObjectMeta: lockMeta("lock-b", "Pod", "pod-2")}) },
+			change: LeaderChanged{Lock: "lock-b", Old: "pod-2"},
+		},
+	} {
+		if want.event != nil {
+			want.event()
+		}
+		if change := nextChange(t, changes); change != want.change {
+			t.Fatalf("change = %+v, want %+v", change, want.change)
+		}
+	}
+
+	cancel()
+	w.Stop()
+	for range changes {
+	}
+}
@@ -0,0 +1,84 @@
+package leader
+
+import (
+	"errors"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ForbiddenError is returned when the apiserver denies a request because the
+// pod's service account lacks a permission. It names the missing verb and
+// resource; Rule returns the Role rule that grants it. apierrors.IsForbidden
+// still reports true for it.
+type ForbiddenError struct {
+	Verb      string
+	Resource  schema.GroupResource
+	Namespace string
+	// ServiceAccount is the account the request was made as, when known.
+	ServiceAccount string
+	Err            error
+}
+
+func (e *ForbiddenError) Error() string {
+	who := "the current identity"
+	if e.ServiceAccount != "" {
+		who = "service account " + e.ServiceAccount
+	}
+	where := ""
+	if e.Namespace != "" {
+		where = " in namespace " + e.Namespace
+	}
+	return fmt.Sprintf("%s may not %s %s%s: %v", who, e.Verb, e.Resource, where, e.Err)
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return e.Err
+}
+
+// Rule returns the RBAC rule that would allow the denied request.
+func (e *ForbiddenError) Rule() rbacv1.PolicyRule {
+	return rule(e.Resource.Group, e.Resource.Resource, e.Verb)
+}
+
+// forbidden turns err into a *ForbiddenError if the apiserver denied verb on
+// resource, and returns it unchanged otherwise. Creates refused because
+// the namespace is terminating are not a permission problem and are left
+// alone too.
+func forbidden(err error, verb string, resource schema.GroupResource, ns string) error {
+	if !apierrors.IsForbidden(err) || namespaceTerminating(err) {
+		return err
+	}
+	return &ForbiddenError{Verb: verb, Resource: resource, Namespace: ns, Err: err}
+}
+
+// explainForbidden records our service account on a *ForbiddenError in err
+// and logs the missing rule, once per verb and resource.
+func (e *PodElector) explainForbidden(err error) {
+	var fe *ForbiddenError
+	if !errors.As(err, &fe) {
+		return
+	}
+	fe.ServiceAccount = e.ns + "/" + e.serviceAccount
+
+	key := fe.Verb + " " + fe.Resource.String()
+	e.mu.Lock()
+	logged := e.forbiddenLogged[key]
+	if e.forbiddenLogged == nil {
+		e.forbiddenLogged = map[string]bool{}
+	}
+	e.forbiddenLogged[key] = true
+	e.mu.Unlock()
+	if logged {
+		return
+	}
+
+	r := fe.Rule()
+	e.log.Error(fe.Err, "Missing RBAC permission; add this rule to the Role bound to the service account",
+		"serviceAccount", fe.ServiceAccount,
+		"apiGroups", fmt.Sprintf("%q", r.APIGroups),
+		"resources", fmt.Sprintf("%q", r.Resources),
+		"verbs", fmt.Sprintf("%q", r.Verbs))
+}
@@ -0,0 +1,80 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// terminatingNamespaceError returns the error the apiserver gives for a
+// create in a terminating namespace.
+func terminatingNamespaceError() error {
+	err := apierrors.NewForbidden(v1.Resource("configmaps"), testLock, errors.New("namespace is being terminated"))
+	err.ErrStatus.Details.Causes = []metav1.StatusCause{{Type: v1.NamespaceTerminatingCause}}
+	return err
+}
+
+func TestForbidden(t *testing.T) {
+	denied := apierrors.NewForbidden(v1.Resource("configmaps"), testLock, errors.New("denied"))
+	for _, tc := range []struct {
+		name      string
+		err       error
+		forbidden bool
+	}{
+		{name: "denied", err: denied, forbidden: true},
+		{name: "wrapped denial", err: fmt.Errorf("get lock: %w", denied), forbidden: true},
+		{name: "namespace terminating", err: terminatingNamespaceError(), forbidden: false},
+		{name: "not found", err: apierrors.NewNotFound(v1.Resource("configmaps"), testLock), forbidden: false},
+		{name: "no error", err: nil, forbidden: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := forbidden(tc.err, "create", v1.Resource("configmaps"), testNamespace)
+			var fe *ForbiddenError
+			if got := errors.As(err, &fe); got != tc.forbidden {
+				t.Fatalf("forbidden = %v, want a ForbiddenError %v", err, tc.forbidden)
+			}
+			if !tc.forbidden {
+				if err != tc.err {
+					t.Fatalf("forbidden changed %v to %v", tc.err, err)
+				}
+				return
+			}
+			if !apierrors.IsForbidden(err) {
+				t.Fatal("apierrors.IsForbidden is false for a ForbiddenError")
+			}
+			want := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}}
+			if got := fe.Rule(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Rule = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestTryAcquireForbidden(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(v1.Resource("configmaps"), testLock, errors.New("denied"))
+	})
+	e := newTestElector(t, client, "pod-1", WithServiceAccount("elector"))
+
+	_, err := e.TryAcquire(context.Background())
+	var fe *ForbiddenError
+	if !errors.As(err, &fe) {
+		t.Fatalf("TryAcquire = %v, want a ForbiddenError", err)
+	}
+	if fe.Verb != "create" || fe.Resource != v1.Resource("configmaps") || fe.Namespace != testNamespace {
+		t.Fatalf("ForbiddenError names %s %s in %s, want create configmaps in %s", fe.Verb, fe.Resource, fe.Namespace, testNamespace)
+	}
+	if fe.ServiceAccount != testNamespace+"/elector" {
+		t.Fatalf("ForbiddenError names service account %q", fe.ServiceAccount)
+	}
+}
@@ -0,0 +1,84 @@
+package leader
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeaderGonePolicy says what a candidate may do when the pod owning the
+// lock no longer exists. The garbage collector removes such a lock, but
+// only once it notices the owner is gone, which can take seconds on a busy
+// cluster and stalls failover meanwhile.
+type LeaderGonePolicy string
+
+const (
+	// LeaderGoneWait waits for the garbage collector. It is the default.
+	LeaderGoneWait LeaderGonePolicy = ""
+
+	// LeaderGoneDeleteLock deletes the lock once its owner is verified to be
+	// gone.
+	LeaderGoneDeleteLock LeaderGonePolicy = "DeleteLock"
+)
+
+// ownerGone reports whether owner, the pod owning the lock, no longer
+// exists: no pod has its name, or the pod that has it was recreated under
+// a different UID, as StatefulSet pods are. The pod is read from the
+// apiserver rather than an informer, so a lagging cache never makes us
+// delete the lock of a running leader.
+func (e *PodElector) ownerGone(ctx context.Context, owner metav1.OwnerReference) (bool, error) {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	pod, err := e.kube().CoreV1().Pods(e.ns).Get(ctx, owner.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return true, nil
+	case err != nil:
+		return false, forbidden(err, "get", v1.Resource("pods"), e.ns)
+	}
+	return pod.UID != owner.UID, nil
+}
+
+// deleteOrphanedLock deletes lock, owned by the pod owner, instead of
+// waiting for the garbage collector, once ownerGone confirms the owner no
+// longer exists. The deletion uses foreground propagation, so that the lock
+// is only removed once any objects it owns are, as the garbage collector
+// would have done, and is guarded by UID and resourceVersion preconditions,
+// so a lock recreated or changed since we verified its owner is never
+// touched.
+func (e *PodElector) deleteOrphanedLock(ctx context.Context, lock metav1.Object, owner metav1.OwnerReference) error {
+	gone, err := e.ownerGone(ctx, owner)
+	if err != nil || !gone {
+		return err
+	}
+
+	e.log.Info("Leader pod no longer exists, deleting its lock", "lock", e.lockName, "leader", owner.Name, "uid", owner.UID)
+	err = deleteForeground(ctx, e.lockBackend(), e.lockName, unchanged(lock))
+	switch {
+	case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+		// gone or changed since we read it; the next attempt looks again
+		return nil
+	case err != nil:
+		return err
+	}
+	e.event(v1.EventTypeNormal, "DeletedOrphanedLock", "Deleted %s of deleted leader %s", e.lockName, owner.Name)
+	e.audit(AuditTookOver, "Deleted %s of deleted leader %s", e.lockName, owner.Name)
+	return nil
+}
+
+// foregroundDeleter is implemented by backends that can delete a lock with
+// foreground propagation.
+type foregroundDeleter interface {
+	DeleteForeground(ctx context.Context, name string, pre metav1.Preconditions) error
+}
+
+// deleteForeground deletes the lock name with foreground propagation if b
+// supports it, and with its default propagation otherwise.
+func deleteForeground(ctx context.Context, b backend, name string, pre metav1.Preconditions) error {
+	if f, ok := b.(foregroundDeleter); ok {
+		return f.DeleteForeground(ctx, name, pre)
+	}
+	return b.Delete(ctx, name, pre)
+}
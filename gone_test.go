@@ -0,0 +1,116 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDeleteOrphanedLock(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// pod2 is the UID pod-2 exists with, or "" if it is gone
+		pod2    types.UID
+		changed bool
+		deleted bool
+	}{
+		{name: "owner gone", deleted: true},
+		{name: "owner recreated", pod2: "pod-2-uid-2", deleted: true},
+		{name: "owner running", pod2: "pod-2-uid", deleted: false},
+		{name: "lock changed since read", changed: true, deleted: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if tc.pod2 != "" {
+				pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: testNamespace, UID: tc.pod2}}
+				if _, err := client.CoreV1().Pods(testNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lock := expiredLock(t, client)
+			if tc.changed {
+				changed := lock.DeepCopy()
+				changed.ResourceVersion = "2"
+				if _, err := client.CoreV1().ConfigMaps(testNamespace).Update(context.Background(), changed, metav1.UpdateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1")
+			b := enforcePreconditions(e)
+
+			if err := e.deleteOrphanedLock(context.Background(), lock, lock.OwnerReferences[0]); err != nil {
+				t.Fatalf("deleteOrphanedLock: %v", err)
+			}
+			if deleted := lockOwner(t, client) == ""; deleted != tc.deleted {
+				t.Fatalf("lock deleted = %v, want %v", deleted, tc.deleted)
+			}
+			for _, pre := range b.deletes {
+				if pre.UID == nil || *pre.UID != lock.UID || pre.ResourceVersion == nil || *pre.ResourceVersion != lock.ResourceVersion {
+					t.Fatalf("lock deleted with preconditions %+v, want its UID and resourceVersion", pre)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteForeground(t *testing.T) {
+	client := newTestClient(t)
+	for _, kind := range []Backend{ConfigMapBackend, LeaseBackend, MigrationBackend} {
+		b, err := newBackend(kind, client, testNamespace, time.Second, defaultLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := b.(foregroundDeleter); !ok {
+			t.Errorf("%s backend cannot delete with foreground propagation", kind)
+		}
+	}
+
+	uid := types.UID("uid-1")
+	opts := foregroundOptions(uidOnly(uid))
+	if opts.PropagationPolicy == nil || *opts.PropagationPolicy != metav1.DeletePropagationForeground {
+		t.Fatalf("propagation policy = %v, want Foreground", opts.PropagationPolicy)
+	}
+	if opts.Preconditions == nil || *opts.Preconditions.UID != uid {
+		t.Fatalf("preconditions = %+v, want the UID kept", opts.Preconditions)
+	}
+}
+
+func TestBecomeAfterLeaderGone(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy LeaderGonePolicy
+		// pod2 is the UID pod-2 exists with, or "" if it is gone
+		pod2  types.UID
+		leads bool
+	}{
+		{name: "owner gone", policy: LeaderGoneDeleteLock, leads: true},
+		{name: "owner recreated", policy: LeaderGoneDeleteLock, pod2: "pod-2-uid-2", leads: true},
+		{name: "owner running", policy: LeaderGoneDeleteLock, pod2: "pod-2-uid"},
+		{name: "waiting for garbage collection", policy: LeaderGoneWait},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if tc.pod2 != "" {
+				pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: testNamespace, UID: tc.pod2}}
+				if _, err := client.CoreV1().Pods(testNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lock := &v1.ConfigMap{ObjectMeta: testLockMeta("pod-2")}
+			if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), lock, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			e := newTestElector(t, client, "pod-1", WithLeaderGonePolicy(tc.policy))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+			defer cancel()
+			if err := e.Become(ctx); (err == nil) != tc.leads {
+				t.Fatalf("Become = %v, want to lead %v", err, tc.leads)
+			}
+		})
+	}
+}
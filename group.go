@@ -0,0 +1,27 @@
+package leader
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// GroupLockName returns the name of the lock the pods of group elect their
+// leader with under WithGroupLabel, for followers and tooling that need to
+// find it.
+func GroupLockName(lockName, group string) string {
+	return objectName(lockName, group)
+}
+
+// groupedLockName returns the lock pod competes for instead of lockName
+// under WithGroupLabel, which is lockName itself without it.
+func (o *options) groupedLockName(lockName string, pod *v1.Pod) (string, error) {
+	if o.groupLabel == "" {
+		return lockName, nil
+	}
+	group := pod.Labels[o.groupLabel]
+	if group == "" {
+		return "", fmt.Errorf("pod %s/%s has no %s label to group its election by", pod.Namespace, pod.Name, o.groupLabel)
+	}
+	return GroupLockName(lockName, group), nil
+}
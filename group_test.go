@@ -0,0 +1,52 @@
+package leader
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupLabel(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2", "pod-3", "pod-4")
+	for pod, shard := range map[string]string{"pod-1": "a", "pod-2": "a", "pod-3": "b"} {
+		shard := shard
+		updatePod(t, client, pod, func(p *v1.Pod) { p.Labels = map[string]string{"shard": shard} })
+	}
+
+	for _, tc := range []struct {
+		pod  string
+		lock string
+		ok   bool
+	}{
+		{pod: "pod-1", lock: GroupLockName(testLock, "a"), ok: true},
+		{pod: "pod-2", lock: GroupLockName(testLock, "a")},
+		{pod: "pod-3", lock: GroupLockName(testLock, "b"), ok: true},
+	} {
+		e := newTestElector(t, client, tc.pod, WithGroupLabel("shard"))
+		if e.lockName != tc.lock {
+			t.Fatalf("%s competes for %s, want %s", tc.pod, e.lockName, tc.lock)
+		}
+		if ok, err := e.TryAcquire(ctx); err != nil || ok != tc.ok {
+			t.Fatalf("TryAcquire of %s = %v, %v, want %v", tc.pod, ok, err, tc.ok)
+		}
+	}
+	for group, leader := range map[string]string{"a": "pod-1", "b": "pod-3"} {
+		lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, GroupLockName(testLock, group), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lock.OwnerReferences[0].Name != leader {
+			t.Fatalf("group %s is led by %s, want %s", group, lock.OwnerReferences[0].Name, leader)
+		}
+	}
+
+	if _, err := NewElector(testLock, append(testOptions(client, "pod-4"), WithGroupLabel("shard"))...); err == nil {
+		t.Fatal("NewElector of a pod without the group label succeeded")
+	}
+	if _, err := NewManager("tenants", append(testOptions(client, "pod-4"), WithGroupLabel("shard"))...); err == nil {
+		t.Fatal("NewManager of a pod without the group label succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// defaultHealthFailures is how many consecutive failed health checks make
+// the leader demote itself by default.
+const defaultHealthFailures = 3
+
+// checkHealth runs the health check from the maintenance loop and reports
+// whether the leader has failed it often enough in a row to demote itself.
+func (e *PodElector) checkHealth(ctx context.Context) bool {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	if err := e.opts.healthCheck(ctx); err != nil {
+		e.healthFailures++
+		e.log.Warn("Leader failed its health check", "lock", e.lockName, "failures", e.healthFailures, "error", err)
+		return e.healthFailures >= e.tuned().healthFailures
+	}
+	e.healthFailures = 0
+	return false
+}
+
+// demote gives up leadership of a sick leader. It drains and resigns, and
+// keeps us from competing again until the cooldown has passed, so another
+// candidate gets the lock.
+func (e *PodElector) demote(ctx context.Context) {
+	e.log.Info("Demoting myself after repeated health check failures", "lock", e.lockName, "cooldown", e.opts.healthCooldown)
+	e.event(v1.EventTypeWarning, "Demoted", "Gave up %s after %d failed health checks", e.lockName, e.healthFailures)
+	e.healthFailures = 0
+
+	e.mu.Lock()
+	e.demotedUntil = time.Now().Add(e.opts.healthCooldown)
+	e.mu.Unlock()
+
+	if err := e.Resign(ctx); err != nil {
+		e.log.Error(err, "Failed to resign", "lock", e.lockName)
+	}
+}
+
+// demoted reports whether the cooldown of a self-demotion is running.
+func (e *PodElector) demoted() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return time.Now().Before(e.demotedUntil)
+}
+
+// demotedSince reports whether we demoted ourselves after t.
+func (e *PodElector) demotedSince(t time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.demotedUntil.Before(t)
+}
+
+// waitDemotion blocks until the cooldown of a self-demotion has passed.
+func (e *PodElector) waitDemotion(ctx context.Context) error {
+	e.mu.Lock()
+	d := time.Until(e.demotedUntil)
+	e.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	e.log.Info("Recently demoted, waiting before competing again", "lock", e.lockName, "remaining", d)
+	return e.sleep(ctx, d)
+}
@@ -0,0 +1,70 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckHealth(t *testing.T) {
+	sick := errors.New("database unreachable")
+	for _, tc := range []struct {
+		name     string
+		failures int
+		results  []error
+		demote   bool
+	}{
+		{name: "healthy", results: []error{nil, nil, nil, nil}, demote: false},
+		{name: "default failures", results: []error{sick, sick, sick}, demote: true},
+		{name: "too few failures", results: []error{sick, sick}, demote: false},
+		{name: "recovered in between", results: []error{sick, sick, nil, sick, sick}, demote: false},
+		{name: "custom failures", failures: 1, results: []error{sick}, demote: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			i := 0
+			e := newTestElector(t, client, "pod-1", WithHealthCheck(func(ctx context.Context) error {
+				err := tc.results[i]
+				i++
+				return err
+			}, tc.failures, time.Minute))
+
+			demote := false
+			for range tc.results {
+				demote = e.checkHealth(context.Background())
+			}
+			if demote != tc.demote {
+				t.Fatalf("checkHealth = %v after %v, want %v", demote, tc.results, tc.demote)
+			}
+		})
+	}
+}
+
+func TestDemote(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithHealthCheck(func(ctx context.Context) error {
+		return errors.New("database unreachable")
+	}, 1, time.Minute), WithMaintenanceInterval(10*time.Millisecond))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.maintain(ctx)
+	if e.IsLeader() {
+		t.Fatal("still the leader after failing the health check")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock still held by %q", owner)
+	}
+
+	// the cooldown keeps us from taking the lock straight back
+	if !e.demoted() {
+		t.Fatal("no cooldown after the demotion")
+	}
+	if ok, err := e.TryAcquire(context.Background()); err != nil || ok {
+		t.Fatalf("TryAcquire during the cooldown = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,67 @@
+package leader
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// verified records that the maintenance loop confirmed we hold the lock.
+func (e *PodElector) verified() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.verifiedAt = time.Now()
+}
+
+// LastVerified returns when the lock was last confirmed to be ours, or the
+// zero time if we do not lead, as IsLeader reports.
+func (e *PodElector) LastVerified() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leading || e.expiredLocked(time.Now()) {
+		return time.Time{}
+	}
+	return e.verifiedAt
+}
+
+// verifyDeadline is how long the leader may go without confirming its lock
+// before it is reported unhealthy: a few maintenance intervals, and the time
+// the last read may take.
+func (e *PodElector) verifyDeadline() time.Duration {
+	return 3*e.tuned().maintenanceInterval + e.opts.requestTimeout
+}
+
+// Check reports an error if we lead but have not confirmed the lock for
+// longer than a few maintenance intervals, which means the maintenance loop
+// is wedged or cannot reach the apiserver. Candidates are always healthy.
+// It has the signature of controller-runtime's healthz.Checker, so it can be
+// registered with mgr.AddHealthzCheck("leader", e.Check). As the apiserver
+// being unreachable also fails it, prefer it for readiness over liveness,
+// or pair it with a liveness failure threshold well above an outage.
+func (e *PodElector) Check(_ *http.Request) error {
+	verified := e.LastVerified()
+	if verified.IsZero() {
+		return nil
+	}
+	if stale := time.Since(verified); stale > e.verifyDeadline() {
+		return fmt.Errorf("leader of %s last verified the lock %s ago", e.lockName, stale.Round(time.Second))
+	}
+	return nil
+}
+
+// HealthzHandler returns an http.Handler for a probe endpoint. It answers
+// 200 with our leader status, or 500 with the error of Check.
+func (e *PodElector) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := e.Check(r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if verified := e.LastVerified(); !verified.IsZero() {
+			fmt.Fprintf(w, "ok: leading %s, lock verified %s ago\n", e.lockName, time.Since(verified).Round(time.Second))
+			return
+		}
+		fmt.Fprintf(w, "ok: not leading %s\n", e.lockName)
+	})
+}
@@ -0,0 +1,46 @@
+package leader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthz(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		leading  bool
+		verified time.Duration
+		code     int
+		body     string
+	}{
+		{name: "candidate", code: http.StatusOK, body: "ok: not leading test-lock"},
+		{name: "verified leader", leading: true, code: http.StatusOK, body: "ok: leading test-lock"},
+		{name: "stale leader", leading: true, verified: -time.Hour, code: http.StatusInternalServerError, body: "last verified the lock 1h0m0s ago"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			if tc.leading {
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+				e.mu.Lock()
+				e.verifiedAt = e.verifiedAt.Add(tc.verified)
+				e.mu.Unlock()
+			}
+
+			if err := e.Check(nil); (err == nil) != (tc.code == http.StatusOK) {
+				t.Fatalf("Check = %v, want status %d", err, tc.code)
+			}
+			rec := httptest.NewRecorder()
+			e.HealthzHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if rec.Code != tc.code || !strings.Contains(rec.Body.String(), tc.body) {
+				t.Fatalf("healthz = %d %q, want %d %q", rec.Code, rec.Body.String(), tc.code, tc.body)
+			}
+		})
+	}
+}
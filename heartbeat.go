@@ -0,0 +1,44 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LastHeartbeatAnnotation records on the lock when the leader last showed
+// it was alive, with WithLockHeartbeat.
+const LastHeartbeatAnnotation = "leader.seamounts.io/last-heartbeat"
+
+// beat records the current time on the lock we hold and, with
+// WithLockExpiry, renews its expiry.
+func (e *PodElector) beat(ctx context.Context) error {
+	now := time.Now().UTC()
+	annotations := map[string]interface{}{
+		LastHeartbeatAnnotation: now.Format(time.RFC3339),
+	}
+	expires := now.Add(e.opts.lockExpiry)
+	if e.opts.lockExpiry > 0 {
+		annotations[ExpiresAtAnnotation] = expires.Format(time.RFC3339)
+	}
+	if err := e.patchLockAnnotations(ctx, annotations); err != nil {
+		return err
+	}
+	if e.opts.lockExpiry > 0 {
+		e.mu.Lock()
+		e.expiresAt = expires
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+// observeHeartbeat exports the age of the leader's last heartbeat as seen on
+// lock. Locks without a heartbeat are not reported.
+func (e *PodElector) observeHeartbeat(lock metav1.Object) {
+	last, err := time.Parse(time.RFC3339, lock.GetAnnotations()[LastHeartbeatAnnotation])
+	if err != nil {
+		return
+	}
+	heartbeatAgeGauge.WithLabelValues(e.lockName).Set(time.Since(last).Seconds())
+}
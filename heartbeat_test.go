@@ -0,0 +1,82 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBeat(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithLockHeartbeat())
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	heartbeat := func() time.Time {
+		t.Helper()
+		lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		last, err := time.Parse(time.RFC3339, lock.Annotations[LastHeartbeatAnnotation])
+		if err != nil {
+			t.Fatalf("heartbeat annotation: %v", err)
+		}
+		return last
+	}
+	if since := time.Since(heartbeat()); since > time.Minute {
+		t.Fatalf("new lock has a heartbeat %v old", since)
+	}
+
+	if err := e.patchLockAnnotations(context.Background(), map[string]interface{}{
+		LastHeartbeatAnnotation: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.beat(context.Background()); err != nil {
+		t.Fatalf("beat: %v", err)
+	}
+	if since := time.Since(heartbeat()); since > time.Minute {
+		t.Fatalf("heartbeat is %v old after beat", since)
+	}
+}
+
+func TestObserveHeartbeat(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		heartbeat string
+		// age is the exported age, or -1 if none is exported
+		age float64
+	}{
+		{name: "recent", heartbeat: time.Now().UTC().Format(time.RFC3339), age: 0},
+		{name: "stale", heartbeat: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339), age: 60},
+		{name: "no heartbeat", age: -1},
+		{name: "malformed", heartbeat: "yesterday", age: -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			heartbeatAgeGauge.DeleteLabelValues(e.lockName)
+			heartbeatAgeGauge.WithLabelValues(e.lockName).Set(-1)
+
+			lock := &v1.ConfigMap{ObjectMeta: testLockMeta("pod-2")}
+			if tc.heartbeat != "" {
+				lock.Annotations = map[string]string{LastHeartbeatAnnotation: tc.heartbeat}
+			}
+			e.observeHeartbeat(lock)
+
+			// the heartbeat is recorded to the second
+			age := testutil.ToFloat64(heartbeatAgeGauge.WithLabelValues(e.lockName))
+			switch {
+			case tc.age < 0 && age != -1:
+				t.Fatalf("heartbeat age %v exported, want none", age)
+			case tc.age >= 0 && (age < tc.age || age > tc.age+2):
+				t.Fatalf("heartbeat age = %v, want %v", age, tc.age)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// LeaderResolver is implemented by Locks that can report who holds them.
+type LeaderResolver interface {
+	Leader(ctx context.Context) (string, error)
+}
+
+var _ LeaderResolver = &PodElector{}
+
+// ErrLeaderUnknown is returned when a Lock cannot report its holder.
+var ErrLeaderUnknown = errors.New("lock does not report its leader")
+
+// Hierarchy runs a two-level election. The pod first becomes the local
+// leader, for example of its namespace, and only local leaders contend for
+// the global lock. Because the global lock usually lives outside the local
+// cluster or namespace, it is any Lock rather than a PodElector.
+type Hierarchy struct {
+	local  *PodElector
+	global Lock
+
+	mu         sync.Mutex
+	globalHeld bool
+	// contending is set while the goroutine contending for the global lock
+	// runs.
+	contending bool
+}
+
+// NewHierarchy returns a Hierarchy electing a global leader among the
+// leaders of local.
+func NewHierarchy(local *PodElector, global Lock) *Hierarchy {
+	return &Hierarchy{
+		local:  local,
+		global: global,
+	}
+}
+
+// Become blocks until the current pod is the local leader, then contends for
+// the global lock in the background for as long as it stays the local
+// leader. It releases the global lock when local leadership is lost. Only
+// one contender runs however often Become is called, and the local
+// Elector's Run waits for it to stop.
+func (h *Hierarchy) Become(ctx context.Context) error {
+	if err := h.local.Become(ctx); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.contending {
+		h.contending = true
+		h.local.background.Add(1)
+		go h.contendGlobal(ctx)
+	}
+	return nil
+}
+
+// contendGlobal contends for the global lock until ctx is done or we are no
+// longer the local leader. It checks the latter with h.mu held, so a Become
+// that regained local leadership in the meantime either sees it still
+// running or starts the next one.
+func (h *Hierarchy) contendGlobal(ctx context.Context) {
+	defer h.local.background.Done()
+	for {
+		h.contend(ctx)
+
+		h.mu.Lock()
+		if ctx.Err() != nil || !h.local.IsLeader() {
+			h.contending = false
+			h.mu.Unlock()
+			return
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *Hierarchy) contend(ctx context.Context) {
+	for h.local.IsLeader() {
+		held, err := h.global.TryAcquire(ctx)
+		if err != nil {
+			h.local.log.Error(err, "Failed to contend for the global lock")
+		}
+		if held && !h.IsGlobalLeader() {
+			h.local.log.Info("Became the global leader")
+		}
+		h.setGlobalHeld(held)
+
+		if err := h.local.sleep(ctx, h.local.tuned().maintenanceInterval); err != nil {
+			break
+		}
+	}
+
+	if h.IsGlobalLeader() {
+		h.local.log.Info("No longer the local leader, releasing the global lock", "lock", h.local.lockName)
+		if err := h.global.Release(context.Background()); err != nil && !errors.Is(err, ErrNotLeader) {
+			h.local.log.Error(err, "Failed to release the global lock")
+		}
+	}
+	h.setGlobalHeld(false)
+}
+
+// IsLocalLeader reports whether the current pod leads its local group.
+func (h *Hierarchy) IsLocalLeader() bool {
+	return h.local.IsLeader()
+}
+
+// IsGlobalLeader reports whether the current pod holds the global lock.
+func (h *Hierarchy) IsGlobalLeader() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.globalHeld
+}
+
+// LocalLeader returns the name of the local leader.
+func (h *Hierarchy) LocalLeader(ctx context.Context) (string, error) {
+	return h.local.Leader(ctx)
+}
+
+// GlobalLeader returns the name of the global leader, if the global Lock can
+// report it.
+func (h *Hierarchy) GlobalLeader(ctx context.Context) (string, error) {
+	resolver, ok := h.global.(LeaderResolver)
+	if !ok {
+		return "", ErrLeaderUnknown
+	}
+	return resolver.Leader(ctx)
+}
+
+func (h *Hierarchy) setGlobalHeld(held bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.globalHeld = held
+}
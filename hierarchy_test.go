@@ -0,0 +1,57 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHierarchyContendsOnce(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	global := &memLock{}
+	h := NewHierarchy(newTestElector(t, client, "pod-1"), global)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 3; i++ {
+		if err := h.Become(ctx); err != nil {
+			t.Fatalf("Become: %v", err)
+		}
+	}
+	waitFor(t, "the global lock", h.IsGlobalLeader)
+	global.mu.Lock()
+	acquires := global.acquires
+	global.mu.Unlock()
+	if acquires != 1 {
+		t.Fatalf("global lock was contended for %d times, want once", acquires)
+	}
+
+	// the local Elector's goroutines, the contender among them, stop with
+	// ctx
+	cancel()
+	h.local.background.Wait()
+	if h.IsGlobalLeader() || global.held {
+		t.Fatal("global lock still held after the contender stopped")
+	}
+}
+
+func TestHierarchyReleasesGlobalWithLocal(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	global := &memLock{}
+	local := newTestElector(t, client, "pod-1", WithMaintenanceInterval(10*time.Millisecond))
+	h := NewHierarchy(local, global)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := h.Become(ctx); err != nil {
+		t.Fatalf("Become: %v", err)
+	}
+	waitFor(t, "the global lock", h.IsGlobalLeader)
+	if err := local.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	waitFor(t, "the release of the global lock", func() bool {
+		global.mu.Lock()
+		defer global.mu.Unlock()
+		return global.releases == 1 && !h.IsGlobalLeader()
+	})
+}
@@ -0,0 +1,221 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultJanitorInterval is how often a Janitor sweeps by default.
+const defaultJanitorInterval = time.Minute * 10
+
+// Janitor deletes lock objects, and the auxiliary objects kept per lock,
+// whose owner pods no longer exist. The garbage collector normally removes
+// them with their pods, but objects it missed, for example during a GC
+// outage, block new elections for good. Only objects labeled with LockLabel
+// are considered, and only those provably orphaned are deleted: every owner
+// is a pod and none of them exists with the recorded UID.
+//
+// A Janitor can be run standalone, or by the leader with WithJanitor. With
+// an empty Namespace it sweeps every namespace, as cmd/leader-janitor does
+// with the rules of ClusterJanitorRBAC.
+type Janitor struct {
+	Client kubernetes.Interface
+	// Namespace is the namespace to sweep, or metav1.NamespaceAll for all
+	// of them.
+	Namespace string
+
+	// StaleAfter, if positive, also has locks deleted whose
+	// ExpiresAtAnnotation passed more than StaleAfter ago, even though their
+	// owner pods exist. Candidates take over expired locks themselves; this
+	// cleans up those nobody is left to take over.
+	StaleAfter time.Duration
+
+	// DryRun makes the janitor only report what it would delete. Deletions
+	// are still sent, as server-side dry runs, so that missing permissions
+	// and failed preconditions show up.
+	DryRun bool
+
+	// Interval is the time between sweeps of Run. It defaults to ten
+	// minutes.
+	Interval time.Duration
+
+	// Log receives the janitor's logs. It defaults to the package's
+	// default logger.
+	Log Logger
+
+	// timeout bounds each API call. It defaults to the request timeout.
+	timeout time.Duration
+}
+
+// JanitorRules returns the rules a Janitor needs, in its namespace or, to
+// sweep every namespace, cluster-wide.
+func JanitorRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		rule("", "configmaps", "list", "delete"),
+		rule(coordinationv1.GroupName, "leases", "list", "delete"),
+		rule("", "pods", "get"),
+	}
+}
+
+func (j *Janitor) logger() Logger {
+	if j.Log == nil {
+		return defaultLogger
+	}
+	return j.Log
+}
+
+// Run sweeps every Interval until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) error {
+	interval := j.Interval
+	if interval <= 0 {
+		interval = defaultJanitorInterval
+	}
+	for {
+		if _, err := j.Sweep(ctx); err != nil && ctx.Err() == nil {
+			janitorErrorsCounter.Inc()
+			j.logger().Error(err, "Failed to sweep orphaned locks", "namespace", j.Namespace)
+		}
+		if _, err := sleepOrWake(ctx, interval, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// Sweep makes a single pass and returns the names of the objects it
+// deleted, or would have with DryRun. When sweeping every namespace the
+// names are qualified by namespace, as namespace/name. An object that cannot
+// be checked or deleted does not stop the pass: the errors of all of them
+// are returned together once it is done.
+func (j *Janitor) Sweep(ctx context.Context) ([]string, error) {
+	timeout := j.timeout
+	if timeout == 0 {
+		timeout = defaultRequestTimeout
+	}
+	list := metav1.ListOptions{LabelSelector: LockLabel}
+
+	var objects []metav1.Object
+	listCtx, cancel := withTimeout(ctx, timeout)
+	configMaps, err := j.Client.CoreV1().ConfigMaps(j.Namespace).List(listCtx, list)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	listCtx, cancel = withTimeout(ctx, timeout)
+	leases, err := j.Client.CoordinationV1().Leases(j.Namespace).List(listCtx, list)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	for i := range leases.Items {
+		objects = append(objects, &leases.Items[i])
+	}
+
+	var (
+		deleted []string
+		errs    []error
+	)
+	for _, obj := range objects {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		reason, err := j.reason(ctx, timeout, obj)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("check %s/%s: %w", obj.GetNamespace(), obj.GetName(), err))
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		// the preconditions keep us from deleting an object that was
+		// recreated or taken over since we listed it
+		pre := unchanged(obj)
+		opts := metav1.DeleteOptions{Preconditions: &pre}
+		if j.DryRun {
+			opts.DryRun = []string{metav1.DryRunAll}
+		}
+		ns := obj.GetNamespace()
+		delCtx, cancel := withTimeout(ctx, timeout)
+		switch obj.(type) {
+		case *coordinationv1.Lease:
+			err = j.Client.CoordinationV1().Leases(ns).Delete(delCtx, obj.GetName(), opts)
+		default:
+			err = j.Client.CoreV1().ConfigMaps(ns).Delete(delCtx, obj.GetName(), opts)
+		}
+		cancel()
+		switch {
+		case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+			continue
+		case err != nil:
+			errs = append(errs, fmt.Errorf("delete %s/%s: %w", ns, obj.GetName(), err))
+			continue
+		}
+		janitorDeletedCounter.WithLabelValues(ns, reason, strconv.FormatBool(j.DryRun)).Inc()
+		if j.DryRun {
+			j.logger().Info("Would delete lock object", "namespace", ns, "name", obj.GetName(), "lock", obj.GetLabels()[LockLabel], "reason", reason)
+		} else {
+			j.logger().Info("Deleted lock object", "namespace", ns, "name", obj.GetName(), "lock", obj.GetLabels()[LockLabel], "reason", reason)
+		}
+		if j.Namespace == metav1.NamespaceAll {
+			deleted = append(deleted, ns+"/"+obj.GetName())
+		} else {
+			deleted = append(deleted, obj.GetName())
+		}
+	}
+	return deleted, utilerrors.NewAggregate(errs)
+}
+
+// reason returns why obj should be deleted: "orphaned" if its owners are
+// gone, "expired" if it went stale, or "" if it should be kept.
+func (j *Janitor) reason(ctx context.Context, timeout time.Duration, obj metav1.Object) (string, error) {
+	if obj.GetDeletionTimestamp() != nil {
+		return "", nil
+	}
+	if j.StaleAfter > 0 {
+		if expiry, expired := lockExpiry(obj); expired && time.Since(expiry) > j.StaleAfter {
+			return "expired", nil
+		}
+	}
+	orphaned, err := j.orphaned(ctx, timeout, obj)
+	if err != nil || !orphaned {
+		return "", err
+	}
+	return "orphaned", nil
+}
+
+// orphaned reports whether every owner of obj is a pod that no longer
+// exists with the recorded UID.
+func (j *Janitor) orphaned(ctx context.Context, timeout time.Duration, obj metav1.Object) (bool, error) {
+	owners := obj.GetOwnerReferences()
+	if len(owners) == 0 || obj.GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+	for _, owner := range owners {
+		if owner.Kind != "Pod" {
+			return false, nil
+		}
+		getCtx, cancel := withTimeout(ctx, timeout)
+		pod, err := j.Client.CoreV1().Pods(obj.GetNamespace()).Get(getCtx, owner.Name, metav1.GetOptions{})
+		cancel()
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return false, err
+		case pod.UID == owner.UID:
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,197 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// janitorObject returns the metadata of a labeled lock object in ns owned
+// by the pod owner of UID uid, or by nobody if owner is empty.
+func janitorObject(ns, name, owner string, uid types.UID) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: ns,
+		Labels:    map[string]string{LockLabel: name},
+	}
+	if owner != "" {
+		meta.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: owner, UID: uid}}
+	}
+	return meta
+}
+
+func newTestJanitor(client *fake.Clientset, ns string) *Janitor {
+	o := defaultOptions()
+	o.logLevel = ErrorLevel
+	return &Janitor{Client: client, Namespace: ns, Log: o.getLogger()}
+}
+
+func TestSweep(t *testing.T) {
+	terminating := metav1.Now()
+	for _, tc := range []struct {
+		name       string
+		meta       metav1.ObjectMeta
+		lease      bool
+		staleAfter time.Duration
+		deleted    bool
+	}{
+		{name: "owner running", meta: janitorObject(testNamespace, "a", "pod-1", "pod-1-uid")},
+		{name: "owner gone", meta: janitorObject(testNamespace, "a", "pod-2", "pod-2-uid"), deleted: true},
+		{name: "owner gone, Lease", meta: janitorObject(testNamespace, "a", "pod-2", "pod-2-uid"), lease: true, deleted: true},
+		{name: "owner recreated", meta: janitorObject(testNamespace, "a", "pod-1", "pod-1-old-uid"), deleted: true},
+		{name: "no owner", meta: janitorObject(testNamespace, "a", "", "")},
+		{
+			name: "owned by something else",
+			meta: func() metav1.ObjectMeta {
+				meta := janitorObject(testNamespace, "a", "pod-2", "pod-2-uid")
+				meta.OwnerReferences[0].Kind = "StatefulSet"
+				return meta
+			}(),
+		},
+		{
+			name: "unlabeled",
+			meta: func() metav1.ObjectMeta {
+				meta := janitorObject(testNamespace, "a", "pod-2", "pod-2-uid")
+				meta.Labels = nil
+				return meta
+			}(),
+		},
+		{
+			name: "terminating",
+			meta: func() metav1.ObjectMeta {
+				meta := janitorObject(testNamespace, "a", "pod-2", "pod-2-uid")
+				meta.DeletionTimestamp = &terminating
+				return meta
+			}(),
+		},
+		{
+			name: "expired but not stale",
+			meta: func() metav1.ObjectMeta {
+				meta := janitorObject(testNamespace, "a", "pod-1", "pod-1-uid")
+				meta.Annotations = map[string]string{ExpiresAtAnnotation: time.Now().Add(-time.Minute).Format(time.RFC3339)}
+				return meta
+			}(),
+			staleAfter: time.Hour,
+		},
+		{
+			name: "stale",
+			meta: func() metav1.ObjectMeta {
+				meta := janitorObject(testNamespace, "a", "pod-1", "pod-1-uid")
+				meta.Annotations = map[string]string{ExpiresAtAnnotation: time.Now().Add(-time.Hour).Format(time.RFC3339)}
+				return meta
+			}(),
+			staleAfter: time.Minute,
+			deleted:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			var err error
+			if tc.lease {
+				_, err = client.CoordinationV1().Leases(testNamespace).Create(context.Background(), &coordinationv1.Lease{ObjectMeta: tc.meta}, metav1.CreateOptions{})
+			} else {
+				_, err = client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), &v1.ConfigMap{ObjectMeta: tc.meta}, metav1.CreateOptions{})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			j := newTestJanitor(client, testNamespace)
+			j.StaleAfter = tc.staleAfter
+
+			deleted, err := j.Sweep(context.Background())
+			if err != nil {
+				t.Fatalf("Sweep: %v", err)
+			}
+			if got := len(deleted) == 1; got != tc.deleted {
+				t.Fatalf("Sweep deleted %v, want deleted %v", deleted, tc.deleted)
+			}
+			if tc.lease {
+				_, err = client.CoordinationV1().Leases(testNamespace).Get(context.Background(), "a", metav1.GetOptions{})
+			} else {
+				_, err = client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "a", metav1.GetOptions{})
+			}
+			if gone := apierrors.IsNotFound(err); gone != tc.deleted {
+				t.Fatalf("object gone = %v, want %v", gone, tc.deleted)
+			}
+		})
+	}
+}
+
+func TestSweepAllNamespaces(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	for _, meta := range []metav1.ObjectMeta{
+		janitorObject("team-a", "a", "pod-2", "pod-2-uid"),
+		janitorObject("team-b", "b", "pod-3", "pod-3-uid"),
+		janitorObject(testNamespace, "c", "pod-1", "pod-1-uid"),
+	} {
+		if _, err := client.CoreV1().ConfigMaps(meta.Namespace).Create(context.Background(), &v1.ConfigMap{ObjectMeta: meta}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := newTestJanitor(client, metav1.NamespaceAll).Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	sort.Strings(deleted)
+	if want := []string{"team-a/a", "team-b/b"}; !reflect.DeepEqual(deleted, want) {
+		t.Fatalf("Sweep of every namespace deleted %v, want %v", deleted, want)
+	}
+}
+
+func TestSweepCarriesOn(t *testing.T) {
+	client := newTestClient(t)
+	for _, name := range []string{"a", "b"} {
+		meta := janitorObject(testNamespace, name, "pod-2", "pod-2-uid")
+		if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), &v1.ConfigMap{ObjectMeta: meta}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.DeleteAction).GetName() == "a" {
+			return true, nil, apierrors.NewForbidden(v1.Resource("configmaps"), "a", nil)
+		}
+		return false, nil, nil
+	})
+
+	deleted, err := newTestJanitor(client, testNamespace).Sweep(context.Background())
+	if !reflect.DeepEqual(deleted, []string{"b"}) || err == nil {
+		t.Fatalf("Sweep = %v, %v, want b deleted and the error of a", deleted, err)
+	}
+}
+
+func TestSweepDryRun(t *testing.T) {
+	client := newTestClient(t)
+	meta := janitorObject(testNamespace, "a", "pod-2", "pod-2-uid")
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), &v1.ConfigMap{ObjectMeta: meta}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the fake does not see delete options, so answer deletes as the
+	// apiserver does dry runs
+	deletes := 0
+	client.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deletes++
+		return true, nil, nil
+	})
+	j := newTestJanitor(client, testNamespace)
+	j.DryRun = true
+
+	deleted, err := j.Sweep(context.Background())
+	if err != nil || !reflect.DeepEqual(deleted, []string{"a"}) {
+		t.Fatalf("Sweep = %v, %v, want a reported", deleted, err)
+	}
+	if deletes != 1 {
+		t.Fatalf("%d deletes sent, want the dry run sent", deletes)
+	}
+}
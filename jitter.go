@@ -0,0 +1,62 @@
+package leader
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// defaultRandom is the randomness used unless WithRandSource injects a
+// source. It is seeded from crypto/rand, so that pods started at the same
+// moment do not back off in lockstep.
+var defaultRandom = newRandom(rand.NewSource(cryptoSeed()))
+
+// random is the source of the randomness in the package's waits: the
+// jitter of backoffs and the election timeouts of a RaftElector. Unlike a
+// rand.Rand, it is safe for concurrent use.
+type random struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newRandom(src rand.Source) *random {
+	return &random{r: rand.New(src)}
+}
+
+// cryptoSeed returns a seed read from crypto/rand, or the time if that
+// fails.
+func cryptoSeed() int64 {
+	var b [8]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// jitter returns a duration between d and d+maxFactor*d, as wait.Jitter
+// does.
+func (r *random) jitter(d time.Duration, maxFactor float64) time.Duration {
+	if maxFactor <= 0 {
+		maxFactor = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return d + time.Duration(r.r.Float64()*maxFactor*float64(d))
+}
+
+// int63n returns a number in [0, n).
+func (r *random) int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Int63n(n)
+}
+
+// random returns the injected source of randomness, or the default one.
+func (o *options) random() *random {
+	if o.rand == nil {
+		return defaultRandom
+	}
+	return o.rand
+}
@@ -0,0 +1,67 @@
+package leader
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitter(t *testing.T) {
+	r := newRandom(rand.NewSource(1))
+	for _, tc := range []struct {
+		name      string
+		d         time.Duration
+		maxFactor float64
+		max       time.Duration
+	}{
+		{name: "factor", d: time.Second, maxFactor: .2, max: 1200 * time.Millisecond},
+		{name: "no factor", d: time.Second, max: 2 * time.Second},
+		{name: "negative factor", d: time.Second, maxFactor: -1, max: 2 * time.Second},
+		{name: "no duration", max: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := r.jitter(tc.d, tc.maxFactor); got < tc.d || got > tc.max {
+					t.Fatalf("jitter(%v, %v) = %v, want within [%v, %v]", tc.d, tc.maxFactor, got, tc.d, tc.max)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRandSource(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	if e := newTestElector(t, client, "pod-1"); e.opts.random() != defaultRandom {
+		t.Fatal("Elector without a source does not use the default randomness")
+	}
+
+	draw := func(seed int64) []time.Duration {
+		e := newTestElector(t, client, "pod-1", WithRandSource(rand.NewSource(seed)))
+		var ds []time.Duration
+		for i := 0; i < 5; i++ {
+			ds = append(ds, e.opts.random().jitter(time.Second, .2))
+		}
+		return ds
+	}
+	a, b, c := draw(1), draw(1), draw(2)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("jitter with the same seed = %v and %v", a, b)
+		}
+	}
+	same := true
+	for i := range a {
+		same = same && a[i] == c[i]
+	}
+	if same {
+		t.Fatalf("jitter with different seeds = %v twice", a)
+	}
+
+	// Electors built with the same options share the source
+	opt := WithRandSource(rand.NewSource(1))
+	e1 := newTestElector(t, client, "pod-1", opt)
+	e2 := newTestElector(t, client, "pod-1", opt)
+	if e1.opts.random() != e2.opts.random() {
+		t.Fatal("Electors built with the same option do not share its source")
+	}
+}
@@ -0,0 +1,88 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunJob runs work in the pod of a Job that holds lockName, for Jobs whose
+// pods must not do their work concurrently. It becomes the leader, runs work
+// with a context that is cancelled if leadership is lost, and releases the
+// lock once work returns, so that the next pod does not wait for the
+// completed pod to be deleted. Candidates free the lock of a pod that
+// finished without releasing it, as with WithFinishedLeaderPolicy and
+// FinishedLeaderDeleteLock, which opts may override.
+func RunJob(ctx context.Context, lockName string, work func(ctx context.Context) error, opts ...Option) error {
+	e, err := NewElector(lockName, jobOptions(opts)...)
+	if err != nil {
+		return err
+	}
+	if err := e.Become(ctx); err != nil {
+		return err
+	}
+
+	workErr := e.runLeading(ctx, work)
+	if err := e.Resign(context.Background()); err != nil && !errors.Is(err, ErrNotLeader) {
+		e.log.Error(err, "Failed to release the lock after the job's work", "lock", lockName)
+	}
+	return workErr
+}
+
+// RunScheduled runs work in exactly one pod of each run of a CronJob. Pods
+// of the same run, such as the pods of a Job with a parallelism above one,
+// or a pod replacing one that failed after its work was done, compete for a
+// lock named after their Job. The pod that wins runs work; once work
+// succeeds, the lock records the completion and is handed to the Job, so
+// that it lasts as long as the Job does, and the other pods of the run
+// return nil without running work. If work fails, the lock is released and
+// another pod of the run may try.
+func RunScheduled(ctx context.Context, work func(ctx context.Context) error, opts ...Option) error {
+	o := defaultOptions()
+	for _, opt := range jobOptions(opts) {
+		opt(&o)
+	}
+	id, err := resolveIdentity(&o)
+	if err != nil {
+		return err
+	}
+	job := metav1.GetControllerOf(id.pod)
+	if job == nil || job.Kind != "Job" {
+		return fmt.Errorf("pod %s/%s is not controlled by a Job", id.ns, id.pod.Name)
+	}
+
+	e, err := newElector(job.Name+"-run", o, id)
+	if err != nil {
+		return err
+	}
+	owner := metav1.OwnerReference{APIVersion: job.APIVersion, Kind: job.Kind, Name: job.Name, UID: job.UID}
+	return e.runOnce(ctx, work, []metav1.OwnerReference{owner})
+}
+
+// jobOptions prepends the defaults of RunJob to opts.
+func jobOptions(opts []Option) []Option {
+	return append([]Option{WithFinishedLeaderPolicy(FinishedLeaderDeleteLock)}, opts...)
+}
+
+// runLeading runs work, which we must lead for, with a context that is
+// cancelled as soon as leadership ends.
+func (e *PodElector) runLeading(ctx context.Context, work func(ctx context.Context) error) error {
+	events := e.Subscribe()
+	defer e.unsubscribe(events)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for e.IsLeader() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-events:
+			}
+		}
+		cancel()
+	}()
+	return work(ctx)
+}
@@ -0,0 +1,101 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// controlledByJob makes the pods controlled by the Job job.
+func controlledByJob(t *testing.T, client *fake.Clientset, job string, pods ...string) {
+	t.Helper()
+	controller := true
+	for _, name := range pods {
+		updatePod(t, client, name, func(pod *v1.Pod) {
+			pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: job, UID: types.UID(job + "-uid"), Controller: &controller}}
+		})
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		workErr error
+	}{
+		{name: "succeeded"},
+		{name: "failed", workErr: errors.New("work failed")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			ran := false
+			err := RunJob(context.Background(), testLock, func(ctx context.Context) error {
+				ran = true
+				if owner := lockOwner(t, client); owner != "pod-1" {
+					t.Errorf("work ran with the lock owned by %q", owner)
+				}
+				return tc.workErr
+			}, testOptions(client, "pod-1")...)
+			if !ran || err != tc.workErr {
+				t.Fatalf("RunJob = %v, ran %v, want %v", err, ran, tc.workErr)
+			}
+			if owner := lockOwner(t, client); owner != "" {
+				t.Fatalf("lock still owned by %q after the job's work", owner)
+			}
+		})
+	}
+}
+
+func TestRunScheduled(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2", "pod-3")
+	controlledByJob(t, client, "job-1", "pod-1", "pod-2")
+	runs := 0
+	work := func(workErr error) func(context.Context) error {
+		return func(context.Context) error {
+			runs++
+			return workErr
+		}
+	}
+
+	failed := errors.New("work failed")
+	if err := RunScheduled(ctx, work(failed), testOptions(client, "pod-1")...); err != failed {
+		t.Fatalf("RunScheduled of failing work = %v, want %v", err, failed)
+	}
+	if err := RunScheduled(ctx, work(nil), testOptions(client, "pod-1")...); err != nil {
+		t.Fatalf("RunScheduled retried after a failure: %v", err)
+	}
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, "job-1-run", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.OwnerReferences) != 1 || lock.OwnerReferences[0].Name != "job-1" {
+		t.Fatalf("completed lock is owned by %v, want the Job", lock.OwnerReferences)
+	}
+	if lock.Annotations[CompletedByAnnotation] != "pod-1" || lock.Annotations[CompletedAnnotation] == "" {
+		t.Fatalf("completed lock annotations = %v", lock.Annotations)
+	}
+
+	// another pod of the run does not repeat the work
+	if err := RunScheduled(ctx, work(nil), testOptions(client, "pod-2")...); err != nil {
+		t.Fatalf("RunScheduled of a completed run: %v", err)
+	}
+	if runs != 2 {
+		t.Fatalf("work ran %d times, want a failure and a success", runs)
+	}
+	if err := RunScheduled(ctx, work(nil), testOptions(client, "pod-3")...); err == nil {
+		t.Fatal("RunScheduled outside a Job succeeded")
+	}
+
+	s, err := Status(ctx, client, testNamespace, ConfigMapBackend, []string{"job-1-run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := s["job-1-run"]; status.Held || status.Completed.IsZero() {
+		t.Fatalf("status of the completed lock = %+v", status)
+	}
+}
@@ -1,17 +1,16 @@
 package leader
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/labstack/gommon/log"
 	v1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -21,9 +20,19 @@ const (
 	// which is the name of the current pod.
 	PodNameEnvVar = "POD_NAME"
 
-	// maxBackoffInterval defines the maximum amount of time to wait between
-	// attempts to become the leader.
-	maxBackoffInterval = time.Second * 16
+	// initialBackoffInterval is the first wait between attempts to become
+	// the leader, and what the wait is reset to when the lock or its holder
+	// is seen to go away.
+	initialBackoffInterval = time.Second
+
+	// defaultMaxBackoffInterval defines the default maximum amount of time
+	// to wait between attempts to become the leader.
+	defaultMaxBackoffInterval = time.Second * 16
+
+	// Files mounted into every pod for its service account.
+	defaultNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	defaultTokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultCAFile        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
 )
 
 // Become ensures that the current pod is the leader within its namespace. If
@@ -32,150 +41,162 @@ const (
 // current pod set as the owner reference. Only one can exist at a time with
 // the same name, so the pod that successfully creates the ConfigMap is the
 // leader. Upon termination of that pod, the garbage collector will delete the
-// ConfigMap, enabling a different pod to become the leader.
-func Become(lockName string) error {
-	log.Info("Trying to become the leader.")
+// ConfigMap, enabling a different pod to become the leader. WithBackend
+// selects a Lease as the lock instead.
+//
+// Become may be called concurrently. Calls for the same lock share one
+// election and all return its result; once it is won, further calls return
+// at once while the process leads. Calls for different locks run
+// independent elections.
+func Become(lockName string, opts ...Option) error {
+	return becomeShared(context.Background(), lockName, opts)
+}
 
-	ns, err := getNamespace()
-	if err != nil {
-		return err
-	}
+// BecomeAsync is Become without blocking the caller. The returned channel
+// delivers nil once the current pod is the leader, or the error that ended
+// the attempt, and is then closed. Cancel ctx to stop trying. This lets an
+// application serve read-only traffic while it waits and switch to active
+// mode when the channel delivers.
+func BecomeAsync(ctx context.Context, lockName string, opts ...Option) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		done <- becomeShared(ctx, lockName, opts)
+	}()
+	return done
+}
 
-	conf, err := rest.InClusterConfig()
-	if err != nil {
-		return err
+func myOwnerRef(myPod *v1.Pod) *metav1.OwnerReference {
+	return &metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       myPod.ObjectMeta.Name,
+		UID:        myPod.ObjectMeta.UID,
 	}
+}
+
+func isPodEvicted(pod *v1.Pod) bool {
+	podFailed := pod.Status.Phase == v1.PodFailed
+	podEvicted := pod.Status.Reason == "Evicted"
+	return podFailed && podEvicted
+}
 
-	client := kubernetes.NewForConfigOrDie(conf)
+func getMyPod(ctx context.Context, client kubernetes.Interface, ns, podName string, logger Logger) (*v1.Pod, error) {
+	if podName == "" {
+		return nil, fmt.Errorf("required env %s not set, please configure downward API", PodNameEnvVar)
+	}
 
-	owner, err := myOwnerRef(client, ns)
+	pod, err := client.CoreV1().Pods(ns).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
-		return err
+		logger.Error(err, "Failed to get Pod", "namespace", ns, "pod", podName)
+		return nil, fmt.Errorf("get pod %s/%s: %w", ns, podName, err)
 	}
 
-	existing, err := client.CoreV1().ConfigMaps(ns).Get(lockName, metav1.GetOptions{})
+	return pod, nil
+}
 
-	switch {
-	case err == nil:
-		for _, existingOwner := range existing.GetOwnerReferences() {
-			if existingOwner.Name == owner.Name {
-				log.Info("Found existing lock with my name. I was likely restarted.")
-				log.Info("Continuing as the leader.")
-				return nil
-			}
+// clientAndNamespace returns the client and namespace configured in o,
+// falling back to the in-cluster defaults.
+func clientAndNamespace(o *options) (kubernetes.Interface, string, error) {
+	ns := o.namespace
+	if ns == "" {
+		var err error
+		ns, err = cachedNamespace(o.namespaceFile)
+		if err != nil {
+			return nil, "", err
+		}
+	}
 
-			log.Info("Found existing lock", "LockOwner", existingOwner.Name)
+	client := o.client
+	if client != nil && o.dryRun {
+		return nil, "", fmt.Errorf("a dry run cannot be made with a client injected by WithClient, inject its config with WithRESTConfig instead")
+	}
+	if client == nil {
+		conf, err := restConfig(o)
+		if err != nil {
+			return nil, "", err
 		}
-	case apierrors.IsNotFound(err):
-		log.Info("No pre-existing lock was found.")
-	default:
-		log.Error(err, "Unknown error trying to get ConfigMap")
-		return err
-	}
-
-	cm := &v1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:            lockName,
-			Namespace:       ns,
-			OwnerReferences: []metav1.OwnerReference{*owner},
-		},
-	}
-
-	// try to create a lock
-	backoff := time.Second
-	for {
-		_, err := client.CoreV1().ConfigMaps(ns).Create(cm)
-		switch {
-		case err == nil:
-			log.Info("Became the leader.")
-			return nil
-		case apierrors.IsAlreadyExists(err):
-			existingOwners := existing.GetOwnerReferences()
-			switch {
-			case len(existingOwners) != 1:
-				log.Info("Leader lock configmap must have exactly one owner reference.", "ConfigMap", existing)
-
-			case existingOwners[0].Kind != "Pod":
-				log.Info("Leader lock configmap owner reference must be a pod.", "OwnerReference", existingOwners[0])
-
-			default:
-				leaderPod, err := client.CoreV1().Pods(ns).Get(existingOwners[0].Name, metav1.GetOptions{})
-				switch {
-				case apierrors.IsNotFound(err):
-					log.Info("Leader pod has been deleted, waiting for garbage collection do remove the lock.")
-				case err != nil:
-					return err
-				case isPodEvicted(leaderPod) && leaderPod.GetDeletionTimestamp() == nil:
-					log.Info("pod with leader lock has been evicted.", "leader", leaderPod.Name)
-					log.Info("Deleting evicted leader.")
-					err := client.CoreV1().Pods(ns).Delete(leaderPod.Name, &metav1.DeleteOptions{})
-					if err != nil {
-						log.Error(err, "Leader pod could not be deleted.")
-					}
-				default:
-					log.Info("Not the leader. Waiting.")
-				}
-			}
-
-			select {
-			case <-time.After(wait.Jitter(backoff, .2)):
-				if backoff < maxBackoffInterval {
-					backoff *= 2
-				}
-				continue
-			}
-
-		default:
-			log.Error(err, "Unknown error creating ConfigMap")
-			return err
+		client, err = kubernetes.NewForConfig(conf)
+		if err != nil {
+			return nil, "", fmt.Errorf("build client: %w", err)
 		}
 	}
+
+	return client, ns, nil
 }
 
-func myOwnerRef(client *kubernetes.Clientset, ns string) (*metav1.OwnerReference, error) {
-	myPod, err := getMyPod(client, ns)
-	if err != nil {
-		return nil, err
+// restConfig returns the injected config, or the in-cluster one, with the
+// impersonation and proxy options applied, sending writes as dry runs with
+// WithDryRun and, at DebugLevel, recording its calls for Dump.
+func restConfig(o *options) (*rest.Config, error) {
+	var conf *rest.Config
+	if o.restConfig != nil {
+		conf = rest.CopyConfig(o.restConfig)
+	} else {
+		var err error
+		conf, err = inClusterConfig(o)
+		if err != nil {
+			return nil, fmt.Errorf("load in-cluster config: %w", err)
+		}
 	}
 
-	owner := &metav1.OwnerReference{
-		APIVersion: "v1",
-		Kind:       "Pod",
-		Name:       myPod.ObjectMeta.Name,
-		UID:        myPod.ObjectMeta.UID,
+	if o.impersonate != nil {
+		conf.Impersonate = *o.impersonate
 	}
-
-	return owner, nil
-}
-
-func isPodEvicted(pod *v1.Pod) bool {
-	podFailed := pod.Status.Phase == v1.PodFailed
-	podEvicted := pod.Status.Reason == "Evicted"
-	return podFailed && podEvicted
+	if o.proxy != nil {
+		conf.Proxy = o.proxy
+	}
+	if o.userAgent != "" {
+		conf.UserAgent = o.userAgent
+	}
+	if o.priorityGroup != "" {
+		if err := addPriorityGroup(conf, o.priorityGroup); err != nil {
+			return nil, err
+		}
+	}
+	if o.dryRun {
+		dryRun(conf, o.getLogger())
+	}
+	if o.logLevel == DebugLevel {
+		if o.calls == nil {
+			o.calls = newCallLog(callLogSize)
+		}
+		recordCalls(conf, o.calls)
+	}
+	return conf, nil
 }
 
-func getMyPod(client *kubernetes.Clientset, ns string) (*v1.Pod, error) {
-	podName := os.Getenv(PodNameEnvVar)
-	if podName == "" {
-		return nil, fmt.Errorf("required env %s not set, please configure downward API", PodNameEnvVar)
+// inClusterConfig is rest.InClusterConfig reading the token and CA bundle
+// from the configured paths.
+func inClusterConfig(o *options) (*rest.Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, rest.ErrNotInCluster
 	}
 
-	pod, err := client.CoreV1().Pods(ns).Get(podName, metav1.GetOptions{})
+	token, err := ioutil.ReadFile(o.tokenFile)
 	if err != nil {
-		log.Errorf("Failed to get Pod, Pod.Namespace %s, Pod.Name %s", ns, podName)
 		return nil, err
 	}
 
-	return pod, err
+	conf := &rest.Config{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		BearerToken:     string(token),
+		BearerTokenFile: o.tokenFile,
+	}
+	if _, err := os.Stat(o.caFile); err == nil {
+		conf.TLSClientConfig.CAFile = o.caFile
+	}
+	return conf, nil
 }
 
-func getNamespace() (string, error) {
-	nsBytes, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+func getNamespace(path string) (string, error) {
+	nsBytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", fmt.Errorf("namespace not found for current environment")
+			return "", fmt.Errorf("namespace not found for current environment: %w", err)
 		}
-		return "", err
+		return "", fmt.Errorf("read namespace: %w", err)
 	}
 	ns := strings.TrimSpace(string(nsBytes))
 
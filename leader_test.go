@@ -0,0 +1,143 @@
+package leader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestRestConfig(t *testing.T) {
+	proxyURL, err := url.Parse("http://proxy.example.com:3128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name        string
+		opts        []Option
+		impersonate string
+		proxied     bool
+	}{
+		{name: "as configured"},
+		{
+			name:        "impersonation",
+			opts:        []Option{WithImpersonation(rest.ImpersonationConfig{UserName: "system:serviceaccount:ops:elector"})},
+			impersonate: "system:serviceaccount:ops:elector",
+		},
+		{name: "proxy", opts: []Option{WithProxy(http.ProxyURL(proxyURL))}, proxied: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base := &rest.Config{Host: "https://apiserver.example.com"}
+			o := defaultOptions()
+			for _, opt := range append([]Option{WithRESTConfig(base)}, tc.opts...) {
+				opt(&o)
+			}
+
+			conf, err := restConfig(&o)
+			if err != nil {
+				t.Fatalf("restConfig: %v", err)
+			}
+			if conf == base {
+				t.Fatal("restConfig returned the injected config rather than a copy")
+			}
+			if conf.Host != base.Host {
+				t.Fatalf("Host = %q, want %q", conf.Host, base.Host)
+			}
+			if conf.Impersonate.UserName != tc.impersonate {
+				t.Fatalf("impersonating %q, want %q", conf.Impersonate.UserName, tc.impersonate)
+			}
+			if proxied := conf.Proxy != nil; proxied != tc.proxied {
+				t.Fatalf("proxied = %v, want %v", proxied, tc.proxied)
+			}
+			if tc.proxied {
+				req, _ := http.NewRequest(http.MethodGet, base.Host, nil)
+				if got, err := conf.Proxy(req); err != nil || got.String() != proxyURL.String() {
+					t.Fatalf("proxy = %v, %v, want %v", got, err, proxyURL)
+				}
+			}
+			if base.Impersonate.UserName != "" || base.Proxy != nil {
+				t.Fatal("restConfig modified the injected config")
+			}
+		})
+	}
+}
+
+func TestInClusterConfigPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"namespace": "apps\n", "token": "secret", "ca.crt": "bundle"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, env := range []string{"KUBERNETES_SERVICE_HOST", "KUBERNETES_SERVICE_PORT"} {
+		old, ok := os.LookupEnv(env)
+		if ok {
+			defer os.Setenv(env, old)
+		} else {
+			defer os.Unsetenv(env)
+		}
+	}
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	os.Setenv("KUBERNETES_SERVICE_PORT", "443")
+
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		token   string
+		caFile  string
+		wantErr bool
+	}{
+		{
+			name:   "configured paths",
+			opts:   []Option{WithTokenFile(filepath.Join(dir, "token")), WithCAFile(filepath.Join(dir, "ca.crt"))},
+			token:  "secret",
+			caFile: filepath.Join(dir, "ca.crt"),
+		},
+		{
+			name:  "missing CA bundle",
+			opts:  []Option{WithTokenFile(filepath.Join(dir, "token")), WithCAFile(filepath.Join(dir, "missing"))},
+			token: "secret",
+		},
+		{name: "missing token", opts: []Option{WithTokenFile(filepath.Join(dir, "missing"))}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := defaultOptions()
+			for _, opt := range tc.opts {
+				opt(&o)
+			}
+			conf, err := inClusterConfig(&o)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("inClusterConfig without a token succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inClusterConfig: %v", err)
+			}
+			if conf.Host != "https://10.0.0.1:443" {
+				t.Fatalf("Host = %q", conf.Host)
+			}
+			if conf.BearerToken != tc.token || conf.TLSClientConfig.CAFile != tc.caFile {
+				t.Fatalf("token %q and CA file %q, want %q and %q", conf.BearerToken, conf.TLSClientConfig.CAFile, tc.token, tc.caFile)
+			}
+		})
+	}
+
+	t.Run("namespace file", func(t *testing.T) {
+		o := defaultOptions()
+		WithNamespaceFile(filepath.Join(dir, "namespace"))(&o)
+		ns, err := getNamespace(o.namespaceFile)
+		if err != nil || ns != "apps" {
+			t.Fatalf("getNamespace = %q, %v, want apps", ns, err)
+		}
+	})
+}
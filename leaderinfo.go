@@ -0,0 +1,92 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// LeaderInfo is the record published by WithLeaderInfo. Its JSON form is
+// stable, unlike the format of the lock object.
+type LeaderInfo struct {
+	Lock    string `json:"lock"`
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	Epoch   int64  `json:"epoch,omitempty"`
+}
+
+// publishLeaderInfo is the transition hook mirroring the leader into the
+// configured ConfigMap key. On losing leadership the key is cleared, unless
+// a new leader has already overwritten it.
+func (e *PodElector) publishLeaderInfo(leading bool) {
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+
+	var value interface{}
+	if leading {
+		address := e.address
+		if address == "" {
+			address = e.podIP
+		}
+		info, err := json.Marshal(LeaderInfo{
+			Lock:    e.lockName,
+			Name:    e.owner.Name,
+			Address: address,
+			Epoch:   e.Epoch(),
+		})
+		if err != nil {
+			e.log.Error(err, "Failed to encode leader info")
+			return
+		}
+		value = string(info)
+	} else {
+		current, err := e.leaderInfo(ctx)
+		if err != nil || current.Name != e.owner.Name {
+			return
+		}
+	}
+
+	if err := e.patchLeaderInfo(ctx, value); err != nil {
+		e.log.Error(err, "Failed to publish leader info", "configMap", e.opts.leaderInfoName, "key", e.opts.leaderInfoKey)
+	}
+}
+
+func (e *PodElector) leaderInfo(ctx context.Context) (LeaderInfo, error) {
+	var info LeaderInfo
+	cm, err := e.kube().CoreV1().ConfigMaps(e.ns).Get(ctx, e.opts.leaderInfoName, metav1.GetOptions{})
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal([]byte(cm.Data[e.opts.leaderInfoKey]), &info)
+	return info, err
+}
+
+// patchLeaderInfo sets, or with a nil value removes, our key in the
+// ConfigMap, creating the ConfigMap if needed. Other keys are left alone as
+// the ConfigMap may be shared.
+func (e *PodElector) patchLeaderInfo(ctx context.Context, value interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{e.opts.leaderInfoKey: value},
+	})
+	if err != nil {
+		return err
+	}
+
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	_, err = configMaps.Patch(ctx, e.opts.leaderInfoName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) || value == nil {
+		return err
+	}
+	_, err = configMaps.Create(ctx, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: e.opts.leaderInfoName, Namespace: e.ns},
+		Data:       map[string]string{e.opts.leaderInfoKey: value.(string)},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Patch(ctx, e.opts.leaderInfoName, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
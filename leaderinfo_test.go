@@ -0,0 +1,81 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// publishedInfo returns the data of the leader-info ConfigMap.
+func publishedInfo(t *testing.T, client *fake.Clientset) map[string]string {
+	t.Helper()
+	cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "leader-info", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cm.Data
+}
+
+func TestPublishLeaderInfo(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// shared is whether the ConfigMap exists with another key
+		shared bool
+		// overwritten is whether the next leader has published itself
+		// before we clear the key
+		overwritten bool
+	}{
+		{name: "new ConfigMap"},
+		{name: "shared ConfigMap", shared: true},
+		{name: "overwritten by the next leader", overwritten: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			setPodIP(t, client, "pod-1", "10.0.0.1")
+			if tc.shared {
+				cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "leader-info", Namespace: testNamespace}, Data: map[string]string{"other": "kept"}}
+				if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(context.Background(), cm, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1", WithLeaderInfo("leader-info", "current"))
+			if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+
+			var info LeaderInfo
+			if err := json.Unmarshal([]byte(publishedInfo(t, client)["current"]), &info); err != nil {
+				t.Fatalf("published info: %v", err)
+			}
+			if want := (LeaderInfo{Lock: testLock, Name: "pod-1", Address: "10.0.0.1", Epoch: e.Epoch()}); info != want {
+				t.Fatalf("published %+v, want %+v", info, want)
+			}
+
+			next := `{"lock":"test-lock","name":"pod-2"}`
+			if tc.overwritten {
+				if err := e.patchLeaderInfo(context.Background(), next); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := e.Release(context.Background()); err != nil {
+				t.Fatalf("Release: %v", err)
+			}
+
+			data := publishedInfo(t, client)
+			current, published := data["current"]
+			switch {
+			case tc.overwritten && current != next:
+				t.Fatalf("info of the next leader replaced by %q", current)
+			case !tc.overwritten && published:
+				t.Fatalf("info left behind after Release: %q", current)
+			}
+			if tc.shared && data["other"] != "kept" {
+				t.Fatalf("other keys of the ConfigMap = %v", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LockGroup is an ordered list of locks, such as primary and secondary,
+// of which a pod holds at most one. Candidates try the locks in order and
+// fall back to the next when one is held, which gives tiered active and
+// standby topologies where each lock stands for a distinct role.
+type LockGroup struct {
+	electors []*PodElector
+	log      Logger
+	backoff  time.Duration
+}
+
+// NewLockGroup returns a LockGroup over names, highest priority first. The
+// options apply to every lock.
+func NewLockGroup(names []string, opts ...Option) (*LockGroup, error) {
+	if len(names) == 0 {
+		return nil, errors.New("lock group needs at least one lock")
+	}
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	id, err := resolveIdentity(&o)
+	if err != nil {
+		return nil, err
+	}
+	g := &LockGroup{log: o.getLogger(), backoff: o.maxBackoff}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("lock %s is listed twice in the group", name)
+		}
+		seen[name] = true
+		e, err := newElector(name, o, id)
+		if err != nil {
+			return nil, err
+		}
+		g.electors = append(g.electors, e)
+	}
+	return g, nil
+}
+
+// Become blocks until the current pod holds one of the group's locks, the
+// first free one in order, or ctx is cancelled. It returns the Elector of
+// the lock taken, whose name tells the role to play. As with Become, the
+// lock is maintained until ctx is cancelled.
+func (g *LockGroup) Become(ctx context.Context) (*PodElector, error) {
+	if e := g.Held(); e != nil {
+		return e, nil
+	}
+
+	backoff := initialBackoffInterval
+	for {
+		for _, e := range g.electors {
+			ok, err := e.TryAcquire(ctx)
+			switch {
+			case ok:
+				g.log.Info("Became the leader in the lock group", "lock", e.lockName)
+				e.startMaintenance(ctx)
+				return e, nil
+			case err != nil && !e.retryable(ctx, err):
+				return nil, err
+			}
+		}
+
+		g.log.Info("Every lock of the group is held. Waiting", "locks", len(g.electors))
+		if _, err := sleepOrWake(ctx, g.electors[0].opts.random().jitter(backoff, .2), nil); err != nil {
+			return nil, err
+		}
+		if backoff < g.backoff {
+			backoff *= 2
+		}
+	}
+}
+
+// Held returns the Elector of the lock we hold, or nil.
+func (g *LockGroup) Held() *PodElector {
+	for _, e := range g.electors {
+		if e.IsLeader() {
+			return e
+		}
+	}
+	return nil
+}
+
+// Resign gives up the lock we hold, if any.
+func (g *LockGroup) Resign(ctx context.Context) error {
+	e := g.Held()
+	if e == nil {
+		return ErrNotLeader
+	}
+	return e.Resign(ctx)
+}
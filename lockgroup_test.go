@@ -0,0 +1,85 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestLockGroup(t *testing.T, client *fake.Clientset, pod string, names ...string) *LockGroup {
+	t.Helper()
+	g, err := NewLockGroup(names, WithClient(client), WithNamespace(testNamespace), WithPodName(pod), WithLogLevel(ErrorLevel))
+	if err != nil {
+		t.Fatalf("NewLockGroup: %v", err)
+	}
+	return g
+}
+
+func TestNewLockGroup(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	for _, tc := range []struct {
+		name  string
+		locks []string
+		ok    bool
+	}{
+		{name: "ordered locks", locks: []string{"primary", "secondary"}, ok: true},
+		{name: "no locks"},
+		{name: "listed twice", locks: []string{"primary", "secondary", "primary"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewLockGroup(tc.locks, WithClient(client), WithNamespace(testNamespace), WithPodName("pod-1"), WithLogLevel(ErrorLevel))
+			if (err == nil) != tc.ok {
+				t.Fatalf("NewLockGroup = %v, want success %v", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestLockGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newTestClient(t, "pod-1", "pod-2", "pod-3")
+	locks := []string{"primary", "secondary"}
+	groups := map[string]*LockGroup{}
+	for _, pod := range []string{"pod-1", "pod-2", "pod-3"} {
+		groups[pod] = newTestLockGroup(t, client, pod, locks...)
+	}
+
+	if err := groups["pod-1"].Resign(ctx); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("Resign without a lock = %v, want ErrNotLeader", err)
+	}
+	for _, take := range []struct{ pod, want string }{{"pod-1", "primary"}, {"pod-2", "secondary"}} {
+		pod, want := take.pod, take.want
+		e, err := groups[pod].Become(ctx)
+		if err != nil {
+			t.Fatalf("Become of %s: %v", pod, err)
+		}
+		if e.lockName != want || groups[pod].Held() != e {
+			t.Fatalf("%s took %s, want %s", pod, e.lockName, want)
+		}
+		// a held lock is returned again
+		if again, err := groups[pod].Become(ctx); err != nil || again != e {
+			t.Fatalf("Become again of %s = %v, %v", pod, again, err)
+		}
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer waitCancel()
+	if _, err := groups["pod-3"].Become(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Become with every lock held = %v, want to wait", err)
+	}
+
+	if err := groups["pod-1"].Resign(ctx); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+	e, err := groups["pod-3"].Become(ctx)
+	if err != nil {
+		t.Fatalf("Become after Resign: %v", err)
+	}
+	if e.lockName != "primary" {
+		t.Fatalf("pod-3 took %s, want the freed primary", e.lockName)
+	}
+}
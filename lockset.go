@@ -0,0 +1,118 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// LockSet is a set of locks held together, as acquired by BecomeAll.
+type LockSet struct {
+	electors []*PodElector
+}
+
+// BecomeAll blocks until the current pod holds every lock in names, or ctx
+// is cancelled. The locks are always taken in sorted order, and whenever one
+// of them cannot be taken those already taken are released before backing
+// off, so the pod never holds part of the set while it waits. Two
+// controllers needing overlapping sets therefore cannot deadlock, whatever
+// order they list the names in. The options apply to every lock. As with
+// Become, each lock is maintained until ctx is cancelled, so IsLeader turns
+// false once any of them is lost.
+func BecomeAll(ctx context.Context, names []string, opts ...Option) (*LockSet, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	id, err := resolveIdentity(&o)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var sorted []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	set := &LockSet{}
+	for _, name := range sorted {
+		e, err := newElector(name, o, id)
+		if err != nil {
+			return nil, err
+		}
+		set.electors = append(set.electors, e)
+	}
+
+	log := o.getLogger()
+	log.Info("Trying to become the leader of a lock set", "locks", sorted)
+	backoff := initialBackoffInterval
+	for {
+		held, err := set.tryAcquireAll(ctx)
+		if held == len(set.electors) {
+			log.Info("Became the leader of the lock set", "locks", sorted)
+			for _, e := range set.electors {
+				e.startMaintenance(ctx)
+			}
+			return set, nil
+		}
+
+		if err := set.Release(ctx); err != nil {
+			log.Error(err, "Failed to release partially acquired lock set", "locks", sorted)
+		}
+		if err != nil && !set.electors[held].retryable(ctx, err) {
+			return nil, err
+		}
+		log.Info("Lock set is not free, releasing and waiting", "held", held, "locks", len(set.electors))
+
+		if _, err := sleepOrWake(ctx, o.random().jitter(backoff, .2), nil); err != nil {
+			return nil, err
+		}
+		if backoff < o.maxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// tryAcquireAll takes the locks in order and stops at the first that
+// cannot be taken. It returns how many were taken.
+func (s *LockSet) tryAcquireAll(ctx context.Context) (int, error) {
+	for i, e := range s.electors {
+		ok, err := e.TryAcquire(ctx)
+		if err != nil || !ok {
+			return i, err
+		}
+	}
+	return len(s.electors), nil
+}
+
+// Electors returns the Electors of the set's locks, in acquisition order.
+func (s *LockSet) Electors() []*PodElector {
+	return append([]*PodElector(nil), s.electors...)
+}
+
+// IsLeader reports whether every lock of the set is believed held.
+func (s *LockSet) IsLeader() bool {
+	for _, e := range s.electors {
+		if !e.IsLeader() {
+			return false
+		}
+	}
+	return true
+}
+
+// Release gives up every lock of the set that is held, in reverse order.
+func (s *LockSet) Release(ctx context.Context) error {
+	var firstErr error
+	for i := len(s.electors) - 1; i >= 0; i-- {
+		if err := s.electors[i].Release(ctx); err != nil && !errors.Is(err, ErrNotLeader) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,76 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestBecomeAll(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// fail is returned once by the creation of lock-b
+		fail    error
+		held    bool
+		wantErr func(error) bool
+	}{
+		{name: "free"},
+		{name: "throttled", fail: apierrors.NewTooManyRequests("slow down", 0)},
+		{name: "forbidden", fail: apierrors.NewForbidden(v1.Resource("configmaps"), "lock-b", errors.New("no")), wantErr: apierrors.IsForbidden},
+		{name: "held by another pod", held: true, wantErr: func(err error) bool { return errors.Is(err, context.DeadlineExceeded) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			if tc.held {
+				other := newTestElectorOf(t, client, "lock-b", "pod-2")
+				if ok, err := other.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+			var once sync.Once
+			client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (handled bool, _ runtime.Object, err error) {
+				if tc.fail == nil || action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName() != "lock-b" {
+					return false, nil, nil
+				}
+				once.Do(func() { handled, err = true, tc.fail })
+				return handled, nil, err
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			if tc.wantErr != nil {
+				ctx, cancel = context.WithTimeout(ctx, 200*time.Millisecond)
+				defer cancel()
+			}
+			set, err := BecomeAll(ctx, []string{"lock-b", "lock-a", "lock-b"},
+				WithClient(client), WithNamespace(testNamespace), WithPodName("pod-1"), WithLogLevel(ErrorLevel))
+			if tc.wantErr != nil {
+				if !tc.wantErr(err) {
+					t.Fatalf("BecomeAll = %v", err)
+				}
+				// nothing is kept of a set that was not taken
+				if _, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), "lock-a", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+					t.Fatalf("lock-a left held: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BecomeAll: %v", err)
+			}
+			if len(set.Electors()) != 2 || set.Electors()[0].lockName != "lock-a" || !set.IsLeader() {
+				t.Fatalf("set of %d locks, leader %v", len(set.Electors()), set.IsLeader())
+			}
+			if err := set.Release(context.Background()); err != nil {
+				t.Fatalf("Release: %v", err)
+			}
+		})
+	}
+}
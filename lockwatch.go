@@ -0,0 +1,103 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fieldsel "k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// lockRewatchInterval is how long to wait before re-establishing a watch on
+// the lock that failed or ended.
+const lockRewatchInterval = time.Second * 2
+
+// watchLock watches the lock object while we wait for it, and wakes the
+// election loop the moment it is deleted or starts being deleted, so that
+// a candidate creates it again right after garbage collection removes it
+// rather than when its backoff next expires. Together with the informer on
+// the leader's pod, this makes failover take as long as garbage collection
+// does. A watch that ended is resumed from the last resourceVersion seen, so
+// nothing that happened in between is missed. It runs until ctx is
+// cancelled.
+func (e *PodElector) watchLock(ctx context.Context) {
+	selector := fieldsel.OneTermEqualSelector("metadata.name", e.lockName).String()
+	watchLocks := func(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+		opts := metav1.ListOptions{FieldSelector: selector, ResourceVersion: resourceVersion}
+		if backendKind(e.lockBackend()) == ConfigMapBackend {
+			return e.kube().CoreV1().ConfigMaps(e.ns).Watch(ctx, opts)
+		}
+		return e.kube().CoordinationV1().Leases(e.ns).Watch(ctx, opts)
+	}
+
+	e.background.Add(1)
+	go func() {
+		defer e.background.Done()
+		resourceVersion := ""
+		for ctx.Err() == nil {
+			w, err := watchLocks(ctx, resourceVersion)
+			if err != nil {
+				if ctx.Err() == nil {
+					e.log.Error(err, "Failed to watch the lock", "lock", e.lockName)
+				}
+				resourceVersion = ""
+				if e.sleep(ctx, lockRewatchInterval) != nil {
+					return
+				}
+				continue
+			}
+			for ev := range w.ResultChan() {
+				lock, ok := ev.Object.(metav1.Object)
+				if !ok || ev.Type == watch.Error {
+					// the watch expired, start over from the current state
+					resourceVersion = ""
+					continue
+				}
+				resourceVersion = lock.GetResourceVersion()
+				switch {
+				case ev.Type == watch.Deleted:
+					e.log.Debug("Lock was deleted", "lock", e.lockName)
+					e.noteFailover(time.Now())
+					e.wake()
+				case lock.GetDeletionTimestamp() != nil:
+					e.noteFailover(lock.GetDeletionTimestamp().Time)
+					e.wake()
+				}
+			}
+			w.Stop()
+			if e.sleep(ctx, lockRewatchInterval) != nil {
+				return
+			}
+		}
+	}()
+}
+
+// noteFailover records that the leader we waited for started to go at t,
+// unless an earlier sign was recorded already.
+func (e *PodElector) noteFailover(t time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failoverStart.IsZero() || t.Before(e.failoverStart) {
+		e.failoverStart = t
+	}
+}
+
+// clearFailover forgets a failover in progress, once a live leader is seen.
+func (e *PodElector) clearFailover() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failoverStart = time.Time{}
+}
+
+// observeFailover records, once we acquired the lock, how long it took from
+// the first sign of the former leader going to our taking over.
+func (e *PodElector) observeFailover() {
+	e.mu.Lock()
+	start := e.failoverStart
+	e.failoverStart = time.Time{}
+	e.mu.Unlock()
+	if !start.IsZero() {
+		failoverSecondsHistogram.WithLabelValues(e.lockName).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,122 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWatchLock(t *testing.T) {
+	deleting := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	for _, tc := range []struct {
+		name     string
+		backend  Backend
+		resource string
+		lock     func(deleting *metav1.Time) runtime.Object
+	}{
+		{
+			name:     "ConfigMaps",
+			backend:  ConfigMapBackend,
+			resource: "configmaps",
+			lock: func(deleting *metav1.Time) runtime.Object {
+				return &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: testLock, Namespace: testNamespace, DeletionTimestamp: deleting}}
+			},
+		},
+		{
+			name:     "Leases",
+			backend:  LeaseBackend,
+			resource: "leases",
+			lock: func(deleting *metav1.Time) runtime.Object {
+				return &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: testLock, Namespace: testNamespace, DeletionTimestamp: deleting}}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			w := watch.NewFake()
+			client.PrependWatchReactor(tc.resource, func(action k8stesting.Action) (bool, watch.Interface, error) {
+				return true, w, nil
+			})
+			e := newTestElector(t, client, "pod-1", WithBackend(tc.backend), WithFailoverWatch())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer func() {
+				cancel()
+				w.Stop()
+				e.background.Wait()
+			}()
+			e.watchLock(ctx)
+
+			for i, ev := range []struct {
+				event    watch.EventType
+				deleting bool
+				wake     bool
+			}{
+				{event: watch.Modified},
+				{event: watch.Modified, deleting: true, wake: true},
+				{event: watch.Deleted, wake: true},
+			} {
+				var ts *metav1.Time
+				if ev.deleting {
+					ts = &deleting
+				}
+				w.Action(ev.event, tc.lock(ts))
+
+				wait := 20 * time.Millisecond
+				if ev.wake {
+					wait = time.Second
+				}
+				select {
+				case <-e.woken:
+					if !ev.wake {
+						t.Fatalf("event %d: woken by a %s lock event", i, ev.event)
+					}
+				case <-time.After(wait):
+					if ev.wake {
+						t.Fatalf("event %d: not woken by a %s lock event", i, ev.event)
+					}
+				}
+			}
+
+			// the failover is timed from the first sign of the leader going
+			e.mu.Lock()
+			start := e.failoverStart
+			e.mu.Unlock()
+			if !start.Equal(deleting.Time) {
+				t.Fatalf("failover started at %v, want the deletion timestamp %v", start, deleting.Time)
+			}
+		})
+	}
+}
+
+func TestFailoverTiming(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+	started := func() time.Time {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return e.failoverStart
+	}
+
+	early := time.Now().Add(-time.Minute)
+	e.noteFailover(early)
+	e.noteFailover(time.Now())
+	if got := started(); !got.Equal(early) {
+		t.Fatalf("failover started at %v, want the earliest sign %v", got, early)
+	}
+	e.clearFailover()
+	if got := started(); !got.IsZero() {
+		t.Fatalf("failover started at %v after a live leader was seen", got)
+	}
+	e.noteFailover(early)
+	e.observeFailover()
+	if got := started(); !got.IsZero() {
+		t.Fatalf("failover started at %v after it was observed", got)
+	}
+}
@@ -0,0 +1,114 @@
+package leader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+)
+
+// Logger is the leveled, structured logger the package writes to. Messages
+// are constant strings; variable data goes in alternating key and value
+// arguments.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(err error, msg string, keysAndValues ...interface{})
+}
+
+// LogLevel is the minimum level the default logger writes.
+type LogLevel uint8
+
+const (
+	DebugLevel LogLevel = LogLevel(log.DEBUG)
+	InfoLevel  LogLevel = LogLevel(log.INFO)
+	WarnLevel  LogLevel = LogLevel(log.WARN)
+	ErrorLevel LogLevel = LogLevel(log.ERROR)
+)
+
+// defaultLogger is used where no Elector options are at hand.
+var defaultLogger = NewLogger(InfoLevel, false)
+
+// gommonLogger is the default Logger. In text mode keys and values are
+// appended to the message as key=value pairs; in JSON mode each becomes a
+// field of the JSON record.
+type gommonLogger struct {
+	l    *log.Logger
+	json bool
+}
+
+// NewLogger returns the package's default Logger writing to stdout at level.
+// With json set, every record is a single JSON object.
+func NewLogger(level LogLevel, json bool) Logger {
+	l := log.New("leader")
+	l.SetLevel(log.Lvl(level))
+	if !json {
+		l.SetHeader("${time_rfc3339} ${level} ${prefix}")
+	}
+	return &gommonLogger{l: l, json: json}
+}
+
+func (g *gommonLogger) Debug(msg string, keysAndValues ...interface{}) {
+	if g.json {
+		g.l.Debugj(fields(msg, nil, keysAndValues))
+		return
+	}
+	g.l.Debug(text(msg, nil, keysAndValues))
+}
+
+func (g *gommonLogger) Info(msg string, keysAndValues ...interface{}) {
+	if g.json {
+		g.l.Infoj(fields(msg, nil, keysAndValues))
+		return
+	}
+	g.l.Info(text(msg, nil, keysAndValues))
+}
+
+func (g *gommonLogger) Warn(msg string, keysAndValues ...interface{}) {
+	if g.json {
+		g.l.Warnj(fields(msg, nil, keysAndValues))
+		return
+	}
+	g.l.Warn(text(msg, nil, keysAndValues))
+}
+
+func (g *gommonLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	if g.json {
+		g.l.Errorj(fields(msg, err, keysAndValues))
+		return
+	}
+	g.l.Error(text(msg, err, keysAndValues))
+}
+
+func fields(msg string, err error, keysAndValues []interface{}) log.JSON {
+	j := log.JSON{"message": msg}
+	if err != nil {
+		j["error"] = err.Error()
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 < len(keysAndValues) {
+			j[key] = fmt.Sprint(keysAndValues[i+1])
+		} else {
+			j[key] = nil
+		}
+	}
+	return j
+}
+
+func text(msg string, err error, keysAndValues []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	if err != nil {
+		fmt.Fprintf(&b, " error=%q", err.Error())
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(&b, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", keysAndValues[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/gommon/log"
+)
+
+func TestText(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		err           error
+		keysAndValues []interface{}
+		want          string
+	}{
+		{name: "message only", want: "Acquired lock"},
+		{name: "keys and values", keysAndValues: []interface{}{"lock", "a", "attempt", 2}, want: "Acquired lock lock=a attempt=2"},
+		{name: "error", err: errors.New("gone away"), keysAndValues: []interface{}{"lock", "a"}, want: `Acquired lock error="gone away" lock=a`},
+		{name: "odd key", keysAndValues: []interface{}{"lock", "a", "dangling"}, want: "Acquired lock lock=a dangling"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := text("Acquired lock", tc.err, tc.keysAndValues); got != tc.want {
+				t.Fatalf("text = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		err           error
+		keysAndValues []interface{}
+		want          log.JSON
+	}{
+		{name: "message only", want: log.JSON{"message": "Acquired lock"}},
+		{name: "keys and values", keysAndValues: []interface{}{"lock", "a", "attempt", 2}, want: log.JSON{"message": "Acquired lock", "lock": "a", "attempt": "2"}},
+		{name: "error", err: errors.New("gone away"), want: log.JSON{"message": "Acquired lock", "error": "gone away"}},
+		{name: "odd key", keysAndValues: []interface{}{"dangling"}, want: log.JSON{"message": "Acquired lock", "dangling": nil}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fields("Acquired lock", tc.err, tc.keysAndValues); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("fields = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		level LogLevel
+		json  bool
+		// lines is the number of records written for one record of each
+		// level
+		lines int
+	}{
+		{name: "info", level: InfoLevel, lines: 3},
+		{name: "error", level: ErrorLevel, lines: 1},
+		{name: "debug JSON", level: DebugLevel, json: true, lines: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := NewLogger(tc.level, tc.json)
+			var out bytes.Buffer
+			logger.(*gommonLogger).l.SetOutput(&out)
+
+			logger.Debug("Debug record", "lock", "a")
+			logger.Info("Info record", "lock", "a")
+			logger.Warn("Warn record", "lock", "a")
+			logger.Error(errors.New("failed"), "Error record", "lock", "a")
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != tc.lines {
+				t.Fatalf("wrote %d records, want %d:\n%s", len(lines), tc.lines, out.String())
+			}
+			for _, line := range lines {
+				if !tc.json {
+					if !strings.Contains(line, "lock=a") {
+						t.Fatalf("text record %q lacks its keys and values", line)
+					}
+					continue
+				}
+				var record map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("record %q is not JSON: %v", line, err)
+				}
+				if record["lock"] != "a" || record["message"] == nil {
+					t.Fatalf("JSON record %v lacks its fields", record)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// startMaintenance runs the maintenance loop in the background unless it is
+// already running.
+func (e *PodElector) startMaintenance(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.maintaining {
+		return
+	}
+	e.maintaining = true
+
+	e.background.Add(1)
+	go func() {
+		defer e.background.Done()
+		e.maintain(ctx)
+
+		e.mu.Lock()
+		e.maintaining = false
+		e.mu.Unlock()
+	}()
+}
+
+// maintain periodically re-reads the lock while we lead. It acts on requests
+// left on the lock by other candidates and notices when the lock has been
+// lost. It returns when leadership ends or ctx is cancelled.
+func (e *PodElector) maintain(ctx context.Context) {
+	for e.IsLeader() {
+		if err := e.nap(ctx, e.untilExpired(e.tuned().maintenanceInterval)); err != nil {
+			return
+		}
+		if e.selfExpired() {
+			e.log.Warn("Lock expired before it could be renewed, no longer the leader", "lock", e.lockName)
+			e.decide("own lock expired", "give up leadership")
+			e.lost()
+			return
+		}
+
+		lock, err := e.getLock(ctx)
+		switch {
+		case apierrors.IsNotFound(err):
+			e.log.Warn("Lock was deleted, no longer the leader", "lock", e.lockName)
+			e.decide("own lock deleted", "give up leadership")
+			e.lost()
+			return
+		case err != nil:
+			if !e.retryable(ctx, err) {
+				e.log.Error(err, "Failed to get lock", "lock", e.lockName)
+			}
+			continue
+		case !e.holds(lock.GetUID()):
+			e.log.Warn("Lock was replaced, no longer the leader", "lock", e.lockName)
+			e.decide("own lock replaced", "give up leadership", "uid", lock.GetUID())
+			e.lost()
+			return
+		}
+		e.verified()
+
+		e.observeLeadershipDuration()
+		e.observeEligibility(lock)
+		e.refreshLabels(ctx)
+		if reason := e.selectedOut(); reason != "" {
+			e.decide("no longer eligible", "hand over", "reason", reason)
+			e.handOverIneligible(ctx, reason)
+			return
+		}
+
+		terminating := lock.GetDeletionTimestamp() != nil
+		if terminating {
+			e.log.Error(nil, "Lock is being deleted while I lead; it is kept by its finalizer", "lock", e.lockName)
+		}
+		lockTerminatingGauge.WithLabelValues(e.lockName).Set(boolGauge(terminating))
+
+		if e.opts.lockHeartbeat {
+			if err := e.beat(ctx); err != nil && !e.retryable(ctx, err) {
+				e.log.Error(err, "Failed to record heartbeat", "lock", e.lockName)
+			}
+		}
+
+		if v, ok := e.lockBackend().(validator); ok {
+			valid, err := v.Validate(ctx, lock)
+			switch {
+			case err != nil:
+				e.log.Error(err, "Failed to validate compatibility lock", "lock", e.lockName)
+			case !valid:
+				e.log.Warn("Compatibility lock is held by another pod, giving up the lock", "lock", e.lockName)
+				e.decide("compatibility lock held elsewhere", "resign")
+				if err := e.Resign(ctx); err != nil {
+					e.log.Error(err, "Failed to resign", "lock", e.lockName)
+					e.lost()
+				}
+				return
+			}
+		}
+
+		if e.opts.registry {
+			e.observeCandidates(ctx)
+		}
+		if e.opts.shards > 0 {
+			e.rebalanceShards(ctx)
+		}
+		if e.opts.janitorInterval > 0 && time.Since(e.swept) >= e.opts.janitorInterval {
+			e.swept = time.Now()
+			j := &Janitor{Client: e.kube(), Namespace: e.ns, Log: e.log, timeout: e.opts.requestTimeout}
+			if _, err := j.Sweep(ctx); err != nil && !e.retryable(ctx, err) {
+				e.log.Error(err, "Failed to sweep orphaned locks", "namespace", e.ns)
+			}
+		}
+
+		if e.opts.healthCheck != nil && e.checkHealth(ctx) {
+			e.decide("health check failing", "demote")
+			e.demote(ctx)
+			return
+		}
+
+		if e.opts.stepDownOnDrain {
+			if reason := e.draining(ctx); reason != "" {
+				e.log.Info("Stepping down", "lock", e.lockName, "reason", reason)
+				e.decide("draining", "step down", "reason", reason)
+				if err := e.Resign(ctx); err != nil {
+					e.log.Error(err, "Failed to resign", "lock", e.lockName)
+				}
+				return
+			}
+		}
+
+		if _, transferring := pendingTransfer(lock); transferring {
+			continue
+		}
+
+		if pinned := e.pinnedElsewhere(ctx); pinned != "" {
+			e.log.Info("Leadership is pinned to another pod, handing over", "lock", e.lockName, "pinned", pinned)
+			e.decide("pinned elsewhere", "transfer", "pinned", pinned)
+			if err := e.TransferTo(ctx, pinned); err != nil {
+				e.log.Error(err, "Failed to hand over to the pinned leader", "lock", e.lockName, "pinned", pinned)
+			}
+			continue
+		}
+
+		if requester, ok := lock.GetAnnotations()[StepDownRequestAnnotation]; ok && requester != "" {
+			e.decide("step-down requested", "step down", "requester", requester)
+			if err := e.stepDown(ctx, requester); err != nil {
+				e.log.Error(err, "Failed to step down", "lock", e.lockName)
+			}
+		}
+	}
+}
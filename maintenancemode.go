@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MaintenanceAnnotation puts the pod it is set on into maintenance mode,
+// whatever its value: the pod does not compete for leadership, and gives it
+// up if it leads. EnterMaintenance sets it to the time maintenance began; it
+// is mirrored on the pod's registry entry, so that the fair queue and
+// eligibility handovers pass over the pod. A pod's electors only notice it
+// being set by others with WithRemoteMaintenance.
+const MaintenanceAnnotation = "leader.seamounts.io/maintenance"
+
+// EnterMaintenance takes our pod out of the leader rotation until
+// ExitMaintenance is called: it resigns if we lead, and keeps any Become in
+// progress from acquiring the lock. The mode is recorded on our pod, so it
+// survives container restarts and shows who is under maintenance; that
+// takes patch on pods, see RequiredRole.
+func (e *PodElector) EnterMaintenance(ctx context.Context) error {
+	value := time.Now().UTC().Format(time.RFC3339)
+	e.setMaintenance(value)
+	e.log.Info("Entering maintenance mode", "lock", e.lockName)
+	e.recordMaintenance(ctx, value)
+
+	if err := e.Resign(ctx); err != nil && !errors.Is(err, ErrNotLeader) {
+		return err
+	}
+	return nil
+}
+
+// ExitMaintenance lets our pod compete for leadership again.
+func (e *PodElector) ExitMaintenance(ctx context.Context) {
+	e.setMaintenance("")
+	e.log.Info("Leaving maintenance mode", "lock", e.lockName)
+	e.recordMaintenance(ctx, "")
+	e.wake()
+}
+
+// InMaintenance reports whether our pod is in maintenance mode.
+func (e *PodElector) InMaintenance() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.maintenance != ""
+}
+
+func (e *PodElector) setMaintenance(value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maintenance = value
+}
+
+// recordMaintenance records the maintenance mode on our pod and registry
+// entry. Failing to is logged only: the mode holds for this process anyway.
+func (e *PodElector) recordMaintenance(ctx context.Context, value string) {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	if err := setMaintenance(ctx, e.kube(), e.ns, e.owner.Name, value); err != nil {
+		e.log.Error(err, "Failed to record maintenance mode on my pod", "pod", e.owner.Name)
+	}
+	if e.opts.registry {
+		if err := e.heartbeat(ctx); err != nil {
+			e.log.Error(err, "Failed to record maintenance mode in the registry", "lock", e.lockName)
+		}
+	}
+}
+
+// SetMaintenance puts the pod podName in ns into maintenance mode, or with
+// on false takes it out, by setting MaintenanceAnnotation on it. Its
+// electors act on it if they use WithRemoteMaintenance.
+func SetMaintenance(ctx context.Context, client kubernetes.Interface, ns, podName string, on bool) error {
+	value := ""
+	if on {
+		value = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := setMaintenance(ctx, client, ns, podName, value); err != nil {
+		return fmt.Errorf("set maintenance mode of pod %s/%s: %w", ns, podName, err)
+	}
+	return nil
+}
+
+// setMaintenance sets MaintenanceAnnotation on the pod to value, or removes
+// it if value is empty.
+func setMaintenance(ctx context.Context, client kubernetes.Interface, ns, podName, value string) error {
+	var annotation interface{}
+	if value != "" {
+		annotation = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{MaintenanceAnnotation: annotation},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Pods(ns).Patch(ctx, podName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return forbidden(err, "patch", v1.Resource("pods"), ns)
+}
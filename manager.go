@@ -0,0 +1,117 @@
+package leader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxLockNameLength keeps lock names usable as label values, which are
+// limited to 63 characters.
+const maxLockNameLength = 63
+
+// Manager holds per-tenant leadership for multi-tenant controllers. Every
+// tenant gets its own lock, but all of them share one client, one lookup of
+// the current pod and one lock backend, so holding leadership for many
+// customers does not multiply connections and startup requests. Sharing the
+// client also makes the tenants' Electors share one informer per leader pod
+// with WithLeaderPodInformer.
+type Manager struct {
+	prefix string
+	opts   options
+	id     *identity
+
+	mu       sync.Mutex
+	electors map[string]*PodElector
+}
+
+// NewManager returns a Manager whose tenant locks are named after prefix.
+// The options apply to every tenant's Elector.
+func NewManager(prefix string, opts ...Option) (*Manager, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	id, err := resolveIdentity(&o)
+	if err != nil {
+		return nil, err
+	}
+	if id.backend, err = electorBackend(&o, id.client, id.ns, o.getLogger()); err != nil {
+		return nil, err
+	}
+	if _, err := o.groupedLockName(prefix, id.pod); err != nil {
+		return nil, err
+	}
+	return &Manager{
+		prefix:   prefix,
+		opts:     o,
+		id:       id,
+		electors: map[string]*PodElector{},
+	}, nil
+}
+
+// For returns the Elector for tenantID, creating it on first use. Repeated
+// calls for the same tenant return the same Elector.
+func (m *Manager) For(tenantID string) (*PodElector, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.electors[tenantID]; ok {
+		return e, nil
+	}
+	e, err := newElector(TenantLockName(m.prefix, tenantID), m.opts, m.id)
+	if err != nil {
+		return nil, fmt.Errorf("elector for tenant %s: %w", tenantID, err)
+	}
+	m.electors[tenantID] = e
+	return e, nil
+}
+
+// Tenants returns the tenants For has been called for.
+func (m *Manager) Tenants() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenants := make([]string, 0, len(m.electors))
+	for tenant := range m.electors {
+		tenants = append(tenants, tenant)
+	}
+	return tenants
+}
+
+// TenantLockName returns the lock name Manager uses for tenantID. Tenant IDs
+// are lowercased and stripped of characters not allowed in object names;
+// whenever that changes the ID, or the name would be too long, a hash of the
+// original ID is appended so that distinct tenants never share a lock.
+func TenantLockName(prefix, tenantID string) string {
+	return objectName(prefix, tenantID)
+}
+
+// objectName joins prefix and an arbitrary id into a valid object name that
+// is unique per id.
+func objectName(prefix, id string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(id) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	clean := strings.Trim(b.String(), "-")
+
+	name := prefix + "-" + clean
+	if clean == id && len(name) <= maxLockNameLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	hash := hex.EncodeToString(sum[:])[:8]
+	if max := maxLockNameLength - len(hash) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-")
+	}
+	return name + "-" + hash
+}
@@ -0,0 +1,81 @@
+package leader
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestTenantLockName(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tenant string
+		want   string
+		// hashed is whether a hash of the tenant ID is appended
+		hashed bool
+	}{
+		{name: "clean ID", tenant: "acme", want: "tenants-acme"},
+		{name: "uppercase", tenant: "Acme", want: "tenants-acme-", hashed: true},
+		{name: "invalid characters", tenant: "acme.corp/eu", want: "tenants-acme-corp-eu-", hashed: true},
+		{name: "too long", tenant: strings.Repeat("a", 80), want: "tenants-aaaa", hashed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := TenantLockName("tenants", tc.tenant)
+			if !strings.HasPrefix(got, tc.want) || (!tc.hashed && got != tc.want) {
+				t.Fatalf("TenantLockName = %q, want %q", got, tc.want)
+			}
+			if len(got) > maxLockNameLength {
+				t.Fatalf("TenantLockName = %q is longer than %d", got, maxLockNameLength)
+			}
+			if errs := validation.IsDNS1123Label(got); len(errs) > 0 {
+				t.Fatalf("TenantLockName = %q is not a valid name: %v", got, errs)
+			}
+		})
+	}
+
+	// tenants that clean up to the same name still get distinct locks
+	if a, b := TenantLockName("tenants", "Acme"), TenantLockName("tenants", "ACME"); a == b {
+		t.Fatalf("distinct tenants share the lock %q", a)
+	}
+}
+
+func TestManager(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	m, err := NewManager("tenants", WithClient(client), WithNamespace(testNamespace), WithPodName("pod-1"), WithLogLevel(ErrorLevel))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	acme, err := m.For("acme")
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+	if again, _ := m.For("acme"); again != acme {
+		t.Fatal("For returned a second Elector for the same tenant")
+	}
+	globex, err := m.For("globex")
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+
+	for _, e := range []*PodElector{acme, globex} {
+		if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+			t.Fatalf("TryAcquire = %v, %v", ok, err)
+		}
+	}
+	if acme.lockName != "tenants-acme" || globex.lockName != "tenants-globex" {
+		t.Fatalf("tenant locks are %q and %q", acme.lockName, globex.lockName)
+	}
+	if acme.kube() != globex.kube() || acme.lockBackend() != globex.lockBackend() {
+		t.Fatal("tenants do not share the client and backend")
+	}
+
+	tenants := m.Tenants()
+	sort.Strings(tenants)
+	if strings.Join(tenants, ",") != "acme,globex" {
+		t.Fatalf("Tenants = %v", tenants)
+	}
+}
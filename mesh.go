@@ -0,0 +1,114 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// EpochLabel is set on the leader pod to its epoch by WithIstioRouting.
+// Only the pod of the current epoch matches the subset selecting it, even
+// while a former leader still carries an older value.
+const EpochLabel = "leader.seamounts.io/epoch"
+
+// destinationRulePath is where the Istio DestinationRule name in ns is
+// served.
+func destinationRulePath(ns, name string) string {
+	return fmt.Sprintf("/apis/networking.istio.io/v1beta1/namespaces/%s/destinationrules/%s", ns, name)
+}
+
+// routeMesh is the transition hook pointing the Istio subset of
+// WithIstioRouting at our pod when we gain leadership. It labels our pod
+// with our epoch first and then selects that label in the subset, so the
+// subset never selects a pod that no longer leads, and traffic routed to it
+// by a VirtualService follows leadership. A later leader repoints it, so
+// nothing is done when leadership is lost.
+func (e *PodElector) routeMesh(leading bool) {
+	if !leading {
+		return
+	}
+	ctx, cancel := e.request(context.Background())
+	defer cancel()
+
+	epoch := strconv.FormatInt(e.Epoch(), 10)
+	if err := e.labelEpoch(ctx, epoch); err != nil {
+		e.log.Error(err, "Failed to label my pod with my epoch", "pod", e.owner.Name)
+		return
+	}
+	if err := e.pointSubset(ctx, epoch); err != nil {
+		e.log.Error(err, "Failed to point the mesh subset at me", "destinationRule", e.opts.meshDestinationRule, "subset", e.opts.meshSubset)
+		return
+	}
+	e.log.Info("Pointed the mesh subset at me", "destinationRule", e.opts.meshDestinationRule, "subset", e.opts.meshSubset, "epoch", epoch)
+}
+
+func (e *PodElector) labelEpoch(ctx context.Context, epoch string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{EpochLabel: epoch},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = e.kube().CoreV1().Pods(e.ns).Patch(ctx, e.owner.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return forbidden(err, "patch", v1.Resource("pods"), e.ns)
+}
+
+// pointSubset makes the subset of the DestinationRule select our pod by
+// epoch, adding the subset if it is missing. Subsets are a list, which a
+// merge patch would replace as a whole, so the rule is updated instead,
+// guarded by its resourceVersion.
+func (e *PodElector) pointSubset(ctx context.Context, epoch string) error {
+	rc := e.kube().Discovery().RESTClient()
+	if rc == nil {
+		return fmt.Errorf("client does not support requests to arbitrary APIs")
+	}
+	path := destinationRulePath(e.ns, e.opts.meshDestinationRule)
+	resource := schema.GroupResource{Group: "networking.istio.io", Resource: "destinationrules"}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		raw, err := rc.Get().AbsPath(path).Do(ctx).Raw()
+		if err != nil {
+			return forbidden(err, "get", resource, e.ns)
+		}
+		var rule map[string]interface{}
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			return err
+		}
+
+		spec, _ := rule["spec"].(map[string]interface{})
+		if spec == nil {
+			spec = map[string]interface{}{}
+			rule["spec"] = spec
+		}
+		subsets, _ := spec["subsets"].([]interface{})
+		subset := map[string]interface{}{"name": e.opts.meshSubset}
+		found := false
+		for _, s := range subsets {
+			if s, ok := s.(map[string]interface{}); ok && s["name"] == e.opts.meshSubset {
+				subset, found = s, true
+				break
+			}
+		}
+		if !found {
+			subsets = append(subsets, subset)
+		}
+		subset["labels"] = map[string]interface{}{EpochLabel: epoch}
+		spec["subsets"] = subsets
+
+		body, err := json.Marshal(rule)
+		if err != nil {
+			return err
+		}
+		err = rc.Put().AbsPath(path).Body(body).SetHeader("Content-Type", "application/json").Do(ctx).Error()
+		return forbidden(err, "update", resource, e.ns)
+	})
+}
@@ -0,0 +1,119 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// destinationRuleServer serves the DestinationRule rule at its path, failing
+// the first conflicts updates with a Conflict, and returns what was put.
+func destinationRuleServer(t *testing.T, rule string, conflicts int) (*httptest.Server, func() map[string]interface{}) {
+	var mu sync.Mutex
+	var put map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != destinationRulePath(testNamespace, "backend") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(rule))
+		case http.MethodPut:
+			if conflicts > 0 {
+				conflicts--
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Conflict","code":409}`))
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &put); err != nil {
+				t.Errorf("put %s: %v", body, err)
+			}
+			w.Write(body)
+		}
+	}))
+	return srv, func() map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return put
+	}
+}
+
+func TestPointSubset(t *testing.T) {
+	leader := map[string]interface{}{"name": "leader", "labels": map[string]interface{}{EpochLabel: "7"}}
+	for _, tc := range []struct {
+		name      string
+		rule      string
+		conflicts int
+		want      []interface{}
+	}{
+		{
+			name: "existing subset",
+			rule: `{"spec":{"host":"backend","subsets":[{"name":"canary","labels":{"track":"canary"}},{"name":"leader","labels":{"app":"backend"}}]}}`,
+			want: []interface{}{
+				map[string]interface{}{"name": "canary", "labels": map[string]interface{}{"track": "canary"}},
+				leader,
+			},
+		},
+		{
+			name: "missing subset",
+			rule: `{"spec":{"host":"backend"}}`,
+			want: []interface{}{leader},
+		},
+		{
+			name: "no spec",
+			rule: `{"metadata":{"name":"backend"}}`,
+			want: []interface{}{leader},
+		},
+		{
+			name:      "conflict",
+			rule:      `{"spec":{"host":"backend"}}`,
+			conflicts: 1,
+			want:      []interface{}{leader},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, put := destinationRuleServer(t, tc.rule, tc.conflicts)
+			defer srv.Close()
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1", WithIstioRouting("backend", "leader"))
+			e.client = kubernetes.NewForConfigOrDie(&rest.Config{Host: srv.URL})
+
+			if err := e.pointSubset(context.Background(), "7"); err != nil {
+				t.Fatalf("pointSubset: %v", err)
+			}
+			spec, _ := put()["spec"].(map[string]interface{})
+			if got := spec["subsets"]; !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("subsets = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRouteMeshLabelsEpoch(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithIstioRouting("backend", "leader"))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	pod, err := client.CoreV1().Pods(testNamespace).Get(context.Background(), "pod-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Labels[EpochLabel] != "1" {
+		t.Fatalf("leader pod labels = %v, want epoch 1", pod.Labels)
+	}
+}
@@ -0,0 +1,162 @@
+package leader
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const metricsNamespace = "leader"
+
+var (
+	candidatesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "candidates",
+		Help:      "Number of live candidates registered for the lock, as seen by the leader.",
+	}, []string{"lock"})
+
+	staleCandidatesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "stale_candidates",
+		Help:      "Number of registered candidates that missed their heartbeats, as seen by the leader.",
+	}, []string{"lock"})
+
+	oldestCandidateWaitGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "oldest_candidate_wait_seconds",
+		Help:      "How long the longest-waiting live candidate has been registered, as seen by the leader.",
+	}, []string{"lock"})
+
+	lockTerminatingGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "lock_terminating",
+		Help:      "1 if the lock held by this leader has been deleted and is kept only by its finalizer.",
+	}, []string{"lock"})
+
+	transientErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transient_errors_total",
+		Help:      "Number of transient apiserver errors the election loop retried, by reason.",
+	}, []string{"lock", "reason"})
+
+	leadershipDurationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "leadership_duration_seconds",
+		Help:      "How long this pod has been leading the lock, 0 when it does not lead.",
+	}, []string{"lock"})
+
+	transitionsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transitions_total",
+		Help:      "Number of times this pod gained or lost leadership of the lock. A high rate signals flapping.",
+	}, []string{"lock", "leading"})
+
+	heartbeatAgeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "heartbeat_age_seconds",
+		Help:      "Age of the leader's last heartbeat on the lock, as seen by a waiting candidate.",
+	}, []string{"lock"})
+
+	failoverSecondsHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "failover_seconds",
+		Help:      "Time from the first sign of the former leader going, such as its pod or the lock being deleted, to this pod taking over.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"lock"})
+
+	lockAttemptsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "lock_attempts_total",
+		Help:      "Number of attempts of this pod to create the lock, by result: acquired, held by another pod, or failed. Bursts of held across a fleet show its retries contending in lockstep.",
+	}, []string{"lock", "result"})
+
+	retryPhaseGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "retry_phase",
+		Help:      "Where in the backoff window this pod retries, as a fraction of it, with WithRetryDesync.",
+	}, []string{"lock"})
+
+	janitorDeletedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "janitor_deleted_total",
+		Help:      "Number of lock objects a Janitor deleted, or would have in a dry run, by reason.",
+	}, []string{"namespace", "reason", "dry_run"})
+
+	janitorErrorsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "janitor_errors_total",
+		Help:      "Number of Janitor sweeps that failed.",
+	})
+)
+
+// RegisterMetrics registers the package's metrics with r.
+func RegisterMetrics(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		candidatesGauge,
+		staleCandidatesGauge,
+		oldestCandidateWaitGauge,
+		lockTerminatingGauge,
+		transientErrorsCounter,
+		heartbeatAgeGauge,
+		leadershipDurationGauge,
+		transitionsCounter,
+		scalingHintGauge,
+		failoverSecondsHistogram,
+		lockAttemptsCounter,
+		retryPhaseGauge,
+		janitorDeletedCounter,
+		janitorErrorsCounter,
+	} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// countTransition is the transition hook counting changes of leadership and
+// starting or stopping the leadership duration.
+func (e *PodElector) countTransition(leading bool) {
+	transitionsCounter.WithLabelValues(e.lockName, strconv.FormatBool(leading)).Inc()
+
+	e.mu.Lock()
+	if leading {
+		e.leadingSince = time.Now()
+	} else {
+		e.leadingSince = time.Time{}
+	}
+	e.mu.Unlock()
+	leadershipDurationGauge.WithLabelValues(e.lockName).Set(0)
+}
+
+// observeLeadershipDuration refreshes the leadership duration of the
+// current term.
+func (e *PodElector) observeLeadershipDuration() {
+	e.mu.Lock()
+	since := e.leadingSince
+	e.mu.Unlock()
+	if !since.IsZero() {
+		leadershipDurationGauge.WithLabelValues(e.lockName).Set(time.Since(since).Seconds())
+	}
+}
+
+// countAttempt counts an attempt to create the lock that returned err.
+func countAttempt(lockName string, err error) {
+	result := "acquired"
+	switch {
+	case apierrors.IsAlreadyExists(err):
+		result = "held"
+	case err != nil:
+		result = "failed"
+	}
+	lockAttemptsCounter.WithLabelValues(lockName, result).Inc()
+}
@@ -0,0 +1,41 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLeadershipMetrics(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+	gained := transitionsCounter.WithLabelValues(testLock, "true")
+	lost := transitionsCounter.WithLabelValues(testLock, "false")
+	duration := leadershipDurationGauge.WithLabelValues(testLock)
+	gainedBefore, lostBefore := testutil.ToFloat64(gained), testutil.ToFloat64(lost)
+
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if got := testutil.ToFloat64(gained) - gainedBefore; got != 1 {
+		t.Fatalf("gained transitions = %v, want 1", got)
+	}
+	time.Sleep(10 * time.Millisecond)
+	e.observeLeadershipDuration()
+	if got := testutil.ToFloat64(duration); got <= 0 {
+		t.Fatalf("leadership duration while leading = %v, want it counting", got)
+	}
+
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if got := testutil.ToFloat64(lost) - lostBefore; got != 1 {
+		t.Fatalf("lost transitions = %v, want 1", got)
+	}
+	e.observeLeadershipDuration()
+	if got := testutil.ToFloat64(duration); got != 0 {
+		t.Fatalf("leadership duration after Release = %v, want 0", got)
+	}
+}
@@ -0,0 +1,214 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// KeyAnnotation records the unsanitized key on a resource lock.
+	KeyAnnotation = "leader.seamounts.io/key"
+
+	mutexRole = "mutex"
+
+	// mutexRenewInterval is how often the holder of a key renews its Lease.
+	mutexRenewInterval = time.Second * 10
+	// mutexTTL is how long a key's Lease outlives its last renewal before a
+	// waiter may take the key over.
+	mutexTTL = mutexRenewInterval * 3
+)
+
+// Unlocker releases a lock taken with Lock.
+type Unlocker interface {
+	Unlock(ctx context.Context) error
+	// Lost is closed when the lock is taken over by another pod, after its
+	// Lease went unrenewed or was deleted. Work guarded by the lock should
+	// stop then. Lost is not closed by Unlock.
+	Lost() <-chan struct{}
+}
+
+// Lock blocks until the current pod holds the lock on key, or ctx is
+// cancelled. Keys name arbitrary external resources, such as a bucket or a
+// device, so application code can serialize access to them with the same
+// machinery as leader election. Each lock is a Lease owned by our pod, so
+// the lock is released by garbage collection if the pod goes away. The Lease
+// is renewed until Unlock, and a Lease left unrenewed for 30 seconds, as by
+// a pod that hangs, is taken over by the next waiter, which closes the
+// Unlocker's Lost channel. Lock does not require leadership.
+func (e *PodElector) Lock(ctx context.Context, key string) (_ Unlocker, err error) {
+	defer func() { err = e.wrap("lock "+key+" under", err) }()
+
+	name := objectName(e.lockName+"-mutex", key)
+	backoff := time.Millisecond * 100
+	for {
+		if e.claimKey(name) {
+			lease, err := e.createMutex(ctx, name, key)
+			switch {
+			case err == nil:
+				return e.holdKey(name, lease.UID), nil
+			case apierrors.IsAlreadyExists(err):
+				uid, ours, expired := e.leftoverMutex(ctx, name)
+				if ours {
+					// taken by us before a container restart
+					return e.holdKey(name, uid), nil
+				}
+				e.releaseKey(name)
+				if expired {
+					backoff = time.Millisecond * 100
+					continue
+				}
+			case e.retryable(ctx, err):
+				e.releaseKey(name)
+			default:
+				e.releaseKey(name)
+				return nil, err
+			}
+		}
+
+		e.log.Debug("Key is locked, waiting", "lock", e.lockName, "key", key)
+		if err := e.sleep(ctx, e.opts.random().jitter(backoff, .2)); err != nil {
+			return nil, err
+		}
+		if backoff < e.tuned().maxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// leftoverMutex returns the UID of the Lease name if our pod owns it. A
+// Lease of another pod that has expired is deleted instead, and expired is
+// true when the key is free to be taken right away.
+func (e *PodElector) leftoverMutex(ctx context.Context, name string) (_ types.UID, ours, expired bool) {
+	leases := e.kube().CoordinationV1().Leases(e.ns)
+	reqCtx, cancel := e.request(ctx)
+	defer cancel()
+	lease, err := leases.Get(reqCtx, name, metav1.GetOptions{})
+	switch {
+	case err != nil:
+		return "", false, false
+	case sameOwners(lease, []metav1.OwnerReference{*e.owner}):
+		return lease.UID, true, false
+	case !leaseExpired(lease, time.Now()):
+		return "", false, false
+	}
+
+	e.log.Info("Key lock was not renewed in time, taking it over", "lock", e.lockName, "key", lease.Annotations[KeyAnnotation])
+	pre := unchanged(lease)
+	err = leases.Delete(reqCtx, name, metav1.DeleteOptions{Preconditions: &pre})
+	return "", false, err == nil || apierrors.IsNotFound(err)
+}
+
+func (e *PodElector) createMutex(ctx context.Context, name, key string) (*coordinationv1.Lease, error) {
+	ttl := int32(mutexTTL / time.Second)
+	now := metav1.NewMicroTime(time.Now())
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	return e.kube().CoordinationV1().Leases(e.ns).Create(ctx, &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       e.ns,
+			OwnerReferences: []metav1.OwnerReference{*e.owner},
+			Labels: map[string]string{
+				LockLabel: e.lockName,
+				RoleLabel: mutexRole,
+			},
+			Annotations: map[string]string{KeyAnnotation: key},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &e.owner.Name,
+			LeaseDurationSeconds: &ttl,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}, metav1.CreateOptions{})
+}
+
+// claimKey serializes Lock calls for the same key within our pod, which
+// would otherwise all see our own Lease as theirs.
+func (e *PodElector) claimKey(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.heldKeys[name] {
+		return false
+	}
+	if e.heldKeys == nil {
+		e.heldKeys = map[string]bool{}
+	}
+	e.heldKeys[name] = true
+	return true
+}
+
+func (e *PodElector) releaseKey(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.heldKeys, name)
+}
+
+type keyLock struct {
+	e    *PodElector
+	name string
+	uid  types.UID
+	once sync.Once
+	done chan struct{}
+	lost chan struct{}
+}
+
+// holdKey returns the lock on the Lease name of uid, renewing the Lease
+// until it is unlocked. Renewal is not bound to the context of Lock, which
+// may end long before the lock is released.
+func (e *PodElector) holdKey(name string, uid types.UID) *keyLock {
+	l := newKeyLock(e, name, uid)
+	go l.renew(mutexRenewInterval)
+	return l
+}
+
+func newKeyLock(e *PodElector, name string, uid types.UID) *keyLock {
+	return &keyLock{e: e, name: name, uid: uid, done: make(chan struct{}), lost: make(chan struct{})}
+}
+
+// renew renews the lock's Lease every interval until it is unlocked, or
+// closes lost if the Lease was taken over.
+func (l *keyLock) renew(interval time.Duration) {
+	e := l.e
+	for {
+		if unlocked, _ := sleepOrWake(context.Background(), interval, l.done); unlocked {
+			return
+		}
+		err := e.renewLease(context.Background(), l.name, l.uid)
+		switch {
+		case err == nil:
+		case apierrors.IsNotFound(err) || apierrors.IsConflict(err):
+			e.log.Warn("Lost key lock, it was taken over", "lock", e.lockName, "name", l.name)
+			close(l.lost)
+			return
+		default:
+			e.log.Error(err, "Failed to renew key lock", "lock", e.lockName, "name", l.name)
+		}
+	}
+}
+
+func (l *keyLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock deletes the lock's Lease. The UID precondition guarantees it never
+// deletes a Lease recreated by another pod.
+func (l *keyLock) Unlock(ctx context.Context) error {
+	e := l.e
+	l.once.Do(func() { close(l.done) })
+	reqCtx, cancel := e.request(ctx)
+	defer cancel()
+
+	err := e.kube().CoordinationV1().Leases(e.ns).Delete(reqCtx, l.name, uidPrecondition(l.uid))
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return e.wrap("unlock "+l.name+" under", err)
+	}
+	e.releaseKey(l.name)
+	return nil
+}
@@ -0,0 +1,138 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testKey = "bucket/photos"
+
+// mutexName is the name of the Lease of testKey.
+var mutexName = objectName(testLock+"-mutex", testKey)
+
+// heldMutex creates the Lease of testKey for pod, last renewed at renewed.
+func heldMutex(t *testing.T, client *fake.Clientset, pod string, renewed time.Time) {
+	t.Helper()
+	e := newTestElector(t, client, pod)
+	if _, err := e.createMutex(context.Background(), mutexName, testKey); err != nil {
+		t.Fatal(err)
+	}
+	leases := client.CoordinationV1().Leases(testNamespace)
+	lease, err := leases.Get(context.Background(), mutexName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewTime := metav1.NewMicroTime(renewed)
+	lease.Spec.RenewTime = &renewTime
+	if _, err := leases.Update(context.Background(), lease, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		holder string
+		// renewed is how long ago the holder last renewed the Lease
+		renewed  time.Duration
+		acquired bool
+	}{
+		{name: "free", acquired: true},
+		{name: "held by us before a restart", holder: "pod-1", acquired: true},
+		{name: "held by another pod", holder: "pod-2", acquired: false},
+		{name: "held by another pod within the TTL", holder: "pod-2", renewed: mutexTTL / 2, acquired: false},
+		{name: "expired", holder: "pod-2", renewed: mutexTTL * 2, acquired: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			if tc.holder != "" {
+				heldMutex(t, client, tc.holder, time.Now().Add(-tc.renewed))
+			}
+			e := newTestElector(t, client, "pod-1")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			l, err := e.Lock(ctx, testKey)
+			if !tc.acquired {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("Lock of a held key = %v, want to wait", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lock: %v", err)
+			}
+			lease, err := client.CoordinationV1().Leases(testNamespace).Get(context.Background(), mutexName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameOwners(lease, []metav1.OwnerReference{*e.owner}) || lease.Annotations[KeyAnnotation] != testKey {
+				t.Fatalf("key Lease owned by %v for %q", lease.OwnerReferences, lease.Annotations[KeyAnnotation])
+			}
+
+			if err := l.Unlock(context.Background()); err != nil {
+				t.Fatalf("Unlock: %v", err)
+			}
+			if _, err := client.CoordinationV1().Leases(testNamespace).Get(context.Background(), mutexName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+				t.Fatalf("key Lease left after Unlock: %v", err)
+			}
+		})
+	}
+}
+
+func TestLockWithinPod(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+	l, err := e.Lock(context.Background(), testKey)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	// our own Lease must not let a second caller in
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := e.Lock(ctx, testKey); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second Lock in the pod = %v, want to wait", err)
+	}
+	if err := l.Unlock(context.Background()); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := e.Lock(context.Background(), testKey); err != nil {
+		t.Fatalf("Lock after Unlock: %v", err)
+	}
+}
+
+func TestLockLost(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+	lease, err := e.createMutex(context.Background(), mutexName, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newKeyLock(e, mutexName, lease.UID)
+	go l.renew(10 * time.Millisecond)
+	defer l.Unlock(context.Background())
+
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-l.Lost():
+		t.Fatal("lock lost while it is renewed")
+	default:
+	}
+
+	// a waiter takes the expired key over
+	if err := client.CoordinationV1().Leases(testNamespace).Delete(context.Background(), mutexName, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost not closed after the takeover")
+	}
+}
@@ -0,0 +1,57 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// drainTaints are taints placed on nodes that are about to be drained.
+var drainTaints = []string{
+	"node.kubernetes.io/unschedulable",
+	"ToBeDeletedByClusterAutoscaler",
+}
+
+// draining returns why our pod is about to be taken down by a drain, or ""
+// if there is no sign of one.
+func (e *PodElector) draining(ctx context.Context) string {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+
+	myPod, err := e.kube().CoreV1().Pods(e.ns).Get(ctx, e.owner.Name, metav1.GetOptions{})
+	if err != nil {
+		e.log.Error(err, "Failed to get my pod", "pod", e.owner.Name)
+		return ""
+	}
+	if myPod.GetDeletionTimestamp() != nil {
+		return "my pod is being deleted"
+	}
+
+	// a virtual node is cordoned or tainted for reasons of its provider
+	if e.nodeName == "" || e.virtual {
+		return ""
+	}
+	node, err := e.kube().CoreV1().Nodes().Get(ctx, e.nodeName, metav1.GetOptions{})
+	if err != nil {
+		e.log.Error(err, "Failed to get my node", "node", e.nodeName)
+		return ""
+	}
+	return nodeDraining(node)
+}
+
+// nodeDraining returns why node is being drained, or "" if it is not.
+func nodeDraining(node *v1.Node) string {
+	if node.Spec.Unschedulable {
+		return fmt.Sprintf("node %s is cordoned", node.Name)
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, key := range drainTaints {
+			if taint.Key == key {
+				return fmt.Sprintf("node %s is tainted %s", node.Name, key)
+			}
+		}
+	}
+	return ""
+}
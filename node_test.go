@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDraining(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		node     v1.NodeSpec
+		deleting bool
+		opts     []Option
+		want     string
+	}{
+		{name: "schedulable", want: ""},
+		{name: "cordoned", node: v1.NodeSpec{Unschedulable: true}, want: "node node-1 is cordoned"},
+		{
+			name: "autoscaler taint",
+			node: v1.NodeSpec{Taints: []v1.Taint{{Key: "ToBeDeletedByClusterAutoscaler", Effect: v1.TaintEffectNoSchedule}}},
+			want: "node node-1 is tainted ToBeDeletedByClusterAutoscaler",
+		},
+		{
+			name: "other taint",
+			node: v1.NodeSpec{Taints: []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectNoSchedule}}},
+			want: "",
+		},
+		{name: "pod deleting", deleting: true, want: "my pod is being deleted"},
+		{name: "virtual node cordoned", node: v1.NodeSpec{Unschedulable: true}, opts: []Option{WithVirtualNodes(VirtualNodesAlways)}, want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			scheduleOn(t, client, "pod-1", &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: tc.node})
+			if tc.deleting {
+				pod, err := client.CoreV1().Pods(testNamespace).Get(context.Background(), "pod-1", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				now := metav1.Now()
+				pod.DeletionTimestamp = &now
+				if _, err := client.CoreV1().Pods(testNamespace).Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e := newTestElector(t, client, "pod-1", append([]Option{WithStepDownOnDrain()}, tc.opts...)...)
+
+			if got := e.draining(context.Background()); got != tc.want {
+				t.Fatalf("draining = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStepDownOnDrain(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	scheduleOn(t, client, "pod-1", &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	drained := false
+	e := newTestElector(t, client, "pod-1", WithStepDownOnDrain(), WithMaintenanceInterval(10*time.Millisecond), WithDrain(func(context.Context) error {
+		drained = true
+		return nil
+	}))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	node, err := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Unschedulable = true
+	if _, err := client.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e.maintain(ctx)
+	if e.IsLeader() {
+		t.Fatal("still the leader on a cordoned node")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock still held by %q", owner)
+	}
+	if !drained {
+		t.Fatal("work was not drained before stepping down")
+	}
+}
@@ -0,0 +1,79 @@
+package leader
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeLossPolicy says what a candidate may do when the leader's pod is
+// bound to a node that no longer exists. Such a pod never runs again, yet
+// it and its lock can linger until the pod garbage collector gets to them,
+// stranding leadership.
+type NodeLossPolicy string
+
+const (
+	// NodeLossWait waits for the pod garbage collector. It is the default.
+	NodeLossWait NodeLossPolicy = ""
+
+	// NodeLossDeletePod deletes the leader's pod, which garbage collection
+	// of the lock then follows.
+	NodeLossDeletePod NodeLossPolicy = "DeletePod"
+
+	// NodeLossDeleteLock deletes the leader's pod and the lock right away.
+	NodeLossDeleteLock NodeLossPolicy = "DeleteLock"
+)
+
+// leaderNodeGone reports whether pod is bound to a node that no longer
+// exists.
+func (e *PodElector) leaderNodeGone(ctx context.Context, pod *v1.Pod) bool {
+	if pod.Spec.NodeName == "" {
+		return false
+	}
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	_, err := e.kube().CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		e.log.Error(forbidden(err, "get", v1.Resource("nodes"), ""), "Failed to get leader's node", "node", pod.Spec.NodeName)
+	}
+	return apierrors.IsNotFound(err)
+}
+
+// adoptFromDeletedNode applies the node loss policy to the leader pod on a
+// deleted node and to its lock. Both deletions are guarded by UID and
+// resourceVersion preconditions, so a pod or lock recreated under the same
+// name, or changed since we read it, is never touched.
+func (e *PodElector) adoptFromDeletedNode(ctx context.Context, lock metav1.Object, pod *v1.Pod) error {
+	e.log.Info("Leader's node no longer exists, deleting the leader pod", "lock", e.lockName, "leader", pod.Name, "node", pod.Spec.NodeName, "policy", e.opts.nodeLoss)
+
+	// no kubelet is left to confirm a graceful deletion
+	grace := int64(0)
+	pre := unchanged(pod)
+	delCtx, cancel := e.request(ctx)
+	err := e.kube().CoreV1().Pods(e.ns).Delete(delCtx, pod.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: &grace,
+		Preconditions:      &pre,
+	})
+	cancel()
+	switch {
+	case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+		// gone or changed since we read it; the next attempt looks again
+		return nil
+	case err != nil:
+		return forbidden(err, "delete", v1.Resource("pods"), e.ns)
+	}
+	e.event(v1.EventTypeWarning, "DeletedLostLeader", "Deleted leader %s of %s on deleted node %s", pod.Name, e.lockName, pod.Spec.NodeName)
+	e.audit(AuditTookOver, "Deleted leader %s of %s on deleted node %s", pod.Name, e.lockName, pod.Spec.NodeName)
+
+	if e.opts.nodeLoss != NodeLossDeleteLock {
+		return nil
+	}
+	e.log.Info("Deleting the lock of the lost leader", "lock", e.lockName, "leader", pod.Name)
+	err = e.lockBackend().Delete(ctx, e.lockName, unchanged(lock))
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return err
+	}
+	return nil
+}
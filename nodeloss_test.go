@@ -0,0 +1,87 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// leadFromNode makes pod-2 the leader, bound to node, which is created
+// unless deleted is set.
+func leadFromNode(t *testing.T, client *fake.Clientset, node string, deleted bool) {
+	t.Helper()
+	if deleted {
+		updatePod(t, client, "pod-2", func(pod *v1.Pod) { pod.Spec.NodeName = node })
+	} else {
+		scheduleOn(t, client, "pod-2", zonedNode(node, "zone-a"))
+	}
+	holder := newTestElector(t, client, "pod-2")
+	if ok, err := holder.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+}
+
+func TestAdoptFromDeletedNode(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		policy      NodeLossPolicy
+		nodeDeleted bool
+		podDeleted  bool
+		lockDeleted bool
+	}{
+		{name: "node present", policy: NodeLossDeleteLock},
+		{name: "delete pod", policy: NodeLossDeletePod, nodeDeleted: true, podDeleted: true},
+		{name: "delete lock", policy: NodeLossDeleteLock, nodeDeleted: true, podDeleted: true, lockDeleted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := newTestClient(t, "pod-1", "pod-2")
+			leadFromNode(t, client, "node-a", tc.nodeDeleted)
+			e := newTestElector(t, client, "pod-1", WithNodeLossPolicy(tc.policy))
+			leader, err := client.CoreV1().Pods(testNamespace).Get(ctx, "pod-2", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lock, err := e.getLock(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gone := e.leaderNodeGone(ctx, leader); gone != tc.nodeDeleted {
+				t.Fatalf("leaderNodeGone = %v, want %v", gone, tc.nodeDeleted)
+			}
+			if tc.nodeDeleted {
+				if err := e.adoptFromDeletedNode(ctx, lock, leader); err != nil {
+					t.Fatalf("adoptFromDeletedNode: %v", err)
+				}
+			}
+			_, err = client.CoreV1().Pods(testNamespace).Get(ctx, "pod-2", metav1.GetOptions{})
+			if deleted := apierrors.IsNotFound(err); deleted != tc.podDeleted {
+				t.Fatalf("leader pod deleted = %v, want %v", deleted, tc.podDeleted)
+			}
+			if deleted := lockOwner(t, client) == ""; deleted != tc.lockDeleted {
+				t.Fatalf("lock deleted = %v, want %v", deleted, tc.lockDeleted)
+			}
+		})
+	}
+}
+
+func TestBecomeAfterNodeLoss(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	leadFromNode(t, client, "node-a", true)
+	e := newTestElector(t, client, "pod-1", WithNodeLossPolicy(NodeLossDeleteLock))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Become(ctx); err != nil {
+		t.Fatalf("Become: %v", err)
+	}
+	if owner := lockOwner(t, client); owner != "pod-1" {
+		t.Fatalf("lock is owned by %q, want pod-1", owner)
+	}
+}
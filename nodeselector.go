@@ -0,0 +1,62 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// nodeRecheckInterval is how often the labels of our node are re-read for
+// WithNodeSelector.
+const nodeRecheckInterval = time.Second * 30
+
+// nodeSelectedOut returns why our node excludes us from leading under
+// WithNodeSelector, or "". Node labels say nothing about the machine behind
+// a virtual node, so a pod on one never matches.
+func (e *PodElector) nodeSelectedOut() string {
+	selector := e.opts.nodeSelector
+	if selector == nil {
+		return ""
+	}
+	if e.virtual {
+		return fmt.Sprintf("running on virtual node %s, which cannot match the node selector %q", e.nodeName, selector.String())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !selector.Matches(labels.Set(e.nodeLabels)) {
+		return fmt.Sprintf("node %s does not match the node selector %q", e.nodeName, selector.String())
+	}
+	return ""
+}
+
+// refreshNodeLabels re-reads the labels of our node for WithNodeSelector, at
+// most every nodeRecheckInterval, so relabelling a node takes effect on
+// running pods.
+func (e *PodElector) refreshNodeLabels(ctx context.Context) {
+	if e.opts.nodeSelector == nil || e.virtual || e.nodeName == "" {
+		return
+	}
+	e.mu.Lock()
+	due := time.Since(e.nodeCheckedAt) >= nodeRecheckInterval
+	e.mu.Unlock()
+	if !due {
+		return
+	}
+
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	node, err := e.kube().CoreV1().Nodes().Get(ctx, e.nodeName, metav1.GetOptions{})
+	if err != nil {
+		e.log.Error(forbidden(err, "get", v1.Resource("nodes"), ""), "Failed to get my node", "node", e.nodeName)
+		return
+	}
+	e.mu.Lock()
+	e.nodeLabels = node.Labels
+	e.nodeCheckedAt = time.Now()
+	e.mu.Unlock()
+}
@@ -0,0 +1,61 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNodeSelector(t *testing.T) {
+	ssd := labels.SelectorFromSet(labels.Set{"disk": "ssd"})
+	for _, tc := range []struct {
+		name string
+		disk string
+		opts []Option
+		want string
+	}{
+		{name: "matching node", disk: "ssd"},
+		{name: "other node", disk: "hdd", want: `node node-a does not match the node selector "disk=ssd"`},
+		{name: "virtual node", disk: "ssd", opts: []Option{WithVirtualNodes(VirtualNodesAlways)}, want: `running on virtual node node-a, which cannot match the node selector "disk=ssd"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			node := zonedNode("node-a", "zone-a")
+			node.Labels["disk"] = tc.disk
+			scheduleOn(t, client, "pod-1", node)
+			e := newTestElector(t, client, "pod-1", append([]Option{WithNodeSelector(ssd)}, tc.opts...)...)
+
+			if got := e.ineligible(); got != tc.want {
+				t.Fatalf("ineligible = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNodeRelabelled(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1")
+	node := zonedNode("node-a", "zone-a")
+	node.Labels["disk"] = "ssd"
+	scheduleOn(t, client, "pod-1", node)
+	e := newTestElector(t, client, "pod-1", WithNodeSelector(labels.SelectorFromSet(labels.Set{"disk": "ssd"})))
+
+	node.Labels["disk"] = "hdd"
+	if _, err := client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	e.refreshNodeLabels(ctx)
+	if got := e.nodeSelectedOut(); got != "" {
+		t.Fatalf("node re-read before the recheck is due: %q", got)
+	}
+	e.mu.Lock()
+	e.nodeCheckedAt = time.Now().Add(-nodeRecheckInterval)
+	e.mu.Unlock()
+	e.refreshNodeLabels(ctx)
+	if got := e.nodeSelectedOut(); got == "" {
+		t.Fatal("relabelled node still matches")
+	}
+}
@@ -0,0 +1,120 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fieldsel "k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// nodeRewatchInterval is how long to wait before re-establishing a watch on
+// the leader's node that failed or ended.
+const nodeRewatchInterval = time.Second * 2
+
+// unreachableTaint is put on nodes the node controller lost contact with.
+const unreachableTaint = "node.kubernetes.io/unreachable"
+
+// nodeWatch is the watch on the node of the leader we wait for.
+type nodeWatch struct {
+	node   string
+	cancel context.CancelFunc
+}
+
+// watchLeaderNode watches the node of leader, the current leader's pod, and
+// wakes the election loop as soon as it is deleted or becomes unreachable,
+// rather than leaving that to the next poll. A virtual node shared with other
+// pods going unreachable is ignored, as the pod may well still run. A watch
+// that ended is resumed from the last resourceVersion seen. Watching a
+// different node stops the previous watch.
+func (e *PodElector) watchLeaderNode(ctx context.Context, leader *v1.Pod) {
+	node := leader.Spec.NodeName
+	wakeUnreachable := e.opts.nodeReflectsPod(leader)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.nodeWatch != nil {
+		if e.nodeWatch.node == node {
+			return
+		}
+		e.nodeWatch.cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	e.nodeWatch = &nodeWatch{node: node, cancel: cancel}
+
+	e.background.Add(1)
+	go func() {
+		defer e.background.Done()
+		unreachable := false
+		resourceVersion := ""
+		for ctx.Err() == nil {
+			w, err := e.kube().CoreV1().Nodes().Watch(ctx, metav1.ListOptions{
+				FieldSelector:   fieldsel.OneTermEqualSelector("metadata.name", node).String(),
+				ResourceVersion: resourceVersion,
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					e.log.Error(err, "Failed to watch leader's node", "node", node)
+				}
+				resourceVersion = ""
+				if e.sleep(ctx, nodeRewatchInterval) != nil {
+					return
+				}
+				continue
+			}
+			for ev := range w.ResultChan() {
+				n, ok := ev.Object.(*v1.Node)
+				if !ok {
+					// the watch expired, start over from the current state
+					resourceVersion = ""
+					continue
+				}
+				resourceVersion = n.ResourceVersion
+				switch {
+				case ev.Type == watch.Deleted:
+					e.log.Info("Leader's node was deleted", "lock", e.lockName, "node", node)
+					e.wake()
+				case hasTaint(n, unreachableTaint) && !unreachable && wakeUnreachable:
+					unreachable = true
+					e.log.Info("Leader's node is unreachable", "lock", e.lockName, "node", node)
+					e.wake()
+				case !hasTaint(n, unreachableTaint):
+					unreachable = false
+				}
+			}
+			w.Stop()
+			if e.sleep(ctx, nodeRewatchInterval) != nil {
+				return
+			}
+		}
+	}()
+}
+
+// stopNodeWatch stops watching the leader's node.
+func (e *PodElector) stopNodeWatch() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.nodeWatch != nil {
+		e.nodeWatch.cancel()
+		e.nodeWatch = nil
+	}
+}
+
+func hasTaint(node *v1.Node, key string) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// wake cuts the election loop's current backoff short.
+func (e *PodElector) wake() {
+	select {
+	case e.woken <- struct{}{}:
+	default:
+	}
+}
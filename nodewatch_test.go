@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// nodeEvent is an event on the watched node and whether it should wake the
+// election loop.
+type nodeEvent struct {
+	event       watch.EventType
+	unreachable bool
+	wake        bool
+}
+
+func TestWatchLeaderNode(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		tolerations []v1.Toleration
+		events      []nodeEvent
+	}{
+		{
+			name: "node",
+			events: []nodeEvent{
+				{event: watch.Modified},
+				{event: watch.Modified, unreachable: true, wake: true},
+				{event: watch.Modified, unreachable: true},
+				{event: watch.Modified},
+				{event: watch.Modified, unreachable: true, wake: true},
+				{event: watch.Deleted, unreachable: true, wake: true},
+			},
+		},
+		{
+			name:        "shared virtual node",
+			tolerations: []v1.Toleration{{Key: virtualKubeletTaint, Operator: v1.TolerationOpExists}},
+			events: []nodeEvent{
+				{event: watch.Modified, unreachable: true},
+				{event: watch.Deleted, wake: true},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			w := watch.NewFake()
+			client.PrependWatchReactor("nodes", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				return true, w, nil
+			})
+			e := newTestElector(t, client, "pod-1", WithLeaderNodeWatch())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer func() {
+				cancel()
+				w.Stop()
+				e.background.Wait()
+			}()
+			leader := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: testNamespace},
+				Spec:       v1.PodSpec{NodeName: "node-a", Tolerations: tc.tolerations},
+			}
+			e.watchLeaderNode(ctx, leader)
+
+			for i, ev := range tc.events {
+				node := zonedNode("node-a", "zone-a")
+				if ev.unreachable {
+					node.Spec.Taints = []v1.Taint{{Key: unreachableTaint, Effect: v1.TaintEffectNoExecute}}
+				}
+				w.Action(ev.event, node)
+
+				wait := 20 * time.Millisecond
+				if ev.wake {
+					wait = time.Second
+				}
+				select {
+				case <-e.woken:
+					if !ev.wake {
+						t.Fatalf("event %d: woken by a %s node event", i, ev.event)
+					}
+				case <-time.After(wait):
+					if ev.wake {
+						t.Fatalf("event %d: not woken by a %s node event", i, ev.event)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Notification describes a change of leadership handed to Notifiers.
+type Notification struct {
+	Lock      string `json:"lock"`
+	Namespace string `json:"namespace"`
+	// Pod is the name of our pod, which gained or lost leadership.
+	Pod     string    `json:"pod"`
+	Leading bool      `json:"leading"`
+	Epoch   int64     `json:"epoch"`
+	Time    time.Time `json:"time"`
+}
+
+func (n Notification) String() string {
+	if n.Leading {
+		return fmt.Sprintf("%s became the leader of %s/%s for epoch %d", n.Pod, n.Namespace, n.Lock, n.Epoch)
+	}
+	return fmt.Sprintf("%s is no longer the leader of %s/%s", n.Pod, n.Namespace, n.Lock)
+}
+
+// Notifier is told about every change of leadership, for example to page a
+// team or post to chat when a failover happens. Notifiers run in the
+// background and do not delay the election.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// WebhookNotifier posts every Notification as JSON to URL.
+type WebhookNotifier struct {
+	URL string
+	// Client is used for the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.Client, w.URL, n)
+}
+
+// SlackNotifier posts every Notification as a message to a Slack incoming
+// webhook.
+type SlackNotifier struct {
+	WebhookURL string
+	// Client is used for the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.Client, s.WebhookURL, map[string]string{"text": n.String()})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post to %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// EventNotifier records every Notification as a Kubernetes Event on the
+// pod that gained or lost leadership. Unlike WithEvents, it records only
+// transitions and does not aggregate.
+type EventNotifier struct {
+	Client kubernetes.Interface
+}
+
+// Notify implements Notifier.
+func (en *EventNotifier) Notify(ctx context.Context, n Notification) error {
+	reason := "LeadershipLost"
+	if n.Leading {
+		reason = "LeaderElected"
+	}
+	ts := metav1.NewTime(n.Time)
+	_, err := en.Client.CoreV1().Events(n.Namespace).Create(ctx, &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: n.Pod + ".",
+			Namespace:    n.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  n.Namespace,
+			Name:       n.Pod,
+		},
+		Type:           v1.EventTypeNormal,
+		Reason:         reason,
+		Message:        n.String(),
+		Source:         v1.EventSource{Component: eventSource},
+		FirstTimestamp: ts,
+		LastTimestamp:  ts,
+		Count:          1,
+	}, metav1.CreateOptions{})
+	return err
+}
+
+// notify is the transition hook handing the change to every Notifier.
+func (e *PodElector) notify(leading bool) {
+	n := Notification{
+		Lock:      e.lockName,
+		Namespace: e.ns,
+		Pod:       e.owner.Name,
+		Leading:   leading,
+		Epoch:     e.Epoch(),
+		Time:      time.Now(),
+	}
+	for _, notifier := range e.opts.notifiers {
+		go func(notifier Notifier) {
+			ctx, cancel := e.request(context.Background())
+			defer cancel()
+			if err := notifier.Notify(ctx, n); err != nil {
+				e.log.Error(err, "Failed to notify about leadership change", "lock", e.lockName, "leading", leading)
+			}
+		}(notifier)
+	}
+}
@@ -0,0 +1,131 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHTTPNotifiers(t *testing.T) {
+	n := Notification{Lock: testLock, Namespace: testNamespace, Pod: "pod-1", Leading: true, Epoch: 3, Time: time.Now()}
+	for _, tc := range []struct {
+		name     string
+		notifier func(url string) Notifier
+		status   int
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "webhook",
+			notifier: func(url string) Notifier { return &WebhookNotifier{URL: url} },
+			status:   http.StatusOK,
+			want:     `"pod":"pod-1"`,
+		},
+		{
+			name:     "Slack",
+			notifier: func(url string) Notifier { return &SlackNotifier{WebhookURL: url} },
+			status:   http.StatusOK,
+			want:     `{"text":"pod-1 became the leader of test/test-lock for epoch 3"}`,
+		},
+		{
+			name:     "rejected",
+			notifier: func(url string) Notifier { return &WebhookNotifier{URL: url} },
+			status:   http.StatusBadRequest,
+			wantErr:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			posted := make(chan string, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				posted <- string(b)
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			err := tc.notifier(srv.URL).Notify(context.Background(), n)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Notify = %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			body := <-posted
+			if !json.Valid([]byte(body)) || !strings.Contains(body, tc.want) {
+				t.Fatalf("posted %s, want %s", body, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventNotifier(t *testing.T) {
+	for _, tc := range []struct {
+		leading bool
+		reason  string
+	}{
+		{leading: true, reason: "LeaderElected"},
+		{leading: false, reason: "LeadershipLost"},
+	} {
+		t.Run(tc.reason, func(t *testing.T) {
+			client := newTestClient(t)
+			n := Notification{Lock: testLock, Namespace: testNamespace, Pod: "pod-1", Leading: tc.leading, Time: time.Now()}
+			if err := (&EventNotifier{Client: client}).Notify(context.Background(), n); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+			events, err := client.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events.Items) != 1 {
+				t.Fatalf("%d events, want 1", len(events.Items))
+			}
+			if ev := events.Items[0]; ev.Reason != tc.reason || ev.InvolvedObject.Name != "pod-1" || ev.Message != n.String() {
+				t.Fatalf("event %s about %s: %q", ev.Reason, ev.InvolvedObject.Name, ev.Message)
+			}
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	notified := make(chan Notification, 2)
+	e := newTestElector(t, client, "pod-1", WithNotifier(NotifierFunc(func(ctx context.Context, n Notification) error {
+		notified <- n
+		return nil
+	})))
+
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if err := e.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	// notifiers run in the background, so they may finish in any order
+	var gained, lost bool
+	for i := 0; i < 2; i++ {
+		select {
+		case n := <-notified:
+			if n.Lock != testLock || n.Pod != "pod-1" {
+				t.Fatalf("notified %+v", n)
+			}
+			gained = gained || n.Leading
+			lost = lost || !n.Leading
+		case <-time.After(5 * time.Second):
+			t.Fatal("notifier was not called")
+		}
+	}
+	if !gained || !lost {
+		t.Fatalf("notified of gaining %v and losing %v, want both", gained, lost)
+	}
+}
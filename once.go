@@ -0,0 +1,56 @@
+package leader
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// RunExactlyOnce runs fn once across every pod and every restart, for
+// one-off work such as a data migration. Pods calling it with the same name
+// compete for a lock of that name; the winner runs fn, and once fn
+// succeeds the completion is recorded on the lock, which from then on
+// belongs to no pod and stays until it is deleted. Every later call,
+// waiting or new, returns nil without running fn. If fn fails, or the pod
+// running it goes away, the lock is released and another pod runs fn
+// again. Delete the lock to run fn once more.
+//
+// The completion is recorded after fn returns; if recording it fails, or
+// the pod dies in between, fn runs again. Make fn safe to repeat when that
+// matters.
+func RunExactlyOnce(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...Option) error {
+	e, err := NewElector(name, jobOptions(opts)...)
+	if err != nil {
+		return err
+	}
+	return e.runOnce(ctx, fn, nil)
+}
+
+// runOnce runs work unless the lock records it completed. It becomes the
+// leader, runs work, and on success completes the lock and hands it to
+// owners; on failure it releases the lock for another pod to try.
+func (e *PodElector) runOnce(ctx context.Context, work func(ctx context.Context) error, owners []metav1.OwnerReference) error {
+	switch err := e.Become(ctx); {
+	case errors.Is(err, ErrCompleted):
+		e.log.Info("The work has already been done", "lock", e.lockName)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if err := e.runLeading(ctx, work); err != nil {
+		if err := e.Resign(context.Background()); err != nil && !errors.Is(err, ErrNotLeader) {
+			e.log.Error(err, "Failed to release the lock after the work failed", "lock", e.lockName)
+		}
+		return err
+	}
+
+	// the work is done; recording that is worth more than a few retries
+	return retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return !errors.Is(err, ErrNotLeader)
+	}, func() error {
+		return e.complete(context.Background(), owners)
+	})
+}
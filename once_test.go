@@ -0,0 +1,58 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunExactlyOnce(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2")
+	runs := 0
+	fn := func(fnErr error) func(context.Context) error {
+		return func(context.Context) error {
+			runs++
+			return fnErr
+		}
+	}
+
+	for i, call := range []struct {
+		pod  string
+		err  error
+		runs int
+	}{
+		{pod: "pod-1", err: errors.New("migration failed"), runs: 1},
+		{pod: "pod-2", runs: 2},
+		{pod: "pod-1", runs: 2},
+		{pod: "pod-2", runs: 2},
+	} {
+		if err := RunExactlyOnce(ctx, "migration", fn(call.err), testOptions(client, call.pod)...); err != call.err {
+			t.Fatalf("call %d: RunExactlyOnce = %v, want %v", i, err, call.err)
+		}
+		if runs != call.runs {
+			t.Fatalf("call %d: fn ran %d times, want %d", i, runs, call.runs)
+		}
+	}
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, "migration", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.OwnerReferences) != 0 || lock.Annotations[CompletedByAnnotation] != "pod-2" {
+		t.Fatalf("completed lock is owned by %v with annotations %v, want no owner", lock.OwnerReferences, lock.Annotations)
+	}
+
+	// deleting the lock runs fn once more
+	if err := client.CoreV1().ConfigMaps(testNamespace).Delete(ctx, "migration", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunExactlyOnce(ctx, "migration", fn(nil), testOptions(client, "pod-1")...); err != nil || runs != 3 {
+		t.Fatalf("RunExactlyOnce after deleting the lock = %v, ran %d times", err, runs)
+	}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, "migration", metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		t.Fatal("completion was not recorded again")
+	}
+}
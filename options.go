@@ -0,0 +1,1099 @@
+package leader
+
+import (
+	"context"
+	"crypto/tls"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// defaultTransferTimeout is how long a leader waits for the named
+	// successor to acknowledge a transfer before giving up.
+	defaultTransferTimeout = time.Second * 30
+
+	// defaultTransferPollInterval is how often both sides of a transfer
+	// check the lock for progress.
+	defaultTransferPollInterval = time.Millisecond * 250
+
+	// defaultMaintenanceInterval is how often the leader re-reads the lock
+	// while holding it.
+	defaultMaintenanceInterval = time.Second * 5
+
+	// defaultTopologyWeight is the factor by which candidates outside the
+	// preferred topology stretch their backoff.
+	defaultTopologyWeight = 2.0
+
+	// defaultRequestTimeout bounds every single API call.
+	defaultRequestTimeout = time.Second * 10
+)
+
+// Option configures an Elector.
+type Option func(*options)
+
+type options struct {
+	client kubernetes.Interface
+	// clientID identifies client, for the caches shared by the Electors of
+	// the process.
+	clientID  *clientID
+	namespace string
+
+	namespaceFile string
+	tokenFile     string
+	caFile        string
+
+	restConfig  *rest.Config
+	impersonate *rest.ImpersonationConfig
+	proxy       func(*http.Request) (*url.URL, error)
+
+	requestTimeout time.Duration
+
+	transferTimeout      time.Duration
+	transferPollInterval time.Duration
+
+	priority            int
+	drain               func(ctx context.Context) error
+	maintenanceInterval time.Duration
+
+	fairQueue bool
+	registry  bool
+
+	preferredZone   string
+	avoidLeaderZone bool
+	topologyWeight  float64
+
+	spotWeight  float64
+	excludeSpot bool
+
+	stepDownOnDrain bool
+
+	backend Backend
+
+	compatBackend Backend
+	compatName    string
+
+	podConditionType v1.PodConditionType
+
+	leaderLabelKey, leaderLabelValue           string
+	leaderAnnotationKey, leaderAnnotationValue string
+
+	serviceAccount string
+
+	lockFinalizer bool
+
+	events        bool
+	eventInterval time.Duration
+
+	logger   Logger
+	logLevel LogLevel
+	jsonLogs bool
+
+	shards        int
+	shardStrategy ShardStrategy
+	shardCooldown time.Duration
+	shardWeight   int
+
+	memberVersion string
+	memberLabels  map[string]string
+
+	leaderInfoName, leaderInfoKey string
+
+	leaderServiceName, leaderServiceHostname string
+	clusterDomain                            string
+
+	notifiers []Notifier
+
+	auditRetention int
+
+	lockHeartbeat bool
+
+	janitorInterval time.Duration
+
+	maxBackoff time.Duration
+
+	readinessCheck func(ctx context.Context) error
+	warmUp         func(ctx context.Context) error
+
+	healthCheck    func(ctx context.Context) error
+	healthFailures int
+	healthCooldown time.Duration
+
+	drainer      *Drainer
+	drainTimeout time.Duration
+
+	eligibilitySelector labels.Selector
+
+	configName string
+
+	nodeLoss        NodeLossPolicy
+	watchLeaderNode bool
+
+	raftPort              int
+	raftElectionTimeout   time.Duration
+	raftHeartbeatInterval time.Duration
+	raftClusterSize       int
+	raftSecret            []byte
+	raftTLS               *tls.Config
+
+	virtualNodes VirtualNodeMode
+
+	readinessGate bool
+
+	// calls records apiserver calls at DebugLevel. It is created with the
+	// client and shared by the electors using it.
+	calls *callLog
+
+	logSampleInterval time.Duration
+
+	leaderPodInformer bool
+
+	finishedLeader FinishedLeaderPolicy
+
+	leaderGone LeaderGonePolicy
+
+	lockExpiry time.Duration
+
+	dryRun bool
+
+	decisionLogSize  int
+	persistDecisions bool
+
+	// rand is nil for the default, crypto-seeded randomness.
+	rand *random
+
+	podName string
+
+	committee int
+
+	retryDesync bool
+
+	userAgent     string
+	priorityGroup string
+
+	remoteMaintenance bool
+
+	nodeSelector labels.Selector
+
+	groupLabel string
+
+	advertise     bool
+	advertiseHost string
+	advertisePort int
+
+	envoyConfigMap string
+	envoyCluster   string
+	envoyPort      int
+
+	meshDestinationRule string
+	meshSubset          string
+
+	backendServiceName string
+
+	failoverWatch bool
+}
+
+func defaultOptions() options {
+	return options{
+		transferTimeout:      defaultTransferTimeout,
+		transferPollInterval: defaultTransferPollInterval,
+		maintenanceInterval:  defaultMaintenanceInterval,
+		topologyWeight:       defaultTopologyWeight,
+		spotWeight:           1,
+		eventInterval:        defaultEventInterval,
+		logLevel:             InfoLevel,
+		requestTimeout:       defaultRequestTimeout,
+		namespaceFile:        defaultNamespaceFile,
+		tokenFile:            defaultTokenFile,
+		caFile:               defaultCAFile,
+		shardWeight:          1,
+		clusterDomain:        defaultClusterDomain,
+		maxBackoff:           defaultMaxBackoffInterval,
+		logSampleInterval:    defaultLogSampleInterval,
+	}
+}
+
+// WithClient makes the Elector use the given client instead of building one
+// from the in-cluster config.
+func WithClient(client kubernetes.Interface) Option {
+	id := &clientID{}
+	return func(o *options) {
+		o.client = client
+		o.clientID = id
+	}
+}
+
+// WithNamespaceFile reads the default namespace from path instead of the
+// service account mount, for runtimes that put it elsewhere.
+func WithNamespaceFile(path string) Option {
+	return func(o *options) {
+		o.namespaceFile = path
+	}
+}
+
+// WithTokenFile reads the service account token for the in-cluster config
+// from path instead of the service account mount. The file is re-read as
+// the token is rotated.
+func WithTokenFile(path string) Option {
+	return func(o *options) {
+		o.tokenFile = path
+	}
+}
+
+// WithCAFile reads the apiserver's CA bundle for the in-cluster config from
+// path instead of the service account mount.
+func WithCAFile(path string) Option {
+	return func(o *options) {
+		o.caFile = path
+	}
+}
+
+// WithRESTConfig builds the client from conf instead of the in-cluster
+// config. Impersonation and proxy settings on conf are honored. It has no
+// effect together with WithClient.
+func WithRESTConfig(conf *rest.Config) Option {
+	return func(o *options) {
+		o.restConfig = conf
+	}
+}
+
+// WithImpersonation makes every request act as the given identity, for
+// clusters where operators must use a dedicated user or service account.
+// It has no effect together with WithClient.
+func WithImpersonation(impersonate rest.ImpersonationConfig) Option {
+	return func(o *options) {
+		o.impersonate = &impersonate
+	}
+}
+
+// WithProxy sends requests to the apiserver through the proxy returned by
+// proxy, such as http.ProxyURL. By default the proxy environment variables
+// are honored. It has no effect together with WithClient.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(o *options) {
+		o.proxy = proxy
+	}
+}
+
+// WithNamespace overrides the namespace the lock is created in. By default
+// the namespace of the current pod's service account is used.
+func WithNamespace(ns string) Option {
+	return func(o *options) {
+		o.namespace = ns
+	}
+}
+
+// WithRequestTimeout bounds each individual API call, so a hung connection
+// to the apiserver cannot freeze the election loop. The default is 10
+// seconds; zero leaves calls bounded only by the caller's context.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = d
+	}
+}
+
+// WithTransferTimeout sets how long TransferTo waits for the successor to
+// acknowledge the handoff. It also bounds how long other candidates defer to
+// a successor after observing a transfer intent.
+func WithTransferTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.transferTimeout = d
+	}
+}
+
+// WithPriority sets the candidate's priority. A candidate whose priority is
+// higher than the current leader's asks the leader to step down in its
+// favour. The default priority is 0.
+func WithPriority(priority int) Option {
+	return func(o *options) {
+		o.priority = priority
+	}
+}
+
+// WithDrain registers a function the leader runs before Resign or TransferTo
+// give up the lock, whether on a step-down request or not, so in-flight work
+// can be finished. It runs before the Drainer of WithDrainer is drained.
+func WithDrain(drain func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.drain = drain
+	}
+}
+
+// WithMaintenanceInterval sets how often the leader re-reads the lock to
+// observe requests from other candidates and detect loss of leadership.
+func WithMaintenanceInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.maintenanceInterval = d
+	}
+}
+
+// WithFairQueue only lets the longest-waiting live candidate in the
+// candidate registry try to take the lock. This avoids starving unlucky pods
+// when many candidates compete under heavy churn. It implies
+// WithCandidateRegistry.
+func WithFairQueue() Option {
+	return func(o *options) {
+		o.fairQueue = true
+		o.registry = true
+	}
+}
+
+// WithCandidateRegistry makes every participating pod, the leader included,
+// keep a heartbeat Lease for the lock recording its liveness, version and
+// labels, so the leader can report how many standbys exist and whether any
+// of them are wedged. Fairness and sharding build on it.
+func WithCandidateRegistry() Option {
+	return func(o *options) {
+		o.registry = true
+	}
+}
+
+// WithPreferredZone makes candidates whose node is not in zone back off
+// longer, so leadership tends to land close to zonal dependencies.
+func WithPreferredZone(zone string) Option {
+	return func(o *options) {
+		o.preferredZone = zone
+	}
+}
+
+// WithAvoidLeaderZone makes candidates in the same zone as the last observed
+// leader back off longer, so a failover tends to leave a failed zone.
+func WithAvoidLeaderZone() Option {
+	return func(o *options) {
+		o.avoidLeaderZone = true
+	}
+}
+
+// WithTopologyWeight sets the factor by which the backoff of candidates that
+// do not match the topology preferences is stretched. The default is 2.
+func WithTopologyWeight(weight float64) Option {
+	return func(o *options) {
+		o.topologyWeight = weight
+	}
+}
+
+// WithSpotPenalty makes candidates on spot or preemptible nodes stretch
+// their backoff by weight, so leadership prefers stable capacity.
+func WithSpotPenalty(weight float64) Option {
+	return func(o *options) {
+		o.spotWeight = weight
+	}
+}
+
+// WithExcludeSpot stops candidates on spot or preemptible nodes from
+// competing for the lock at all.
+func WithExcludeSpot() Option {
+	return func(o *options) {
+		o.excludeSpot = true
+	}
+}
+
+// WithStepDownOnDrain makes the leader resign, after draining its work, as
+// soon as its node is cordoned or its pod is being evicted, so
+// failover happens before the pod is killed.
+func WithStepDownOnDrain() Option {
+	return func(o *options) {
+		o.stepDownOnDrain = true
+	}
+}
+
+// WithBackend selects the kind of object used as the lock. See
+// MigrationBackend for moving an existing fleet from ConfigMap to Lease
+// locks, and AutoBackend for picking one from what the cluster allows.
+func WithBackend(b Backend) Option {
+	return func(o *options) {
+		o.backend = b
+	}
+}
+
+// WithCompatibilityLock makes the Elector also hold the lock name of kind b,
+// as written by an older release, for as long as it leads. Enable it for the
+// release that changes the lock format, so that old and new binaries running
+// side by side during the rollout never both believe they lead.
+func WithCompatibilityLock(b Backend, name string) Option {
+	return func(o *options) {
+		o.compatBackend = b
+		o.compatName = name
+	}
+}
+
+// WithPodCondition keeps a condition of type conditionType on our pod's
+// status in sync with leadership, so kubectl, readiness gates and other
+// controllers can see which pod leads. LeaderPodCondition is a suitable
+// type.
+func WithPodCondition(conditionType v1.PodConditionType) Option {
+	return func(o *options) {
+		o.podConditionType = conditionType
+	}
+}
+
+// WithLeaderLabel sets the label key=value on the leader pod and removes it
+// from former leaders of the same lock, giving external tooling a selector
+// for the active instance. The lock is recorded in LeaderOfLabel, and a pod
+// leading several locks with the same label keeps it until it leads none.
+func WithLeaderLabel(key, value string) Option {
+	return func(o *options) {
+		o.leaderLabelKey = key
+		o.leaderLabelValue = value
+	}
+}
+
+// WithLeaderAnnotation sets the annotation key=value on the leader pod and
+// removes it from itself when leadership is lost.
+func WithLeaderAnnotation(key, value string) Option {
+	return func(o *options) {
+		o.leaderAnnotationKey = key
+		o.leaderAnnotationValue = value
+	}
+}
+
+// WithServiceAccount names the service account the pod runs as. It is used
+// for generated RBAC bindings and diagnostics. The default is "default".
+func WithServiceAccount(name string) Option {
+	return func(o *options) {
+		o.serviceAccount = name
+	}
+}
+
+// WithLockFinalizer puts a finalizer on the lock that is only removed when
+// leadership is given up on purpose, or by a candidate once the owner pod is
+// gone. An accidental deletion then leaves the lock Terminating, which the
+// leader reports, instead of instantly letting a second pod lead.
+func WithLockFinalizer() Option {
+	return func(o *options) {
+		o.lockFinalizer = true
+	}
+}
+
+// WithEvents records Kubernetes Events on our pod for leadership changes
+// and for what standbys observe while waiting. Identical Events are sent at
+// most once per interval, with the repeats folded into the Event's count.
+func WithEvents(interval time.Duration) Option {
+	return func(o *options) {
+		o.events = true
+		if interval > 0 {
+			o.eventInterval = interval
+		}
+	}
+}
+
+// WithLogger makes the Elector write to l instead of the default logger.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithLogLevel sets the minimum level written by the default logger.
+func WithLogLevel(level LogLevel) Option {
+	return func(o *options) {
+		o.logLevel = level
+	}
+}
+
+// WithJSONLogs makes the default logger write every record as a JSON object
+// so election logs can be parsed by log pipelines.
+func WithJSONLogs() Option {
+	return func(o *options) {
+		o.jsonLogs = true
+	}
+}
+
+// withTimeout derives the context for a single API call from ctx.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// WithSharding makes the leader assign shards 0 to shards-1 to the members
+// of the election, itself and every live candidate, and rebalance them with
+// strategy when the membership changes, at most once per cooldown. Members
+// learn their shards from Shards. It implies WithCandidateRegistry.
+//
+// The assignments are stored in the ConfigMap <lock>-shards. It has no
+// owner, so that the assignments outlive the leader that made them, and the
+// Janitor leaves it alone: delete it by hand when retiring the election.
+func WithSharding(shards int, strategy ShardStrategy, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.shards = shards
+		o.shardStrategy = strategy
+		o.shardCooldown = cooldown
+		o.registry = true
+	}
+}
+
+// WithShardWeight sets our weight for ShardWeighted. The default is 1.
+func WithShardWeight(weight int) Option {
+	return func(o *options) {
+		o.shardWeight = weight
+	}
+}
+
+// WithMemberVersion records version, typically the build of the binary, on
+// our registry entry, so the leader and tooling can see which versions take
+// part in the election. It implies WithCandidateRegistry.
+func WithMemberVersion(version string) Option {
+	return func(o *options) {
+		o.memberVersion = version
+		o.registry = true
+	}
+}
+
+// WithMemberLabels records labels on our registry entry. It implies
+// WithCandidateRegistry.
+func WithMemberLabels(labels map[string]string) Option {
+	return func(o *options) {
+		o.memberLabels = labels
+		o.registry = true
+	}
+}
+
+// WithLeaderInfo makes the leader mirror its name, pod IP and epoch, as a
+// JSON LeaderInfo, into key of the ConfigMap name, for example leader-info
+// and current. Other components can mount or watch that key without knowing
+// the format of the lock. Other keys of the ConfigMap are left alone.
+func WithLeaderInfo(name, key string) Option {
+	return func(o *options) {
+		o.leaderInfoName = name
+		o.leaderInfoKey = key
+	}
+}
+
+// WithLeaderService keeps the ExternalName Service name pointed at the
+// leader pod's DNS name. If externalHostname is not empty, the Service is
+// also annotated so that external-dns publishes externalHostname with the
+// leader's IP for clients outside the cluster.
+func WithLeaderService(name, externalHostname string) Option {
+	return func(o *options) {
+		o.leaderServiceName = name
+		o.leaderServiceHostname = externalHostname
+	}
+}
+
+// WithClusterDomain sets the cluster's DNS domain used for pod DNS names.
+// The default is cluster.local.
+func WithClusterDomain(domain string) Option {
+	return func(o *options) {
+		o.clusterDomain = domain
+	}
+}
+
+// WithNotifier adds a Notifier told about every change of leadership. It
+// may be given more than once.
+func WithNotifier(n Notifier) Option {
+	return func(o *options) {
+		o.notifiers = append(o.notifiers, n)
+	}
+}
+
+// WithAudit records every acquisition, resignation, loss and takeover of
+// the lock, with an AuditReason, as an Event labeled with the lock and the
+// audit role. At most retain entries are kept per lock, 100 if retain is 0.
+// The Events are also subject to the apiserver's event TTL, which should
+// be raised where the trail must outlive it.
+func WithAudit(retain int) Option {
+	return func(o *options) {
+		if retain <= 0 {
+			retain = defaultAuditRetention
+		}
+		o.auditRetention = retain
+	}
+}
+
+// WithLockHeartbeat makes the leader record the time on the lock, in
+// LastHeartbeatAnnotation, every maintenance interval. Waiting candidates
+// export the age of that heartbeat as leader_heartbeat_age_seconds, so a
+// stuck leader shows up before anything takes over from it.
+func WithLockHeartbeat() Option {
+	return func(o *options) {
+		o.lockHeartbeat = true
+	}
+}
+
+// WithLockExpiry makes the leader record on the lock, in
+// ExpiresAtAnnotation, that it expires ttl from now, and renew it with every
+// heartbeat as WithLockHeartbeat does. Candidates take over a lock past its
+// expiry even if its owner pod still exists, and a leader that could not
+// renew it in time gives up leadership, so failover from a wedged leader
+// takes at most ttl. ttl should span several maintenance intervals and the
+// warm-up, and exceed the clock skew between nodes.
+func WithLockExpiry(ttl time.Duration) Option {
+	return func(o *options) {
+		o.lockHeartbeat = true
+		o.lockExpiry = ttl
+	}
+}
+
+// WithJanitor makes the leader run a Janitor over the namespace every
+// interval, ten minutes if interval is 0, deleting lock objects orphaned by
+// garbage collection failures.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = defaultJanitorInterval
+		}
+		o.janitorInterval = interval
+	}
+}
+
+// WithMaxBackoff caps the wait between attempts to become the leader, and
+// to take a resource lock, at d. The default is 16 seconds. Large standby
+// fleets want a longer cap to spare the apiserver; small latency-sensitive
+// ones a shorter cap for faster failover.
+func WithMaxBackoff(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.maxBackoff = d
+		}
+	}
+}
+
+// WithReadinessCheck sets a check that must succeed before every attempt to
+// take the lock, for example that caches have synced or migrations were
+// checked, so a pod that could not do the work never wins leadership. While
+// it fails, the candidate keeps backing off and checking again.
+func WithReadinessCheck(check func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.readinessCheck = check
+	}
+}
+
+// WithWarmUp sets a hook run once the lock is taken but before leadership
+// is announced: before Become returns, transition hooks such as the leader
+// label or Events run, and subscribers are told. If the hook fails, the lock
+// is given back and the candidate competes again, so nothing advertises a
+// leader that cannot serve. The hook's ctx carries the new epoch.
+func WithWarmUp(warmUp func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.warmUp = warmUp
+	}
+}
+
+// WithHealthCheck makes the leader run check every maintenance interval.
+// After failures consecutive failures, 3 if failures is 0, the leader
+// drains, resigns and does not compete again for cooldown, so an alive but
+// sick leader hands over without outside help. Run then competes again once
+// the cooldown has passed; callers of Become that loop to re-enter
+// candidacy are held back for the cooldown.
+func WithHealthCheck(check func(ctx context.Context) error, failures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		if failures <= 0 {
+			failures = defaultHealthFailures
+		}
+		o.healthCheck = check
+		o.healthFailures = failures
+		o.healthCooldown = cooldown
+	}
+}
+
+// WithDrainer makes Resign and TransferTo drain d before deleting the lock,
+// waiting at most timeout, 30 seconds if timeout is 0, for in-flight work.
+func WithDrainer(d *Drainer, timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout <= 0 {
+			timeout = defaultDrainTimeout
+		}
+		o.drainer = d
+		o.drainTimeout = timeout
+	}
+}
+
+// WithEligibilitySelector lets only pods whose labels match selector hold
+// the lock, for example to keep leadership on canaries. Labels are re-read
+// while competing and while leading, so removing a label from the leader
+// makes it step down, which also serves to exclude a bad pod in an
+// emergency.
+func WithEligibilitySelector(selector labels.Selector) Option {
+	return func(o *options) {
+		o.eligibilitySelector = selector
+	}
+}
+
+// WithConfigMap reads election parameters from the ConfigMap name and
+// watches it, applying changes without a restart, so failover behavior can
+// be tuned while an incident is ongoing. The keys are the Config constants;
+// values in the ConfigMap override those set by options.
+func WithConfigMap(name string) Option {
+	return func(o *options) {
+		o.configName = name
+	}
+}
+
+// WithNodeLossPolicy says what candidates may do when the leader's pod is
+// bound to a node that has been deleted. The default, NodeLossWait, leaves
+// it to the pod garbage collector.
+func WithNodeLossPolicy(policy NodeLossPolicy) Option {
+	return func(o *options) {
+		o.nodeLoss = policy
+	}
+}
+
+// WithLeaderNodeWatch makes waiting candidates watch the leader's node and
+// re-evaluate taking over as soon as it is deleted or tainted unreachable,
+// instead of when their backoff next expires. Together with
+// WithNodeLossPolicy this makes failover after losing a node fast.
+func WithLeaderNodeWatch() Option {
+	return func(o *options) {
+		o.watchLeaderNode = true
+	}
+}
+
+// WithRaftPort sets the port a RaftElector serves its peers on, 2380 by
+// default.
+func WithRaftPort(port int) Option {
+	return func(o *options) {
+		o.raftPort = port
+	}
+}
+
+// WithRaftTimeouts sets how long a RaftElector follower waits for the
+// leader before standing for election, and how often the leader sends
+// heartbeats. Zero keeps the defaults of 3 seconds and 500 milliseconds.
+func WithRaftTimeouts(election, heartbeat time.Duration) Option {
+	return func(o *options) {
+		o.raftElectionTimeout = election
+		o.raftHeartbeatInterval = heartbeat
+	}
+}
+
+// WithRaftClusterSize fixes the number of RaftElector peers a majority is
+// counted from, instead of the number of addresses the service has when
+// the elector starts. Set it to the replica count when pods may start
+// before all of their peers are resolvable.
+func WithRaftClusterSize(n int) Option {
+	return func(o *options) {
+		o.raftClusterSize = n
+	}
+}
+
+// WithRaftSecret makes RaftElector peers sign their votes and heartbeats
+// with secret, shared by every peer, and reject messages not signed with
+// it or sent longer than an election timeout ago. Mount it from a Secret.
+func WithRaftSecret(secret []byte) Option {
+	return func(o *options) {
+		o.raftSecret = secret
+	}
+}
+
+// WithRaftTLS makes RaftElector peers talk over TLS with conf, both as
+// servers and as clients. To authenticate peers, give conf a certificate
+// trusted by the other peers, their CA as RootCAs and ClientCAs, and
+// tls.RequireAndVerifyClientCert as ClientAuth.
+func WithRaftTLS(conf *tls.Config) Option {
+	return func(o *options) {
+		o.raftTLS = conf
+	}
+}
+
+// WithVirtualNodes sets whether pods are taken to run on virtual kubelet
+// nodes, by default detected from the pod.
+func WithVirtualNodes(mode VirtualNodeMode) Option {
+	return func(o *options) {
+		o.virtualNodes = mode
+	}
+}
+
+// WithReadinessGate keeps the condition conditionType, which the pod
+// template must list in spec.readinessGates, True only on the leader. The
+// leader is then the only Ready pod, so a plain Service routes to it
+// without selectors or labels that change with leadership. Candidates clear
+// a condition left True by an earlier run of their container before they
+// compete. LeaderPodCondition is a suitable type.
+func WithReadinessGate(conditionType v1.PodConditionType) Option {
+	return func(o *options) {
+		o.podConditionType = conditionType
+		o.readinessGate = true
+	}
+}
+
+// WithLogSampling logs messages a waiting candidate repeats, such as "Not
+// the leader. Waiting", at most once per interval, one minute by default,
+// noting how many repeats were suppressed. A new leader or reason is logged
+// right away. Zero logs every repeat.
+func WithLogSampling(interval time.Duration) Option {
+	return func(o *options) {
+		o.logSampleInterval = interval
+	}
+}
+
+// WithLeaderPodInformer makes waiting candidates read the leader's pod from
+// an informer on it instead of getting it from the apiserver every time
+// their backoff expires, and re-evaluate taking over as soon as it is
+// deleted, terminates, fails or turns unready. Candidates of the process
+// waiting for the same pod share the informer. It needs list and watch on
+// pods.
+func WithLeaderPodInformer() Option {
+	return func(o *options) {
+		o.leaderPodInformer = true
+	}
+}
+
+// WithFinishedLeaderPolicy says what candidates may do when the leader's
+// pod has succeeded or failed for any other reason than eviction. The
+// default, FinishedLeaderWait, waits for the pod to be deleted.
+func WithFinishedLeaderPolicy(policy FinishedLeaderPolicy) Option {
+	return func(o *options) {
+		o.finishedLeader = policy
+	}
+}
+
+// WithLeaderGonePolicy says what candidates may do when the pod owning the
+// lock no longer exists. The default, LeaderGoneWait, leaves the lock to the
+// garbage collector.
+func WithLeaderGonePolicy(policy LeaderGonePolicy) Option {
+	return func(o *options) {
+		o.leaderGone = policy
+	}
+}
+
+// WithRemoteMaintenance makes the Elector re-read its pod every attempt and
+// maintenance tick to honour MaintenanceAnnotation set on it by others,
+// such as with leaderctl maintenance, not only by EnterMaintenance.
+func WithRemoteMaintenance() Option {
+	return func(o *options) {
+		o.remoteMaintenance = true
+	}
+}
+
+// WithNodeSelector restricts leadership to pods whose node matches
+// selector, such as nodes with local SSDs or in a given zone. Candidates on
+// other nodes do not compete, and a leader whose node stops matching hands
+// over. Node labels are re-read every 30 seconds. It needs get on nodes.
+func WithNodeSelector(selector labels.Selector) Option {
+	return func(o *options) {
+		o.nodeSelector = selector
+	}
+}
+
+// WithGroupLabel partitions the election by the value of the pod label key,
+// such as shard=a, b or c, so that the pods of one deployment elect a leader
+// per group, each among its own group only. The lock of a group is named by
+// GroupLockName after the lock name and the group. Pods without the label
+// cannot take part.
+func WithGroupLabel(key string) Option {
+	return func(o *options) {
+		o.groupLabel = key
+	}
+}
+
+// WithAdvertiseAddress makes the leader record host:port on the lock as the
+// address clients reach it at, for ResolveLeaderAddress, the follower
+// package and LeaderInfo. An empty host is the pod IP; a zero port leaves
+// the port out.
+func WithAdvertiseAddress(host string, port int) Option {
+	return func(o *options) {
+		o.advertise = true
+		o.advertiseHost = host
+		o.advertisePort = port
+	}
+}
+
+// WithEnvoyEndpoints makes the leader export itself as the only endpoint
+// of the Envoy cluster, at port of its pod IP, whenever it gains leadership.
+// The export is an Envoy v3 EDS discovery response kept under EDSKey of the
+// ConfigMap name, which Envoy can read as a file where it is mounted, or
+// poll from EDSHandler.
+func WithEnvoyEndpoints(name, cluster string, port int) Option {
+	return func(o *options) {
+		o.envoyConfigMap = name
+		o.envoyCluster = cluster
+		o.envoyPort = port
+	}
+}
+
+// WithIstioRouting keeps the subset of the Istio DestinationRule
+// destinationRule selecting the leader pod alone, by the EpochLabel the
+// leader puts on its pod, so that a VirtualService routing to the subset
+// sends mesh traffic to the leader only. The subset is added if the rule
+// lacks it. It needs patch on pods and get and update on destinationrules.
+func WithIstioRouting(destinationRule, subset string) Option {
+	return func(o *options) {
+		o.meshDestinationRule = destinationRule
+		o.meshSubset = subset
+	}
+}
+
+// WithBackendService keeps the selector of the Service name selecting the
+// leader pod alone, by the EpochLabel the leader puts on its pod, for Gateway
+// API HTTPRoutes and other consumers that route to a Service's endpoints.
+// The Service must exist; its selector is replaced on every change of
+// leader. It needs patch on pods and services.
+func WithBackendService(name string) Option {
+	return func(o *options) {
+		o.backendServiceName = name
+	}
+}
+
+// WithFailoverWatch makes waiting candidates watch the lock and, as with
+// WithLeaderPodInformer, the leader's pod, and re-evaluate taking over the
+// moment either is deleted or starts being deleted, so that failover takes
+// as long as garbage collection rather than as long as the backoff. The
+// time from the first sign of the leader going to taking over is exported
+// as leader_failover_seconds. It needs list and watch on pods and watch on
+// the lock objects.
+func WithFailoverWatch() Option {
+	return func(o *options) {
+		o.failoverWatch = true
+	}
+}
+
+// WithDryRun makes the Elector read and decide as usual but change
+// nothing: every write is sent as a server-side dry run, so that it is still
+// validated, admitted and authorized, and logged, and the Elector never
+// becomes the leader, logging instead that it would have. Become then only
+// returns once ctx is cancelled. Use it to validate policies against a
+// production cluster. It needs a client the package builds, so it cannot be
+// combined with WithClient.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithDecisionLog makes the Elector record the decisions of its election
+// and maintenance loops, the latest size of them or 128 if size is 0, for
+// Decisions to return. With persist, they are also written on every change
+// of leadership to the lock's companion ConfigMap, <lock>-decisions, under
+// the pod's name, so failovers can be reconstructed after the pods
+// involved are gone. That needs get, create and patch on configmaps.
+func WithDecisionLog(size int, persist bool) Option {
+	return func(o *options) {
+		if size <= 0 {
+			size = defaultDecisionLogSize
+		}
+		o.decisionLogSize = size
+		o.persistDecisions = persist
+	}
+}
+
+// WithRandSource makes the Elector draw the jitter of its backoffs, and a
+// RaftElector its election timeouts, from src rather than from a source
+// seeded from crypto/rand, so that tests and simulations can reproduce
+// their timing with rand.NewSource(seed). Electors built with the same
+// options share src.
+func WithRandSource(src rand.Source) Option {
+	r := newRandom(src)
+	return func(o *options) {
+		o.rand = r
+	}
+}
+
+// WithPodName sets the name of the pod the Elector runs in, instead of
+// reading it from the POD_NAME environment variable, for harnesses that run
+// many candidates in one process, such as cmd/leader-bench.
+func WithPodName(name string) Option {
+	return func(o *options) {
+		o.podName = name
+	}
+}
+
+// WithCommittee makes the election two-staged, for hundreds of candidates:
+// candidates are spread over seats buckets by a hash of their pod name, the
+// candidates of each bucket elect one of them to a committee seat, and only
+// committee members contend for the lock. Candidates without a seat only
+// poll their bucket's seat, so a failover has at most seats candidates
+// racing for the lock instead of the whole fleet. Every candidate of a lock
+// must use the same number of seats. It needs get, create, patch and delete
+// on leases.
+func WithCommittee(seats int) Option {
+	return func(o *options) {
+		o.committee = seats
+	}
+}
+
+// WithRetryDesync makes candidates retry in fixed slots of their backoff
+// window, derived from a hash of their pod's UID, instead of at a random
+// jitter from when they last tried. The slots are laid on the wall clock, so
+// a fleet that starts waiting at the same moment, as after a failover or a
+// rollout, keeps its retries spread evenly over the window rather than
+// spiking together. The leader_lock_attempts_total and leader_retry_phase
+// metrics show the effect.
+func WithRetryDesync() Option {
+	return func(o *options) {
+		o.retryDesync = true
+	}
+}
+
+// WithUserAgent sets the user agent of the Elector's requests, so that
+// election traffic can be told apart in audit logs and apiserver metrics.
+// FlowSchemas cannot match a user agent; use WithPriorityGroup to classify
+// the traffic under API Priority and Fairness. It has no effect together
+// with WithClient.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithPriorityGroup adds group to the groups of every request, by having
+// the pod's service account impersonate itself with the extra group, so
+// that cluster admins can match election traffic with a FlowSchema subject
+// and give standby polling a low priority level. A request rejected with
+// 429 is retried no sooner than its Retry-After says, with or without this
+// option. It needs to impersonate the service account and the group, and
+// has no effect together with WithClient.
+func WithPriorityGroup(group string) Option {
+	return func(o *options) {
+		o.priorityGroup = group
+	}
+}
+
+// WithLargeFleetProfile tunes the Elector for hundreds of candidates per
+// lock, for which the defaults have the apiserver answer a poll from every
+// standby every few seconds, and a failover set them all polling at once:
+//   - backoff is capped at a minute rather than 16 seconds, cutting the
+//     steady-state polling of the standbys by nearly four;
+//   - standbys watch the lock and the leader's pod, WithFailoverWatch, so
+//     that the longer cap does not slow failover down;
+//   - members keep heartbeat Leases, WithCandidateRegistry, so that the
+//     leader reports how many standbys are alive;
+//   - standbys retry in slots of their backoff window, WithRetryDesync, so
+//     that those losing the race for a freed lock do not retry together;
+//   - repeated waiting messages are logged once a minute.
+//
+// Options given after it override its choices. Measure a configuration with
+// cmd/leader-bench before relying on it.
+func WithLargeFleetProfile() Option {
+	profile := []Option{
+		WithMaxBackoff(time.Minute),
+		WithFailoverWatch(),
+		WithCandidateRegistry(),
+		WithRetryDesync(),
+		WithLogSampling(time.Minute),
+	}
+	return func(o *options) {
+		for _, opt := range profile {
+			opt(o)
+		}
+	}
+}
+
+func (o *options) getLogger() Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return NewLogger(o.logLevel, o.jsonLogs)
+}
+
+// nodeAware reports whether any option needs our node to be looked up.
+func (o *options) nodeAware() bool {
+	return o.preferredZone != "" || o.avoidLeaderZone || o.spotWeight != 1 || o.excludeSpot || o.nodeSelector != nil
+}
+
+// myPodName returns the name of our pod, from WithPodName or the downward
+// API.
+func (o *options) myPodName() string {
+	if o.podName != "" {
+		return o.podName
+	}
+	return os.Getenv(PodNameEnvVar)
+}
@@ -0,0 +1,66 @@
+package leader
+
+import (
+	"context"
+	"errors"
+)
+
+// Pause stops the Elector from competing for the lock until Resume is
+// called, for example while the pod recovers local state or backfills
+// caches that make it unfit to lead. A Become in progress waits without
+// making attempts. If resign is true and we lead, leadership is given up as
+// well; otherwise a current leader keeps the lock and only a later
+// candidacy is held back.
+func (e *PodElector) Pause(ctx context.Context, resign bool) error {
+	e.mu.Lock()
+	if e.resumed == nil {
+		e.resumed = make(chan struct{})
+	}
+	e.mu.Unlock()
+	e.log.Info("Pausing candidacy", "lock", e.lockName)
+
+	if !resign {
+		return nil
+	}
+	if err := e.Resign(ctx); err != nil && !errors.Is(err, ErrNotLeader) {
+		return err
+	}
+	return nil
+}
+
+// Resume lets a paused Elector compete for the lock again.
+func (e *PodElector) Resume() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.resumed == nil {
+		return
+	}
+	close(e.resumed)
+	e.resumed = nil
+	e.log.Info("Resuming candidacy", "lock", e.lockName)
+}
+
+// Paused reports whether candidacy is paused.
+func (e *PodElector) Paused() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.resumed != nil
+}
+
+// waitResumed blocks while candidacy is paused, or until ctx is cancelled.
+func (e *PodElector) waitResumed(ctx context.Context) error {
+	e.mu.Lock()
+	resumed := e.resumed
+	e.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+
+	e.log.Info("Candidacy is paused, waiting", "lock", e.lockName)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		leader bool
+		resign bool
+		// leading is whether we still lead once paused
+		leading bool
+	}{
+		{name: "candidate", leading: false},
+		{name: "leader keeps the lock", leader: true, leading: true},
+		{name: "leader resigns", leader: true, resign: true, leading: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1")
+			if tc.leader {
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+
+			if err := e.Pause(context.Background(), tc.resign); err != nil {
+				t.Fatalf("Pause: %v", err)
+			}
+			if !e.Paused() {
+				t.Fatal("Paused is false after Pause")
+			}
+			if e.IsLeader() != tc.leading {
+				t.Fatalf("IsLeader = %v after Pause, want %v", e.IsLeader(), tc.leading)
+			}
+			if tc.leading {
+				return
+			}
+			if ok, err := e.TryAcquire(context.Background()); err != nil || ok {
+				t.Fatalf("TryAcquire while paused = %v, %v", ok, err)
+			}
+
+			e.Resume()
+			if e.Paused() {
+				t.Fatal("Paused is true after Resume")
+			}
+			if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire after Resume = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestBecomeWaitsWhilePaused(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+	if err := e.Pause(context.Background(), false); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := e.BecomeAsync(ctx)
+	select {
+	case err := <-done:
+		t.Fatalf("Become returned %v while paused", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock taken by %q while paused", owner)
+	}
+
+	e.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("Become after Resume: %v", err)
+	}
+	if !e.IsLeader() {
+		t.Fatal("not the leader after Resume")
+	}
+}
@@ -0,0 +1,73 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PinnedLeaderAnnotation pins, on the lock, leadership to the pod it names,
+// for example an instrumented pod during an incident. A leader that is not
+// the pod hands the lock to it with a cooperative transfer, and other
+// candidates defer to it, for as long as the pod is running. Every new
+// leader carries it over to the lock it creates.
+const PinnedLeaderAnnotation = "leader.seamounts.io/pinned-leader"
+
+// PinLeader pins leadership of the lock lockName in ns to the pod podName,
+// or with an empty podName lifts the pin. b is the backend the electors
+// use.
+func PinLeader(ctx context.Context, client kubernetes.Interface, ns, lockName string, b Backend, podName string) error {
+	lb, err := newBackend(b, client, ns, defaultRequestTimeout, defaultLogger)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if podName != "" {
+		value = podName
+	}
+	err = setAnnotations(ctx, lb, lockName, "", map[string]interface{}{
+		PinnedLeaderAnnotation: value,
+	})
+	if err != nil {
+		return fmt.Errorf("pin leader of lock %s/%s: %w", ns, lockName, err)
+	}
+	return nil
+}
+
+// pinned returns the pod leadership was last seen pinned to.
+func (e *PodElector) pinned() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pinnedLeader
+}
+
+// pinnedElsewhere returns the pod leadership is pinned to if it is not ours
+// and is running, or "". A pin to a pod that is gone or not running is
+// ignored, so that a stale pin never leaves the lock without a leader.
+func (e *PodElector) pinnedElsewhere(ctx context.Context) string {
+	pinned := e.pinned()
+	if pinned == "" || pinned == e.owner.Name {
+		return ""
+	}
+
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	pod, err := e.kube().CoreV1().Pods(e.ns).Get(ctx, pinned, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		e.warnSampled("Leadership is pinned to a pod that does not exist, ignoring the pin", "lock", e.lockName, "pinned", pinned)
+		return ""
+	case err != nil:
+		e.log.Error(forbidden(err, "get", v1.Resource("pods"), e.ns), "Failed to get the pinned leader", "pinned", pinned)
+		return ""
+	case pod.Status.Phase != v1.PodRunning || pod.GetDeletionTimestamp() != nil:
+		e.warnSampled("Leadership is pinned to a pod that is not running, ignoring the pin", "lock", e.lockName, "pinned", pinned, "phase", pod.Status.Phase)
+		return ""
+	}
+	return pinned
+}
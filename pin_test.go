@@ -0,0 +1,94 @@
+package leader
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPinLeader(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2")
+	e := newTestElector(t, client, "pod-1")
+	if ok, err := e.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	for _, pod := range []string{"pod-2", ""} {
+		if err := PinLeader(ctx, client, testNamespace, testLock, ConfigMapBackend, pod); err != nil {
+			t.Fatalf("PinLeader(%q): %v", pod, err)
+		}
+		lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, testLock, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pinned, ok := lock.Annotations[PinnedLeaderAnnotation]; pinned != pod || ok != (pod != "") {
+			t.Fatalf("lock annotations after PinLeader(%q) = %v", pod, lock.Annotations)
+		}
+	}
+	if err := PinLeader(ctx, client, testNamespace, "missing", ConfigMapBackend, "pod-2"); err == nil {
+		t.Fatal("PinLeader of a missing lock succeeded")
+	}
+}
+
+func TestPinnedElsewhere(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		pinned string
+		phase  v1.PodPhase
+		want   string
+	}{
+		{name: "not pinned", phase: v1.PodRunning},
+		{name: "pinned to us", pinned: "pod-1", phase: v1.PodRunning},
+		{name: "pinned to a missing pod", pinned: "pod-3", phase: v1.PodRunning},
+		{name: "pinned to a pending pod", pinned: "pod-2", phase: v1.PodPending},
+		{name: "pinned to a running pod", pinned: "pod-2", phase: v1.PodRunning, want: "pod-2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			updatePod(t, client, "pod-2", func(pod *v1.Pod) { pod.Status.Phase = tc.phase })
+			e := newTestElector(t, client, "pod-1")
+			e.mu.Lock()
+			e.pinnedLeader = tc.pinned
+			e.mu.Unlock()
+
+			if got := e.pinnedElsewhere(context.Background()); got != tc.want {
+				t.Fatalf("pinnedElsewhere = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPinCarriedOver(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2")
+	holder := newTestElector(t, client, "pod-2")
+	if ok, err := holder.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if err := PinLeader(ctx, client, testNamespace, testLock, ConfigMapBackend, "pod-1"); err != nil {
+		t.Fatal(err)
+	}
+	e := newTestElector(t, client, "pod-1")
+	lock, err := e.getLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.observeEligibility(lock)
+	if err := holder.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	if ok, err := e.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire of the pinned pod = %v, %v", ok, err)
+	}
+	created, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, testLock, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Annotations[PinnedLeaderAnnotation] != "pod-1" {
+		t.Fatalf("new lock annotations = %v, want the pin carried over", created.Annotations)
+	}
+}
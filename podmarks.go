@@ -0,0 +1,149 @@
+package leader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// LeaderOfLabelPrefix starts the labels set to "true" on the leader pod, one
+// per lock it leads, next to the label and annotation of WithLeaderLabel and
+// WithLeaderAnnotation. See LeaderOfLabel.
+const LeaderOfLabelPrefix = "leader.seamounts.io/leader-of-"
+
+// maxLabelNameLength is the longest name part of a label key.
+const maxLabelNameLength = 63
+
+// LeaderOfLabel returns the label recording that a pod leads lockName. Lock
+// names too long for a label are shortened and suffixed with a hash of the
+// full name.
+func LeaderOfLabel(lockName string) string {
+	prefix := strings.SplitN(LeaderOfLabelPrefix, "/", 2)[1]
+	name := lockName
+	if max := maxLabelNameLength - len(prefix); len(name) > max {
+		sum := sha256.Sum256([]byte(lockName))
+		hash := hex.EncodeToString(sum[:])[:8]
+		name = strings.TrimRight(name[:max-len(hash)-1], "-.") + "-" + hash
+	}
+	return LeaderOfLabelPrefix + name
+}
+
+// markLeaderPod adds the configured leader label and annotation to our pod
+// when we gain leadership and removes them when we lose it. On gaining
+// leadership it also strips the marks from any former leader of this lock
+// that did not get the chance to remove them itself.
+func (e *PodElector) markLeaderPod(leading bool) {
+	// losing leadership may be down to the election's context being
+	// cancelled, and the marks should still come off
+	ctx := context.Background()
+	if leading {
+		ctx = e.electionContext()
+	}
+	if err := e.patchPodMarks(ctx, e.owner.Name, leading); err != nil {
+		e.log.Error(err, "Failed to update leader marks", "pod", e.owner.Name)
+	}
+
+	if !leading {
+		return
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{LeaderOfLabel(e.lockName): "true"})
+	listCtx, cancel := e.request(ctx)
+	pods, err := e.kube().CoreV1().Pods(e.ns).List(listCtx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	cancel()
+	if err != nil {
+		e.log.Error(err, "Failed to list former leader pods")
+		return
+	}
+	for _, pod := range pods.Items {
+		if pod.Name == e.owner.Name {
+			continue
+		}
+		e.log.Info("Removing leader marks from former leader", "pod", pod.Name)
+		if err := e.patchPodMarks(ctx, pod.Name, false); err != nil {
+			e.log.Error(err, "Failed to remove leader marks", "pod", pod.Name)
+		}
+	}
+}
+
+// patchPodMarks sets the leader label and annotation and our LeaderOfLabel
+// on the named pod or, when it no longer leads, removes our LeaderOfLabel.
+// A pod may lead other locks with the same marks, so the label and
+// annotation only come off with the last LeaderOfLabel.
+func (e *PodElector) patchPodMarks(ctx context.Context, podName string, leading bool) error {
+	if leading {
+		return e.patchPod(ctx, podName, "", e.podMarks(map[string]interface{}{LeaderOfLabel(e.lockName): "true"}, true))
+	}
+
+	pods := e.kube().CoreV1().Pods(e.ns)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		reqCtx, cancel := e.request(ctx)
+		pod, err := pods.Get(reqCtx, podName, metav1.GetOptions{})
+		cancel()
+		switch {
+		case apierrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			return forbidden(err, "get", v1.Resource("pods"), e.ns)
+		}
+
+		ours := LeaderOfLabel(e.lockName)
+		for key := range pod.Labels {
+			if key != ours && strings.HasPrefix(key, LeaderOfLabelPrefix) {
+				// still leading another lock
+				return e.patchPod(ctx, podName, pod.ResourceVersion, map[string]interface{}{
+					"labels": map[string]interface{}{ours: nil},
+				})
+			}
+		}
+		return e.patchPod(ctx, podName, pod.ResourceVersion, e.podMarks(map[string]interface{}{ours: nil}, false))
+	})
+}
+
+// podMarks returns the metadata of a merge patch setting or removing the
+// configured marks, along with podLabels.
+func (e *PodElector) podMarks(podLabels map[string]interface{}, leading bool) map[string]interface{} {
+	if key := e.opts.leaderLabelKey; key != "" {
+		podLabels[key] = markValue(leading, e.opts.leaderLabelValue)
+	}
+	metadata := map[string]interface{}{"labels": podLabels}
+	if key := e.opts.leaderAnnotationKey; key != "" {
+		metadata["annotations"] = map[string]interface{}{key: markValue(leading, e.opts.leaderAnnotationValue)}
+	}
+	return metadata
+}
+
+// patchPod merges metadata into the named pod, provided it is still at
+// resourceVersion unless that is empty.
+func (e *PodElector) patchPod(ctx context.Context, podName, resourceVersion string, metadata map[string]interface{}) error {
+	if resourceVersion != "" {
+		metadata["resourceVersion"] = resourceVersion
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	_, err = e.kube().CoreV1().Pods(e.ns).Patch(ctx, podName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return forbidden(err, "patch", v1.Resource("pods"), e.ns)
+}
+
+func markValue(leading bool, value string) interface{} {
+	if leading {
+		return value
+	}
+	return nil
+}
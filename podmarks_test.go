@@ -0,0 +1,103 @@
+package leader
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestLeaderOfLabel(t *testing.T) {
+	long := strings.Repeat("a", 80)
+	for _, tc := range []struct {
+		lock string
+		want string
+	}{
+		{lock: "my-lock", want: LeaderOfLabelPrefix + "my-lock"},
+		{lock: "tenant.my-lock", want: LeaderOfLabelPrefix + "tenant.my-lock"},
+		{lock: long},
+		{lock: long + "-b"},
+	} {
+		got := LeaderOfLabel(tc.lock)
+		if errs := validation.IsQualifiedName(got); len(errs) > 0 {
+			t.Errorf("LeaderOfLabel(%q) = %q is not a label key: %v", tc.lock, got, errs)
+		}
+		if tc.want != "" && got != tc.want {
+			t.Errorf("LeaderOfLabel(%q) = %q, want %q", tc.lock, got, tc.want)
+		}
+	}
+	if LeaderOfLabel(long) == LeaderOfLabel(long+"-b") {
+		t.Error("long lock names sharing a prefix got the same label")
+	}
+}
+
+// podMarks returns the labels and annotations of pod.
+func podMarks(t *testing.T, e *PodElector, pod string) (map[string]string, map[string]string) {
+	t.Helper()
+	p, err := e.kube().CoreV1().Pods(testNamespace).Get(context.Background(), pod, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Labels, p.Annotations
+}
+
+func TestLeaderMarksOfSeveralLocks(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	opts := []Option{WithLeaderLabel("role", "leader"), WithLeaderAnnotation("example.com/leading", "yes")}
+	a := newTestElectorOf(t, client, "lock-a", "pod-1", opts...)
+	b := newTestElectorOf(t, client, "lock-b", "pod-1", opts...)
+	for _, e := range []*PodElector{a, b} {
+		if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+			t.Fatalf("TryAcquire = %v, %v", ok, err)
+		}
+	}
+	podLabels, _ := podMarks(t, a, "pod-1")
+	if podLabels["role"] != "leader" || podLabels[LeaderOfLabel("lock-a")] != "true" || podLabels[LeaderOfLabel("lock-b")] != "true" {
+		t.Fatalf("leader labels = %v", podLabels)
+	}
+
+	if err := a.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	podLabels, annotations := podMarks(t, a, "pod-1")
+	if _, ok := podLabels[LeaderOfLabel("lock-a")]; ok {
+		t.Fatalf("label of the lost lock left behind: %v", podLabels)
+	}
+	if podLabels["role"] != "leader" || annotations["example.com/leading"] != "yes" {
+		t.Fatalf("marks removed while still leading lock-b: %v, %v", podLabels, annotations)
+	}
+
+	if err := b.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	podLabels, annotations = podMarks(t, a, "pod-1")
+	if len(podLabels) != 0 || len(annotations) != 0 {
+		t.Fatalf("marks left after losing every lock: %v, %v", podLabels, annotations)
+	}
+}
+
+func TestLeaderMarksOfFormerLeader(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	former := newTestElector(t, client, "pod-2", WithLeaderLabel("role", "leader"))
+	other := newTestElectorOf(t, client, "other-lock", "pod-2", WithLeaderLabel("role", "leader"))
+	for _, e := range []*PodElector{former, other} {
+		if err := e.patchPodMarks(context.Background(), "pod-2", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// pod-2 went away without a word and pod-1 took over
+	e := newTestElector(t, client, "pod-1", WithLeaderLabel("role", "leader"))
+	if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	podLabels, _ := podMarks(t, e, "pod-2")
+	if _, ok := podLabels[LeaderOfLabel(testLock)]; ok {
+		t.Fatalf("former leader still marked as the leader of %s: %v", testLock, podLabels)
+	}
+	if podLabels["role"] != "leader" || podLabels[LeaderOfLabel("other-lock")] != "true" {
+		t.Fatalf("former leader lost the marks of other-lock: %v", podLabels)
+	}
+}
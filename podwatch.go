@@ -0,0 +1,179 @@
+package leader
+
+import (
+	"context"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fieldsel "k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podInformers are the informers on leader pods of the process. Electors of
+// different locks waiting for the same leader, as the electors of a Manager
+// in a standby pod usually are, share one.
+var podInformers = struct {
+	mu        sync.Mutex
+	informers map[podInformerKey]*podInformer
+}{informers: map[podInformerKey]*podInformer{}}
+
+type podInformerKey struct {
+	client    *clientID
+	namespace string
+	name      string
+}
+
+// podInformer is a shared informer on a single pod, which wakes the
+// electors subscribed to it when the pod changes in a way that matters for
+// the election. It is stopped and evicted once its last subscriber is gone.
+type podInformer struct {
+	informer cache.SharedInformer
+	stop     context.CancelFunc
+
+	// subscribers is guarded by podInformers.mu.
+	subscribers map[*PodElector]bool
+}
+
+// podWatch is the subscription of an elector to the informer on the pod of
+// the leader it waits for.
+type podWatch struct {
+	key      podInformerKey
+	informer *podInformer
+}
+
+// watchLeaderPod subscribes us to an informer on name, the current leader's
+// pod, so that leaderPod reads it from the informer's cache rather than the
+// apiserver, and the election loop is woken as soon as the pod is deleted,
+// terminates, fails or turns unready. Watching a different pod ends the
+// previous subscription.
+func (e *PodElector) watchLeaderPod(name string) {
+	key := podInformerKey{client: e.clientID, namespace: e.ns, name: name}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.podWatch != nil {
+		if e.podWatch.key == key {
+			return
+		}
+		e.podWatch.unsubscribe(e)
+	}
+
+	podInformers.mu.Lock()
+	defer podInformers.mu.Unlock()
+	i := podInformers.informers[key]
+	if i == nil {
+		i = newPodInformer(key, e.kube(), e.log)
+		podInformers.informers[key] = i
+	}
+	i.subscribers[e] = true
+	e.podWatch = &podWatch{key: key, informer: i}
+}
+
+// stopPodWatch ends our subscription to the leader pod's informer.
+func (e *PodElector) stopPodWatch() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.podWatch != nil {
+		e.podWatch.unsubscribe(e)
+		e.podWatch = nil
+	}
+}
+
+// watchedLeaderPod returns name from the informer we are subscribed to, if
+// it watches name and has synced. A pod missing from the cache is left to a
+// GET to confirm, so that a lagging informer never makes us act on a leader
+// that is gone.
+func (e *PodElector) watchedLeaderPod(name string) (*v1.Pod, bool) {
+	e.mu.Lock()
+	w := e.podWatch
+	e.mu.Unlock()
+	if w == nil || w.key.name != name || !w.informer.informer.HasSynced() {
+		return nil, false
+	}
+	obj, ok, err := w.informer.informer.GetStore().GetByKey(e.ns + "/" + name)
+	if err != nil || !ok {
+		return nil, false
+	}
+	return obj.(*v1.Pod).DeepCopy(), true
+}
+
+// unsubscribe removes e from the informer, stopping it once nobody is left.
+func (w *podWatch) unsubscribe(e *PodElector) {
+	podInformers.mu.Lock()
+	defer podInformers.mu.Unlock()
+	delete(w.informer.subscribers, e)
+	if len(w.informer.subscribers) == 0 {
+		w.informer.stop()
+		delete(podInformers.informers, w.key)
+	}
+}
+
+func newPodInformer(key podInformerKey, client kubernetes.Interface, logger Logger) *podInformer {
+	selector := fieldsel.OneTermEqualSelector("metadata.name", key.name).String()
+	pods := client.CoreV1().Pods(key.namespace)
+	ctx, cancel := context.WithCancel(context.Background())
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = selector
+			return pods.List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = selector
+			return pods.Watch(ctx, opts)
+		},
+	}
+
+	i := &podInformer{
+		informer:    cache.NewSharedInformer(lw, &v1.Pod{}, 0),
+		stop:        cancel,
+		subscribers: map[*PodElector]bool{},
+	}
+	i.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, ok := oldObj.(*v1.Pod)
+			pod, ok2 := newObj.(*v1.Pod)
+			if ok && ok2 && leaderPodChanged(old, pod) {
+				logger.Debug("Leader's pod changed", "namespace", key.namespace, "pod", key.name, "phase", pod.Status.Phase)
+				i.wake()
+			}
+		},
+		DeleteFunc: func(interface{}) {
+			logger.Debug("Leader's pod was deleted", "namespace", key.namespace, "pod", key.name)
+			i.wake()
+		},
+	})
+	go i.informer.Run(ctx.Done())
+	return i
+}
+
+// wake wakes the election loops of the subscribers.
+func (i *podInformer) wake() {
+	podInformers.mu.Lock()
+	defer podInformers.mu.Unlock()
+	for e := range i.subscribers {
+		e.wake()
+	}
+}
+
+// leaderPodChanged reports whether pod changed from old in a way that may
+// let candidates take over: it started terminating, failed, was evicted or
+// turned unready.
+func leaderPodChanged(old, pod *v1.Pod) bool {
+	return (old.DeletionTimestamp == nil) != (pod.DeletionTimestamp == nil) ||
+		old.Status.Phase != pod.Status.Phase ||
+		old.Status.Reason != pod.Status.Reason ||
+		podReady(old) != podReady(pod)
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PriorityAnnotation records the priority of the pod holding the lock.
+	PriorityAnnotation = "leader.seamounts.io/priority"
+
+	// StepDownRequestAnnotation is set on the lock by a higher-priority
+	// candidate to ask the leader to step down in its favour.
+	StepDownRequestAnnotation = "leader.seamounts.io/step-down-requested-by"
+
+	// StepDownPriorityAnnotation records the priority of the candidate that
+	// requested the step-down, so only a still higher one may replace it.
+	StepDownPriorityAnnotation = "leader.seamounts.io/step-down-priority"
+)
+
+// lockPriority returns the priority recorded in an annotation of lock, or 0
+// if it is missing or malformed.
+func lockPriority(lock metav1.Object, annotation string) int {
+	p, err := strconv.Atoi(lock.GetAnnotations()[annotation])
+	if err != nil {
+		return 0
+	}
+	return p
+}
+
+// requestStepDown asks the holder of lock to step down if we outrank both
+// it and any candidate that already asked.
+func (e *PodElector) requestStepDown(ctx context.Context, lock metav1.Object) error {
+	if e.opts.priority <= lockPriority(lock, PriorityAnnotation) {
+		return nil
+	}
+
+	annotations := lock.GetAnnotations()
+	if requester, ok := annotations[StepDownRequestAnnotation]; ok {
+		if requester == e.owner.Name || e.opts.priority <= lockPriority(lock, StepDownPriorityAnnotation) {
+			return nil
+		}
+	}
+
+	e.log.Info("My priority outranks the leader's, requesting it to step down", "lock", e.lockName, "priority", e.opts.priority)
+	return e.annotateLock(ctx, lock, map[string]interface{}{
+		StepDownRequestAnnotation:  e.owner.Name,
+		StepDownPriorityAnnotation: strconv.Itoa(e.opts.priority),
+	})
+}
+
+// stepDown hands leadership to requester, falling back to a plain release if
+// the requester does not take over. Either way our work is drained first.
+func (e *PodElector) stepDown(ctx context.Context, requester string) error {
+	e.log.Info("Stepping down on request", "lock", e.lockName, "requester", requester)
+	e.event(v1.EventTypeNormal, "SteppingDown", "Stepping down from %s at the request of %s", e.lockName, requester)
+
+	err := e.TransferTo(ctx, requester)
+	if err == nil || errors.Is(err, ErrNotLeader) {
+		return err
+	}
+
+	e.log.Warn("Could not transfer, releasing the lock", "lock", e.lockName, "requester", requester, "error", err)
+	return e.Resign(ctx)
+}
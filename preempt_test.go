@@ -0,0 +1,76 @@
+package leader
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequestStepDown(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		leader   int
+		asked    string
+		askedBy  int
+		priority int
+		want     string
+	}{
+		{name: "outranked", leader: 1, priority: 2, want: "pod-1"},
+		{name: "equal", leader: 1, priority: 1},
+		{name: "lower", leader: 2, priority: 1},
+		{name: "outranks the earlier request", leader: 1, asked: "pod-3", askedBy: 2, priority: 3, want: "pod-1"},
+		{name: "outranked by the earlier request", leader: 1, asked: "pod-3", askedBy: 3, priority: 2, want: "pod-3"},
+		{name: "asked already", leader: 1, asked: "pod-1", askedBy: 2, priority: 2, want: "pod-1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2", "pod-3")
+			leader := newTestElector(t, client, "pod-2", WithPriority(tc.leader))
+			if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+			if tc.asked != "" {
+				if err := leader.patchLockAnnotations(context.Background(), map[string]interface{}{
+					StepDownRequestAnnotation:  tc.asked,
+					StepDownPriorityAnnotation: strconv.Itoa(tc.askedBy),
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e := newTestElector(t, client, "pod-1", WithPriority(tc.priority))
+			lock, err := e.getLock(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := e.requestStepDown(context.Background(), lock); err != nil {
+				t.Fatalf("requestStepDown: %v", err)
+			}
+			lock, err = e.getLock(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := lock.GetAnnotations()[StepDownRequestAnnotation]; got != tc.want {
+				t.Fatalf("step-down requested by %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStepDownOnRequest(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	// the requester never takes over, so the leader releases the lock
+	leader := newTestElector(t, client, "pod-2", WithTransferTimeout(50*time.Millisecond))
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if err := leader.stepDown(context.Background(), "pod-1"); err != nil {
+		t.Fatalf("stepDown: %v", err)
+	}
+	if leader.IsLeader() {
+		t.Fatal("IsLeader is true after stepping down")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock still held by %q", owner)
+	}
+}
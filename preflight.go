@@ -0,0 +1,160 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PreflightCheck is the outcome of one check of Preflight.
+type PreflightCheck struct {
+	// Name identifies the check, such as "namespace" or "rbac".
+	Name string
+	// OK is true if the check passed.
+	OK bool
+	// Skipped is true if the check was not run because an earlier one it
+	// depends on failed.
+	Skipped bool
+	// Detail says what was found, or what is wrong.
+	Detail string
+}
+
+// PreflightReport lists the checks made by Preflight, in order.
+type PreflightReport struct {
+	Checks []PreflightCheck
+}
+
+// OK reports whether every check passed.
+func (r *PreflightReport) OK() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the report, one check per line.
+func (r *PreflightReport) String() string {
+	var b strings.Builder
+	for _, c := range r.Checks {
+		status := "ok"
+		switch {
+		case c.Skipped:
+			status = "skipped"
+		case !c.OK:
+			status = "FAILED"
+		}
+		fmt.Fprintf(&b, "%-12s %-8s %s\n", c.Name, status, c.Detail)
+	}
+	return b.String()
+}
+
+func (r *PreflightReport) add(name string, err error, detail string) bool {
+	c := PreflightCheck{Name: name, OK: err == nil, Detail: detail}
+	if err != nil {
+		c.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+	return c.OK
+}
+
+func (r *PreflightReport) skip(names ...string) *PreflightReport {
+	for _, name := range names {
+		r.Checks = append(r.Checks, PreflightCheck{Name: name, Skipped: true, Detail: "an earlier check failed"})
+	}
+	return r
+}
+
+// Preflight checks, without competing for it, that an Elector for lockName
+// with opts could work: that the downward API provides the pod name, the
+// namespace can be found, the client can be built, the pod can be read, the
+// service account has every permission RequiredRole lists, and the lock's
+// backend is served. Most first-run failures show up here, before the
+// election loop retries them. Run it at startup, for example behind a
+// --preflight flag, and print the report.
+func Preflight(ctx context.Context, lockName string, opts ...Option) *PreflightReport {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	logger := o.getLogger()
+	r := &PreflightReport{}
+
+	podName := o.myPodName()
+	var envErr error
+	if podName == "" {
+		envErr = fmt.Errorf("%s is not set, please configure downward API", PodNameEnvVar)
+	}
+	r.add("downward-api", envErr, fmt.Sprintf("%s=%s", PodNameEnvVar, podName))
+
+	ns, source := o.namespace, "option"
+	if ns == "" {
+		var err error
+		ns, err = getNamespace(o.namespaceFile)
+		source = o.namespaceFile
+		if err != nil {
+			r.add("namespace", err, "")
+			return r.skip("client", "pod", "rbac", "backend")
+		}
+	}
+	r.add("namespace", nil, fmt.Sprintf("%s (from %s)", ns, source))
+	o.namespace = ns
+
+	client, _, err := clientAndNamespace(&o)
+	if !r.add("client", err, "built") {
+		return r.skip("pod", "rbac", "backend")
+	}
+
+	if envErr != nil {
+		r.skip("pod")
+	} else {
+		reqCtx, cancel := withTimeout(ctx, o.requestTimeout)
+		pod, err := getMyPod(reqCtx, client, ns, podName, logger)
+		cancel()
+		detail := ""
+		if err == nil {
+			detail = fmt.Sprintf("%s on node %s, service account %s", pod.Name, pod.Spec.NodeName, pod.Spec.ServiceAccountName)
+		}
+		r.add("pod", err, detail)
+	}
+
+	resolved := append(append([]Option(nil), opts...), WithClient(client), WithNamespace(ns))
+	r.add("rbac", VerifyRBAC(ctx, lockName, resolved...), "every required permission is granted")
+
+	detail, err := checkBackend(ctx, &o, client, ns, lockName, logger)
+	r.add("backend", err, detail)
+	return r
+}
+
+// checkBackend verifies that the lock's backend is served, and describes
+// the lock.
+func checkBackend(ctx context.Context, o *options, client kubernetes.Interface, ns, lockName string, logger Logger) (string, error) {
+	b, err := newBackend(o.backend, client, ns, o.requestTimeout, logger)
+	if err != nil {
+		return "", err
+	}
+	_, err = b.Get(ctx, lockName)
+	switch {
+	case err == nil:
+		return fmt.Sprintf("%s %s is held", b.Resource(), lockName), nil
+	case apierrors.IsNotFound(err) && !isMissingResource(err):
+		return fmt.Sprintf("%s are served, %s is free", b.Resource(), lockName), nil
+	}
+	return "", err
+}
+
+// isMissingResource reports whether a NotFound is about the resource type,
+// rather than the object, not existing.
+func isMissingResource(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+	details := status.Status().Details
+	return details == nil || details.Name == ""
+}
@@ -0,0 +1,131 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// reviewAccess answers access reviews on client as an authorizer granting
+// everything but the resources in denied.
+func reviewAccess(client *fake.Clientset, denied ...string) {
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).DeepCopy()
+		review.Status.Allowed = true
+		for _, resource := range denied {
+			if review.Spec.ResourceAttributes.Resource == resource {
+				review.Status.Allowed = false
+			}
+		}
+		return true, review, nil
+	})
+}
+
+func TestPreflight(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		pod      string
+		opts     []Option
+		held     bool
+		denied   []string
+		unserved bool
+		detail   string
+		want     map[string]string
+	}{
+		{
+			name:   "free lock",
+			pod:    "pod-1",
+			want:   map[string]string{"downward-api": "ok", "namespace": "ok", "client": "ok", "pod": "ok", "rbac": "ok", "backend": "ok"},
+			detail: "is free",
+		},
+		{
+			name:   "held lock",
+			pod:    "pod-1",
+			held:   true,
+			want:   map[string]string{"backend": "ok"},
+			detail: "is held",
+		},
+		{
+			name: "no pod name",
+			want: map[string]string{"downward-api": "FAILED", "pod": "skipped", "backend": "ok"},
+		},
+		{
+			name: "unknown pod",
+			pod:  "pod-2",
+			want: map[string]string{"pod": "FAILED", "rbac": "ok"},
+		},
+		{
+			name: "no namespace",
+			pod:  "pod-1",
+			opts: []Option{WithNamespace(""), WithNamespaceFile(filepath.Join("testdata", "missing"))},
+			want: map[string]string{"namespace": "FAILED", "client": "skipped", "pod": "skipped", "rbac": "skipped", "backend": "skipped"},
+		},
+		{
+			name:   "missing permission",
+			pod:    "pod-1",
+			denied: []string{"configmaps"},
+			want:   map[string]string{"pod": "ok", "rbac": "FAILED"},
+		},
+		{
+			name:     "backend not served",
+			pod:      "pod-1",
+			opts:     []Option{WithBackend(LeaseBackend)},
+			unserved: true,
+			want:     map[string]string{"rbac": "ok", "backend": "FAILED"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if tc.held {
+				e := newTestElector(t, client, "pod-1")
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire = %v, %v", ok, err)
+				}
+			}
+			reviewAccess(client, tc.denied...)
+			if tc.unserved {
+				client.PrependReactor("get", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewNotFound(action.GetResource().GroupResource(), "")
+				})
+			}
+
+			opts := append([]Option{WithClient(client), WithNamespace(testNamespace), WithPodName(tc.pod), WithLogLevel(ErrorLevel)}, tc.opts...)
+			r := Preflight(context.Background(), testLock, opts...)
+			got := map[string]string{}
+			for _, c := range r.Checks {
+				switch {
+				case c.Skipped:
+					got[c.Name] = "skipped"
+				case c.OK:
+					got[c.Name] = "ok"
+				default:
+					got[c.Name] = "FAILED"
+				}
+			}
+			for name, status := range tc.want {
+				if got[name] != status {
+					t.Errorf("check %s = %s, want %s in\n%s", name, got[name], status, r)
+				}
+			}
+			for _, c := range r.Checks {
+				if c.Name == "backend" && !strings.Contains(c.Detail, tc.detail) {
+					t.Errorf("backend detail = %q, want it to say %q", c.Detail, tc.detail)
+				}
+			}
+			allOK := true
+			for _, status := range got {
+				allOK = allOK && status == "ok"
+			}
+			if r.OK() != allOK {
+				t.Errorf("OK = %v with the checks %v", r.OK(), got)
+			}
+		})
+	}
+}
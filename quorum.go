@@ -0,0 +1,153 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Lock is a mutual-exclusion primitive a Quorum can be built from. A
+// PodElector is a Lock backed by a ConfigMap or Lease; other backends, such as
+// an external store, can be plugged in by implementing this interface.
+type Lock interface {
+	// TryAcquire makes a single, non-blocking attempt to take the lock and
+	// reports whether it is held afterwards.
+	TryAcquire(ctx context.Context) (bool, error)
+	// Held reports whether the lock is still held.
+	Held(ctx context.Context) (bool, error)
+	// Release gives up the lock if it is held.
+	Release(ctx context.Context) error
+}
+
+var _ Lock = &PodElector{}
+
+// Quorum grants leadership only while at least K of its N locks are held.
+// Spreading the locks across independent coordination systems means one
+// misbehaving system cannot, on its own, make two pods believe they lead.
+type Quorum struct {
+	locks []Lock
+	k     int
+
+	mu   sync.Mutex
+	held []bool
+}
+
+// NewQuorum returns a Quorum requiring k of locks.
+func NewQuorum(k int, locks ...Lock) (*Quorum, error) {
+	if k <= len(locks)/2 || k > len(locks) {
+		return nil, fmt.Errorf("quorum of %d is not a majority of %d locks", k, len(locks))
+	}
+	return &Quorum{
+		locks: locks,
+		k:     k,
+		held:  make([]bool, len(locks)),
+	}, nil
+}
+
+// Become blocks until a quorum of locks is held or ctx is cancelled. When an
+// attempt falls short it releases whatever it took, so two candidates
+// holding partial sets cannot deadlock each other.
+func (q *Quorum) Become(ctx context.Context) error {
+	defaultLogger.Info("Trying to become the leader of a quorum", "locks", len(q.locks), "needed", q.k)
+
+	backoff := initialBackoffInterval
+	for {
+		held := q.tryAcquireAll(ctx)
+		if held >= q.k {
+			defaultLogger.Info("Became the leader of the quorum", "held", held, "locks", len(q.locks))
+			return nil
+		}
+
+		defaultLogger.Info("No quorum. Releasing and waiting", "held", held, "locks", len(q.locks), "needed", q.k)
+		q.releaseAll(ctx)
+
+		if _, err := sleepOrWake(ctx, defaultRandom.jitter(backoff, .2), nil); err != nil {
+			return err
+		}
+		if backoff < defaultMaxBackoffInterval {
+			backoff *= 2
+		}
+	}
+}
+
+// IsLeader reports whether a quorum was held at the last check.
+func (q *Quorum) IsLeader() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count() >= q.k
+}
+
+// Verify re-checks every lock and reports whether a quorum is still held.
+func (q *Quorum) Verify(ctx context.Context) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var firstErr error
+	for i, l := range q.locks {
+		if !q.held[i] {
+			continue
+		}
+		ok, err := l.Held(ctx)
+		if err != nil {
+			// an unreachable backend cannot vouch for us
+			ok = false
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		q.held[i] = ok
+	}
+
+	if q.count() >= q.k {
+		return true, nil
+	}
+	return false, firstErr
+}
+
+// Resign releases every lock of the quorum.
+func (q *Quorum) Resign(ctx context.Context) error {
+	defaultLogger.Info("Resigning leadership of the quorum")
+	return q.releaseAll(ctx)
+}
+
+func (q *Quorum) tryAcquireAll(ctx context.Context) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, l := range q.locks {
+		ok, err := l.TryAcquire(ctx)
+		if err != nil {
+			defaultLogger.Error(err, "Failed to acquire lock of the quorum", "index", i)
+		}
+		q.held[i] = ok
+	}
+	return q.count()
+}
+
+func (q *Quorum) releaseAll(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var firstErr error
+	for i, l := range q.locks {
+		if !q.held[i] {
+			continue
+		}
+		if err := l.Release(ctx); err != nil && !errors.Is(err, ErrNotLeader) && firstErr == nil {
+			firstErr = err
+		}
+		q.held[i] = false
+	}
+	return firstErr
+}
+
+func (q *Quorum) count() int {
+	n := 0
+	for _, ok := range q.held {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,210 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// memLock is a Lock held in memory, that can be made unavailable.
+type memLock struct {
+	mu          sync.Mutex
+	held        bool
+	unavailable bool
+	acquires    int
+	releases    int
+}
+
+func (l *memLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.acquires++
+	if l.unavailable {
+		return false, errors.New("unavailable")
+	}
+	l.held = true
+	return true, nil
+}
+
+func (l *memLock) Held(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unavailable {
+		return false, errors.New("unavailable")
+	}
+	return l.held, nil
+}
+
+func (l *memLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return ErrNotLeader
+	}
+	l.held = false
+	l.releases++
+	return nil
+}
+
+func TestNewQuorumNeedsMajority(t *testing.T) {
+	for _, tc := range []struct {
+		k, n int
+		ok   bool
+	}{
+		{k: 1, n: 1, ok: true},
+		{k: 1, n: 2, ok: false},
+		{k: 2, n: 2, ok: true},
+		{k: 1, n: 3, ok: false},
+		{k: 2, n: 3, ok: true},
+		{k: 3, n: 3, ok: true},
+		{k: 4, n: 3, ok: false},
+		{k: 3, n: 5, ok: true},
+	} {
+		locks := make([]Lock, tc.n)
+		for i := range locks {
+			locks[i] = &memLock{}
+		}
+		if _, err := NewQuorum(tc.k, locks...); (err == nil) != tc.ok {
+			t.Errorf("NewQuorum(%d of %d) = %v, want ok %v", tc.k, tc.n, err, tc.ok)
+		}
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		unavailable []bool
+		leads       bool
+	}{
+		{name: "all locks", unavailable: []bool{false, false, false}, leads: true},
+		{name: "majority", unavailable: []bool{false, true, false}, leads: true},
+		{name: "minority", unavailable: []bool{true, false, true}, leads: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var locks []*memLock
+			var all []Lock
+			for _, unavailable := range tc.unavailable {
+				l := &memLock{unavailable: unavailable}
+				locks = append(locks, l)
+				all = append(all, l)
+			}
+			q, err := NewQuorum(2, all...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err = q.Become(ctx)
+			if leads := err == nil; leads != tc.leads {
+				t.Fatalf("Become = %v, want leadership %v", err, tc.leads)
+			}
+			if q.IsLeader() != tc.leads {
+				t.Fatalf("IsLeader = %v, want %v", q.IsLeader(), tc.leads)
+			}
+			if !tc.leads {
+				// partial sets are given back, so they cannot deadlock
+				for i, l := range locks {
+					if l.held {
+						t.Errorf("lock %d is still held without a quorum", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestQuorumVerify(t *testing.T) {
+	locks := []*memLock{{}, {}, {}}
+	q, err := NewQuorum(2, locks[0], locks[1], locks[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Become(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	locks[0].mu.Lock()
+	locks[0].unavailable = true
+	locks[0].mu.Unlock()
+	// an unreachable lock cannot vouch for us, but two others still do
+	if ok, err := q.Verify(context.Background()); !ok || err != nil {
+		t.Fatalf("Verify with 2 of 3 locks = %v, %v", ok, err)
+	}
+	locks[1].mu.Lock()
+	locks[1].held = false
+	locks[1].mu.Unlock()
+	if ok, err := q.Verify(context.Background()); ok || err != nil {
+		t.Fatalf("Verify with 1 of 3 locks = %v, %v", ok, err)
+	}
+	if q.IsLeader() {
+		t.Fatal("IsLeader after losing the quorum")
+	}
+
+	if err := q.Resign(context.Background()); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+	if locks[2].held || locks[2].releases != 1 {
+		t.Fatal("Resign did not release the lock still held")
+	}
+}
+
+func TestQuorumOfElectors(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	names := []string{"lock-a", "lock-b", "lock-c"}
+	var ours, theirs []Lock
+	for _, name := range names {
+		ours = append(ours, newTestElectorOf(t, client, name, "pod-1"))
+		theirs = append(theirs, newTestElectorOf(t, client, name, "pod-2"))
+	}
+	if ok, err := theirs[1].TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	q, err := NewQuorum(2, ours...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Become(context.Background()); err != nil {
+		t.Fatalf("Become: %v", err)
+	}
+
+	// the locks of the quorum are lost from under us
+	for _, name := range []string{"lock-a", "lock-c"} {
+		if err := client.CoreV1().ConfigMaps(testNamespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := q.Verify(context.Background()); ok || err != nil {
+		t.Fatalf("Verify after losing the locks = %v, %v", ok, err)
+	}
+}
+
+func TestTryAcquire(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	first := newTestElector(t, client, "pod-1")
+	second := newTestElector(t, client, "pod-2")
+
+	if ok, err := first.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire of a free lock = %v, %v", ok, err)
+	}
+	if !first.IsLeader() {
+		t.Fatal("IsLeader is false after acquiring the lock")
+	}
+	if ok, err := second.TryAcquire(context.Background()); err != nil || ok {
+		t.Fatalf("TryAcquire of a held lock = %v, %v", ok, err)
+	}
+	if owner := lockOwner(t, client); owner != "pod-1" {
+		t.Fatalf("lock is owned by %q, want pod-1", owner)
+	}
+
+	// a restarted leader resumes the lock its pod still owns
+	restarted := newTestElector(t, client, "pod-1")
+	if ok, err := restarted.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire of our own lock = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,847 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// PodIPEnvVar is the env var holding the pod IP, set through the
+	// downward API. Raft peers find themselves among the addresses of the
+	// headless service with it.
+	PodIPEnvVar = "POD_IP"
+
+	// defaultRaftPort is the port peers serve the raft protocol on.
+	defaultRaftPort = 2380
+
+	// defaultRaftElectionTimeout is how long a follower waits without
+	// hearing from a leader before it stands for election.
+	defaultRaftElectionTimeout = time.Second * 3
+
+	// defaultRaftHeartbeatInterval is how often the leader asserts its
+	// leadership to its peers.
+	defaultRaftHeartbeatInterval = time.Millisecond * 500
+
+	// raftSyncInterval is how often the leader tries to record itself on
+	// the lock ConfigMap.
+	raftSyncInterval = time.Second * 10
+
+	raftVotePath      = "/raft/vote"
+	raftHeartbeatPath = "/raft/heartbeat"
+
+	// raftSignatureHeader carries the HMAC of a request made with
+	// WithRaftSecret, over raftDateHeader, the path and the body.
+	raftSignatureHeader = "X-Raft-Signature"
+	raftDateHeader      = "X-Raft-Date"
+
+	// raftMaxMessage bounds the size of a request a peer reads.
+	raftMaxMessage = 1 << 16
+)
+
+// RaftElector elects a leader among the pods behind a headless service by
+// voting over pod-to-pod networking, so leadership keeps working while the
+// apiserver is unavailable. Terms play the role of epochs. Whenever the
+// apiserver can be reached the leader records itself on a ConfigMap lock
+// named after the election, which keeps Status, Explain and followers of
+// the regular backends working.
+//
+// Only leadership is agreed on; there is no replicated log. The cluster size
+// is fixed when the elector starts, from the number of addresses of the
+// service then, or by WithRaftClusterSize, so losing pods can never shrink
+// the majority needed to lead. Votes are not persisted: a restarted peer
+// declines to vote for one election timeout after starting, by which time
+// any lease built on a vote it cast before restarting has run out.
+//
+// Peers only accept votes and heartbeats from the addresses of the service.
+// Anything else able to reach the port and spoof such an address could still
+// depose the leader or pose as one, so sign the messages with WithRaftSecret
+// or authenticate peers with WithRaftTLS.
+type RaftElector struct {
+	lockName string
+	ns       string
+	service  string
+	self     string
+	name     string
+	opts     options
+	log      Logger
+	client   kubernetes.Interface
+	http     *http.Client
+
+	mu      sync.Mutex
+	size    int
+	peers   []string
+	term    int64
+	leading bool
+	leader  string
+	// lastContact is when we last heard from a leader, granted a vote or
+	// started, whichever is latest.
+	lastContact time.Time
+	// acks are when each peer last acknowledged a heartbeat, measured at
+	// the time the heartbeat was sent.
+	acks        map[string]time.Time
+	resignedAt  time.Time
+	owner       *metav1.OwnerReference
+	subscribers []chan Event
+
+	// startMu serializes starting the protocol; stopped is closed once the
+	// protocol started by a Run stops, with stopErr the reason.
+	startMu sync.Mutex
+	stopped chan struct{}
+	stopErr error
+}
+
+var _ Elector = &RaftElector{}
+
+type raftVoteRequest struct {
+	Term      int64  `json:"term"`
+	Candidate string `json:"candidate"`
+	// Pre asks whether the vote would be granted, without changing the
+	// state of the peer.
+	Pre bool `json:"pre,omitempty"`
+}
+
+type raftHeartbeat struct {
+	Term   int64  `json:"term"`
+	Leader string `json:"leader"`
+	Name   string `json:"name"`
+}
+
+type raftResponse struct {
+	Term int64 `json:"term"`
+	OK   bool  `json:"ok"`
+}
+
+// NewRaftElector returns a RaftElector for lockName whose peers are the
+// addresses of service, a headless service selecting the candidate pods. The
+// service should publish not-ready addresses so that peers can find each
+// other before they are ready. The client and namespace options are only
+// used to sync the leader to the lock ConfigMap.
+func NewRaftElector(lockName, service string, opts ...Option) (*RaftElector, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.raftPort == 0 {
+		o.raftPort = defaultRaftPort
+	}
+	if o.raftElectionTimeout <= 0 {
+		o.raftElectionTimeout = defaultRaftElectionTimeout
+	}
+	if o.raftHeartbeatInterval <= 0 {
+		o.raftHeartbeatInterval = defaultRaftHeartbeatInterval
+	}
+	if o.raftHeartbeatInterval >= o.raftElectionTimeout {
+		return nil, fmt.Errorf("raft heartbeat interval %s must be shorter than the election timeout %s", o.raftHeartbeatInterval, o.raftElectionTimeout)
+	}
+
+	self := os.Getenv(PodIPEnvVar)
+	if net.ParseIP(self) == nil {
+		return nil, fmt.Errorf("required env %s not set to an IP, please configure downward API", PodIPEnvVar)
+	}
+	name := o.myPodName()
+	if name == "" {
+		return nil, fmt.Errorf("required env %s not set, please configure downward API", PodNameEnvVar)
+	}
+
+	client, ns, err := clientAndNamespace(&o)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: o.raftHeartbeatInterval}
+	if o.raftTLS != nil {
+		httpClient.Transport = &http.Transport{TLSClientConfig: o.raftTLS.Clone()}
+	}
+	return &RaftElector{
+		lockName: lockName,
+		ns:       ns,
+		service:  service,
+		self:     self,
+		name:     name,
+		opts:     o,
+		log:      o.getLogger(),
+		client:   client,
+		http:     httpClient,
+		size:     o.raftClusterSize,
+		acks:     map[string]time.Time{},
+	}, nil
+}
+
+// IsLeader reports whether a majority of peers acknowledged our leadership
+// recently enough that none of them can have voted for another leader.
+func (r *RaftElector) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leading && r.holdsLease(time.Now())
+}
+
+// Leader returns the name of the pod last known to lead, or "" if none is.
+func (r *RaftElector) Leader() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leader
+}
+
+// Term returns the current term.
+func (r *RaftElector) Term() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.term
+}
+
+// Subscribe returns a channel receiving an Event for every change of
+// leadership, with the term as its epoch.
+func (r *RaftElector) Subscribe() <-chan Event {
+	ch := make(chan Event, subscriberBuffer)
+	r.mu.Lock()
+	r.subscribers = append(r.subscribers, ch)
+	r.mu.Unlock()
+	return ch
+}
+
+func (r *RaftElector) unsubscribe(ch <-chan Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, sub := range r.subscribers {
+		if sub == ch {
+			r.subscribers = append(r.subscribers[:i:i], r.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Resign steps down and stays out of the next election, so that another
+// peer takes over once it times out waiting for our heartbeats.
+func (r *RaftElector) Resign(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resignedAt = time.Now()
+	if r.leading {
+		r.log.Info("Resigning raft leadership", "lock", r.lockName, "term", r.term)
+		r.stepDown()
+	}
+	return nil
+}
+
+// Run takes part in elections until leadership, gained while it runs, is
+// lost, or ctx is cancelled. If we lead when ctx is cancelled we resign
+// first. The peers need every member to keep voting, so the protocol keeps
+// being served after Run returns because leadership was lost: the first Run
+// starts it, a later Run joins it, and it stops only once the context of the
+// Run that started it is cancelled.
+func (r *RaftElector) Run(ctx context.Context) error {
+	stopped, err := r.start(ctx)
+	if err != nil {
+		return err
+	}
+	events := r.Subscribe()
+	defer r.unsubscribe(events)
+	return r.untilLost(ctx, events, stopped)
+}
+
+// untilLost waits, on the events of a subscription, for leadership to be
+// gained and then lost, for ctx to be cancelled or for the protocol to stop.
+func (r *RaftElector) untilLost(ctx context.Context, events <-chan Event, stopped <-chan struct{}) error {
+	led := r.IsLeader()
+	for {
+		select {
+		case <-ctx.Done():
+			if r.IsLeader() {
+				r.Resign(context.Background())
+			}
+			return ctx.Err()
+		case <-stopped:
+			r.startMu.Lock()
+			defer r.startMu.Unlock()
+			return r.stopErr
+		case ev := <-events:
+			if ev.Leading {
+				led = true
+			} else if led {
+				return nil
+			}
+		}
+	}
+}
+
+// start starts serving the raft protocol and taking part in elections,
+// bound to ctx, unless that is already running, and returns a channel closed
+// once it stops.
+func (r *RaftElector) start(ctx context.Context) (<-chan struct{}, error) {
+	r.startMu.Lock()
+	defer r.startMu.Unlock()
+	if r.stopped != nil {
+		select {
+		case <-r.stopped:
+		default:
+			return r.stopped, nil
+		}
+	}
+
+	if err := r.bootstrap(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.lastContact = time.Now()
+	r.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(raftVotePath, r.serveVote)
+	mux.HandleFunc(raftHeartbeatPath, r.serveHeartbeat)
+	listener, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(r.opts.raftPort)))
+	if err != nil {
+		return nil, fmt.Errorf("listen for raft peers: %w", err)
+	}
+	if r.opts.raftTLS != nil {
+		listener = tls.NewListener(listener, r.opts.raftTLS)
+	}
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+
+	r.log.Info("Joined raft election", "lock", r.lockName, "service", r.service, "size", r.size)
+
+	stopped := make(chan struct{})
+	r.stopped = stopped
+	go r.syncLock(ctx)
+	go func() {
+		err := r.elect(ctx)
+		server.Close()
+		r.startMu.Lock()
+		r.stopErr = err
+		close(stopped)
+		r.startMu.Unlock()
+	}()
+	return stopped, nil
+}
+
+// elect leads or follows, as our state says, until ctx is cancelled.
+func (r *RaftElector) elect(ctx context.Context) error {
+	for {
+		r.mu.Lock()
+		leading := r.leading
+		r.mu.Unlock()
+
+		var err error
+		if leading {
+			err = r.lead(ctx)
+		} else {
+			err = r.follow(ctx)
+		}
+		if err != nil {
+			r.mu.Lock()
+			if r.leading {
+				r.stepDown()
+			}
+			r.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// bootstrap resolves the service until it has at least one address, and
+// fixes the cluster size from it unless one was configured.
+func (r *RaftElector) bootstrap(ctx context.Context) error {
+	backoff := initialBackoffInterval
+	for {
+		err := r.resolvePeers(ctx)
+		if err == nil {
+			break
+		}
+		r.log.Warn("Failed to look up raft peers, retrying", "service", r.service, "error", err)
+		if _, err := sleepOrWake(ctx, backoff, nil); err != nil {
+			return err
+		}
+		if backoff *= 2; backoff > defaultMaxBackoffInterval {
+			backoff = defaultMaxBackoffInterval
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size == 0 {
+		r.size = len(r.peers) + 1
+	}
+	return nil
+}
+
+// resolvePeers refreshes the peer addresses from the service. Addresses
+// change as pods are replaced, but the cluster size does not.
+func (r *RaftElector) resolvePeers(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, r.opts.requestTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, r.service)
+	if err != nil {
+		return err
+	}
+
+	var peers []string
+	for _, addr := range addrs {
+		if addr != r.self {
+			peers = append(peers, addr)
+		}
+	}
+	sort.Strings(peers)
+	r.mu.Lock()
+	r.peers = peers
+	r.mu.Unlock()
+	return nil
+}
+
+// follow waits for the election timeout, randomized so that peers rarely
+// stand at once, and stands for election if no leader was heard from.
+func (r *RaftElector) follow(ctx context.Context) error {
+	timeout := r.opts.raftElectionTimeout + time.Duration(r.opts.random().int63n(int64(r.opts.raftElectionTimeout)))
+	if _, err := sleepOrWake(ctx, timeout, nil); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	quiet := now.Sub(r.lastContact) >= r.opts.raftElectionTimeout && now.Sub(r.resignedAt) >= r.opts.raftElectionTimeout
+	r.mu.Unlock()
+	if quiet {
+		r.campaign(ctx)
+	}
+	return nil
+}
+
+// campaign starts a new term and asks every peer for its vote. A pre-vote
+// comes first, so that a peer cut off from the others does not inflate the
+// term and depose a healthy leader when it returns.
+func (r *RaftElector) campaign(ctx context.Context) {
+	if err := r.resolvePeers(ctx); err != nil {
+		r.log.Warn("Failed to refresh raft peers", "service", r.service, "error", err)
+	}
+
+	r.mu.Lock()
+	term, peers := r.term+1, r.peers
+	r.mu.Unlock()
+	if votes, _ := r.requestVotes(ctx, peers, raftVoteRequest{Term: term, Candidate: r.self, Pre: true}); votes <= r.size/2 {
+		return
+	}
+
+	r.mu.Lock()
+	if r.term >= term {
+		r.mu.Unlock()
+		return
+	}
+	r.term = term
+	r.leader = ""
+	r.mu.Unlock()
+	r.log.Debug("Standing for raft election", "lock", r.lockName, "term", term)
+
+	asked := time.Now()
+	votes, voters := r.requestVotes(ctx, peers, raftVoteRequest{Term: term, Candidate: r.self})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.term != term || votes <= r.size/2 {
+		return
+	}
+	// voters will not vote again within the election timeout of
+	// receiving our request, so the lease runs from when we asked
+	r.acks = map[string]time.Time{r.self: asked}
+	for _, peer := range voters {
+		r.acks[peer] = asked
+	}
+	r.leading = true
+	r.leader = r.name
+	r.log.Info("Became the raft leader", "lock", r.lockName, "term", term, "votes", votes, "size", r.size)
+	r.publish(true)
+}
+
+// lead sends heartbeats until ctx is cancelled or leadership is lost.
+func (r *RaftElector) lead(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.raftHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		r.mu.Lock()
+		if !r.leading {
+			r.mu.Unlock()
+			return nil
+		}
+		term, peers := r.term, r.peers
+		r.mu.Unlock()
+
+		sent := time.Now()
+		acked := map[string]bool{}
+		for resp := range r.broadcast(ctx, peers, raftHeartbeatPath, raftHeartbeat{Term: term, Leader: r.self, Name: r.name}) {
+			if r.observeTerm(resp.Term) {
+				return nil
+			}
+			if resp.OK {
+				acked[resp.peer] = true
+			}
+		}
+
+		r.mu.Lock()
+		r.acks[r.self] = sent
+		for peer := range acked {
+			r.acks[peer] = sent
+		}
+		if r.leading && !r.holdsLease(time.Now()) {
+			r.log.Warn("Lost contact with a majority of raft peers, stepping down", "lock", r.lockName, "term", r.term)
+			r.stepDown()
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// requestVotes asks peers for their vote and returns how many votes we
+// have, counting our own, and who granted theirs.
+func (r *RaftElector) requestVotes(ctx context.Context, peers []string, msg raftVoteRequest) (int, []string) {
+	votes := 1
+	var voters []string
+	for resp := range r.broadcast(ctx, peers, raftVotePath, msg) {
+		if r.observeTerm(resp.Term) {
+			return 0, nil
+		}
+		if resp.OK {
+			votes++
+			voters = append(voters, resp.peer)
+		}
+	}
+	return votes, voters
+}
+
+type peerResponse struct {
+	raftResponse
+	peer string
+}
+
+// broadcast posts msg to path on every peer in parallel and returns the
+// responses of those that answered.
+func (r *RaftElector) broadcast(ctx context.Context, peers []string, path string, msg interface{}) <-chan peerResponse {
+	body, _ := json.Marshal(msg)
+	responses := make(chan peerResponse, len(peers))
+
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			resp, err := r.post(ctx, peer, path, body)
+			if err != nil {
+				r.log.Debug("Raft peer did not answer", "peer", peer, "path", path, "error", err)
+				return
+			}
+			responses <- peerResponse{raftResponse: *resp, peer: peer}
+		}(peer)
+	}
+	go func() {
+		wg.Wait()
+		close(responses)
+	}()
+	return responses
+}
+
+func (r *RaftElector) post(ctx context.Context, peer, path string, body []byte) (*raftResponse, error) {
+	scheme := "http://"
+	if r.opts.raftTLS != nil {
+		scheme = "https://"
+	}
+	url := scheme + net.JoinHostPort(peer, strconv.Itoa(r.opts.raftPort)) + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.opts.raftSecret != nil {
+		date := strconv.FormatInt(time.Now().UnixNano(), 10)
+		req.Header.Set(raftDateHeader, date)
+		req.Header.Set(raftSignatureHeader, hex.EncodeToString(r.sign(date, path, body)))
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("raft peer %s returned %s", peer, resp.Status)
+	}
+	var out raftResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// serveVote grants our vote only for a term later than ours, which it moves
+// us to, so we vote at most once per term. It never does so within the
+// election timeout of starting, of hearing from a leader or of granting a
+// vote, so the lease of a leader elected or confirmed then cannot overlap
+// with that of the next one.
+func (r *RaftElector) serveVote(w http.ResponseWriter, req *http.Request) {
+	body, ok := r.authenticate(w, req)
+	if !ok {
+		return
+	}
+	var msg raftVoteRequest
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if msg.Term <= r.term || r.leading || now.Sub(r.lastContact) < r.opts.raftElectionTimeout {
+		writeRaft(w, raftResponse{Term: r.term})
+		return
+	}
+	if msg.Pre {
+		writeRaft(w, raftResponse{Term: r.term, OK: true})
+		return
+	}
+	r.term = msg.Term
+	r.lastContact = now
+	writeRaft(w, raftResponse{Term: r.term, OK: true})
+}
+
+// serveHeartbeat accepts the sender as the leader of its term unless we
+// already know of a later one.
+func (r *RaftElector) serveHeartbeat(w http.ResponseWriter, req *http.Request) {
+	body, ok := r.authenticate(w, req)
+	if !ok {
+		return
+	}
+	var msg raftHeartbeat
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if msg.Term < r.term {
+		writeRaft(w, raftResponse{Term: r.term})
+		return
+	}
+	if msg.Term > r.term {
+		r.term = msg.Term
+	}
+	if r.leading {
+		r.stepDown()
+	}
+	r.leader = msg.Name
+	r.lastContact = time.Now()
+	writeRaft(w, raftResponse{Term: r.term, OK: true})
+}
+
+// authenticate reads the body of a request of a peer and reports whether
+// the request may be acted on: it must come from an address of the service,
+// re-resolved once in case the peer is new, and carry a valid, recent
+// signature if WithRaftSecret is set. It answers rejected requests itself.
+func (r *RaftElector) authenticate(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, raftMaxMessage))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil || !r.isPeer(host) && (r.resolvePeers(req.Context()) != nil || !r.isPeer(host)) {
+		r.log.Warn("Rejected raft message from an address that is not a peer", "lock", r.lockName, "address", req.RemoteAddr, "path", req.URL.Path)
+		http.Error(w, "not a peer", http.StatusForbidden)
+		return nil, false
+	}
+
+	if r.opts.raftSecret != nil {
+		date := req.Header.Get(raftDateHeader)
+		signature, err := hex.DecodeString(req.Header.Get(raftSignatureHeader))
+		sent, dateErr := strconv.ParseInt(date, 10, 64)
+		skew := time.Since(time.Unix(0, sent))
+		if skew < 0 {
+			skew = -skew
+		}
+		if err != nil || dateErr != nil || skew > r.opts.raftElectionTimeout || !hmac.Equal(signature, r.sign(date, req.URL.Path, body)) {
+			r.log.Warn("Rejected raft message with a missing, stale or invalid signature", "lock", r.lockName, "address", req.RemoteAddr, "path", req.URL.Path)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return nil, false
+		}
+	}
+	return body, true
+}
+
+// isPeer reports whether addr is the address of a peer.
+func (r *RaftElector) isPeer(addr string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, peer := range r.peers {
+		if peer == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// sign returns the HMAC of a message sent at date to path, under the
+// secret set with WithRaftSecret. Covering the date keeps a captured message
+// from being replayed beyond the election timeout.
+func (r *RaftElector) sign(date, path string, body []byte) []byte {
+	mac := hmac.New(sha256.New, r.opts.raftSecret)
+	io.WriteString(mac, date+"\n"+path+"\n")
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func writeRaft(w http.ResponseWriter, resp raftResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// observeTerm moves to term if a peer reported a later one, stepping down if
+// we led, and reports whether it did.
+func (r *RaftElector) observeTerm(term int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if term <= r.term {
+		return false
+	}
+	r.term = term
+	r.leader = ""
+	if r.leading {
+		r.stepDown()
+	}
+	return true
+}
+
+// holdsLease reports whether a majority, counting ourselves, acknowledged a
+// heartbeat sent within the election timeout. Must be called with mu held.
+func (r *RaftElector) holdsLease(now time.Time) bool {
+	acked := 0
+	for _, at := range r.acks {
+		if now.Sub(at) < r.opts.raftElectionTimeout {
+			acked++
+		}
+	}
+	return acked > r.size/2
+}
+
+// stepDown gives up leadership. Must be called with mu held.
+func (r *RaftElector) stepDown() {
+	r.leading = false
+	r.leader = ""
+	r.acks = map[string]time.Time{}
+	r.log.Info("No longer the raft leader", "lock", r.lockName, "term", r.term)
+	r.publish(false)
+}
+
+// publish delivers an Event to subscribers. Must be called with mu held.
+func (r *RaftElector) publish(leading bool) {
+	ev := Event{Lock: r.lockName, Leading: leading, Epoch: r.term, Time: time.Now()}
+	for _, ch := range r.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			r.log.Warn("Subscriber is not keeping up, dropping event", "lock", r.lockName, "leading", leading)
+		}
+	}
+}
+
+// syncLock records the leader on the lock ConfigMap whenever the apiserver
+// can be reached. Failures are only logged: the raft election does not
+// depend on it.
+func (r *RaftElector) syncLock(ctx context.Context) {
+	ticker := time.NewTicker(raftSyncInterval)
+	defer ticker.Stop()
+	for {
+		if r.IsLeader() {
+			if err := r.recordLeader(ctx); err != nil && ctx.Err() == nil {
+				r.log.Debug("Failed to sync the raft leader to the lock", "lock", r.lockName, "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordLeader makes the lock ConfigMap owned by our pod, with the term as
+// its epoch, as if we held it through the ConfigMap backend.
+func (r *RaftElector) recordLeader(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, r.opts.requestTimeout)
+	defer cancel()
+
+	r.mu.Lock()
+	owner := r.owner
+	term := r.term
+	r.mu.Unlock()
+	if owner == nil {
+		myPod, err := cachedMyPod(ctx, r.opts.clientID, r.client, r.ns, r.name, r.log)
+		if err != nil {
+			return err
+		}
+		owner = myOwnerRef(myPod)
+		r.mu.Lock()
+		r.owner = owner
+		r.mu.Unlock()
+	}
+
+	configMaps := r.client.CoreV1().ConfigMaps(r.ns)
+	lock, err := configMaps.Get(ctx, r.lockName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            r.lockName,
+				Namespace:       r.ns,
+				OwnerReferences: []metav1.OwnerReference{*owner},
+				Labels: map[string]string{
+					LockLabel: r.lockName,
+					RoleLabel: LockRole,
+				},
+				Annotations: map[string]string{
+					EpochAnnotation: strconv.FormatInt(term, 10),
+				},
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if lockEpoch(lock) > term {
+		return errors.New("lock records a later term")
+	}
+	if len(lock.OwnerReferences) == 1 && lock.OwnerReferences[0].UID == owner.UID && lockEpoch(lock) == term {
+		return nil
+	}
+	lock.OwnerReferences = []metav1.OwnerReference{*owner}
+	if lock.Annotations == nil {
+		lock.Annotations = map[string]string{}
+	}
+	lock.Annotations[EpochAnnotation] = strconv.FormatInt(term, 10)
+	_, err = configMaps.Update(ctx, lock, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,224 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testPeerA = "10.0.0.1"
+	testPeerB = "10.0.0.2"
+)
+
+func newTestRaft(opts ...Option) *RaftElector {
+	o := defaultOptions()
+	o.raftElectionTimeout = time.Second
+	o.raftHeartbeatInterval = 100 * time.Millisecond
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &RaftElector{
+		lockName: "test",
+		service:  "peers.invalid",
+		self:     "10.0.0.3",
+		name:     "pod-3",
+		opts:     o,
+		log:      o.getLogger(),
+		size:     3,
+		peers:    []string{testPeerA, testPeerB},
+		acks:     map[string]time.Time{},
+	}
+}
+
+// raftCall posts msg to the handler of path as the peer at from, signed
+// with secret unless it is nil.
+func raftCall(t *testing.T, r *RaftElector, path, from string, msg interface{}, secret []byte) (int, raftResponse) {
+	t.Helper()
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.RemoteAddr = from + ":40000"
+	if secret != nil {
+		signer := newTestRaft(WithRaftSecret(secret))
+		date := strconv.FormatInt(time.Now().UnixNano(), 10)
+		req.Header.Set(raftDateHeader, date)
+		req.Header.Set(raftSignatureHeader, hex.EncodeToString(signer.sign(date, path, body)))
+	}
+	w := httptest.NewRecorder()
+	switch path {
+	case raftVotePath:
+		r.serveVote(w, req)
+	default:
+		r.serveHeartbeat(w, req)
+	}
+	var resp raftResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestRaftVotesOncePerTerm(t *testing.T) {
+	r := newTestRaft()
+	if _, resp := raftCall(t, r, raftVotePath, testPeerA, raftVoteRequest{Term: 1, Candidate: testPeerA}, nil); !resp.OK {
+		t.Fatal("vote for the first candidate of term 1 was not granted")
+	}
+	// the vote resets the election timeout, backdate it to see the term
+	// check on its own
+	r.lastContact = time.Time{}
+	if _, resp := raftCall(t, r, raftVotePath, testPeerB, raftVoteRequest{Term: 1, Candidate: testPeerB}, nil); resp.OK {
+		t.Fatal("a second vote was granted in term 1")
+	}
+	if r.Term() != 1 {
+		t.Fatalf("term = %d, want 1", r.Term())
+	}
+}
+
+func TestRaftWithholdsVoteWhileLeaderIsHeard(t *testing.T) {
+	r := newTestRaft()
+	if _, resp := raftCall(t, r, raftHeartbeatPath, testPeerA, raftHeartbeat{Term: 1, Leader: testPeerA, Name: "pod-1"}, nil); !resp.OK {
+		t.Fatal("heartbeat of term 1 was rejected")
+	}
+	if _, resp := raftCall(t, r, raftVotePath, testPeerB, raftVoteRequest{Term: 2, Candidate: testPeerB}, nil); resp.OK {
+		t.Fatal("vote granted within the election timeout of a heartbeat")
+	}
+	if r.Term() != 1 {
+		t.Fatalf("term = %d, want 1", r.Term())
+	}
+}
+
+func TestRaftPreVoteKeepsTerm(t *testing.T) {
+	r := newTestRaft()
+	if _, resp := raftCall(t, r, raftVotePath, testPeerA, raftVoteRequest{Term: 4, Candidate: testPeerA, Pre: true}, nil); !resp.OK {
+		t.Fatal("pre-vote was not granted")
+	}
+	if r.Term() != 0 {
+		t.Fatalf("pre-vote moved the term to %d", r.Term())
+	}
+}
+
+func TestRaftRejectsHeartbeatOfEarlierTerm(t *testing.T) {
+	r := newTestRaft()
+	r.term, r.leader = 5, "pod-2"
+	if _, resp := raftCall(t, r, raftHeartbeatPath, testPeerA, raftHeartbeat{Term: 4, Leader: testPeerA, Name: "pod-1"}, nil); resp.OK || resp.Term != 5 {
+		t.Fatalf("heartbeat of term 4 answered %+v in term 5", resp)
+	}
+	if r.Leader() != "pod-2" {
+		t.Fatalf("leader = %q after a stale heartbeat", r.Leader())
+	}
+}
+
+func TestRaftHeartbeatOfLaterTermDeposesLeader(t *testing.T) {
+	r := newTestRaft()
+	r.term, r.leading, r.leader = 2, true, r.name
+	if _, resp := raftCall(t, r, raftHeartbeatPath, testPeerA, raftHeartbeat{Term: 3, Leader: testPeerA, Name: "pod-1"}, nil); !resp.OK {
+		t.Fatal("heartbeat of a later term was rejected")
+	}
+	if r.leading || r.Term() != 3 || r.Leader() != "pod-1" {
+		t.Fatalf("leading %v, term %d, leader %q; want a follower of pod-1 in term 3", r.leading, r.Term(), r.Leader())
+	}
+}
+
+func TestRaftObservedLaterTermStepsDown(t *testing.T) {
+	r := newTestRaft()
+	r.term, r.leading = 2, true
+	if r.observeTerm(2) {
+		t.Fatal("the current term was taken as a later one")
+	}
+	if !r.observeTerm(3) || r.leading {
+		t.Fatal("a later term did not make the leader step down")
+	}
+}
+
+func TestRaftRejectsNonPeers(t *testing.T) {
+	r := newTestRaft()
+	r.term, r.leading = 2, true
+	code, _ := raftCall(t, r, raftHeartbeatPath, "10.0.0.9", raftHeartbeat{Term: 9, Leader: "10.0.0.9", Name: "intruder"}, nil)
+	if code != http.StatusForbidden {
+		t.Fatalf("heartbeat from a non-peer answered %d, want %d", code, http.StatusForbidden)
+	}
+	if !r.leading || r.Term() != 2 {
+		t.Fatal("a heartbeat from a non-peer deposed the leader")
+	}
+	if code, _ := raftCall(t, r, raftVotePath, "10.0.0.9", raftVoteRequest{Term: 9, Candidate: "10.0.0.9"}, nil); code != http.StatusForbidden {
+		t.Fatalf("vote request from a non-peer answered %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestRaftChecksSignatures(t *testing.T) {
+	secret := []byte("s3cret")
+	r := newTestRaft(WithRaftSecret(secret))
+	r.term, r.leading = 2, true
+
+	if code, _ := raftCall(t, r, raftHeartbeatPath, testPeerA, raftHeartbeat{Term: 3, Leader: testPeerA}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned heartbeat answered %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code, _ := raftCall(t, r, raftHeartbeatPath, testPeerA, raftHeartbeat{Term: 3, Leader: testPeerA}, []byte("wrong")); code != http.StatusUnauthorized {
+		t.Fatalf("heartbeat signed with another secret answered %d, want %d", code, http.StatusUnauthorized)
+	}
+	if !r.leading {
+		t.Fatal("an unauthenticated heartbeat deposed the leader")
+	}
+
+	body, _ := json.Marshal(raftHeartbeat{Term: 3, Leader: testPeerA})
+	req := httptest.NewRequest(http.MethodPost, raftHeartbeatPath, bytes.NewReader(body))
+	req.RemoteAddr = testPeerA + ":40000"
+	date := strconv.FormatInt(time.Now().Add(-time.Minute).UnixNano(), 10)
+	req.Header.Set(raftDateHeader, date)
+	req.Header.Set(raftSignatureHeader, hex.EncodeToString(r.sign(date, raftHeartbeatPath, body)))
+	w := httptest.NewRecorder()
+	r.serveHeartbeat(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed heartbeat answered %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if _, resp := raftCall(t, r, raftHeartbeatPath, testPeerA, raftHeartbeat{Term: 3, Leader: testPeerA, Name: "pod-1"}, secret); !resp.OK || r.leading {
+		t.Fatal("a signed heartbeat of a later term was not accepted")
+	}
+}
+
+func TestRaftRunReturnsWhenLeadershipIsLost(t *testing.T) {
+	r := newTestRaft()
+	events := make(chan Event, 2)
+	done := make(chan error, 1)
+	go func() { done <- r.untilLost(context.Background(), events, make(chan struct{})) }()
+
+	events <- Event{Leading: false}
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v before leadership was gained", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	events <- Event{Leading: true}
+	events <- Event{Leading: false}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("did not return once leadership was lost")
+	}
+}
+
+func TestRaftRunReturnsWhenProtocolStops(t *testing.T) {
+	r := newTestRaft()
+	stopped := make(chan struct{})
+	r.stopErr = context.Canceled
+	close(stopped)
+	if err := r.untilLost(context.Background(), make(chan Event), stopped); err != context.Canceled {
+		t.Fatalf("returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,450 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// RBAC holds the RBAC objects an Elector needs for a given configuration.
+// Node access and group impersonation are cluster scoped, so ClusterRole
+// and ClusterRoleBinding are only set when an option needs to read nodes or,
+// with WithPriorityGroup, to impersonate a group.
+type RBAC struct {
+	Role               *rbacv1.Role
+	RoleBinding        *rbacv1.RoleBinding
+	ClusterRole        *rbacv1.ClusterRole
+	ClusterRoleBinding *rbacv1.ClusterRoleBinding
+}
+
+// RequiredRole returns the RBAC objects needed to elect a leader for
+// lockName in ns with opts. Access to the lock and its companion objects is
+// restricted to their names, except for create, list and watch, which
+// cannot be; with WithGroupLabel the lock's name depends on the pod, so none
+// of it is. The read-write lock methods and Lock additionally need get,
+// create, patch, delete and list on leases, and the scaling hint methods get,
+// create and patch on configmaps. EnterMaintenance and ExitMaintenance patch
+// our pod, which only the options marking pods, such as WithLeaderLabel or
+// WithRemoteMaintenance, grant; without it the mode still holds for the
+// process, but is not recorded on the pod.
+func RequiredRole(ns, lockName string, opts ...Option) *RBAC {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	sa := o.serviceAccountName()
+	name := lockName + "-leader"
+
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      sa,
+		Namespace: ns,
+	}}
+
+	r := &RBAC{
+		Role: &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Rules:      o.namespacedRules(lockName),
+		},
+		RoleBinding: &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+			Subjects:   subjects,
+		},
+	}
+
+	if rules := o.clusterRules(); len(rules) > 0 {
+		clusterName := ns + "-" + name
+		r.ClusterRole = &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+			Rules:      rules,
+		}
+		r.ClusterRoleBinding = &rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterName},
+			Subjects:   subjects,
+		}
+	}
+	return r
+}
+
+// ClusterJanitorRBAC returns the RBAC objects a Janitor sweeping every
+// namespace needs, bound to the service account serviceAccount in ns. It
+// only sets ClusterRole and ClusterRoleBinding.
+func ClusterJanitorRBAC(ns, serviceAccount string) *RBAC {
+	name := "leader-janitor"
+	if ns != "" {
+		name = ns + "-" + name
+	}
+	return &RBAC{
+		ClusterRole: &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Rules:      JanitorRules(),
+		},
+		ClusterRoleBinding: &rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+			Subjects: []rbacv1.Subject{{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      serviceAccount,
+				Namespace: ns,
+			}},
+		},
+	}
+}
+
+// YAML renders the objects as a multi-document YAML manifest.
+func (r *RBAC) YAML() ([]byte, error) {
+	var objects []interface{}
+	if r.Role != nil {
+		objects = append(objects, r.Role, r.RoleBinding)
+	}
+	if r.ClusterRole != nil {
+		objects = append(objects, r.ClusterRole, r.ClusterRoleBinding)
+	}
+
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+// MissingPermissionsError lists the permissions VerifyRBAC found missing.
+type MissingPermissionsError struct {
+	ServiceAccount string
+	Missing        []authorizationv1.ResourceAttributes
+}
+
+func (e *MissingPermissionsError) Error() string {
+	missing := make([]string, 0, len(e.Missing))
+	for _, attr := range e.Missing {
+		missing = append(missing, describeAttributes(attr))
+	}
+	return fmt.Sprintf("service account %s is missing permissions: %s", e.ServiceAccount, strings.Join(missing, ", "))
+}
+
+// VerifyRBAC checks with SelfSubjectAccessReviews that the current identity
+// has every permission RequiredRole would grant for lockName and opts. It
+// returns a *MissingPermissionsError if any are missing.
+func VerifyRBAC(ctx context.Context, lockName string, opts ...Option) error {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	client, ns, err := clientAndNamespace(&o)
+	if err != nil {
+		return err
+	}
+
+	r := RequiredRole(ns, lockName, opts...)
+	var attrs []authorizationv1.ResourceAttributes
+	attrs = append(attrs, ruleAttributes(r.Role.Rules, ns)...)
+	if r.ClusterRole != nil {
+		attrs = append(attrs, ruleAttributes(r.ClusterRole.Rules, "")...)
+	}
+
+	missing := &MissingPermissionsError{ServiceAccount: o.serviceAccountName()}
+	for i := range attrs {
+		reqCtx, cancel := withTimeout(ctx, o.requestTimeout)
+		review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(reqCtx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs[i]},
+		}, metav1.CreateOptions{})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("review access to %s: %w", describeAttributes(attrs[i]), err)
+		}
+		if !review.Status.Allowed {
+			missing.Missing = append(missing.Missing, attrs[i])
+		}
+	}
+
+	if len(missing.Missing) > 0 {
+		return missing
+	}
+	return nil
+}
+
+func (o *options) serviceAccountName() string {
+	if o.serviceAccount != "" {
+		return o.serviceAccount
+	}
+	return "default"
+}
+
+// namespacedRules returns the namespaced rules the configuration needs to
+// elect a leader for lockName.
+func (o *options) namespacedRules(lockName string) []rbacv1.PolicyRule {
+	// names returns the names of lockName's objects, or none if they are
+	// not known up front
+	names := func(suffixes ...string) []string {
+		if o.groupLabel != "" {
+			return nil
+		}
+		var names []string
+		for _, suffix := range suffixes {
+			names = append(names, lockName+suffix)
+		}
+		return names
+	}
+
+	lockVerbs := []string{"get", "create", "patch", "delete"}
+	if o.failoverWatch {
+		lockVerbs = append(lockVerbs, "watch")
+	}
+
+	var rules []rbacv1.PolicyRule
+	for i, b := range o.backends() {
+		lockNames := names("")
+		if o.compatName != "" && i == len(o.backends())-1 {
+			// the compatibility lock comes last
+			lockNames = []string{o.compatName}
+		}
+		switch b {
+		case LeaseBackend:
+			rules = append(rules, namedRules(coordinationv1.GroupName, "leases", lockNames, lockVerbs...)...)
+		default:
+			rules = append(rules, namedRules("", "configmaps", lockNames, lockVerbs...)...)
+		}
+	}
+
+	// the epoch counter is a ConfigMap whatever the backend
+	rules = append(rules, namedRules("", "configmaps", names("-epoch"), "get", "create", "update")...)
+
+	// evicted leaders are deleted so that GC releases their lock
+	podVerbs := []string{"get", "delete"}
+	if o.leaderLabelKey != "" || o.leaderAnnotationKey != "" || o.remoteMaintenance || o.meshDestinationRule != "" || o.backendServiceName != "" {
+		podVerbs = append(podVerbs, "patch")
+	}
+	if o.leaderLabelKey != "" || o.leaderAnnotationKey != "" || o.leaderPodInformer || o.failoverWatch {
+		podVerbs = append(podVerbs, "list")
+	}
+	if o.leaderPodInformer || o.failoverWatch {
+		podVerbs = append(podVerbs, "watch")
+	}
+	rules = append(rules, rule("", "pods", podVerbs...))
+
+	if o.podConditionType != "" {
+		rules = append(rules, rule("", "pods/status", "patch"))
+	}
+	if o.priorityGroup != "" {
+		r := rule("", "serviceaccounts", "impersonate")
+		r.ResourceNames = []string{o.serviceAccountName()}
+		rules = append(rules, r)
+	}
+	if o.committee > 0 {
+		var seats []string
+		for i := 0; i < o.committee; i++ {
+			seats = append(seats, fmt.Sprintf("-committee-%d", i))
+		}
+		rules = append(rules, namedRules(coordinationv1.GroupName, "leases", names(seats...), "get", "create", "patch", "delete")...)
+	}
+	if o.registry {
+		rules = append(rules, rule(coordinationv1.GroupName, "leases", "get", "create", "update", "delete", "list"))
+	}
+	if o.events {
+		rules = append(rules, rule("", "events", "create", "update"))
+	}
+	if o.shards > 0 {
+		rules = append(rules, namedRules("", "configmaps", names("-shards"), "get", "create", "update")...)
+	}
+	if o.backendServiceName != "" {
+		rules = append(rules, namedRules("", "services", []string{o.backendServiceName}, "patch")...)
+	}
+	if o.meshDestinationRule != "" {
+		rules = append(rules, namedRules("networking.istio.io", "destinationrules", []string{o.meshDestinationRule}, "get", "update")...)
+	}
+	if o.envoyConfigMap != "" {
+		rules = append(rules, namedRules("", "configmaps", []string{o.envoyConfigMap}, "get", "create", "patch")...)
+	}
+	if o.leaderInfoName != "" {
+		rules = append(rules, namedRules("", "configmaps", []string{o.leaderInfoName}, "get", "create", "patch")...)
+	}
+	if o.leaderServiceName != "" {
+		rules = append(rules, namedRules("", "services", []string{o.leaderServiceName}, "get", "create", "update")...)
+	}
+	if o.persistDecisions {
+		rules = append(rules, namedRules("", "configmaps", names("-decisions"), "get", "create", "patch")...)
+	}
+	if o.auditRetention > 0 {
+		rules = append(rules, rule("", "events", "create", "list", "delete"))
+	}
+	if o.configName != "" {
+		rules = append(rules, namedRules("", "configmaps", []string{o.configName}, "get", "watch")...)
+	}
+	if o.janitorInterval > 0 {
+		rules = append(rules, JanitorRules()...)
+	}
+
+	return mergeRules(rules)
+}
+
+// clusterRules returns the cluster-scoped rules the configuration needs.
+func (o *options) clusterRules() []rbacv1.PolicyRule {
+	verbs := []string{"get"}
+	if o.watchLeaderNode {
+		verbs = append(verbs, "watch")
+	}
+	var rules []rbacv1.PolicyRule
+	if o.nodeAware() || o.stepDownOnDrain || o.nodeLoss != NodeLossWait || o.watchLeaderNode {
+		rules = append(rules, rule("", "nodes", verbs...))
+	}
+	if o.priorityGroup != "" {
+		r := rule("", "groups", "impersonate")
+		r.ResourceNames = []string{o.priorityGroup}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// backends returns every lock backend the configuration touches.
+func (o *options) backends() []Backend {
+	var backends []Backend
+	switch o.backend {
+	case MigrationBackend, AutoBackend:
+		backends = append(backends, ConfigMapBackend, LeaseBackend)
+	case LeaseBackend:
+		backends = append(backends, LeaseBackend)
+	default:
+		backends = append(backends, ConfigMapBackend)
+	}
+	if o.compatName != "" {
+		backends = append(backends, o.compatBackend)
+	}
+	return backends
+}
+
+func rule(group, resource string, verbs ...string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{
+		APIGroups: []string{group},
+		Resources: []string{resource},
+		Verbs:     verbs,
+	}
+}
+
+// namedRules returns the rules granting verbs on the objects of resource
+// called names, or on all of them if names is empty. Create, list and watch
+// are never restricted to names: the apiserver does not know the name of an
+// object being created, nor filters collections by resource name.
+func namedRules(group, resource string, names []string, verbs ...string) []rbacv1.PolicyRule {
+	if len(names) == 0 {
+		return []rbacv1.PolicyRule{rule(group, resource, verbs...)}
+	}
+	named := rule(group, resource)
+	named.ResourceNames = names
+	unnamed := rule(group, resource)
+	for _, verb := range verbs {
+		switch verb {
+		case "create", "list", "watch":
+			unnamed.Verbs = append(unnamed.Verbs, verb)
+		default:
+			named.Verbs = append(named.Verbs, verb)
+		}
+	}
+
+	var rules []rbacv1.PolicyRule
+	for _, r := range []rbacv1.PolicyRule{named, unnamed} {
+		if len(r.Verbs) > 0 {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// mergeRules folds rules for the same group, resource and resource names
+// into one, keeping the order in which they first appear.
+func mergeRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var merged []rbacv1.PolicyRule
+	index := map[string]int{}
+	for _, r := range rules {
+		key := r.APIGroups[0] + "/" + r.Resources[0] + "/" + strings.Join(r.ResourceNames, ",")
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, rbacv1.PolicyRule{
+				APIGroups:     r.APIGroups,
+				Resources:     r.Resources,
+				ResourceNames: r.ResourceNames,
+			})
+			i = len(merged) - 1
+		}
+		for _, verb := range r.Verbs {
+			if !contains(merged[i].Verbs, verb) {
+				merged[i].Verbs = append(merged[i].Verbs, verb)
+			}
+		}
+	}
+	return merged
+}
+
+// ruleAttributes expands rules into one access review per verb.
+func ruleAttributes(rules []rbacv1.PolicyRule, ns string) []authorizationv1.ResourceAttributes {
+	var attrs []authorizationv1.ResourceAttributes
+	for _, r := range rules {
+		resource, subresource := r.Resources[0], ""
+		if i := strings.Index(resource, "/"); i >= 0 {
+			resource, subresource = resource[:i], resource[i+1:]
+		}
+		names := r.ResourceNames
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, verb := range r.Verbs {
+			for _, name := range names {
+				attrs = append(attrs, authorizationv1.ResourceAttributes{
+					Namespace:   ns,
+					Verb:        verb,
+					Group:       r.APIGroups[0],
+					Resource:    resource,
+					Subresource: subresource,
+					Name:        name,
+				})
+			}
+		}
+	}
+	return attrs
+}
+
+func describeAttributes(attr authorizationv1.ResourceAttributes) string {
+	resource := attr.Resource
+	if attr.Subresource != "" {
+		resource += "/" + attr.Subresource
+	}
+	if attr.Group != "" {
+		resource += "." + attr.Group
+	}
+	if attr.Name != "" {
+		resource += " " + attr.Name
+	}
+	return attr.Verb + " " + resource
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package leader
+
+import (
+	"strings"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// access is a request the generated rules are checked against.
+type access struct {
+	group, resource, name, verb string
+}
+
+// allows reports whether rules grant a, as the RBAC authorizer would.
+func allows(rules []rbacv1.PolicyRule, a access) bool {
+	for _, r := range rules {
+		if contains(r.APIGroups, a.group) && contains(r.Resources, a.resource) && contains(r.Verbs, a.verb) &&
+			(len(r.ResourceNames) == 0 || contains(r.ResourceNames, a.name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequiredRole(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		allowed []access
+		denied  []access
+		cluster bool
+	}{
+		{
+			name: "defaults",
+			allowed: []access{
+				{"", "configmaps", "my-lock", "get"},
+				{"", "configmaps", "", "create"},
+				{"", "configmaps", "my-lock", "delete"},
+				{"", "configmaps", "my-lock-epoch", "update"},
+				{"", "pods", "pod-1", "get"},
+				{"", "pods", "pod-1", "delete"},
+			},
+			denied: []access{
+				{"", "configmaps", "other", "get"},
+				{"", "configmaps", "other", "delete"},
+				{"coordination.k8s.io", "leases", "my-lock", "get"},
+				{"", "pods", "", "list"},
+				// maintenance mode is not recorded, see RequiredRole
+				{"", "pods", "pod-1", "patch"},
+			},
+		},
+		{
+			name: "lease backend with failover watch",
+			opts: []Option{WithBackend(LeaseBackend), WithFailoverWatch()},
+			allowed: []access{
+				{"coordination.k8s.io", "leases", "my-lock", "patch"},
+				{"coordination.k8s.io", "leases", "", "watch"},
+				{"", "pods", "", "list"},
+				{"", "pods", "", "watch"},
+			},
+			denied: []access{
+				{"", "configmaps", "my-lock", "get"},
+			},
+		},
+		{
+			name: "compatibility lock",
+			opts: []Option{WithBackend(LeaseBackend), WithCompatibilityLock(ConfigMapBackend, "old-lock")},
+			allowed: []access{
+				{"coordination.k8s.io", "leases", "my-lock", "get"},
+				{"", "configmaps", "old-lock", "delete"},
+			},
+			denied: []access{
+				{"", "configmaps", "my-lock", "get"},
+			},
+		},
+		{
+			name: "group label",
+			opts: []Option{WithGroupLabel("shard")},
+			allowed: []access{
+				// names depend on the pod
+				{"", "configmaps", "my-lock-shard-a", "get"},
+				{"", "configmaps", "anything-epoch", "update"},
+			},
+		},
+		{
+			name: "committee and shards",
+			opts: []Option{WithCommittee(2), WithSharding(4, ShardEvenSpread, 0)},
+			allowed: []access{
+				{"coordination.k8s.io", "leases", "my-lock-committee-1", "patch"},
+				{"", "configmaps", "my-lock-shards", "update"},
+			},
+			denied: []access{
+				{"coordination.k8s.io", "leases", "my-lock-committee-2", "patch"},
+			},
+		},
+		{
+			name: "leader marks and pod condition",
+			opts: []Option{WithLeaderLabel("role", "leader"), WithPodCondition(LeaderPodCondition)},
+			allowed: []access{
+				{"", "pods", "pod-2", "patch"},
+				{"", "pods", "", "list"},
+				{"", "pods/status", "pod-1", "patch"},
+			},
+		},
+		{
+			name:    "remote maintenance",
+			opts:    []Option{WithRemoteMaintenance()},
+			allowed: []access{{"", "pods", "pod-1", "patch"}},
+		},
+		{
+			name:    "node-aware",
+			opts:    []Option{WithPreferredZone("zone-a")},
+			cluster: true,
+		},
+		{
+			name:    "priority group",
+			opts:    []Option{WithPriorityGroup("leaders")},
+			allowed: []access{{"", "serviceaccounts", "default", "impersonate"}},
+			denied:  []access{{"", "serviceaccounts", "other", "impersonate"}},
+			cluster: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := RequiredRole("ns", "my-lock", tc.opts...)
+			for _, a := range tc.allowed {
+				if !allows(r.Role.Rules, a) {
+					t.Errorf("%s of %s %q is not allowed by %v", a.verb, a.resource, a.name, r.Role.Rules)
+				}
+			}
+			for _, a := range tc.denied {
+				if allows(r.Role.Rules, a) {
+					t.Errorf("%s of %s %q is allowed", a.verb, a.resource, a.name)
+				}
+			}
+			if (r.ClusterRole != nil) != tc.cluster {
+				t.Errorf("ClusterRole = %v, want one %v", r.ClusterRole, tc.cluster)
+			}
+			if r.RoleBinding.Subjects[0].Name != "default" || r.RoleBinding.RoleRef.Name != r.Role.Name {
+				t.Errorf("RoleBinding = %+v does not bind the Role to the service account", r.RoleBinding)
+			}
+		})
+	}
+}
+
+func TestRBACYAML(t *testing.T) {
+	out, err := RequiredRole("ns", "my-lock", WithPreferredZone("zone-a")).YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(string(out), "---\n")
+	var kinds []string
+	for _, doc := range docs {
+		for _, line := range strings.Split(doc, "\n") {
+			if strings.HasPrefix(line, "kind: ") {
+				kinds = append(kinds, strings.TrimPrefix(line, "kind: "))
+			}
+		}
+	}
+	if strings.Join(kinds, ",") != "Role,RoleBinding,ClusterRole,ClusterRoleBinding" {
+		t.Fatalf("manifest holds %v", kinds)
+	}
+}
@@ -0,0 +1,40 @@
+package leader
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// checkReadinessGate warns if pod does not declare the readiness gate we
+// maintain, which would leave every candidate Ready, and notes whether the
+// condition was left True.
+func (e *PodElector) checkReadinessGate(pod *v1.Pod) {
+	declared := false
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == e.opts.podConditionType {
+			declared = true
+		}
+	}
+	if !declared {
+		e.log.Warn("Pod does not declare the readiness gate; add it to spec.readinessGates so that only the leader is Ready", "pod", pod.Name, "condition", e.opts.podConditionType)
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == e.opts.podConditionType && c.Status == v1.ConditionTrue {
+			e.gateStale = true
+		}
+	}
+}
+
+// clearStaleGate sets the readiness gate condition False if an earlier run
+// of our container left it True, so we are not Ready while not leading. A
+// lock we still hold sets it True again as soon as we resume.
+func (e *PodElector) clearStaleGate() {
+	e.mu.Lock()
+	stale := e.gateStale && !e.leading
+	e.gateStale = false
+	e.mu.Unlock()
+	if stale {
+		e.log.Info("Clearing readiness gate left set by an earlier run", "condition", e.opts.podConditionType)
+		e.setPodCondition(false)
+	}
+}
@@ -0,0 +1,54 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestReadinessGate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		stale  bool
+		holder string
+		want   v1.ConditionStatus
+	}{
+		{name: "candidate", holder: "pod-2", want: v1.ConditionFalse},
+		{name: "stale candidate", stale: true, holder: "pod-2", want: v1.ConditionFalse},
+		{name: "restarted leader", holder: "pod-1", want: v1.ConditionTrue},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			holder := newTestElector(t, client, tc.holder, WithReadinessGate(LeaderPodCondition))
+			if ok, err := holder.TryAcquire(context.Background()); err != nil || !ok {
+				t.Fatalf("TryAcquire = %v, %v", ok, err)
+			}
+			if tc.stale {
+				updatePod(t, client, "pod-1", func(pod *v1.Pod) {
+					pod.Status.Conditions = []v1.PodCondition{{Type: LeaderPodCondition, Status: v1.ConditionTrue}}
+				})
+			}
+			e := newTestElector(t, client, "pod-1", WithReadinessGate(LeaderPodCondition))
+			if e.gateStale != (tc.stale || tc.holder == "pod-1") {
+				t.Fatalf("condition left True detected = %v", e.gateStale)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err := e.Become(ctx)
+			if leads := tc.holder == "pod-1"; leads != (err == nil) || (!leads && !errors.Is(err, context.DeadlineExceeded)) {
+				t.Fatalf("Become = %v", err)
+			}
+			var got v1.ConditionStatus
+			if c := podCondition(t, client, "pod-1"); c != nil {
+				got = c.Status
+			}
+			if got != tc.want {
+				t.Fatalf("readiness gate condition = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
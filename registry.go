@@ -0,0 +1,327 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// LockLabel is set on lock objects and their auxiliary objects to the
+	// name of the lock they belong to.
+	LockLabel = "leader.seamounts.io/lock"
+
+	// RoleLabel distinguishes the lock object from the auxiliary objects
+	// kept per lock.
+	RoleLabel = "leader.seamounts.io/role"
+
+	// LockRole is the RoleLabel value of lock objects themselves.
+	LockRole = "lock"
+
+	candidateRole = "candidate"
+	epochRole     = "epoch"
+
+	// candidateTTL is how long a registry entry stays live without a
+	// heartbeat. Members heartbeat every candidateHeartbeatInterval.
+	candidateTTL = candidateHeartbeatInterval * 3
+
+	candidateHeartbeatInterval = defaultMaxBackoffInterval
+
+	// VersionAnnotation records a member's version on its registry entry.
+	VersionAnnotation = "leader.seamounts.io/version"
+
+	// LeadingAnnotation marks the registry entry of the leader.
+	LeadingAnnotation = "leader.seamounts.io/leading"
+)
+
+// Candidate describes a pod registered in the membership registry of a lock.
+type Candidate struct {
+	// Name is the name of the candidate pod.
+	Name string
+	// Since is when the candidate registered.
+	Since time.Time
+	// LastHeartbeat is when the candidate last renewed its entry.
+	LastHeartbeat time.Time
+	// Live is false once the candidate has missed its heartbeats, which
+	// usually means it is wedged.
+	Live bool
+	// Weight is the candidate's shard weight.
+	Weight int
+	// Version is the version the candidate reported with WithMemberVersion.
+	Version string
+	// Labels are the labels the candidate reported with WithMemberLabels.
+	Labels map[string]string
+	// Leading is true for the entry of the leader.
+	Leading bool
+	// Maintenance is true for a candidate in maintenance mode, which does
+	// not compete.
+	Maintenance bool
+	// Denied is true for a candidate denied candidacy by
+	// CandidacyDeniedAnnotation, which does not compete either.
+	Denied bool
+}
+
+// competing reports whether c takes part in the election.
+func (c Candidate) competing() bool {
+	return c.Live && !c.Maintenance && !c.Denied
+}
+
+func (e *PodElector) candidateEntryName() string {
+	return fmt.Sprintf("%s-candidate-%s", e.lockName, e.owner.Name)
+}
+
+func (e *PodElector) candidateSelector() string {
+	return labels.SelectorFromSet(labels.Set{
+		LockLabel: e.lockName,
+		RoleLabel: candidateRole,
+	}).String()
+}
+
+// startHeartbeat keeps our registry entry alive in the background, whether
+// we lead or wait, until ctx is cancelled. The entry is then removed.
+func (e *PodElector) startHeartbeat(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.heartbeating {
+		return
+	}
+	e.heartbeating = true
+
+	go func() {
+		for {
+			if err := e.heartbeat(ctx); err != nil && ctx.Err() == nil {
+				e.log.Error(err, "Failed to renew registry entry", "lock", e.lockName)
+			}
+			if err := e.sleep(ctx, candidateHeartbeatInterval); err != nil {
+				break
+			}
+		}
+		e.unregister(context.Background())
+
+		e.mu.Lock()
+		e.heartbeating = false
+		e.mu.Unlock()
+	}()
+}
+
+// membershipChanged is the transition hook updating our entry right away,
+// so other members see who leads without waiting for the next heartbeat.
+func (e *PodElector) membershipChanged(leading bool) {
+	if err := e.heartbeat(context.Background()); err != nil {
+		e.log.Error(err, "Failed to update registry entry", "lock", e.lockName)
+	}
+}
+
+// heartbeat registers us in the registry, or renews our existing entry,
+// recording our version, labels and whether we lead. The entry is owned by
+// our pod so it is garbage collected with it.
+func (e *PodElector) heartbeat(ctx context.Context) error {
+	leases := e.kube().CoordinationV1().Leases(e.ns)
+	now := metav1.NewMicroTime(time.Now())
+
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	entry, err := leases.Get(ctx, e.candidateEntryName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		ttl := int32(candidateTTL / time.Second)
+		entry = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            e.candidateEntryName(),
+				Namespace:       e.ns,
+				OwnerReferences: []metav1.OwnerReference{*e.owner},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &e.owner.Name,
+				LeaseDurationSeconds: &ttl,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		e.describeMember(entry)
+		_, err = leases.Create(ctx, entry, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	entry.Spec.RenewTime = &now
+	e.describeMember(entry)
+	_, err = leases.Update(ctx, entry, metav1.UpdateOptions{})
+	return err
+}
+
+// describeMember sets what other members learn about us on our entry.
+func (e *PodElector) describeMember(entry *coordinationv1.Lease) {
+	entry.Labels = map[string]string{}
+	for k, v := range e.opts.memberLabels {
+		entry.Labels[k] = v
+	}
+	entry.Labels[LockLabel] = e.lockName
+	entry.Labels[RoleLabel] = candidateRole
+
+	if entry.Annotations == nil {
+		entry.Annotations = map[string]string{}
+	}
+	entry.Annotations[ShardWeightAnnotation] = strconv.Itoa(e.opts.shardWeight)
+	entry.Annotations[VersionAnnotation] = e.opts.memberVersion
+	if e.IsLeader() {
+		entry.Annotations[LeadingAnnotation] = "true"
+	} else {
+		delete(entry.Annotations, LeadingAnnotation)
+	}
+	e.mu.Lock()
+	if e.maintenance != "" {
+		entry.Annotations[MaintenanceAnnotation] = e.maintenance
+	} else {
+		delete(entry.Annotations, MaintenanceAnnotation)
+	}
+	if e.denied {
+		entry.Annotations[CandidacyDeniedAnnotation] = e.deniedBy
+	} else {
+		delete(entry.Annotations, CandidacyDeniedAnnotation)
+	}
+	e.mu.Unlock()
+}
+
+// unregister drops our registry entry.
+func (e *PodElector) unregister(ctx context.Context) {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	err := e.kube().CoordinationV1().Leases(e.ns).Delete(ctx, e.candidateEntryName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		e.log.Error(err, "Failed to remove candidate entry", "entry", e.candidateEntryName())
+	}
+}
+
+// ListCandidates returns the pods registered as candidates for the lock,
+// longest-waiting first. The leader is not a candidate. Pods register only
+// when the registry or fair queue is enabled.
+func (e *PodElector) ListCandidates(ctx context.Context) ([]Candidate, error) {
+	members, err := e.ListMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates := members[:0]
+	for _, m := range members {
+		if !m.Leading {
+			candidates = append(candidates, m)
+		}
+	}
+	return candidates, nil
+}
+
+// ListMembers returns every pod registered for the lock, the leader
+// included, longest-registered first.
+func (e *PodElector) ListMembers(ctx context.Context) (_ []Candidate, err error) {
+	defer func() { err = e.wrap("list members of", err) }()
+
+	listCtx, cancel := e.request(ctx)
+	defer cancel()
+	list, err := e.kube().CoordinationV1().Leases(e.ns).List(listCtx, metav1.ListOptions{
+		LabelSelector: e.candidateSelector(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		ti, tj := list.Items[i].CreationTimestamp, list.Items[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	now := time.Now()
+	members := make([]Candidate, 0, len(list.Items))
+	for i := range list.Items {
+		entry := &list.Items[i]
+		c := Candidate{
+			Since:   entry.CreationTimestamp.Time,
+			Live:    !leaseExpired(entry, now),
+			Weight:  candidateWeight(entry.Annotations),
+			Version: entry.Annotations[VersionAnnotation],
+			Leading: entry.Annotations[LeadingAnnotation] == "true",
+			Labels:  map[string]string{},
+		}
+		_, c.Maintenance = entry.Annotations[MaintenanceAnnotation]
+		_, c.Denied = entry.Annotations[CandidacyDeniedAnnotation]
+		for k, v := range entry.Labels {
+			if k != LockLabel && k != RoleLabel {
+				c.Labels[k] = v
+			}
+		}
+		if entry.Spec.HolderIdentity != nil {
+			c.Name = *entry.Spec.HolderIdentity
+		}
+		if entry.Spec.RenewTime != nil {
+			c.LastHeartbeat = entry.Spec.RenewTime.Time
+		}
+		members = append(members, c)
+	}
+	return members, nil
+}
+
+// observeCandidates refreshes the candidate metrics: how many candidates
+// wait and how long the oldest has been waiting, which reveal
+// over-replication and starvation. Only the leader calls it, so the fleet
+// does not multiply List calls.
+func (e *PodElector) observeCandidates(ctx context.Context) {
+	candidates, err := e.ListCandidates(ctx)
+	if err != nil {
+		e.log.Error(err, "Failed to list candidates", "lock", e.lockName)
+		return
+	}
+
+	live, stale := 0, 0
+	var oldest time.Duration
+	for _, c := range candidates {
+		if !c.Live {
+			stale++
+			continue
+		}
+		live++
+		if live == 1 {
+			// candidates come longest-waiting first
+			oldest = time.Since(c.Since)
+		}
+	}
+	candidatesGauge.WithLabelValues(e.lockName).Set(float64(live))
+	staleCandidatesGauge.WithLabelValues(e.lockName).Set(float64(stale))
+	oldestCandidateWaitGauge.WithLabelValues(e.lockName).Set(oldest.Seconds())
+}
+
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	ttl := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	return lease.Spec.RenewTime.Add(ttl).Before(now)
+}
+
+// renewLease renews the Lease name of uid that we hold. The UID in the patch
+// makes it fail with Conflict once the Lease has expired and been taken
+// over.
+func (e *PodElector) renewLease(ctx context.Context, name string, uid types.UID) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"uid": uid},
+		"spec":     map[string]interface{}{"renewTime": metav1.NewMicroTime(time.Now())},
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	_, err = e.kube().CoordinationV1().Leases(e.ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	return forbidden(err, "patch", coordinationv1.Resource("leases"), e.ns)
+}
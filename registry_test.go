@@ -0,0 +1,150 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// register makes pod a member of testLock's registry with opts and returns
+// its Elector.
+func register(t *testing.T, client *fake.Clientset, pod string, opts ...Option) *PodElector {
+	t.Helper()
+	e := newTestElector(t, client, pod, append([]Option{WithCandidateRegistry()}, opts...)...)
+	if err := e.heartbeat(context.Background()); err != nil {
+		t.Fatalf("heartbeat of %s: %v", pod, err)
+	}
+	return e
+}
+
+// missHeartbeats makes the registry entry of pod look stale.
+func missHeartbeats(t *testing.T, client *fake.Clientset, pod string) {
+	t.Helper()
+	leases := client.CoordinationV1().Leases(testNamespace)
+	entry, err := leases.Get(context.Background(), testLock+"-candidate-"+pod, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := metav1.NewMicroTime(time.Now().Add(-2 * candidateTTL))
+	entry.Spec.RenewTime = &stale
+	if _, err := leases.Update(context.Background(), entry, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListCandidates(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2", "pod-3")
+	e := newTestElector(t, client, "pod-1", WithCandidateRegistry())
+	register(t, client, "pod-2")
+	register(t, client, "pod-3")
+	missHeartbeats(t, client, "pod-3")
+
+	candidates, err := e.ListCandidates(context.Background())
+	if err != nil {
+		t.Fatalf("ListCandidates: %v", err)
+	}
+	want := []struct {
+		name string
+		live bool
+	}{{"pod-2", true}, {"pod-3", false}}
+	if len(candidates) != len(want) {
+		t.Fatalf("candidates = %+v, want %v", candidates, want)
+	}
+	for i, w := range want {
+		if c := candidates[i]; c.Name != w.name || c.Live != w.live || c.Since.IsZero() {
+			t.Errorf("candidate %d = %+v, want %s live %v", i, c, w.name, w.live)
+		}
+	}
+}
+
+func TestListMembers(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2", "pod-3")
+	leader := register(t, client, "pod-1", WithMemberVersion("v2"))
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	register(t, client, "pod-2", WithMemberLabels(map[string]string{"zone": "a"}), WithShardWeight(3))
+	register(t, client, "pod-3")
+	missHeartbeats(t, client, "pod-3")
+
+	members, err := leader.ListMembers(context.Background())
+	if err != nil {
+		t.Fatalf("ListMembers: %v", err)
+	}
+	if len(members) != 3 {
+		t.Fatalf("%d members, want 3", len(members))
+	}
+	for i, want := range []Candidate{
+		{Name: "pod-1", Live: true, Leading: true, Version: "v2", Weight: 1},
+		{Name: "pod-2", Live: true, Weight: 3},
+		{Name: "pod-3", Live: false, Weight: 1},
+	} {
+		got := members[i]
+		if got.Name != want.Name || got.Live != want.Live || got.Leading != want.Leading || got.Version != want.Version || got.Weight != want.Weight {
+			t.Errorf("member %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if members[1].Labels["zone"] != "a" || len(members[1].Labels) != 1 {
+		t.Errorf("labels of pod-2 = %v", members[1].Labels)
+	}
+
+	// the leader is a member but not a candidate
+	candidates, err := leader.ListCandidates(context.Background())
+	if err != nil {
+		t.Fatalf("ListCandidates: %v", err)
+	}
+	if len(candidates) != 2 || candidates[0].Name != "pod-2" {
+		t.Fatalf("candidates = %+v, want those waiting", candidates)
+	}
+
+	// giving up leadership is recorded right away
+	if err := leader.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if members, err := leader.ListMembers(context.Background()); err != nil || members[0].Leading {
+		t.Fatalf("ListMembers after Release = %+v, %v, want no leader", members, err)
+	}
+
+	leader.unregister(context.Background())
+	if members, err := leader.ListMembers(context.Background()); err != nil || len(members) != 2 {
+		t.Fatalf("ListMembers after unregister = %d, %v", len(members), err)
+	}
+}
+
+func TestObserveCandidates(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2", "pod-3", "pod-4")
+	leader := register(t, client, "pod-1")
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	register(t, client, "pod-2")
+	register(t, client, "pod-3")
+	register(t, client, "pod-4")
+	missHeartbeats(t, client, "pod-4")
+
+	// pod-2 has waited a minute
+	leases := client.CoordinationV1().Leases(testNamespace)
+	entry, err := leases.Get(context.Background(), testLock+"-candidate-pod-2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+	if _, err := leases.Update(context.Background(), entry, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	leader.observeCandidates(context.Background())
+	if live := testutil.ToFloat64(candidatesGauge.WithLabelValues(testLock)); live != 2 {
+		t.Errorf("candidates = %v, want 2", live)
+	}
+	if stale := testutil.ToFloat64(staleCandidatesGauge.WithLabelValues(testLock)); stale != 1 {
+		t.Errorf("stale candidates = %v, want 1", stale)
+	}
+	if wait := testutil.ToFloat64(oldestCandidateWaitGauge.WithLabelValues(testLock)); wait < 60 || wait > 70 {
+		t.Errorf("oldest candidate wait = %vs, want a minute", wait)
+	}
+}
@@ -0,0 +1,51 @@
+package leader
+
+import (
+	"context"
+	"time"
+)
+
+// Run takes part in the election for as long as ctx allows. It becomes the
+// leader, keeps the lock maintained while it leads, and returns nil once
+// leadership is lost or given up, or ctx.Err() once ctx is cancelled. A
+// leader that demoted itself under WithHealthCheck is not done: Run waits
+// out the cooldown and competes again. If ctx is cancelled while we lead,
+// Run drains and releases the lock before it returns, so a successor takes
+// over right away instead of waiting for our pod to go. Every goroutine it
+// started has stopped by the time it returns, which makes Run suitable for
+// an errgroup alongside the application's other long-running components. An
+// errgroup only cancels its context on an error, so return one from the
+// group function when leadership ending should stop the rest of the group.
+func (e *PodElector) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		e.background.Wait()
+	}()
+
+	events := e.Subscribe()
+	defer e.unsubscribe(events)
+
+	for {
+		// Become waits out the cooldown of a demotion
+		started := time.Now()
+		if err := e.Become(ctx); err != nil {
+			return err
+		}
+
+		for e.IsLeader() {
+			select {
+			case <-ctx.Done():
+				// ctx is gone, but the successor should not wait for our pod
+				if err := e.Resign(context.Background()); err != nil {
+					e.log.Error(err, "Failed to release the lock on shutdown", "lock", e.lockName)
+				}
+				return ctx.Err()
+			case <-events:
+			}
+		}
+		if !e.demotedSince(started) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// WriteIntentAnnotation is set on the lock by the leader while it wants
+	// or holds write access. New readers do not join while it is present.
+	WriteIntentAnnotation = "leader.seamounts.io/write-intent"
+
+	readerRole = "reader"
+
+	// readerRenewInterval is how often a reader renews its entry.
+	readerRenewInterval = time.Second * 10
+	// readerTTL is how long a reader's entry outlives its last renewal
+	// before the writer stops waiting for it.
+	readerTTL = readerRenewInterval * 3
+)
+
+func (e *PodElector) readerEntryName() string {
+	return fmt.Sprintf("%s-reader-%s", e.lockName, e.owner.Name)
+}
+
+// AcquireRead registers the current pod as a reader of the lock. It blocks
+// while the leader holds or is waiting for write access, or until ctx is
+// cancelled. Any number of pods can hold read access at the same time. The
+// entry is renewed until ReleaseRead; one left unrenewed for 30 seconds, as
+// by a process that died in a pod that did not, no longer holds up writers.
+func (e *PodElector) AcquireRead(ctx context.Context) (err error) {
+	defer func() { err = e.wrap("acquire read access to", err) }()
+
+	for {
+		writing, err := e.writeIntent(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !writing {
+			uid, err := e.createReaderEntry(ctx)
+			if err != nil {
+				return err
+			}
+
+			// the leader may have declared its intent while we registered
+			writing, err = e.writeIntent(ctx)
+			if err != nil {
+				return err
+			}
+			if !writing {
+				e.holdRead(uid)
+				return nil
+			}
+			if err := e.ReleaseRead(ctx); err != nil {
+				return err
+			}
+		}
+
+		e.log.Debug("Leader is writing, waiting to read", "lock", e.lockName)
+		if err := e.sleep(ctx, e.opts.transferPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// ReleaseRead gives up read access.
+func (e *PodElector) ReleaseRead(ctx context.Context) error {
+	e.mu.Lock()
+	if e.stopReading != nil {
+		e.stopReading()
+		e.stopReading = nil
+	}
+	e.mu.Unlock()
+
+	reqCtx, cancel := e.request(ctx)
+	defer cancel()
+	err := e.kube().CoordinationV1().Leases(e.ns).Delete(reqCtx, e.readerEntryName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return e.wrap("release read access to", err)
+	}
+	return nil
+}
+
+// AcquireWrite declares that the leader wants exclusive access and blocks
+// until every reader has released, or until ctx is cancelled. Only the
+// leader may acquire write access.
+func (e *PodElector) AcquireWrite(ctx context.Context) (err error) {
+	defer func() { err = e.wrap("acquire write access to", err) }()
+
+	if !e.IsLeader() {
+		return ErrNotLeader
+	}
+
+	err = e.patchLockAnnotations(ctx, map[string]interface{}{
+		WriteIntentAnnotation: e.owner.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		readers, err := e.readers(ctx)
+		if err == nil && len(readers) == 0 {
+			e.log.Info("All readers drained, holding write access", "lock", e.lockName)
+			return nil
+		}
+		if err == nil {
+			e.log.Info("Waiting for readers to drain", "lock", e.lockName, "readers", len(readers))
+			err = e.sleep(ctx, e.opts.transferPollInterval)
+		}
+		if err != nil {
+			// readers must not wait on an intent nobody acts on
+			if err := e.ReleaseWrite(context.Background()); err != nil {
+				e.log.Error(err, "Failed to clear write intent", "lock", e.lockName)
+			}
+			return err
+		}
+	}
+}
+
+// ReleaseWrite gives up write access, letting readers join again.
+func (e *PodElector) ReleaseWrite(ctx context.Context) error {
+	err := e.patchLockAnnotations(ctx, map[string]interface{}{
+		WriteIntentAnnotation: nil,
+	})
+	return e.wrap("release write access to", err)
+}
+
+func (e *PodElector) writeIntent(ctx context.Context) (bool, error) {
+	lock, err := e.getLock(ctx)
+	switch {
+	case apierrors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	_, ok := lock.GetAnnotations()[WriteIntentAnnotation]
+	return ok, nil
+}
+
+// createReaderEntry records our read access in a Lease owned by our pod, so
+// a reader that dies is garbage collected instead of blocking the writer,
+// and returns its UID.
+func (e *PodElector) createReaderEntry(ctx context.Context) (types.UID, error) {
+	ttl := int32(readerTTL / time.Second)
+	now := metav1.NewMicroTime(time.Now())
+	leases := e.kube().CoordinationV1().Leases(e.ns)
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	lease, err := leases.Create(ctx, &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            e.readerEntryName(),
+			Namespace:       e.ns,
+			OwnerReferences: []metav1.OwnerReference{*e.owner},
+			Labels: map[string]string{
+				LockLabel: e.lockName,
+				RoleLabel: readerRole,
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &e.owner.Name,
+			LeaseDurationSeconds: &ttl,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// ours, from before a container restart; renewing it revives it
+		lease, err = leases.Get(ctx, e.readerEntryName(), metav1.GetOptions{})
+	}
+	if err != nil {
+		return "", err
+	}
+	return lease.UID, nil
+}
+
+// holdRead renews our reader entry of uid until ReleaseRead. Renewal is not
+// bound to the context of AcquireRead, which may end long before.
+func (e *PodElector) holdRead(uid types.UID) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	if e.stopReading != nil {
+		e.stopReading()
+	}
+	e.stopReading = cancel
+	e.mu.Unlock()
+
+	name := e.readerEntryName()
+	go func() {
+		for {
+			if _, err := sleepOrWake(ctx, readerRenewInterval, nil); err != nil {
+				return
+			}
+			err := e.renewLease(ctx, name, uid)
+			switch {
+			case err == nil || ctx.Err() != nil:
+			case apierrors.IsNotFound(err) || apierrors.IsConflict(err):
+				e.log.Warn("Lost reader entry, it was deleted", "lock", e.lockName, "name", name)
+				return
+			default:
+				e.log.Error(err, "Failed to renew reader entry", "lock", e.lockName, "name", name)
+			}
+		}
+	}()
+}
+
+func (e *PodElector) readers(ctx context.Context) ([]coordinationv1.Lease, error) {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	list, err := e.kube().CoordinationV1().Leases(e.ns).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			LockLabel: e.lockName,
+			RoleLabel: readerRole,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// readers that stopped renewing their entry no longer hold us up
+	now := time.Now()
+	var live []coordinationv1.Lease
+	for _, lease := range list.Items {
+		if !leaseExpired(&lease, now) {
+			live = append(live, lease)
+		}
+	}
+	return live, nil
+}
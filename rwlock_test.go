@@ -0,0 +1,142 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// readerEntry returns a reader entry of pod renewed at renewed, or never
+// if it is zero.
+func readerEntry(pod string, renewed time.Time) *coordinationv1.Lease {
+	lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{
+		Name:      testLock + "-reader-" + pod,
+		Namespace: testNamespace,
+		Labels:    map[string]string{LockLabel: testLock, RoleLabel: readerRole},
+	}}
+	if !renewed.IsZero() {
+		ttl := int32(readerTTL / time.Second)
+		renewTime := metav1.NewMicroTime(renewed)
+		lease.Spec.LeaseDurationSeconds = &ttl
+		lease.Spec.RenewTime = &renewTime
+	}
+	return lease
+}
+
+func TestReaders(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		renewed time.Time
+		live    bool
+	}{
+		{name: "renewed", renewed: time.Now(), live: true},
+		{name: "renewed within the TTL", renewed: time.Now().Add(-readerTTL / 2), live: true},
+		{name: "expired", renewed: time.Now().Add(-readerTTL * 2), live: false},
+		{name: "never renewed", live: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			if _, err := client.CoordinationV1().Leases(testNamespace).Create(context.Background(), readerEntry("pod-2", tc.renewed), metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			e := newTestElector(t, client, "pod-1")
+			readers, err := e.readers(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if live := len(readers) == 1; live != tc.live {
+				t.Fatalf("reader entry counted = %v, want %v", live, tc.live)
+			}
+		})
+	}
+}
+
+func TestAcquireWriteWaitsForReaders(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	writer := newTestElector(t, client, "pod-1")
+	reader := newTestElector(t, client, "pod-2")
+	if ok, err := writer.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	if err := reader.AcquireRead(context.Background()); err != nil {
+		t.Fatalf("AcquireRead: %v", err)
+	}
+	lease, err := client.CoordinationV1().Leases(testNamespace).Get(context.Background(), reader.readerEntryName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		t.Fatal("reader entry was created without an expiry")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := writer.AcquireWrite(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AcquireWrite with a reader = %v, want to wait", err)
+	}
+	if err := reader.ReleaseRead(context.Background()); err != nil {
+		t.Fatalf("ReleaseRead: %v", err)
+	}
+	if err := writer.AcquireWrite(context.Background()); err != nil {
+		t.Fatalf("AcquireWrite without readers: %v", err)
+	}
+
+	// new readers wait for the writer
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := reader.AcquireRead(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AcquireRead while writing = %v, want to wait", err)
+	}
+	if err := writer.ReleaseWrite(context.Background()); err != nil {
+		t.Fatalf("ReleaseWrite: %v", err)
+	}
+	if err := reader.AcquireRead(context.Background()); err != nil {
+		t.Fatalf("AcquireRead after the write: %v", err)
+	}
+}
+
+func TestAcquireWriteIgnoresExpiredReaders(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	if _, err := client.CoordinationV1().Leases(testNamespace).Create(context.Background(), readerEntry("pod-2", time.Now().Add(-time.Hour)), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	writer := newTestElector(t, client, "pod-1")
+	if ok, err := writer.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := writer.AcquireWrite(ctx); err != nil {
+		t.Fatalf("AcquireWrite with an expired reader: %v", err)
+	}
+}
+
+func TestAcquireWriteClearsIntentOnError(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	writer := newTestElector(t, client, "pod-1")
+	if ok, err := writer.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	client.PrependReactor("list", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(errors.New("etcd is unavailable"))
+	})
+
+	if err := writer.AcquireWrite(context.Background()); !apierrors.IsInternalError(err) {
+		t.Fatalf("AcquireWrite with unlistable readers = %v, want the list error", err)
+	}
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.Annotations[WriteIntentAnnotation]; ok {
+		t.Fatal("write intent left on the lock")
+	}
+}
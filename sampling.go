@@ -0,0 +1,92 @@
+package leader
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogSampleInterval is how often a repeated wait message is logged
+// by default.
+const defaultLogSampleInterval = time.Minute
+
+// logSampler lets a repeated message through at most once per interval and
+// counts the repeats it suppressed in between.
+type logSampler struct {
+	mu      sync.Mutex
+	samples map[string]*logSample
+}
+
+type logSample struct {
+	logged     time.Time
+	suppressed int
+}
+
+// allow reports whether the message keyed by key should be logged now, and
+// how many repeats of it were suppressed since it last was.
+func (s *logSampler) allow(key string, interval time.Duration) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.samples == nil {
+		s.samples = map[string]*logSample{}
+	}
+	sample, ok := s.samples[key]
+	if !ok {
+		sample = &logSample{}
+		s.samples[key] = sample
+	}
+	if ok && time.Since(sample.logged) < interval {
+		sample.suppressed++
+		return 0, false
+	}
+	suppressed := sample.suppressed
+	sample.logged = time.Now()
+	sample.suppressed = 0
+	return suppressed, true
+}
+
+// sampleKey identifies a message by its text and values, so the same wait
+// for a different leader is logged right away.
+func sampleKey(msg string, keysAndValues []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, kv := range keysAndValues {
+		fmt.Fprintf(&b, "\x00%v", kv)
+	}
+	return b.String()
+}
+
+// infoSampled logs a message the election loop repeats while it waits at
+// most once per sampling interval, with the number of repeats suppressed
+// since it was last logged.
+func (e *PodElector) infoSampled(msg string, keysAndValues ...interface{}) {
+	if e.opts.logSampleInterval <= 0 {
+		e.log.Info(msg, keysAndValues...)
+		return
+	}
+	suppressed, ok := e.sampler.allow(sampleKey(msg, keysAndValues), e.opts.logSampleInterval)
+	if !ok {
+		return
+	}
+	if suppressed > 0 {
+		keysAndValues = append(keysAndValues, "suppressed", suppressed)
+	}
+	e.log.Info(msg, keysAndValues...)
+}
+
+// warnSampled is infoSampled at warning level.
+func (e *PodElector) warnSampled(msg string, keysAndValues ...interface{}) {
+	if e.opts.logSampleInterval <= 0 {
+		e.log.Warn(msg, keysAndValues...)
+		return
+	}
+	suppressed, ok := e.sampler.allow(sampleKey(msg, keysAndValues), e.opts.logSampleInterval)
+	if !ok {
+		return
+	}
+	if suppressed > 0 {
+		keysAndValues = append(keysAndValues, "suppressed", suppressed)
+	}
+	e.log.Warn(msg, keysAndValues...)
+}
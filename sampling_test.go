@@ -0,0 +1,91 @@
+package leader
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger records the messages logged at info level and above.
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordingLogger) record(msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, strings.TrimSuffix(fmt.Sprintln(append([]interface{}{msg}, keysAndValues...)...), "\n"))
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues)
+}
+
+func (l *recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.record(msg, append(keysAndValues, "error", err))
+}
+
+func TestLogSampler(t *testing.T) {
+	var s logSampler
+	for i, step := range []struct {
+		key        string
+		sleep      time.Duration
+		allowed    bool
+		suppressed int
+	}{
+		{key: "a", allowed: true},
+		{key: "a"},
+		{key: "a"},
+		{key: "b", allowed: true},
+		{key: "a", sleep: 30 * time.Millisecond, allowed: true, suppressed: 2},
+		{key: "a"},
+	} {
+		time.Sleep(step.sleep)
+		suppressed, allowed := s.allow(step.key, 20*time.Millisecond)
+		if allowed != step.allowed || suppressed != step.suppressed {
+			t.Fatalf("step %d: allow(%s) = %d, %v, want %d, %v", i, step.key, suppressed, allowed, step.suppressed, step.allowed)
+		}
+	}
+}
+
+func TestInfoSampled(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval time.Duration
+		want     []string
+	}{
+		{
+			name:     "sampled",
+			interval: time.Hour,
+			want:     []string{"waiting leader pod-2", "waiting leader pod-3"},
+		},
+		{
+			name: "every repeat",
+			want: []string{"waiting leader pod-2", "waiting leader pod-2", "waiting leader pod-3"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			client := newTestClient(t, "pod-1")
+			e := newTestElector(t, client, "pod-1", WithLogger(logger), WithLogSampling(tc.interval))
+			logger.logs = nil
+
+			e.infoSampled("waiting", "leader", "pod-2")
+			e.infoSampled("waiting", "leader", "pod-2")
+			e.infoSampled("waiting", "leader", "pod-3")
+			if !reflect.DeepEqual(logger.logs, tc.want) {
+				t.Fatalf("logged %q, want %q", logger.logs, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	scalingRole = "scaling"
+
+	// ScalingUpdatedAnnotation records on the scaling hints ConfigMap when
+	// the leader last published them.
+	ScalingUpdatedAnnotation = "leader.seamounts.io/scaling-updated"
+
+	// ScalingLeaderAnnotation names the leader that published them.
+	ScalingLeaderAnnotation = "leader.seamounts.io/scaling-leader"
+)
+
+var scalingHintGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "scaling_hint",
+	Help:      "Scaling hint published by the leader, such as its backlog, for the HPA or KEDA to scale the standby pods by.",
+}, []string{"lock", "hint"})
+
+// ScalingHints are the hints the leader last published for the lock.
+type ScalingHints struct {
+	// Hints holds the value of each hint by its name.
+	Hints map[string]float64 `json:"hints"`
+	// Leader is the pod that published them.
+	Leader string `json:"leader,omitempty"`
+	// Updated is when they were published.
+	Updated time.Time `json:"updated"`
+}
+
+func (e *PodElector) scalingName() string {
+	return e.lockName + "-scaling"
+}
+
+// PublishScalingHints publishes hints, such as the depth of a queue only
+// the leader consumes, for autoscalers to scale the deployment by while
+// leadership stays single-active. They are exported as the
+// leader_scaling_hint gauge, for the Prometheus adapter of the HPA or the
+// Prometheus scaler of KEDA, and recorded in the lock's companion
+// ConfigMap, which ScalingHandler serves for the metrics-api scaler of KEDA
+// from any pod. Hints not named are left as they are. Only the leader may
+// publish; it needs get, create and patch on configmaps.
+func (e *PodElector) PublishScalingHints(ctx context.Context, hints map[string]float64) (err error) {
+	defer func() { err = e.wrap("publish scaling hints of", err) }()
+	if !e.IsLeader() {
+		return ErrNotLeader
+	}
+
+	data := map[string]string{}
+	e.mu.Lock()
+	for name, value := range hints {
+		data[name] = strconv.FormatFloat(value, 'g', -1, 64)
+		scalingHintGauge.WithLabelValues(e.lockName, name).Set(value)
+		e.exportedHints[name] = true
+	}
+	e.mu.Unlock()
+	annotations := map[string]string{
+		ScalingUpdatedAnnotation: time.Now().UTC().Format(time.RFC3339),
+		ScalingLeaderAnnotation:  e.owner.Name,
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+		"data":     data,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	_, err = configMaps.Patch(ctx, e.scalingName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) {
+		return forbidden(err, "patch", v1.Resource("configmaps"), e.ns)
+	}
+	// the companion has no owner, so hints outlive a change of leader
+	_, err = configMaps.Create(ctx, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      e.scalingName(),
+			Namespace: e.ns,
+			Labels: map[string]string{
+				LockLabel: e.lockName,
+				RoleLabel: scalingRole,
+			},
+			Annotations: annotations,
+		},
+		Data: data,
+	}, metav1.CreateOptions{FieldManager: FieldManager})
+	return forbidden(err, "create", v1.Resource("configmaps"), e.ns)
+}
+
+// unexportScalingHints is the transition hook removing the hints we
+// exported once we stop leading, so that autoscalers summing the gauge over
+// pods count the leader's hints only once.
+func (e *PodElector) unexportScalingHints(leading bool) {
+	if leading {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name := range e.exportedHints {
+		scalingHintGauge.DeleteLabelValues(e.lockName, name)
+	}
+	e.exportedHints = map[string]bool{}
+}
+
+// ScalingHints returns the hints last published for the lock, by whichever
+// pod led then.
+func (e *PodElector) ScalingHints(ctx context.Context) (_ ScalingHints, err error) {
+	defer func() { err = e.wrap("read scaling hints of", err) }()
+
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	cm, err := e.kube().CoreV1().ConfigMaps(e.ns).Get(ctx, e.scalingName(), metav1.GetOptions{})
+	if err != nil {
+		return ScalingHints{}, forbidden(err, "get", v1.Resource("configmaps"), e.ns)
+	}
+
+	s := ScalingHints{Hints: map[string]float64{}, Leader: cm.Annotations[ScalingLeaderAnnotation]}
+	s.Updated, _ = time.Parse(time.RFC3339, cm.Annotations[ScalingUpdatedAnnotation])
+	for name, value := range cm.Data {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			s.Hints[name] = v
+		}
+	}
+	return s, nil
+}
+
+// ScalingHandler returns an http.Handler serving the published hints as
+// JSON, such as {"hints":{"backlog":42},...}, for the metrics-api scaler of
+// KEDA with a valueLocation of hints.backlog. Every pod serves them, so it
+// can sit behind the deployment's Service. It answers 404 until hints have
+// been published.
+func (e *PodElector) ScalingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := e.ScalingHints(r.Context())
+		switch {
+		case apierrors.IsNotFound(err):
+			http.Error(w, "no scaling hints published", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	})
+}
@@ -0,0 +1,71 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPublishScalingHints(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, "pod-1", "pod-2")
+	leader := newTestElector(t, client, "pod-1")
+	follower := newTestElector(t, client, "pod-2")
+
+	if err := leader.PublishScalingHints(ctx, map[string]float64{"backlog": 1}); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("PublishScalingHints of a candidate = %v, want ErrNotLeader", err)
+	}
+	rec := httptest.NewRecorder()
+	follower.ScalingHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scaling", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("scaling hints before any were published = %d, want 404", rec.Code)
+	}
+
+	if ok, err := leader.TryAcquire(ctx); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	// the first publish creates the companion, the next patches it
+	for _, hints := range []map[string]float64{{"backlog": 42, "workers": 2}, {"workers": 3}} {
+		if err := leader.PublishScalingHints(ctx, hints); err != nil {
+			t.Fatalf("PublishScalingHints(%v): %v", hints, err)
+		}
+	}
+	if got := testutil.ToFloat64(scalingHintGauge.WithLabelValues(testLock, "workers")); got != 3 {
+		t.Fatalf("workers hint gauge = %v, want 3", got)
+	}
+
+	want := map[string]float64{"backlog": 42, "workers": 3}
+	s, err := follower.ScalingHints(ctx)
+	if err != nil {
+		t.Fatalf("ScalingHints: %v", err)
+	}
+	if !reflect.DeepEqual(s.Hints, want) || s.Leader != "pod-1" || s.Updated.IsZero() {
+		t.Fatalf("ScalingHints = %+v, want %v published by pod-1", s, want)
+	}
+	rec = httptest.NewRecorder()
+	follower.ScalingHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scaling", nil))
+	var served ScalingHints
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("scaling handler = %d, %v", rec.Code, err)
+	}
+	if !reflect.DeepEqual(served.Hints, want) {
+		t.Fatalf("scaling handler served %v, want %v", served.Hints, want)
+	}
+
+	// a former leader no longer exports the hints
+	if err := leader.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if scalingHintGauge.DeleteLabelValues(testLock, "backlog") {
+		t.Fatal("hints are still exported after Release")
+	}
+	if s, err := follower.ScalingHints(ctx); err != nil || !reflect.DeepEqual(s.Hints, want) {
+		t.Fatalf("ScalingHints after Release = %+v, %v, want them kept", s, err)
+	}
+}
@@ -0,0 +1,100 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// selfPodTTL bounds how long a lookup of our own pod is reused. Most of what
+// is read from it, such as its UID, node and IP, is fixed for the life of
+// the pod; the TTL bounds how stale its labels and conditions can be.
+const selfPodTTL = time.Second * 30
+
+// selfCache memoizes what every Elector of the process looks up about its
+// environment, so that a Manager, or an application calling Become for many
+// locks, does not re-read the namespace file and re-fetch its own pod for
+// every lock.
+var selfCache = struct {
+	mu         sync.Mutex
+	namespaces map[string]string
+	pods       map[selfPodKey]cachedPod
+}{
+	namespaces: map[string]string{},
+	pods:       map[selfPodKey]cachedPod{},
+}
+
+// clientID identifies a client in the caches shared by the Electors of the
+// process. Clients are interfaces whose dynamic type need not be comparable,
+// so they cannot key a map themselves. Each client injected with WithClient
+// gets one, as does each identity for the client built for it.
+type clientID struct {
+	// a clientID must not be zero-sized, or distinct ones could compare
+	// equal
+	_ byte
+}
+
+type selfPodKey struct {
+	client    *clientID
+	namespace string
+	name      string
+}
+
+type cachedPod struct {
+	pod     *v1.Pod
+	fetched time.Time
+}
+
+// cachedNamespace is getNamespace remembering the namespace read from each
+// path. A pod's namespace never changes.
+func cachedNamespace(path string) (string, error) {
+	selfCache.mu.Lock()
+	ns, ok := selfCache.namespaces[path]
+	selfCache.mu.Unlock()
+	if ok {
+		return ns, nil
+	}
+
+	ns, err := getNamespace(path)
+	if err != nil {
+		return "", err
+	}
+	selfCache.mu.Lock()
+	selfCache.namespaces[path] = ns
+	selfCache.mu.Unlock()
+	return ns, nil
+}
+
+// cachedMyPod is getMyPod reusing a lookup made with the client of id within
+// selfPodTTL. Callers get their own copy. A nil id, for a client built for a
+// single Elector, is not cached, and lookups that have expired are evicted
+// whenever another is stored.
+func cachedMyPod(ctx context.Context, id *clientID, client kubernetes.Interface, ns, podName string, logger Logger) (*v1.Pod, error) {
+	if id == nil {
+		return getMyPod(ctx, client, ns, podName, logger)
+	}
+	key := selfPodKey{client: id, namespace: ns, name: podName}
+	selfCache.mu.Lock()
+	cached, ok := selfCache.pods[key]
+	selfCache.mu.Unlock()
+	if ok && time.Since(cached.fetched) < selfPodTTL {
+		return cached.pod.DeepCopy(), nil
+	}
+
+	pod, err := getMyPod(ctx, client, ns, podName, logger)
+	if err != nil {
+		return nil, err
+	}
+	selfCache.mu.Lock()
+	for k, cached := range selfCache.pods {
+		if time.Since(cached.fetched) >= selfPodTTL {
+			delete(selfCache.pods, k)
+		}
+	}
+	selfCache.pods[key] = cachedPod{pod: pod.DeepCopy(), fetched: time.Now()}
+	selfCache.mu.Unlock()
+	return pod, nil
+}
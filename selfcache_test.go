@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// countGets counts the gets of pods on client.
+func countGets(client *fake.Clientset) func() int {
+	var mu sync.Mutex
+	gets := 0
+	client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gets++
+		return false, nil, nil
+	})
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return gets
+	}
+}
+
+func TestCachedMyPod(t *testing.T) {
+	shared := &clientID{}
+	for _, tc := range []struct {
+		name    string
+		ids     []*clientID
+		expired bool
+		gets    int
+	}{
+		{name: "same client", ids: []*clientID{shared, shared, shared}, gets: 1},
+		{name: "uncached client", ids: []*clientID{nil, nil}, gets: 2},
+		{name: "different clients", ids: []*clientID{{}, {}}, gets: 2},
+		{name: "expired", ids: []*clientID{shared, shared}, expired: true, gets: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			gets := countGets(client)
+			logger := NewLogger(ErrorLevel, false)
+			defer func() {
+				selfCache.mu.Lock()
+				defer selfCache.mu.Unlock()
+				for key := range selfCache.pods {
+					if key.namespace == testNamespace {
+						delete(selfCache.pods, key)
+					}
+				}
+			}()
+
+			for i, id := range tc.ids {
+				if tc.expired && i > 0 {
+					selfCache.mu.Lock()
+					key := selfPodKey{client: id, namespace: testNamespace, name: "pod-1"}
+					selfCache.pods[key] = cachedPod{pod: selfCache.pods[key].pod, fetched: time.Now().Add(-selfPodTTL)}
+					selfCache.mu.Unlock()
+				}
+				pod, err := cachedMyPod(context.Background(), id, client, testNamespace, "pod-1", logger)
+				if err != nil {
+					t.Fatalf("cachedMyPod: %v", err)
+				}
+				if pod.Name != "pod-1" || pod.Labels["changed"] != "" {
+					t.Fatalf("cachedMyPod = %s with labels %v, want an unchanged pod-1", pod.Name, pod.Labels)
+				}
+				// callers get their own copy
+				pod.Labels = map[string]string{"changed": "true"}
+			}
+			if got := gets(); got != tc.gets {
+				t.Fatalf("pod read %d times, want %d", got, tc.gets)
+			}
+		})
+	}
+}
+
+func TestCachedNamespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "namespace")
+	if err := ioutil.WriteFile(path, []byte("ns-1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if ns, err := cachedNamespace(path); err != nil || ns != "ns-1" {
+		t.Fatalf("cachedNamespace = %q, %v", ns, err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if ns, err := cachedNamespace(path); err != nil || ns != "ns-1" {
+		t.Fatalf("cachedNamespace after the file went = %q, %v, want it remembered", ns, err)
+	}
+	if _, err := cachedNamespace(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("cachedNamespace of a missing file succeeded")
+	}
+}
@@ -0,0 +1,261 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ShardWeightAnnotation records a candidate's weight on its registry
+	// entry, for ShardWeighted.
+	ShardWeightAnnotation = "leader.seamounts.io/shard-weight"
+
+	shardsRole = "shards"
+
+	// assignmentsKey is the key in the shards ConfigMap holding the
+	// assignment of every shard to a member.
+	assignmentsKey = "assignments"
+)
+
+// ShardStrategy decides how the leader spreads shards over the members of
+// the election.
+type ShardStrategy string
+
+const (
+	// ShardSticky only moves the shards of members that left, to the least
+	// loaded remaining members. It moves the fewest shards.
+	ShardSticky ShardStrategy = "Sticky"
+
+	// ShardEvenSpread moves shards so that every member owns the same
+	// number, give or take one.
+	ShardEvenSpread ShardStrategy = "EvenSpread"
+
+	// ShardWeighted spreads shards in proportion to the members' weights,
+	// set with WithShardWeight.
+	ShardWeighted ShardStrategy = "Weighted"
+)
+
+// shardState is what the leader remembers between rebalances.
+type shardState struct {
+	members    []string
+	rebalanced time.Time
+}
+
+func (e *PodElector) shardsName() string {
+	return e.lockName + "-shards"
+}
+
+// Shards returns the shards currently assigned to our pod.
+func (e *PodElector) Shards(ctx context.Context) (_ []int, err error) {
+	defer func() { err = e.wrap("get shards of", err) }()
+
+	assignments, _, err := e.getAssignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var mine []int
+	for shard, member := range assignments {
+		if member == e.owner.Name {
+			mine = append(mine, shard)
+		}
+	}
+	return mine, nil
+}
+
+func (e *PodElector) getAssignments(ctx context.Context) ([]string, *v1.ConfigMap, error) {
+	ctx, cancel := e.request(ctx)
+	defer cancel()
+	cm, err := e.kube().CoreV1().ConfigMaps(e.ns).Get(ctx, e.shardsName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, nil, nil
+	case err != nil:
+		return nil, nil, err
+	}
+	var assignments []string
+	if data := cm.Data[assignmentsKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &assignments); err != nil {
+			return nil, nil, err
+		}
+	}
+	return assignments, cm, nil
+}
+
+// rebalanceShards is run by the leader on every maintenance tick. When the
+// membership differs from the one the assignments were made for, and the
+// cooldown since the last rebalance has passed, it assigns the shards anew.
+// The cooldown keeps rolling updates, which change the membership pod by
+// pod, from moving shards over and over.
+func (e *PodElector) rebalanceShards(ctx context.Context) {
+	candidates, err := e.ListMembers(ctx)
+	if err != nil {
+		e.log.Error(err, "Failed to list members for sharding", "lock", e.lockName)
+		return
+	}
+
+	weights := map[string]int{e.owner.Name: e.opts.shardWeight}
+	for _, c := range candidates {
+		if c.Live && c.Name != "" {
+			weights[c.Name] = c.Weight
+		}
+	}
+	members := make([]string, 0, len(weights))
+	for name := range weights {
+		members = append(members, name)
+	}
+	sort.Strings(members)
+
+	if sameMembers(members, e.shards.members) {
+		return
+	}
+	if time.Since(e.shards.rebalanced) < e.opts.shardCooldown {
+		e.log.Debug("Membership changed, waiting for the cooldown to rebalance", "lock", e.lockName)
+		return
+	}
+
+	current, cm, err := e.getAssignments(ctx)
+	if err != nil {
+		e.log.Error(err, "Failed to get shard assignments", "lock", e.lockName)
+		return
+	}
+	assignments := assignShards(e.opts.shardStrategy, e.opts.shards, current, members, weights)
+	data, err := json.Marshal(assignments)
+	if err != nil {
+		e.log.Error(err, "Failed to encode shard assignments", "lock", e.lockName)
+		return
+	}
+
+	reqCtx, cancel := e.request(ctx)
+	defer cancel()
+	configMaps := e.kube().CoreV1().ConfigMaps(e.ns)
+	if cm == nil {
+		_, err = configMaps.Create(reqCtx, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      e.shardsName(),
+				Namespace: e.ns,
+				Labels: map[string]string{
+					LockLabel: e.lockName,
+					RoleLabel: shardsRole,
+				},
+			},
+			Data: map[string]string{assignmentsKey: string(data)},
+		}, metav1.CreateOptions{})
+	} else {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[assignmentsKey] = string(data)
+		_, err = configMaps.Update(reqCtx, cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		e.log.Error(err, "Failed to store shard assignments", "lock", e.lockName)
+		return
+	}
+
+	e.log.Info("Rebalanced shards", "lock", e.lockName, "members", len(members), "strategy", e.opts.shardStrategy)
+	e.shards = shardState{members: members, rebalanced: time.Now()}
+}
+
+// assignShards returns the owner of each of n shards among members.
+func assignShards(strategy ShardStrategy, n int, current, members []string, weights map[string]int) []string {
+	assignments := make([]string, n)
+	if len(members) == 0 {
+		return assignments
+	}
+
+	weight := func(member string) int {
+		if strategy != ShardWeighted || weights[member] < 1 {
+			return 1
+		}
+		return weights[member]
+	}
+	total := 0
+	for _, m := range members {
+		total += weight(m)
+	}
+	// quotas[m] is the most shards m should own: its share rounded down, with
+	// the shards left over going one each to the members with the largest
+	// remainders, and among equal remainders to the earlier members, so that
+	// the quotas add up to n
+	quotas := map[string]int{}
+	assigned := 0
+	for _, m := range members {
+		quotas[m] = n * weight(m) / total
+		assigned += quotas[m]
+	}
+	ranked := append([]string(nil), members...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return n*weight(ranked[i])%total > n*weight(ranked[j])%total
+	})
+	for _, m := range ranked[:n-assigned] {
+		quotas[m]++
+	}
+
+	load := map[string]int{}
+	isMember := map[string]bool{}
+	for _, m := range members {
+		isMember[m] = true
+	}
+	for shard := 0; shard < n && shard < len(current); shard++ {
+		owner := current[shard]
+		if !isMember[owner] {
+			continue
+		}
+		if strategy != ShardSticky && load[owner] >= quotas[owner] {
+			continue
+		}
+		assignments[shard] = owner
+		load[owner]++
+	}
+
+	for shard := range assignments {
+		if assignments[shard] != "" {
+			continue
+		}
+		// the member furthest below its share takes the shard, among those
+		// still below their quota if any
+		best := ""
+		for _, m := range members {
+			switch {
+			case best == "":
+				best = m
+			case (load[m] < quotas[m]) != (load[best] < quotas[best]):
+				if load[m] < quotas[m] {
+					best = m
+				}
+			case load[m]*weight(best) < load[best]*weight(m):
+				best = m
+			}
+		}
+		assignments[shard] = best
+		load[best]++
+	}
+	return assignments
+}
+
+func sameMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func candidateWeight(annotations map[string]string) int {
+	w, err := strconv.Atoi(annotations[ShardWeightAnnotation])
+	if err != nil || w < 1 {
+		return 1
+	}
+	return w
+}
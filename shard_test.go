@@ -0,0 +1,95 @@
+package leader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// shardCounts returns how many shards each member owns in assignments.
+func shardCounts(assignments []string) map[string]int {
+	counts := map[string]int{}
+	for _, owner := range assignments {
+		counts[owner]++
+	}
+	return counts
+}
+
+func TestAssignShardsSpreadsEvenly(t *testing.T) {
+	members := []string{"pod-1", "pod-2", "pod-3"}
+	before := assignShards(ShardEvenSpread, 10, nil, members, nil)
+	after := assignShards(ShardEvenSpread, 10, before, append(members, "pod-4"), nil)
+
+	counts := shardCounts(after)
+	for member, want := range map[string]int{"pod-1": 3, "pod-2": 3, "pod-3": 2, "pod-4": 2} {
+		if counts[member] != want {
+			t.Errorf("%s owns %d shards, want %d (assignments %v)", member, counts[member], want, after)
+		}
+	}
+	// members only give up shards they own beyond their quota
+	moved := 0
+	for shard := range after {
+		if after[shard] != before[shard] {
+			moved++
+		}
+	}
+	if moved != counts["pod-4"] {
+		t.Errorf("%d shards moved to give pod-4 its %d", moved, counts["pod-4"])
+	}
+}
+
+func TestAssignShardsWeighted(t *testing.T) {
+	members := []string{"pod-1", "pod-2"}
+	weights := map[string]int{"pod-1": 3, "pod-2": 1}
+	counts := shardCounts(assignShards(ShardWeighted, 8, nil, members, weights))
+	if counts["pod-1"] != 6 || counts["pod-2"] != 2 {
+		t.Fatalf("weights 3:1 split 8 shards as %v", counts)
+	}
+}
+
+func TestAssignShardsStickyKeepsOwners(t *testing.T) {
+	current := []string{"pod-1", "pod-1", "pod-1", "pod-2"}
+	after := assignShards(ShardSticky, 4, current, []string{"pod-1", "pod-2", "pod-3"}, nil)
+	for shard := range current {
+		if after[shard] != current[shard] {
+			t.Fatalf("sticky assignment moved shard %d from %s to %s", shard, current[shard], after[shard])
+		}
+	}
+}
+
+func TestAssignShardsDropsDepartedMembers(t *testing.T) {
+	current := []string{"pod-1", "pod-2", "pod-3", "pod-3"}
+	after := assignShards(ShardSticky, 4, current, []string{"pod-1", "pod-2"}, nil)
+	counts := shardCounts(after)
+	if counts["pod-3"] != 0 || counts["pod-1"] != 2 || counts["pod-2"] != 2 {
+		t.Fatalf("shards of pod-3 were handed out as %v", after)
+	}
+}
+
+func TestRebalanceShards(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1", WithSharding(4, ShardEvenSpread, 0))
+	e.rebalanceShards(context.Background())
+
+	shards, err := e.Shards(context.Background())
+	if err != nil {
+		t.Fatalf("Shards: %v", err)
+	}
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(shards, want) {
+		t.Fatalf("Shards of the only member = %v, want %v", shards, want)
+	}
+
+	// the assignments survive their members, the Janitor included
+	if err := client.CoreV1().Pods(testNamespace).Delete(context.Background(), "pod-1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	j := &Janitor{Client: client, Namespace: testNamespace, Log: e.log}
+	if deleted, err := j.Sweep(context.Background()); err != nil || len(deleted) != 0 {
+		t.Fatalf("Sweep = %v, %v, want the shards kept", deleted, err)
+	}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), e.shardsName(), metav1.GetOptions{}); err != nil {
+		t.Fatalf("shards ConfigMap: %v", err)
+	}
+}
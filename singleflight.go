@@ -0,0 +1,95 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flights holds the package-level elections of this process by lock, so
+// that concurrent Become calls for the same lock share one election instead
+// of racing two electors of the same pod for one lock.
+var flights = struct {
+	sync.Mutex
+	m map[string]*flight
+}{m: map[string]*flight{}}
+
+// flight is one election run on behalf of every caller waiting on it.
+type flight struct {
+	done chan struct{}
+	e    *PodElector
+	err  error
+}
+
+// flightKey identifies the lock an election with opts is for.
+func flightKey(lockName string, opts []Option) string {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.namespace + "/" + string(o.backend) + "/" + lockName
+}
+
+// becomeShared becomes the leader of lockName, joining the election already
+// running in this process for it, if any. Every caller of an election gets
+// its result, and once it succeeded later callers return at once for as
+// long as the process leads. Callers whose election was abandoned because
+// the context of the caller that started it was cancelled start over.
+// Elections for different locks are independent.
+func becomeShared(ctx context.Context, lockName string, opts []Option) error {
+	key := flightKey(lockName, opts)
+	for {
+		flights.Lock()
+		f, ok := flights.m[key]
+		if ok {
+			select {
+			case <-f.done:
+				if f.err == nil && f.e.IsLeader() {
+					flights.Unlock()
+					return nil
+				}
+				// a finished election that no longer leads is not joined
+				ok = false
+			default:
+			}
+		}
+		if !ok {
+			f = &flight{done: make(chan struct{})}
+			flights.m[key] = f
+			flights.Unlock()
+			return f.run(ctx, key, lockName, opts)
+		}
+		flights.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.done:
+		}
+		if !abandoned(f.err) {
+			return f.err
+		}
+	}
+}
+
+func (f *flight) run(ctx context.Context, key, lockName string, opts []Option) error {
+	f.e, f.err = NewElector(lockName, opts...)
+	if f.err == nil {
+		f.err = f.e.Become(ctx)
+	}
+	if f.err != nil {
+		flights.Lock()
+		if flights.m[key] == f {
+			delete(flights.m, key)
+		}
+		flights.Unlock()
+	}
+	close(f.done)
+	return f.err
+}
+
+// abandoned reports whether an election ended because the context of the
+// caller running it was cancelled.
+func abandoned(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
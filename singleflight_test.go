@@ -0,0 +1,108 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// countCreates counts the creates of the locks named in names on client.
+func countCreates(client *fake.Clientset, names ...string) func() int {
+	var mu sync.Mutex
+	creates := 0
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, lock := range names {
+			if name == lock {
+				creates++
+			}
+		}
+		return false, nil, nil
+	})
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return creates
+	}
+}
+
+func TestBecomeShared(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newTestClient(t, "pod-1")
+	creates := countCreates(client, "shared-lock", "other-lock")
+	opts := testOptions(client, "pod-1")
+
+	errs := make(chan error, 5)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- becomeShared(ctx, "shared-lock", opts) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("becomeShared: %v", err)
+		}
+	}
+	if got := creates(); got != 1 {
+		t.Fatalf("concurrent calls created %d locks, want one election", got)
+	}
+
+	// a won election is returned at once
+	if err := becomeShared(ctx, "shared-lock", opts); err != nil {
+		t.Fatalf("becomeShared while leading: %v", err)
+	}
+	if got := creates(); got != 1 {
+		t.Fatalf("becomeShared while leading created a lock")
+	}
+
+	// other locks run their own election
+	if err := becomeShared(ctx, "other-lock", opts); err != nil {
+		t.Fatalf("becomeShared of another lock: %v", err)
+	}
+	if got := creates(); got != 2 {
+		t.Fatalf("%d locks created, want one per lock", got)
+	}
+}
+
+func TestBecomeSharedAbandoned(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	holder := newTestElectorOf(t, client, "abandoned-lock", "pod-2")
+	if ok, err := holder.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	opts := testOptions(client, "pod-1")
+
+	// the first caller starts the election and gives up on it
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() { first <- becomeShared(firstCtx, "abandoned-lock", opts) }()
+	waitFor(t, "the election to start", func() bool {
+		flights.Lock()
+		defer flights.Unlock()
+		return flights.m[flightKey("abandoned-lock", opts)] != nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	second := make(chan error, 1)
+	go func() { second <- becomeShared(ctx, "abandoned-lock", opts) }()
+
+	firstCancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("becomeShared of the abandoning caller = %v, want Canceled", err)
+	}
+	if err := holder.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	// the caller that joined starts over rather than sharing the cancellation
+	if err := <-second; err != nil {
+		t.Fatalf("becomeShared of the joined caller: %v", err)
+	}
+}
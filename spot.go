@@ -0,0 +1,44 @@
+package leader
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// spotLabels maps node labels used by common providers and autoscalers to
+// the value that marks spot or preemptible capacity.
+var spotLabels = map[string]string{
+	"cloud.google.com/gke-spot":             "true",
+	"cloud.google.com/gke-preemptible":      "true",
+	"eks.amazonaws.com/capacityType":        "SPOT",
+	"karpenter.sh/capacity-type":            "spot",
+	"kubernetes.azure.com/scalesetpriority": "spot",
+	"node.kubernetes.io/lifecycle":          "spot",
+	"cloud.google.com/gke-provisioning":     "spot",
+}
+
+// spotTaints lists taint keys providers put on spot or preemptible nodes.
+var spotTaints = []string{
+	"cloud.google.com/gke-spot",
+	"cloud.google.com/gke-preemptible",
+	"kubernetes.azure.com/scalesetpriority",
+}
+
+// isSpotNode reports whether node is spot or preemptible capacity, judged
+// by well-known labels and taints.
+func isSpotNode(node *v1.Node) bool {
+	for key, want := range spotLabels {
+		if got, ok := node.Labels[key]; ok && strings.EqualFold(got, want) {
+			return true
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, key := range spotTaints {
+			if taint.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
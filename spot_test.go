@@ -0,0 +1,69 @@
+package leader
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsSpotNode(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		taints []v1.Taint
+		want   bool
+	}{
+		{name: "on-demand", labels: map[string]string{"eks.amazonaws.com/capacityType": "ON_DEMAND"}, want: false},
+		{name: "GKE spot", labels: map[string]string{"cloud.google.com/gke-spot": "true"}, want: true},
+		{name: "EKS spot", labels: map[string]string{"eks.amazonaws.com/capacityType": "SPOT"}, want: true},
+		{name: "label case", labels: map[string]string{"karpenter.sh/capacity-type": "Spot"}, want: true},
+		{name: "spot taint", taints: []v1.Taint{{Key: "kubernetes.azure.com/scalesetpriority", Value: "spot", Effect: v1.TaintEffectNoSchedule}}, want: true},
+		{name: "other taint", taints: []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectNoSchedule}}, want: false},
+		{name: "no labels", want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: tc.labels},
+				Spec:       v1.NodeSpec{Taints: tc.taints},
+			}
+			if got := isSpotNode(node); got != tc.want {
+				t.Fatalf("isSpotNode = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSpotCandidates(t *testing.T) {
+	spot := map[string]string{"cloud.google.com/gke-spot": "true"}
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		opts   []Option
+		weight float64
+		taken  bool
+	}{
+		{name: "spot penalty", labels: spot, opts: []Option{WithSpotPenalty(3)}, weight: 3, taken: true},
+		{name: "spot penalty on stable capacity", opts: []Option{WithSpotPenalty(3)}, weight: 1, taken: true},
+		{name: "spot excluded", labels: spot, opts: []Option{WithExcludeSpot()}, weight: 1, taken: false},
+		{name: "stable capacity with spot excluded", opts: []Option{WithExcludeSpot()}, weight: 1, taken: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			scheduleOn(t, client, "pod-1", &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: tc.labels}})
+			e := newTestElector(t, client, "pod-1", tc.opts...)
+
+			if got := e.topologyWeight(); got != tc.weight {
+				t.Fatalf("topologyWeight = %v, want %v", got, tc.weight)
+			}
+			ok, err := e.TryAcquire(context.Background())
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+			if ok != tc.taken {
+				t.Fatalf("TryAcquire = %v, want %v", ok, tc.taken)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LockStatus describes the state of a lock as recorded on its object.
+type LockStatus struct {
+	// Name is the name of the lock.
+	Name string
+	// Held is false if the lock is free.
+	Held bool
+	// Leader is the name of the pod holding the lock.
+	Leader string
+	// Address is the address the leader advertises, if any.
+	Address string
+	// Epoch is the term of the leader.
+	Epoch int64
+	// Since is when the lock was taken.
+	Since time.Time
+	// Uptime is how long the current leader has held the lock.
+	Uptime time.Duration
+	// Transitions is how many times the lock has been acquired. Every
+	// acquisition issues a new epoch, so it equals the epoch; compare it
+	// over time to spot flapping.
+	Transitions int64
+	// LastHeartbeat is the leader's last heartbeat, if it records them.
+	LastHeartbeat time.Time
+	// ExpiresAt is when the lock expires unless renewed, if it does.
+	ExpiresAt time.Time
+	// Successor is the pod leadership is being transferred to, if any.
+	Successor string
+	// Terminating is true if the lock is being deleted.
+	Terminating bool
+	// Backend is the kind of object the lock is, detected for AutoBackend.
+	Backend Backend
+	// Completed is when the work guarded by the lock was completed, if it
+	// was. A completed lock is not held.
+	Completed time.Time
+}
+
+// lockStatus returns the status recorded on lock.
+func lockStatus(lock metav1.Object) LockStatus {
+	s := LockStatus{
+		Name:        lock.GetName(),
+		Held:        true,
+		Epoch:       lockEpoch(lock),
+		Since:       lock.GetCreationTimestamp().Time,
+		Terminating: lock.GetDeletionTimestamp() != nil,
+	}
+	s.Uptime = time.Since(s.Since)
+	s.Transitions = s.Epoch
+	for _, owner := range lock.GetOwnerReferences() {
+		if owner.Kind == "Pod" {
+			s.Leader = owner.Name
+			break
+		}
+	}
+	if t, done := completedAt(lock); done {
+		s.Held = false
+		s.Completed = t
+	}
+	s.Address = lock.GetAnnotations()[AddressAnnotation]
+	s.Successor, _ = pendingTransfer(lock)
+	if t, err := time.Parse(time.RFC3339, lock.GetAnnotations()[LastHeartbeatAnnotation]); err == nil {
+		s.LastHeartbeat = t
+	}
+	s.ExpiresAt, _ = lockExpiry(lock)
+	return s
+}
+
+// Status resolves many locks in ns at once. It lists the lock objects by
+// label in a single call, rather than getting each one, which matters for
+// dashboards and tooling in clusters with hundreds of locks. Every name is
+// in the result; locks that do not exist are reported as not held. If names
+// is empty, every lock in ns is reported. b is the backend the electors use.
+func Status(ctx context.Context, client kubernetes.Interface, ns string, b Backend, names []string) (map[string]LockStatus, error) {
+	if b == AutoBackend {
+		var err error
+		if b, err = detectBackend(ctx, client, ns, defaultRequestTimeout, defaultLogger); err != nil {
+			return nil, err
+		}
+	}
+	if b == "" {
+		b = ConfigMapBackend
+	}
+
+	list := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{RoleLabel: LockRole}).String(),
+	}
+
+	var locks []metav1.Object
+	switch b {
+	case ConfigMapBackend:
+		configMaps, err := client.CoreV1().ConfigMaps(ns).List(ctx, list)
+		if err != nil {
+			return nil, fmt.Errorf("list locks in %s: %w", ns, err)
+		}
+		for i := range configMaps.Items {
+			locks = append(locks, &configMaps.Items[i])
+		}
+	case LeaseBackend, MigrationBackend:
+		leases, err := client.CoordinationV1().Leases(ns).List(ctx, list)
+		if err != nil {
+			return nil, fmt.Errorf("list locks in %s: %w", ns, err)
+		}
+		for i := range leases.Items {
+			locks = append(locks, &leases.Items[i])
+		}
+	default:
+		return nil, fmt.Errorf("unknown lock backend %q", b)
+	}
+
+	statuses := make(map[string]LockStatus, len(names))
+	for _, name := range names {
+		statuses[name] = LockStatus{Name: name, Backend: b}
+	}
+	for _, lock := range locks {
+		if _, ok := statuses[lock.GetName()]; ok || len(names) == 0 {
+			s := lockStatus(lock)
+			s.Backend = b
+			statuses[lock.GetName()] = s
+		}
+	}
+	return statuses, nil
+}
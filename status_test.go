@@ -0,0 +1,75 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		backend Backend
+		names   []string
+		want    map[string]string
+	}{
+		{
+			name:    "named locks",
+			backend: ConfigMapBackend,
+			names:   []string{"lock-a", "lock-c"},
+			want:    map[string]string{"lock-a": "pod-1", "lock-c": ""},
+		},
+		{
+			name:    "every lock",
+			backend: ConfigMapBackend,
+			want:    map[string]string{"lock-a": "pod-1", "lock-b": "pod-2"},
+		},
+		{
+			name:    "Leases",
+			backend: LeaseBackend,
+			names:   []string{"lock-b"},
+			want:    map[string]string{"lock-b": "pod-2"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1", "pod-2")
+			for lock, pod := range map[string]string{"lock-a": "pod-1", "lock-b": "pod-2"} {
+				e := newTestElectorOf(t, client, lock, pod, WithBackend(tc.backend))
+				if ok, err := e.TryAcquire(context.Background()); err != nil || !ok {
+					t.Fatalf("TryAcquire of %s = %v, %v", lock, ok, err)
+				}
+			}
+			var mu sync.Mutex
+			var calls []string
+			client.PrependReactor("*", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, action.GetVerb()+" "+action.GetResource().Resource)
+				return false, nil, nil
+			})
+
+			statuses, err := Status(context.Background(), client, testNamespace, tc.backend, tc.names)
+			if err != nil {
+				t.Fatalf("Status: %v", err)
+			}
+			if len(calls) != 1 {
+				t.Fatalf("Status made the calls %v, want a single list", calls)
+			}
+			if len(statuses) != len(tc.want) {
+				t.Fatalf("Status = %+v, want %v", statuses, tc.want)
+			}
+			for name, leader := range tc.want {
+				s, ok := statuses[name]
+				if !ok || s.Name != name || s.Leader != leader || s.Held != (leader != "") || s.Backend != tc.backend {
+					t.Fatalf("status of %s = %+v, want it led by %q", name, s, leader)
+				}
+				if s.Held && (s.Epoch == 0 || s.Since.IsZero() || s.Uptime <= 0 || s.Transitions != s.Epoch) {
+					t.Fatalf("status of held %s lacks its epoch, age or transitions: %+v", name, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package leader
+
+import "time"
+
+// subscriberBuffer is how many Events a subscriber may fall behind by before
+// further Events are dropped for it.
+const subscriberBuffer = 16
+
+// Event describes a change of leadership.
+type Event struct {
+	// Lock is the name of the lock.
+	Lock string
+	// Leading is true when leadership was gained and false when it was
+	// lost or given up.
+	Leading bool
+	// Epoch is the epoch of the term that started or ended.
+	Epoch int64
+	// Time is when the change was observed.
+	Time time.Time
+}
+
+// Subscribe returns a channel receiving an Event for every change of
+// leadership. The channel is buffered; a subscriber that falls too far
+// behind misses Events rather than stalling the election, and should call
+// IsLeader for the current state.
+func (e *PodElector) Subscribe() <-chan Event {
+	ch := make(chan Event, subscriberBuffer)
+	e.mu.Lock()
+	e.subscribers = append(e.subscribers, ch)
+	e.mu.Unlock()
+	return ch
+}
+
+func (e *PodElector) unsubscribe(sub <-chan Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, ch := range e.subscribers {
+		if ch == sub {
+			e.subscribers = append(e.subscribers[:i], e.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish is the transition hook delivering Events to subscribers.
+func (e *PodElector) publish(leading bool) {
+	ev := Event{
+		Lock:    e.lockName,
+		Leading: leading,
+		Epoch:   e.Epoch(),
+		Time:    time.Now(),
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, ch := range e.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			e.log.Warn("Subscriber is not keeping up, dropping event", "lock", e.lockName, "leading", leading)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package leader
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrNamespaceTerminating is returned when the lock cannot be created
+// because its namespace is being deleted. No pod can become the leader
+// there again, so retrying is pointless; this typically means the
+// application is being uninstalled and should exit.
+var ErrNamespaceTerminating = errors.New("namespace is terminating")
+
+// namespaceTerminating reports whether err is the Forbidden error the
+// apiserver returns for new objects in a terminating namespace.
+func namespaceTerminating(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+	details := status.Status().Details
+	if details == nil {
+		return false
+	}
+	for _, cause := range details.Causes {
+		if cause.Type == v1.NamespaceTerminatingCause {
+			return true
+		}
+	}
+	return false
+}
+
+// terminatingError returns ErrNamespaceTerminating, for ns, if err says
+// the namespace is terminating, and err unchanged otherwise.
+func terminatingError(err error, ns string) error {
+	if !namespaceTerminating(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %s: %v", ErrNamespaceTerminating, ns, err)
+}
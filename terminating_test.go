@@ -0,0 +1,47 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNamespaceTerminating(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		call func(e *PodElector, ctx context.Context) error
+	}{
+		{name: "Become", call: func(e *PodElector, ctx context.Context) error { return e.Become(ctx) }},
+		{
+			name: "TryAcquire",
+			call: func(e *PodElector, ctx context.Context) error {
+				_, err := e.TryAcquire(ctx)
+				return err
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, terminatingNamespaceError()
+			})
+			e := newTestElector(t, client, "pod-1")
+			// Become gives up rather than retrying until ctx is done
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err := tc.call(e, ctx)
+			if !errors.Is(err, ErrNamespaceTerminating) {
+				t.Fatalf("%s = %v, want ErrNamespaceTerminating", tc.name, err)
+			}
+			var fe *ForbiddenError
+			if errors.As(err, &fe) {
+				t.Fatalf("%s = %v, reported as a missing permission", tc.name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package leader
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ZoneAnnotation records the topology zone of the leader's node.
+	ZoneAnnotation = "leader.seamounts.io/zone"
+
+	zoneLabel           = "topology.kubernetes.io/zone"
+	deprecatedZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+)
+
+// nodeZone returns the zone label of node.
+func nodeZone(node *v1.Node) string {
+	if zone, ok := node.Labels[zoneLabel]; ok {
+		return zone
+	}
+	return node.Labels[deprecatedZoneLabel]
+}
+
+// topologyWeight returns the factor to stretch our backoff by, given the
+// configured topology preferences.
+func (e *PodElector) topologyWeight() float64 {
+	weight := 1.0
+	if e.virtual {
+		return weight
+	}
+	if e.opts.preferredZone != "" && e.zone != e.opts.preferredZone {
+		weight *= e.opts.topologyWeight
+	}
+	if e.opts.avoidLeaderZone && e.zone != "" && e.zone == e.leaderZone {
+		weight *= e.opts.topologyWeight
+	}
+	if e.spot {
+		weight *= e.opts.spotWeight
+	}
+	return weight
+}
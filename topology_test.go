@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// scheduleOn creates node, unless it exists, and schedules pod on it.
+func scheduleOn(t *testing.T, client *fake.Clientset, pod string, node *v1.Node) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := client.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		t.Fatal(err)
+	}
+	p, err := client.CoreV1().Pods(testNamespace).Get(ctx, pod, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Spec.NodeName = node.Name
+	if _, err := client.CoreV1().Pods(testNamespace).Update(ctx, p, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// zonedNode returns a node in zone.
+func zonedNode(name, zone string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{zoneLabel: zone}}}
+}
+
+func TestNodeZone(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "zone label", labels: map[string]string{zoneLabel: "a"}, want: "a"},
+		{name: "deprecated label", labels: map[string]string{deprecatedZoneLabel: "b"}, want: "b"},
+		{name: "both labels", labels: map[string]string{zoneLabel: "a", deprecatedZoneLabel: "b"}, want: "a"},
+		{name: "no zone", want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: tc.labels}}
+			if got := nodeZone(node); got != tc.want {
+				t.Fatalf("nodeZone = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTopologyWeight(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		opts       []Option
+		leaderZone string
+		want       float64
+	}{
+		{name: "no preferences", want: 1},
+		{name: "in the preferred zone", opts: []Option{WithPreferredZone("a")}, want: 1},
+		{name: "outside the preferred zone", opts: []Option{WithPreferredZone("b")}, want: 2},
+		{name: "custom weight", opts: []Option{WithPreferredZone("b"), WithTopologyWeight(5)}, want: 5},
+		{name: "in the leader's zone", opts: []Option{WithAvoidLeaderZone()}, leaderZone: "a", want: 2},
+		{name: "outside the leader's zone", opts: []Option{WithAvoidLeaderZone()}, leaderZone: "b", want: 1},
+		{name: "both preferences missed", opts: []Option{WithPreferredZone("b"), WithAvoidLeaderZone()}, leaderZone: "a", want: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			scheduleOn(t, client, "pod-1", zonedNode("node-1", "a"))
+			e := newTestElector(t, client, "pod-1", tc.opts...)
+			e.leaderZone = tc.leaderZone
+
+			if got := e.topologyWeight(); got != tc.want {
+				t.Fatalf("topologyWeight = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestZoneAnnotation(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	scheduleOn(t, client, "pod-1", zonedNode("node-1", "a"))
+	leader := newTestElector(t, client, "pod-1", WithAvoidLeaderZone())
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	lock, err := client.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), testLock, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lock.Annotations[ZoneAnnotation]; got != "a" {
+		t.Fatalf("zone annotation = %q, want the leader's zone", got)
+	}
+}
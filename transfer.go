@@ -0,0 +1,115 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// TransferToAnnotation is set on the lock by the leader to name the pod
+	// it intends to hand leadership to.
+	TransferToAnnotation = "leader.seamounts.io/transfer-to"
+
+	// TransferAckAnnotation is set on the lock by the named successor once
+	// it is ready to take over.
+	TransferAckAnnotation = "leader.seamounts.io/transfer-ack"
+)
+
+// ErrTransferTimeout is returned by TransferTo when the successor does not
+// acknowledge the handoff in time. The caller remains the leader.
+var ErrTransferTimeout = errors.New("leadership transfer was not acknowledged")
+
+// TransferTo cooperatively hands leadership to the pod named successor. It
+// records the intent on the lock, waits for the successor to acknowledge
+// that it is ready, and then releases the lock. Other candidates that see
+// the intent defer to the successor for the transfer timeout, so the
+// successor acquires the lock without racing the rest of the fleet.
+func (e *PodElector) TransferTo(ctx context.Context, successor string) (err error) {
+	defer func() { err = e.wrap("transfer", err) }()
+
+	if !e.IsLeader() {
+		return ErrNotLeader
+	}
+	if successor == e.owner.Name {
+		return fmt.Errorf("cannot transfer leadership to the current leader %s", successor)
+	}
+
+	err = e.patchLockAnnotations(ctx, map[string]interface{}{
+		TransferToAnnotation:  successor,
+		TransferAckAnnotation: nil,
+	})
+	if err != nil {
+		return err
+	}
+	e.log.Info("Transferring leadership", "lock", e.lockName, "successor", successor)
+	e.event(v1.EventTypeNormal, "TransferringLeadership", "Transferring %s to %s", e.lockName, successor)
+
+	deadline := time.Now().Add(e.tuned().transferTimeout)
+	for {
+		lock, err := e.getLock(ctx)
+		if err != nil {
+			e.abortTransfer()
+			return err
+		}
+		if lock.GetAnnotations()[TransferAckAnnotation] == successor {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			e.log.Warn("Successor did not acknowledge the transfer, remaining the leader", "lock", e.lockName, "successor", successor)
+			e.abortTransfer()
+			return ErrTransferTimeout
+		}
+		if err := e.sleep(ctx, e.opts.transferPollInterval); err != nil {
+			e.abortTransfer()
+			return err
+		}
+	}
+
+	e.log.Info("Successor is ready, releasing the lock", "lock", e.lockName, "successor", successor)
+	e.drainWork(ctx)
+	if err := e.release(ctx); err != nil {
+		e.undrain()
+		return err
+	}
+	return nil
+}
+
+// abortTransfer clears the transfer intent. It does not take a context as it
+// also runs after the caller's context has been cancelled.
+func (e *PodElector) abortTransfer() {
+	err := e.patchLockAnnotations(context.Background(), map[string]interface{}{
+		TransferToAnnotation:  nil,
+		TransferAckAnnotation: nil,
+	})
+	if err != nil {
+		e.log.Error(err, "Failed to clear transfer intent", "lock", e.lockName)
+	}
+	if e.opts.drainer != nil {
+		e.opts.drainer.reopen()
+	}
+}
+
+// acknowledgeTransfer tells the leader that we, the named successor, are
+// ready to take over.
+func (e *PodElector) acknowledgeTransfer(ctx context.Context, lock metav1.Object) error {
+	if lock.GetAnnotations()[TransferAckAnnotation] == e.owner.Name {
+		return nil
+	}
+
+	e.log.Info("Leadership is being transferred to me, acknowledging", "lock", e.lockName)
+	return e.annotateLock(ctx, lock, map[string]interface{}{
+		TransferAckAnnotation: e.owner.Name,
+	})
+}
+
+// pendingTransfer returns the successor named on the lock, if any.
+func pendingTransfer(lock metav1.Object) (string, bool) {
+	successor, ok := lock.GetAnnotations()[TransferToAnnotation]
+	return successor, ok && successor != ""
+}
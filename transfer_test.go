@@ -0,0 +1,102 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestTransferTo(t *testing.T) {
+	client := newTestClient(t, "pod-1", "pod-2")
+	leader := newTestElector(t, client, "pod-1")
+	successor := newTestElector(t, client, "pod-2")
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	acked := make(chan error, 1)
+	go func() {
+		// play the part of the successor's election loop
+		for ctx.Err() == nil {
+			lock, err := successor.getLock(ctx)
+			if err != nil {
+				acked <- err
+				return
+			}
+			if target, ok := pendingTransfer(lock); ok && target == "pod-2" {
+				acked <- successor.acknowledgeTransfer(ctx, lock)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		acked <- ctx.Err()
+	}()
+
+	if err := leader.TransferTo(ctx, "pod-2"); err != nil {
+		t.Fatalf("TransferTo: %v", err)
+	}
+	if err := <-acked; err != nil {
+		t.Fatalf("acknowledge transfer: %v", err)
+	}
+	if leader.IsLeader() {
+		t.Fatal("still the leader after the transfer")
+	}
+	if owner := lockOwner(t, client); owner != "" {
+		t.Fatalf("lock is still owned by %q after the transfer", owner)
+	}
+	if ok, err := successor.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("successor's TryAcquire = %v, %v", ok, err)
+	}
+}
+
+func TestTransferToTimesOut(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	leader := newTestElector(t, client, "pod-1", WithTransferTimeout(50*time.Millisecond))
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+
+	if err := leader.TransferTo(context.Background(), "pod-2"); !errors.Is(err, ErrTransferTimeout) {
+		t.Fatalf("TransferTo to an absent successor = %v, want ErrTransferTimeout", err)
+	}
+	if !leader.IsLeader() {
+		t.Fatal("lost leadership after an unacknowledged transfer")
+	}
+	lock, err := leader.getLock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pendingTransfer(lock); ok {
+		t.Fatal("transfer intent left on the lock")
+	}
+}
+
+func TestTransferToClearsIntentOnError(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	leader := newTestElector(t, client, "pod-1")
+	if ok, err := leader.TryAcquire(context.Background()); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(errors.New("etcd is unavailable"))
+	})
+
+	if err := leader.TransferTo(context.Background(), "pod-2"); !apierrors.IsInternalError(err) {
+		t.Fatalf("TransferTo with an unreadable lock = %v, want the read error", err)
+	}
+	obj, err := client.Tracker().Get(v1.SchemeGroupVersion.WithResource("configmaps"), testNamespace, testLock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pendingTransfer(obj.(*v1.ConfigMap)); ok {
+		t.Fatal("transfer intent left on the lock")
+	}
+}
@@ -0,0 +1,63 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// transientReason classifies err as a brief failure of the apiserver or of
+// the connection to it, which the election loop retries rather than treats
+// as fatal. It returns "" for any other error.
+func transientReason(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection_reset"
+	case strings.Contains(err.Error(), "TLS handshake timeout"):
+		return "tls_handshake_timeout"
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err):
+		return "timeout"
+	case apierrors.IsTooManyRequests(err):
+		return "throttled"
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code >= 500 {
+		return "server_error"
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	return ""
+}
+
+// retryable reports whether the election loop should back off and retry
+// after err, rather than return it. Cancellation of ctx is never retried.
+func (e *PodElector) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	reason := transientReason(err)
+	if reason == "" && apierrors.IsUnauthorized(err) && e.reloadCredentials() {
+		reason = "unauthorized"
+	}
+	if reason == "" {
+		return false
+	}
+	e.holdOff(err)
+	e.log.Warn("Transient error talking to the apiserver, retrying", "lock", e.lockName, "reason", reason, "error", err)
+	transientErrorsCounter.WithLabelValues(e.lockName, reason).Inc()
+	return true
+}
@@ -0,0 +1,65 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestTransientReason(t *testing.T) {
+	configmaps := schema.GroupResource{Resource: "configmaps"}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "no error", err: nil, want: ""},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, want: "connection_refused"},
+		{name: "connection reset", err: fmt.Errorf("get lock: %w", syscall.ECONNRESET), want: "connection_reset"},
+		{name: "TLS handshake timeout", err: errors.New("net/http: TLS handshake timeout"), want: "tls_handshake_timeout"},
+		{name: "deadline exceeded", err: fmt.Errorf("get lock: %w", context.DeadlineExceeded), want: "timeout"},
+		{name: "server timeout", err: apierrors.NewServerTimeout(configmaps, "get", 1), want: "timeout"},
+		{name: "throttled", err: apierrors.NewTooManyRequests("slow down", 1), want: "throttled"},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("etcd is unavailable")), want: "server_error"},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("starting"), want: "server_error"},
+		{name: "not found", err: apierrors.NewNotFound(configmaps, testLock), want: ""},
+		{name: "forbidden", err: apierrors.NewForbidden(configmaps, testLock, errors.New("denied")), want: ""},
+		{name: "other", err: errors.New("bad lock"), want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := transientReason(tc.err); got != tc.want {
+				t.Fatalf("transientReason(%v) = %q, want %q", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	client := newTestClient(t, "pod-1")
+	e := newTestElector(t, client, "pod-1")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "transient", ctx: context.Background(), err: apierrors.NewInternalError(errors.New("etcd is unavailable")), want: true},
+		{name: "permanent", ctx: context.Background(), err: errors.New("bad lock"), want: false},
+		{name: "transient after cancellation", ctx: cancelled, err: apierrors.NewInternalError(errors.New("etcd is unavailable")), want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := e.retryable(tc.ctx, tc.err); got != tc.want {
+				t.Fatalf("retryable = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
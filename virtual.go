@@ -0,0 +1,73 @@
+package leader
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// VirtualNodeMode says whether pods are taken to run on virtual kubelet
+// providers, such as Fargate or ACI, where Node objects do not stand for
+// real machines. On a virtual node, node topology and capacity labels are
+// ignored, cordons and taints are not taken as a sign our pod is going away,
+// and the state of a node shared by many pods is not taken as the state of
+// the leader's pod.
+type VirtualNodeMode string
+
+const (
+	// VirtualNodesAuto detects virtual nodes from the pod. It is the
+	// default.
+	VirtualNodesAuto VirtualNodeMode = ""
+
+	// VirtualNodesAlways treats every node as virtual, for providers that
+	// cannot be detected.
+	VirtualNodesAlways VirtualNodeMode = "Always"
+
+	// VirtualNodesNever treats every node as a real machine.
+	VirtualNodesNever VirtualNodeMode = "Never"
+)
+
+const (
+	// virtualKubeletTaint is the taint virtual kubelet providers put on
+	// their nodes, which their pods must tolerate.
+	virtualKubeletTaint = "virtual-kubelet.io/provider"
+
+	// fargateProfileLabel is set on pods scheduled to Fargate, which runs
+	// every pod on a node of its own.
+	fargateProfileLabel = "eks.amazonaws.com/fargate-profile"
+	fargateNodePrefix   = "fargate-"
+)
+
+// virtual reports whether o treats pod as running on a virtual node.
+func (o *options) virtual(pod *v1.Pod) bool {
+	switch o.virtualNodes {
+	case VirtualNodesAlways:
+		return true
+	case VirtualNodesNever:
+		return false
+	}
+	return dedicatedVirtualNode(pod) || tolerates(pod, virtualKubeletTaint)
+}
+
+// dedicatedVirtualNode reports whether pod runs on a virtual node of its
+// own, whose fate is then that of the pod.
+func dedicatedVirtualNode(pod *v1.Pod) bool {
+	_, fargate := pod.Labels[fargateProfileLabel]
+	return fargate || strings.HasPrefix(pod.Spec.NodeName, fargateNodePrefix)
+}
+
+// nodeReflectsPod reports whether the state of pod's node tells us about
+// pod, which it does not for a virtual node shared with other pods: such a
+// node goes unreachable when the provider's kubelet does, not the pod.
+func (o *options) nodeReflectsPod(pod *v1.Pod) bool {
+	return !o.virtual(pod) || dedicatedVirtualNode(pod)
+}
+
+func tolerates(pod *v1.Pod, key string) bool {
+	for _, toleration := range pod.Spec.Tolerations {
+		if toleration.Key == key {
+			return true
+		}
+	}
+	return false
+}
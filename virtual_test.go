@@ -0,0 +1,39 @@
+package leader
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVirtualNodes(t *testing.T) {
+	tolerating := v1.PodSpec{NodeName: "vk-1", Tolerations: []v1.Toleration{{Key: virtualKubeletTaint, Operator: v1.TolerationOpExists}}}
+	for _, tc := range []struct {
+		name     string
+		mode     VirtualNodeMode
+		labels   map[string]string
+		spec     v1.PodSpec
+		virtual  bool
+		reflects bool
+	}{
+		{name: "real node", spec: v1.PodSpec{NodeName: "node-a"}, reflects: true},
+		{name: "virtual kubelet", spec: tolerating, virtual: true},
+		{name: "fargate label", labels: map[string]string{fargateProfileLabel: "default"}, spec: v1.PodSpec{NodeName: "ip-10-0-0-1"}, virtual: true, reflects: true},
+		{name: "fargate node", spec: v1.PodSpec{NodeName: "fargate-ip-10-0-0-1"}, virtual: true, reflects: true},
+		{name: "always", mode: VirtualNodesAlways, spec: v1.PodSpec{NodeName: "node-a"}, virtual: true},
+		{name: "never", mode: VirtualNodesNever, spec: tolerating, reflects: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := defaultOptions()
+			WithVirtualNodes(tc.mode)(&o)
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Labels: tc.labels}, Spec: tc.spec}
+			if got := o.virtual(pod); got != tc.virtual {
+				t.Errorf("virtual = %v, want %v", got, tc.virtual)
+			}
+			if got := o.nodeReflectsPod(pod); got != tc.reflects {
+				t.Errorf("nodeReflectsPod = %v, want %v", got, tc.reflects)
+			}
+		})
+	}
+}
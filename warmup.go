@@ -0,0 +1,32 @@
+package leader
+
+import (
+	"context"
+)
+
+// warmUp runs the warm-up hook, if any, once we hold the lock but before
+// leadership is announced to hooks, subscribers or the caller of Become. If
+// it fails, the lock is given back unannounced and the hook's error is
+// returned.
+func (e *PodElector) warmUp(ctx context.Context) error {
+	if e.opts.warmUp == nil {
+		return nil
+	}
+	err := e.opts.warmUp(withEpoch(ctx, e.Epoch()))
+	if err == nil {
+		return nil
+	}
+
+	e.log.Error(err, "Warm-up failed, giving the lock back", "lock", e.lockName)
+	e.giveBack()
+	return err
+}
+
+// giveBack drops the lock we just took, before leadership was announced. It
+// does not take a context as the caller's may be why we give it back.
+func (e *PodElector) giveBack() {
+	if err := e.dropLock(context.Background()); err != nil {
+		e.log.Error(err, "Failed to give the lock back", "lock", e.lockName)
+		e.clearLeading()
+	}
+}
@@ -0,0 +1,50 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWarmUp(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		warmUp  error
+		leading bool
+	}{
+		{name: "warmed up", leading: true},
+		{name: "failed", warmUp: errors.New("cache not loaded"), leading: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, "pod-1")
+			var e *PodElector
+			e = newTestElector(t, client, "pod-1", WithLeaderLabel("leader", "true"), WithWarmUp(func(ctx context.Context) error {
+				if epoch, ok := EpochFromContext(ctx); !ok || epoch != e.Epoch() {
+					t.Errorf("warm-up epoch = %v, %v, want %v", epoch, ok, e.Epoch())
+				}
+				// leadership is not announced yet
+				pod, err := client.CoreV1().Pods(testNamespace).Get(ctx, "pod-1", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, labeled := pod.Labels["leader"]; labeled {
+					t.Error("pod labeled as the leader before warming up")
+				}
+				return tc.warmUp
+			}))
+
+			ok, err := e.TryAcquire(context.Background())
+			if !errors.Is(err, tc.warmUp) {
+				t.Fatalf("TryAcquire = %v, want %v", err, tc.warmUp)
+			}
+			if ok != tc.leading || e.IsLeader() != tc.leading {
+				t.Fatalf("TryAcquire = %v and IsLeader = %v, want %v", ok, e.IsLeader(), tc.leading)
+			}
+			if owner := lockOwner(t, client); (owner == "pod-1") != tc.leading {
+				t.Fatalf("lock owner = %q", owner)
+			}
+		})
+	}
+}
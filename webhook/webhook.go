@@ -0,0 +1,255 @@
+// Package webhook provides a validating admission webhook that protects
+// leader lock objects from being modified or deleted by anyone other than
+// the pods taking part in the election and the garbage collector.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	leader "github.com/seamounts/k8s-leader"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// garbageCollectorUsers may always delete locks, since that is how a lock is
+// released when its owner pod goes away.
+var garbageCollectorUsers = []string{
+	"system:serviceaccount:kube-system:generic-garbage-collector",
+	"system:kube-controller-manager",
+}
+
+// Handler reviews UPDATE and DELETE requests for lock objects. A request is
+// allowed if it comes from the service account of the pod owning the lock,
+// from the garbage collector, or from one of AllowedUsers. Requests for
+// objects that are not labeled as locks, or whose owner pod no longer
+// exists, are always allowed. Requests the Handler cannot decide, because
+// looking up the object or its owner pod failed, are allowed too unless
+// FailClosed is set.
+type Handler struct {
+	// Client is used to look up the service account of a lock's owner pod.
+	Client kubernetes.Interface
+
+	// AllowedUsers may additionally modify and delete locks, for example
+	// a break-glass admin account.
+	AllowedUsers []string
+
+	// FailClosed denies requests whose object or owner pod cannot be looked
+	// up, rather than allowing them. The lock's owner then cannot release
+	// it either while the apiserver is unavailable to the Handler.
+	FailClosed bool
+
+	// Log receives the handler's logs. It defaults to the package's
+	// default logger.
+	Log leader.Logger
+}
+
+func (h *Handler) logger() leader.Logger {
+	if h.Log == nil {
+		return leader.NewLogger(leader.InfoLevel, false)
+	}
+	return h.Log
+}
+
+// ServeHTTP implements http.Handler for AdmissionReview requests.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review := &admissionv1beta1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "malformed AdmissionReview", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = h.Review(r.Context(), review.Request)
+	review.Response.UID = review.Request.UID
+
+	out, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// Review decides a single admission request. ctx bounds the API lookups it
+// makes.
+func (h *Handler) Review(ctx context.Context, req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Operation != admissionv1beta1.Update && req.Operation != admissionv1beta1.Delete {
+		return allow()
+	}
+
+	meta, err := h.existingMeta(ctx, req)
+	if err != nil {
+		h.logger().Error(err, "Failed to read object under review", "resource", req.Resource.Resource, "namespace", req.Namespace, "name", req.Name)
+		return h.undecided(err)
+	}
+	if meta == nil || meta.Labels[leader.RoleLabel] != leader.LockRole {
+		return allow()
+	}
+
+	for _, user := range append(garbageCollectorUsers, h.AllowedUsers...) {
+		if req.UserInfo.Username == user {
+			return allow()
+		}
+	}
+
+	refs := meta.GetOwnerReferences()
+	if len(refs) != 1 || refs[0].Kind != "Pod" {
+		return allow()
+	}
+
+	pod, err := h.Client.CoreV1().Pods(req.Namespace).Get(ctx, refs[0].Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// the lock is orphaned, let anyone clean it up
+		return allow()
+	case err != nil:
+		h.logger().Error(err, "Failed to get owner pod of lock", "namespace", req.Namespace, "pod", refs[0].Name, "lock", req.Name)
+		return h.undecided(err)
+	}
+
+	sa := pod.Spec.ServiceAccountName
+	if sa == "" {
+		sa = "default"
+	}
+	if req.UserInfo.Username == fmt.Sprintf("system:serviceaccount:%s:%s", req.Namespace, sa) {
+		return allow()
+	}
+
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonForbidden,
+			Code:    http.StatusForbidden,
+			Message: fmt.Sprintf("%s is a leader lock held by pod %s; only its service account %s may modify it", req.Name, pod.Name, sa),
+		},
+	}
+}
+
+// existingMeta returns the metadata of the object as it was before the
+// request. Older API servers do not send the old object on DELETE, in which
+// case it is read from the API.
+func (h *Handler) existingMeta(ctx context.Context, req *admissionv1beta1.AdmissionRequest) (*metav1.ObjectMeta, error) {
+	if len(req.OldObject.Raw) > 0 {
+		obj := struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		}{}
+		if err := json.Unmarshal(req.OldObject.Raw, &obj); err != nil {
+			return nil, err
+		}
+		return &obj.Metadata, nil
+	}
+
+	var (
+		obj metav1.Object
+		err error
+	)
+	switch req.Resource.Resource {
+	case "configmaps":
+		obj, err = h.Client.CoreV1().ConfigMaps(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+	case "leases":
+		obj, err = h.Client.CoordinationV1().Leases(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+	default:
+		return nil, nil
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &metav1.ObjectMeta{
+		Labels:          obj.GetLabels(),
+		OwnerReferences: obj.GetOwnerReferences(),
+	}, nil
+}
+
+func allow() *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+// undecided answers a request that could not be reviewed because of err.
+func (h *Handler) undecided(err error) *admissionv1beta1.AdmissionResponse {
+	if !h.FailClosed {
+		return allow()
+	}
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonServiceUnavailable,
+			Code:    http.StatusServiceUnavailable,
+			Message: fmt.Sprintf("cannot review leader lock: %v", err),
+		},
+	}
+}
+
+// Configuration returns a ValidatingWebhookConfiguration routing UPDATE and
+// DELETE of lock ConfigMaps and Leases to the Handler served by service at
+// path. An object selector on the lock role label keeps every other
+// ConfigMap and Lease away from the webhook. The failure policy is Ignore,
+// so an unavailable webhook never blocks the election itself.
+func Configuration(name string, service admissionregistrationv1beta1.ServiceReference, path string, caBundle []byte) *admissionregistrationv1beta1.ValidatingWebhookConfiguration {
+	service.Path = &path
+	failurePolicy := admissionregistrationv1beta1.Ignore
+	sideEffects := admissionregistrationv1beta1.SideEffectClassNone
+	timeout := int32(5)
+
+	return &admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admissionregistration.k8s.io/v1beta1",
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1beta1.ValidatingWebhook{{
+			Name: name,
+			ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
+				Service:  &service,
+				CABundle: caBundle,
+			},
+			Rules: []admissionregistrationv1beta1.RuleWithOperations{
+				{
+					Operations: []admissionregistrationv1beta1.OperationType{
+						admissionregistrationv1beta1.Update,
+						admissionregistrationv1beta1.Delete,
+					},
+					Rule: admissionregistrationv1beta1.Rule{
+						APIGroups:   []string{""},
+						APIVersions: []string{"v1"},
+						Resources:   []string{"configmaps"},
+					},
+				},
+				{
+					Operations: []admissionregistrationv1beta1.OperationType{
+						admissionregistrationv1beta1.Update,
+						admissionregistrationv1beta1.Delete,
+					},
+					Rule: admissionregistrationv1beta1.Rule{
+						APIGroups:   []string{"coordination.k8s.io"},
+						APIVersions: []string{"v1"},
+						Resources:   []string{"leases"},
+					},
+				},
+			},
+			ObjectSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{leader.RoleLabel: leader.LockRole},
+			},
+			FailurePolicy:  &failurePolicy,
+			SideEffects:    &sideEffects,
+			TimeoutSeconds: &timeout,
+		}},
+	}
+}
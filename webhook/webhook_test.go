@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	leader "github.com/seamounts/k8s-leader"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "test"
+
+// testLock returns a lock ConfigMap owned by the pod named owner.
+func testLock(owner string) *v1.ConfigMap {
+	return &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:            "my-lock",
+		Namespace:       testNamespace,
+		Labels:          map[string]string{leader.RoleLabel: leader.LockRole},
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: owner, UID: "owner-uid"}},
+	}}
+}
+
+func raw(t *testing.T, obj interface{}) runtime.RawExtension {
+	t.Helper()
+	out, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: out}
+}
+
+func TestReview(t *testing.T) {
+	owner := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: testNamespace},
+		Spec:       v1.PodSpec{ServiceAccountName: "elector"},
+	}
+	ownerSA := "system:serviceaccount:test:elector"
+	notLock := testLock("pod-1")
+	notLock.Labels = nil
+
+	for _, tc := range []struct {
+		name       string
+		op         admissionv1beta1.Operation
+		user       string
+		old        interface{}
+		stored     *v1.ConfigMap
+		noPod      bool
+		podErr     bool
+		failClosed bool
+		allowed    bool
+		code       int32
+	}{
+		{name: "create", op: admissionv1beta1.Create, user: "mallory", allowed: true},
+		{name: "not a lock", op: admissionv1beta1.Update, user: "mallory", old: notLock, allowed: true},
+		{name: "owner", op: admissionv1beta1.Update, user: ownerSA, old: testLock("pod-1"), allowed: true},
+		{name: "someone else", op: admissionv1beta1.Update, user: "mallory", old: testLock("pod-1"), code: http.StatusForbidden},
+		{name: "garbage collector", op: admissionv1beta1.Delete, user: garbageCollectorUsers[0], old: testLock("pod-1"), allowed: true},
+		{name: "allowed user", op: admissionv1beta1.Delete, user: "admin", old: testLock("pod-1"), allowed: true},
+		{name: "orphaned lock", op: admissionv1beta1.Delete, user: "mallory", old: testLock("pod-1"), noPod: true, allowed: true},
+		{name: "delete without old object", op: admissionv1beta1.Delete, user: "mallory", stored: testLock("pod-1"), code: http.StatusForbidden},
+		{name: "delete of a lock gone", op: admissionv1beta1.Delete, user: "mallory", allowed: true},
+		{name: "owner lookup failed", op: admissionv1beta1.Update, user: "mallory", old: testLock("pod-1"), podErr: true, allowed: true},
+		{name: "owner lookup failed closed", op: admissionv1beta1.Update, user: "mallory", old: testLock("pod-1"), podErr: true, failClosed: true, code: http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if !tc.noPod {
+				client.Tracker().Add(owner)
+			}
+			if tc.stored != nil {
+				client.Tracker().Add(tc.stored)
+			}
+			if tc.podErr {
+				client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewInternalError(errors.New("etcd is unavailable"))
+				})
+			}
+			h := &Handler{
+				Client:       client,
+				AllowedUsers: []string{"admin"},
+				FailClosed:   tc.failClosed,
+				Log:          leader.NewLogger(leader.ErrorLevel, false),
+			}
+			req := &admissionv1beta1.AdmissionRequest{
+				Operation: tc.op,
+				Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+				Namespace: testNamespace,
+				Name:      "my-lock",
+				UserInfo:  authenticationv1.UserInfo{Username: tc.user},
+			}
+			if tc.old != nil {
+				req.OldObject = raw(t, tc.old)
+			}
+
+			resp := h.Review(context.Background(), req)
+			if resp.Allowed != tc.allowed {
+				t.Fatalf("Allowed = %v, want %v (%+v)", resp.Allowed, tc.allowed, resp.Result)
+			}
+			if !tc.allowed && resp.Result.Code != tc.code {
+				t.Fatalf("denied with %d, want %d", resp.Result.Code, tc.code)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	h := &Handler{Client: fake.NewSimpleClientset(), Log: leader.NewLogger(leader.ErrorLevel, false)}
+	review := &admissionv1beta1.AdmissionReview{Request: &admissionv1beta1.AdmissionRequest{
+		UID:       "review-1",
+		Operation: admissionv1beta1.Update,
+		Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		Namespace: testNamespace,
+		Name:      "my-lock",
+		UserInfo:  authenticationv1.UserInfo{Username: "mallory"},
+		OldObject: raw(t, testLock("pod-1")),
+	}}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var out admissionv1beta1.AdmissionReview
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	// the owner pod is gone, so the lock may be cleaned up
+	if out.Response == nil || out.Response.UID != "review-1" || !out.Response.Allowed {
+		t.Fatalf("response = %+v", out.Response)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader([]byte("{}"))))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status of a review without request = %d", w.Code)
+	}
+}
+
+func TestConfiguration(t *testing.T) {
+	service := admissionregistrationv1beta1.ServiceReference{Namespace: testNamespace, Name: "lock-guard"}
+	conf := Configuration("lock-guard", service, "/validate", nil)
+	webhook := conf.Webhooks[0]
+	if *webhook.ClientConfig.Service.Path != "/validate" {
+		t.Fatalf("path = %s", *webhook.ClientConfig.Service.Path)
+	}
+	if webhook.ObjectSelector.MatchLabels[leader.RoleLabel] != leader.LockRole {
+		t.Fatalf("object selector = %v, want lock objects only", webhook.ObjectSelector)
+	}
+}